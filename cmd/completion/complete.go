@@ -0,0 +1,182 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package completion
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	tools "github.com/MottainaiCI/mottainai-cli/common"
+	client "github.com/MottainaiCI/mottainai-server/pkg/client"
+	nodes "github.com/MottainaiCI/mottainai-server/pkg/nodes"
+	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
+	storage "github.com/MottainaiCI/mottainai-server/pkg/storage"
+	citasks "github.com/MottainaiCI/mottainai-server/pkg/tasks"
+	schema "github.com/MottainaiCI/mottainai-server/routes/schema"
+	v1 "github.com/MottainaiCI/mottainai-server/routes/schema/v1"
+	cobra "github.com/spf13/cobra"
+	pflag "github.com/spf13/pflag"
+	viper "github.com/spf13/viper"
+)
+
+// Tasks completed, the most recent page of the master.
+const completeTasks = 100
+
+func NewCompleteCommand(config *setting.Config) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:    "__complete [word...] <current-word>",
+		Short:  "Print the completion candidates of a command line",
+		Hidden: true,
+		// The flags belong to the completed command line.
+		DisableFlagParsing: true,
+		// The profile is loaded after parsing the completed command line.
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {},
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) == 0 {
+				return
+			}
+			current := args[len(args)-1]
+			for _, c := range Candidates(config, cmd.Root(), args[:len(args)-1], current) {
+				fmt.Println(c)
+			}
+		},
+	}
+
+	return cmd
+}
+
+// Candidates returns the values starting with current for the word
+// following words: the flags, the subcommands or the identifiers of
+// the resource expected by the command.
+func Candidates(config *setting.Config, root *cobra.Command, words []string, current string) []string {
+	var ans []string
+	for _, c := range candidates(config, root, words, current) {
+		if strings.HasPrefix(c, current) {
+			ans = append(ans, c)
+		}
+	}
+	return ans
+}
+
+func candidates(config *setting.Config, root *cobra.Command, words []string, current string) []string {
+	target, rest, err := root.Find(words)
+	if err != nil {
+		return nil
+	}
+	if strings.HasPrefix(current, "-") {
+		return flagNames(target)
+	}
+
+	target.FParseErrWhitelist.UnknownFlags = true
+	if err := target.ParseFlags(rest); err != nil {
+		return nil
+	}
+	// The value of a flag is left to the shell ( e.g. file names ).
+	if n := len(rest); n > 0 && tools.FlagTakesValue(target.Flags(), rest[n-1]) {
+		return nil
+	}
+
+	args := target.Flags().Args()
+	if target.HasAvailableSubCommands() && len(args) == 0 {
+		var ans []string
+		for _, c := range target.Commands() {
+			if c.IsAvailableCommand() {
+				ans = append(ans, c.Name())
+			}
+		}
+		return ans
+	}
+
+	kind := tools.CompletionKind(target.Use, len(args))
+	if kind == "" {
+		return nil
+	}
+	// Same profile and transport of the completed command.
+	root.PersistentPreRun(target, args)
+
+	var v *viper.Viper = config.Viper
+	fetch := func() ([]string, error) {
+		fetcher := client.NewTokenClient(v.GetString("master"), v.GetString("apikey"), config)
+		return identifiers(fetcher, kind)
+	}
+	key := strings.Join([]string{v.GetString("master"), v.GetString("apikey"), kind}, "\n")
+	ans, err := tools.NewCompletionCache().Get(key, fetch)
+	if err != nil {
+		return nil
+	}
+	return ans
+}
+
+func flagNames(cmd *cobra.Command) []string {
+	var ans []string
+	add := func(f *pflag.Flag) {
+		if !f.Hidden {
+			ans = append(ans, "--"+f.Name)
+		}
+	}
+	cmd.LocalFlags().VisitAll(add)
+	cmd.InheritedFlags().VisitAll(add)
+	sort.Strings(ans)
+	return ans
+}
+
+// identifiers returns the identifiers of the resources of kind.
+func identifiers(fetcher client.HttpClient, kind string) ([]string, error) {
+	var ans []string
+
+	switch kind {
+	case tools.COMPLETE_TASK:
+		var tlist []citasks.Task
+		pager := tools.NewPager(fetcher, v1.Schema.GetTaskRoute("show_all"), 1, completeTasks)
+		if _, err := pager.Next(&tlist); err != nil {
+			return nil, err
+		}
+		for _, t := range tlist {
+			ans = append(ans, t.ID)
+		}
+	case tools.COMPLETE_NODE:
+		var nlist []nodes.Node
+		req := schema.Request{Route: v1.Schema.GetNodeRoute("show_all"), Target: &nlist}
+		if err := fetcher.Handle(req); err != nil {
+			return nil, err
+		}
+		for _, n := range nlist {
+			ans = append(ans, n.ID)
+		}
+	case tools.COMPLETE_NAMESPACE:
+		req := schema.Request{Route: v1.Schema.GetNamespaceRoute("show_all"), Target: &ans}
+		if err := fetcher.Handle(req); err != nil {
+			return nil, err
+		}
+	case tools.COMPLETE_STORAGE:
+		var slist []storage.Storage
+		req := schema.Request{Route: v1.Schema.GetStorageRoute("show_all"), Target: &slist}
+		if err := fetcher.Handle(req); err != nil {
+			return nil, err
+		}
+		for _, s := range slist {
+			ans = append(ans, s.ID)
+		}
+	}
+
+	return ans, nil
+}
@@ -0,0 +1,96 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package completion
+
+import (
+	"fmt"
+
+	tools "github.com/MottainaiCI/mottainai-cli/common"
+	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
+	cobra "github.com/spf13/cobra"
+)
+
+// Scripts of the supported shells, every completion is resolved
+// by the hidden __complete command.
+var scripts = map[string]string{
+	"bash": `# bash completion for mottainai-cli
+_mottainai_cli() {
+    local cur=${COMP_WORDS[COMP_CWORD]}
+    local IFS=$'\n'
+    COMPREPLY=( $(mottainai-cli __complete "${COMP_WORDS[@]:1:COMP_CWORD-1}" "$cur" 2>/dev/null) )
+}
+complete -o default -F _mottainai_cli mottainai-cli
+`,
+	"zsh": `#compdef mottainai-cli
+# zsh completion for mottainai-cli
+_mottainai_cli() {
+    local -a candidates
+    candidates=(${(f)"$(mottainai-cli __complete "${(@)words[2,CURRENT-1]}" "${words[CURRENT]}" 2>/dev/null)"})
+    if (( ${#candidates} )); then
+        compadd -a candidates
+    else
+        _files
+    fi
+}
+compdef _mottainai_cli mottainai-cli
+`,
+	"fish": `# fish completion for mottainai-cli
+function __mottainai_cli_complete
+    set -l words (commandline -opc)
+    set -l out (mottainai-cli __complete $words[2..-1] (commandline -ct) 2>/dev/null)
+    if test (count $out) -eq 0
+        __fish_complete_path (commandline -ct)
+    else
+        printf '%s\n' $out
+    end
+end
+complete -c mottainai-cli -f -a '(__mottainai_cli_complete)'
+`,
+}
+
+func NewCompletionCommand(config *setting.Config) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "completion <bash|zsh|fish>",
+		Short: "Generate the shell completion script",
+		Long: `Generate the completion script of bash, zsh or fish.
+
+Besides commands and flags, the identifiers of tasks, nodes,
+namespaces and storages are completed reading them from the master
+of the active profile. They are cached for 30 seconds.
+
+$> source <(mottainai-cli completion bash)
+$> mottainai-cli completion zsh > "${fpath[1]}/_mottainai-cli"
+$> mottainai-cli completion fish > ~/.config/fish/completions/mottainai-cli.fish`,
+		ValidArgs: []string{"bash", "zsh", "fish"},
+		Args:      cobra.ExactArgs(1),
+		// The script doesn't depend on the profile.
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {},
+		Run: func(cmd *cobra.Command, args []string) {
+			script, ok := scripts[args[0]]
+			if !ok {
+				tools.CheckError(tools.ValidationError("Invalid shell %s, valid shells are: bash, zsh, fish", args[0]))
+			}
+			fmt.Print(script)
+		},
+	}
+
+	return cmd
+}
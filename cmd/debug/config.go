@@ -52,7 +52,7 @@ func newDebugConfigCommand(config *setting.Config) *cobra.Command {
 			fmt.Println(fmt.Sprintf("  profile: %s", config.Viper.GetString("profile")))
 			fmt.Println(fmt.Sprintf("  master:  %s", config.Viper.GetString("master")))
 			if config.Viper.GetBool("all") {
-				fmt.Println("\n")
+				fmt.Print("\n\n")
 				fmt.Println(fmt.Sprintf("%s\n%s\n",
 					title("Mottainai Agent Options:"), config.Agent.String()))
 				fmt.Println(fmt.Sprintf("%s\n%s\n",
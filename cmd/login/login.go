@@ -0,0 +1,305 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package login
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	tools "github.com/MottainaiCI/mottainai-cli/common"
+	client "github.com/MottainaiCI/mottainai-server/pkg/client"
+	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
+	token "github.com/MottainaiCI/mottainai-server/pkg/token"
+	schema "github.com/MottainaiCI/mottainai-server/routes/schema"
+	v1 "github.com/MottainaiCI/mottainai-server/routes/schema/v1"
+	cobra "github.com/spf13/cobra"
+	viper "github.com/spf13/viper"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// Name of the profile created when none is active.
+const defaultProfile = "default"
+
+func NewLoginCommand(config *setting.Config) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "login [OPTIONS]",
+		Short: "Sign in on the master and store a new API token",
+		Long: `Sign in on the master with username and password and store
+a new API token on the active profile, or on its credential store
+( e.g. the keyring ).
+
+The profile selected with --profile, or the active one, is updated
+with the token and with the --master url when supplied. Without
+profiles a new profile named "default" is created and activated.
+
+The password is read from the terminal without echo, or as a line
+of stdin:
+
+$> mottainai-cli login -m https://mottainai.example.com -u admin
+$> echo $PASSWORD | mottainai-cli login -u admin
+
+With --sso the sign in is done on the external identity provider of
+the master with the OAuth2 device flow: open the printed url, enter
+the code and the token of the provider is stored on the profile,
+with the "sso" auth mode. The provider is remembered for the next
+sign in:
+
+$> mottainai-cli login --sso --issuer https://idp.example.com --client-id mottainai-cli`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			var v *viper.Viper = config.Viper
+
+			if sso, _ := cmd.Flags().GetBool("sso"); sso {
+				loginSSO(cmd, config)
+				return
+			}
+
+			username, err := cmd.Flags().GetString("username")
+			tools.CheckError(err)
+			if username == "" {
+				username, err = readUsername()
+				tools.CheckError(err)
+			}
+			password, err := tools.ReadPassword("Password: ")
+			tools.CheckError(err)
+			if password == "" {
+				tools.CheckError(tools.ValidationError("You need to define a password"))
+			}
+
+			master := v.GetString("master")
+			jar, err := tools.SessionLogin(master+config.GetWeb().BuildURI(""), username, password)
+			tools.CheckError(err)
+
+			// The token is created with the session of the user.
+			fetcher := client.NewClient(master, config)
+			if f, ok := fetcher.(*client.Fetcher); ok {
+				f.Jar = &jar
+			}
+			res, err := fetcher.TokenCreate()
+			tools.CheckError(err)
+			if len(res.Error) > 0 || len(res.ID) == 0 {
+				tools.PrintResponse(res)
+				tools.CheckError(errors.New("Token creation failed"))
+			}
+
+			// Test API call with the new key before saving it.
+			check := client.NewTokenClient(master, "", config)
+			check.SetToken(findKey(fetcher, res.ID))
+			t, err := findToken(check, res.ID)
+			tools.CheckError(err)
+
+			name, f := saveProfile(cmd, v, master, t.Key, nil)
+
+			fmt.Printf("Logged in as %s on %s.\n", whoami(check, t.UserId, username), master)
+			fmt.Printf("Profile %s updated on file %s with token %s.\n", name, f, t.ID)
+		},
+	}
+
+	var flags = cmd.Flags()
+	flags.StringP("username", "u", "", "Username, prompted when not supplied")
+	flags.Bool("sso", false, "Sign in on the external identity provider of the master")
+	flags.String("issuer", "", "URL of the identity provider, with --sso")
+	flags.String("client-id", "", "OAuth2 client id registered on the identity provider, with --sso")
+	flags.StringSlice("scope", []string{"openid"}, "OAuth2 scopes requested, with --sso")
+
+	return cmd
+}
+
+// loginSSO signs in with the OAuth2 device authorization flow on the
+// identity provider and stores its token on the profile.
+func loginSSO(cmd *cobra.Command, config *setting.Config) {
+	var v *viper.Viper = config.Viper
+
+	if cmd.Flag("username").Changed {
+		tools.CheckError(tools.ValidationError("--username can't be used with --sso"))
+	}
+	issuer, err := cmd.Flags().GetString("issuer")
+	tools.CheckError(err)
+	clientID, err := cmd.Flags().GetString("client-id")
+	tools.CheckError(err)
+	scopes, err := cmd.Flags().GetStringSlice("scope")
+	tools.CheckError(err)
+
+	// The identity provider of a previous sign in is used again.
+	conf, name := loadProfiles(v)
+	if p, _ := conf.GetProfile(name); p != nil {
+		if issuer == "" {
+			issuer = p.SSOIssuer
+		}
+		if clientID == "" {
+			clientID = p.SSOClientID
+		}
+	}
+	if issuer == "" {
+		tools.CheckError(tools.ValidationError("You need to define the identity provider with --issuer"))
+	}
+	if clientID == "" {
+		tools.CheckError(tools.ValidationError("You need to define the OAuth2 client id with --client-id"))
+	}
+
+	flow := tools.NewDeviceFlow(issuer, clientID, scopes)
+	tools.CheckError(flow.Discover())
+	auth, err := flow.Authorize()
+	tools.CheckError(err)
+
+	fmt.Printf("To sign in, open %s and enter the code %s\n", auth.VerificationURI, auth.UserCode)
+	if auth.VerificationURIComplete != "" {
+		fmt.Printf("or open %s\n", auth.VerificationURIComplete)
+	}
+	fmt.Println("Waiting for the sign in...")
+	t, err := flow.Poll(tools.CommandContext(), auth)
+	tools.CheckError(err)
+
+	// Test API call with the new token before saving it.
+	master := v.GetString("master")
+	if v.GetString("auth") != tools.AUTH_SSO {
+		http.DefaultTransport = &tools.BearerTransport{Next: http.DefaultTransport}
+	}
+	check := client.NewTokenClient(master, t.AccessToken, config)
+	if _, err := listTokens(check); err != nil {
+		tools.CheckError(&tools.CLIError{Code: tools.EXIT_AUTH,
+			Err: errors.New("The token of " + issuer + " isn't accepted by the master: " + err.Error())})
+	}
+
+	name, f := saveProfile(cmd, v, master, t.AccessToken, func(p *tools.Profile) {
+		p.Auth = tools.AUTH_SSO
+		p.SSOIssuer = issuer
+		p.SSOClientID = clientID
+	})
+
+	fmt.Printf("Logged in on %s with the identity provider %s.\n", master, issuer)
+	fmt.Printf("Profile %s updated on file %s.\n", name, f)
+}
+
+// loadProfiles returns the profiles and the name of the profile to
+// update: the one selected with --profile, the active one or "default".
+func loadProfiles(v *viper.Viper) (tools.ProfileConf, string) {
+	var conf tools.ProfileConf
+
+	if v.Get("profiles") != nil {
+		tools.CheckError(v.Unmarshal(&conf))
+	} else {
+		conf = *tools.NewProfileConf()
+	}
+	name := v.GetString("profile")
+	if name == "" {
+		name = conf.GetCurrent()
+	}
+	if name == "" {
+		name = defaultProfile
+	}
+	return conf, name
+}
+
+// saveProfile stores key on the profile, creating it when missing,
+// and returns the name of the profile and of the file written.
+// The profile is changed with update before saving it.
+func saveProfile(cmd *cobra.Command, v *viper.Viper, master, key string, update func(p *tools.Profile)) (string, string) {
+	conf, name := loadProfiles(v)
+
+	if p, _ := conf.GetProfile(name); p == nil {
+		tools.CheckError(conf.AddProfile(name, master, "", ""))
+	} else if cmd.Flag("master").Changed {
+		p.Master = master
+		conf.Profiles[name] = *p
+	}
+	if update != nil {
+		p, _ := conf.GetProfile(name)
+		update(p)
+		conf.Profiles[name] = *p
+	}
+	tools.CheckError(conf.SaveApiKey(name, key))
+	if conf.GetCurrent() == "" {
+		tools.CheckError(conf.SetCurrent(name))
+	}
+	f, err := conf.Write(v.ConfigFileUsed())
+	tools.CheckError(err)
+	return name, f
+}
+
+func readUsername() (string, error) {
+	if !terminal.IsTerminal(int(os.Stdin.Fd())) {
+		return "", tools.ValidationError("You need to define a username with --username")
+	}
+	fmt.Fprint(os.Stderr, "Username: ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return "", tools.ValidationError("You need to define a username")
+	}
+	return line, nil
+}
+
+func listTokens(fetcher client.HttpClient) ([]token.Token, error) {
+	var tlist []token.Token
+	req := schema.Request{
+		Route:  v1.Schema.GetTokenRoute("show"),
+		Target: &tlist,
+	}
+	err := fetcher.Handle(req)
+	return tlist, err
+}
+
+// findKey returns the key of the token id, exposed only by the list.
+func findKey(fetcher client.HttpClient, id string) string {
+	tlist, _ := listTokens(fetcher)
+	for _, t := range tlist {
+		if t.ID == id {
+			return t.Key
+		}
+	}
+	return ""
+}
+
+// findToken checks that the token id is accepted by the master.
+func findToken(fetcher client.HttpClient, id string) (*token.Token, error) {
+	tlist, err := listTokens(fetcher)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tlist {
+		if t.ID == id {
+			return &t, nil
+		}
+	}
+	return nil, &tools.CLIError{Code: tools.EXIT_AUTH,
+		Err: errors.New("The new token " + id + " isn't accepted by the master")}
+}
+
+// whoami returns the name of the user of the token, or the fallback
+// when the user isn't visible to the token.
+func whoami(fetcher client.HttpClient, id, fallback string) string {
+	if id == "" {
+		return fallback
+	}
+	u, err := showUser(fetcher, id)
+	if err != nil || u.Name == "" {
+		return fallback
+	}
+	return u.Name
+}
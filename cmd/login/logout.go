@@ -0,0 +1,146 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package login
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	tools "github.com/MottainaiCI/mottainai-cli/common"
+	client "github.com/MottainaiCI/mottainai-server/pkg/client"
+	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
+	cobra "github.com/spf13/cobra"
+	viper "github.com/spf13/viper"
+)
+
+func NewLogoutCommand(config *setting.Config) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "logout [OPTIONS]",
+		Short: "Revoke and remove the API token of the profile",
+		Long: `Revoke the API token of the active profile on the master and
+remove it from the profiles file and from the credential store
+( e.g. the keyring ). The other settings of the profile are kept.
+
+The profile selected with --profile, or the active one, is logged out.
+With --all-profiles all the profiles are logged out and the stored
+macaroons are removed too.
+
+Tokens of an external identity provider ( auth sso ) are only removed
+locally. When the master can't be reached the token is removed anyway.
+
+$> mottainai-cli logout
+$> mottainai-cli logout --all-profiles`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			var v *viper.Viper = config.Viper
+
+			all, err := cmd.Flags().GetBool("all-profiles")
+			tools.CheckError(err)
+
+			if v.Get("profiles") == nil {
+				fmt.Println("No profiles to log out.")
+				return
+			}
+			conf, name := loadProfiles(v)
+
+			var names []string
+			if all {
+				for n := range conf.Profiles {
+					names = append(names, n)
+				}
+				sort.Strings(names)
+			} else {
+				if p, _ := conf.GetProfile(name); p == nil {
+					tools.CheckError(tools.NotFoundError("profile", name))
+				}
+				names = []string{name}
+			}
+
+			removeMacaroons := all
+			for _, n := range names {
+				p := conf.Profiles[n]
+				if p.Auth == tools.AUTH_MACAROON {
+					removeMacaroons = true
+				}
+
+				key, err := p.ResolveApiKey(n)
+				if err != nil {
+					fmt.Printf("Error on read api-key of profile %s: %s\n", n, err)
+				}
+				if key == "" {
+					fmt.Printf("Profile %s has no token.\n", n)
+				} else if p.Auth == tools.AUTH_SSO {
+					fmt.Printf("Token of profile %s issued by %s is removed without revoking it.\n", n, p.SSOIssuer)
+				} else if id, err := revokeToken(p.GetMaster(), key, config); err != nil {
+					fmt.Printf("Token of profile %s not revoked on %s: %s\n", n, p.GetMaster(), err)
+				} else {
+					fmt.Printf("Token %s of profile %s revoked on %s.\n", id, n, p.GetMaster())
+				}
+
+				if err := conf.DeleteApiKey(n); err != nil {
+					fmt.Println("Error on remove api-key from credential store: ", err)
+				}
+			}
+
+			if removeMacaroons {
+				err := os.Remove(filepath.Join(tools.CacheDir(), tools.MCLI_MACAROON_FILE))
+				if err != nil && !os.IsNotExist(err) {
+					fmt.Println("Error on remove macaroons: ", err)
+				}
+			}
+
+			f, err := conf.Write(v.ConfigFileUsed())
+			tools.CheckError(err)
+			fmt.Printf("Logged out from %d profile(s), file %s updated.\n", len(names), f)
+		},
+	}
+
+	var flags = cmd.Flags()
+	flags.Bool("all-profiles", false, "Log out from all the profiles")
+
+	return cmd
+}
+
+// revokeToken deletes the token with key on master and returns its id.
+func revokeToken(master, key string, config *setting.Config) (string, error) {
+	fetcher := client.NewTokenClient(master, key, config)
+	tlist, err := listTokens(fetcher)
+	if err != nil {
+		return "", err
+	}
+	for _, t := range tlist {
+		if t.Key != key {
+			continue
+		}
+		res, err := fetcher.TokenDelete(t.ID)
+		if err != nil {
+			return "", err
+		}
+		if len(res.Error) > 0 {
+			return "", errors.New(res.Error)
+		}
+		return t.ID, nil
+	}
+	return "", errors.New("The token isn't visible to its user")
+}
@@ -0,0 +1,139 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package login
+
+import (
+	"errors"
+	"strings"
+
+	tools "github.com/MottainaiCI/mottainai-cli/common"
+	client "github.com/MottainaiCI/mottainai-server/pkg/client"
+	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
+	user "github.com/MottainaiCI/mottainai-server/pkg/user"
+	schema "github.com/MottainaiCI/mottainai-server/routes/schema"
+	v1 "github.com/MottainaiCI/mottainai-server/routes/schema/v1"
+	cobra "github.com/spf13/cobra"
+	viper "github.com/spf13/viper"
+)
+
+// Identity describes the user and the master used by the commands.
+type Identity struct {
+	Profile string   `json:"profile,omitempty"`
+	Master  string   `json:"master"`
+	Auth    string   `json:"auth"`
+	UserID  string   `json:"user_id,omitempty"`
+	User    string   `json:"user,omitempty"`
+	Email   string   `json:"email,omitempty"`
+	Roles   []string `json:"roles"`
+	TokenID string   `json:"token_id,omitempty"`
+	Scopes  []string `json:"scopes"`
+}
+
+func NewWhoamiCommand(config *setting.Config) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "whoami [OPTIONS]",
+		Short: "Show the authenticated user and the master in use",
+		Long: `Show the user authenticated by the API token, its roles and the
+token, with the master and the profile used by the commands.
+
+Tokens of the master aren't restricted to a scope: they grant all
+the permissions of the user, reported as the "all" scope.
+
+$> mottainai-cli whoami
+$> mottainai-cli whoami -o json --profile prod`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			var v *viper.Viper = config.Viper
+
+			id := Identity{
+				Master: v.GetString("master"),
+				Auth:   v.GetString("auth"),
+				Roles:  []string{},
+				Scopes: []string{},
+			}
+			if id.Auth == "" {
+				id.Auth = tools.AUTH_TOKEN
+			}
+			if v.Get("profiles") != nil {
+				_, id.Profile = loadProfiles(v)
+			}
+
+			key := v.GetString("apikey")
+			if key == "" && id.Auth != tools.AUTH_MACAROON {
+				tools.CheckError(&tools.CLIError{Code: tools.EXIT_AUTH,
+					Err: errors.New("No API token available, sign in with the login command")})
+			}
+
+			fetcher := client.NewTokenClient(id.Master, key, config)
+			tlist, err := listTokens(fetcher)
+			tools.CheckError(err)
+			for _, t := range tlist {
+				if key != "" && t.Key == key {
+					id.TokenID, id.UserID = t.ID, t.UserId
+					id.Scopes = []string{"all"}
+				}
+			}
+
+			if id.UserID != "" {
+				u, err := showUser(fetcher, id.UserID)
+				tools.CheckError(err)
+				id.User, id.Email = u.Name, u.Email
+				if u.IsAdmin() {
+					id.Roles = append(id.Roles, "admin")
+				}
+				if u.IsManager() {
+					id.Roles = append(id.Roles, "manager")
+				}
+				if len(id.Roles) == 0 {
+					id.Roles = append(id.Roles, "user")
+				}
+			}
+
+			table := tools.NewTable([]string{"Field", "Value"})
+			table.Append([]string{"User", id.User})
+			table.Append([]string{"User ID", id.UserID})
+			table.Append([]string{"Email", id.Email})
+			table.Append([]string{"Roles", strings.Join(id.Roles, ", ")})
+			table.Append([]string{"Token", id.TokenID})
+			table.Append([]string{"Scopes", strings.Join(id.Scopes, ", ")})
+			table.Append([]string{"Auth", id.Auth})
+			table.Append([]string{"Master", id.Master})
+			table.Append([]string{"Profile", id.Profile})
+
+			tools.CheckError(tools.NewOutput(v).PrintList(id, table))
+		},
+	}
+
+	return cmd
+}
+
+func showUser(fetcher client.HttpClient, id string) (*user.User, error) {
+	var u user.User
+	req := schema.Request{
+		Route:   v1.Schema.GetUserRoute("show"),
+		Options: map[string]interface{}{":id": id},
+		Target:  &u,
+	}
+	if err := fetcher.Handle(req); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
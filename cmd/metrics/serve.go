@@ -0,0 +1,202 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	node "github.com/MottainaiCI/mottainai-cli/cmd/node"
+	tools "github.com/MottainaiCI/mottainai-cli/common"
+	client "github.com/MottainaiCI/mottainai-server/pkg/client"
+	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
+	citasks "github.com/MottainaiCI/mottainai-server/pkg/tasks"
+	v1 "github.com/MottainaiCI/mottainai-server/routes/schema/v1"
+	cobra "github.com/spf13/cobra"
+	viper "github.com/spf13/viper"
+)
+
+// exporter scrapes the master periodically and serves the last
+// collected metrics.
+type exporter struct {
+	fetcher client.HttpClient
+
+	mutex   sync.RWMutex
+	metrics []byte
+}
+
+func newMetricsServeCommand(config *setting.Config) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "serve [OPTIONS]",
+		Short: "Expose the metrics of nodes and tasks in Prometheus format",
+		Long: `Scrape the nodes and the tasks of the master every --interval
+and expose them on /metrics in the Prometheus text format.
+
+$> mottainai-cli metrics serve --listen :9110 --interval 30s
+`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			var v *viper.Viper = config.Viper
+
+			listen, err := cmd.Flags().GetString("listen")
+			tools.CheckError(err)
+			interval, err := cmd.Flags().GetDuration("interval")
+			tools.CheckError(err)
+
+			e := &exporter{
+				fetcher: client.NewTokenClient(v.GetString("master"), v.GetString("apikey"), config),
+			}
+			e.Scrape()
+			go func() {
+				for range time.Tick(interval) {
+					e.Scrape()
+				}
+			}()
+
+			http.Handle("/metrics", e)
+			fmt.Println("Serving the metrics of " + v.GetString("master") + " on " + listen + "/metrics")
+			log.Fatalln(http.ListenAndServe(listen, nil))
+		},
+	}
+
+	var flags = cmd.Flags()
+	flags.String("listen", ":9110", "Address of the metrics HTTP server")
+	flags.Duration("interval", 30*time.Second, "Interval between the scrapes of the master")
+
+	return cmd
+}
+
+func (e *exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.Write(e.metrics)
+}
+
+// Scrape collects the metrics from the master. A failed scrape is
+// reported by the mottainai_up metric.
+func (e *exporter) Scrape() {
+	start := time.Now()
+	metrics := []*tools.PromMetric{}
+	up := 1.0
+
+	nodes, err := node.FetchNodes(e.fetcher, false)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error on retrieve nodes: "+err.Error())
+		up = 0
+	} else {
+		metrics = append(metrics, nodeMetrics(nodes)...)
+	}
+
+	var tasks []citasks.Task
+	pager := tools.NewPager(e.fetcher, v1.Schema.GetTaskRoute("show_all"), 1, 0)
+	if err = pager.All(&tasks); err != nil {
+		fmt.Fprintln(os.Stderr, "Error on retrieve tasks: "+err.Error())
+		up = 0
+	} else {
+		metrics = append(metrics, taskMetrics(tasks)...)
+	}
+
+	m := tools.NewPromMetric("mottainai_up", "gauge", "1 when the last scrape of the master succeeded")
+	m.Add(up)
+	metrics = append(metrics, m)
+	m = tools.NewPromMetric("mottainai_scrape_duration_seconds", "gauge", "Duration of the last scrape of the master")
+	m.Add(time.Since(start).Seconds())
+	metrics = append(metrics, m)
+	m = tools.NewPromMetric("mottainai_scrape_timestamp_seconds", "gauge", "Time of the last scrape of the master")
+	m.Add(float64(start.Unix()))
+	metrics = append(metrics, m)
+
+	var buf bytes.Buffer
+	tools.WritePrometheus(&buf, metrics)
+
+	e.mutex.Lock()
+	e.metrics = buf.Bytes()
+	e.mutex.Unlock()
+}
+
+func nodeMetrics(nodes []*node.NodeStatus) []*tools.PromMetric {
+	states := map[string]int{node.NODE_STATE_ONLINE: 0, node.NODE_STATE_OFFLINE: 0, node.NODE_STATE_UNKNOWN: 0}
+	age := tools.NewPromMetric("mottainai_node_heartbeat_age_seconds", "gauge",
+		"Seconds elapsed since the last heartbeat of the node")
+	running := tools.NewPromMetric("mottainai_node_running_tasks", "gauge", "Number of tasks running on the node")
+
+	for _, n := range nodes {
+		states[n.State]++
+		if n.State != node.NODE_STATE_UNKNOWN {
+			age.Add(n.Age().Seconds(), "node", n.ID, "hostname", n.Hostname)
+		}
+		running.Add(float64(n.RunningTasks), "node", n.ID, "hostname", n.Hostname)
+	}
+
+	count := tools.NewPromMetric("mottainai_nodes", "gauge", "Number of nodes by state")
+	for _, s := range []string{node.NODE_STATE_ONLINE, node.NODE_STATE_OFFLINE, node.NODE_STATE_UNKNOWN} {
+		count.Add(float64(states[s]), "state", s)
+	}
+
+	return []*tools.PromMetric{count, age, running}
+}
+
+func taskMetrics(tasks []citasks.Task) []*tools.PromMetric {
+	byStatus := make(map[string]map[string]int)
+	queued := make(map[string]int)
+
+	for _, t := range tasks {
+		if byStatus[t.Status] == nil {
+			byStatus[t.Status] = make(map[string]int)
+		}
+		byStatus[t.Status][t.Result]++
+		if t.IsWaiting() {
+			queued[t.Queue]++
+		}
+	}
+
+	count := tools.NewPromMetric("mottainai_tasks", "gauge", "Number of tasks by status and result")
+	for _, status := range sortedKeys(byStatus) {
+		results := byStatus[status]
+		for _, result := range sortedKeys(results) {
+			count.Add(float64(results[result]), "status", status, "result", result)
+		}
+	}
+	waiting := tools.NewPromMetric("mottainai_queue_waiting_tasks", "gauge", "Number of waiting tasks by queue")
+	for _, q := range sortedKeys(queued) {
+		waiting.Add(float64(queued[q]), "queue", q)
+	}
+
+	return []*tools.PromMetric{count, waiting}
+}
+
+func sortedKeys(m interface{}) []string {
+	ans := []string{}
+	for _, k := range reflect.ValueOf(m).MapKeys() {
+		ans = append(ans, k.String())
+	}
+	sort.Strings(ans)
+	return ans
+}
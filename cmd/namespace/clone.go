@@ -21,7 +21,9 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package namespace
 
 import (
+	"fmt"
 	"log"
+	"os"
 
 	tools "github.com/MottainaiCI/mottainai-cli/common"
 	client "github.com/MottainaiCI/mottainai-server/pkg/client"
@@ -32,32 +34,67 @@ import (
 
 func newNamespaceCloneCommand(config *setting.Config) *cobra.Command {
 	var cmd = &cobra.Command{
-		Use:   "clone <namespace> --from <ns_orig> [OPTIONS]",
+		Use:   "clone <from> <to> [OPTIONS]",
 		Short: "clone a namespace",
-		Args:  cobra.RangeArgs(1, 1),
+		Long: `Clone all the artefacts of a namespace in another namespace.
+
+The copy is done by the master. A namespace with artefacts isn't
+changed without --overwrite.
+
+$> mottainai-cli namespace clone testing/foo stable/foo
+`,
+		Args: cobra.RangeArgs(1, 2),
 		Run: func(cmd *cobra.Command, args []string) {
 			var err error
-			var ns_orig string
+			var from, to string
 			var v *viper.Viper = config.Viper
 
 			fetcher := client.NewTokenClient(v.GetString("master"), v.GetString("apikey"), config)
 
-			ns_orig, err = cmd.Flags().GetString("from")
+			from, err = cmd.Flags().GetString("from")
+			tools.CheckError(err)
+			overwrite, err := cmd.Flags().GetBool("overwrite")
+			tools.CheckError(err)
+
+			// clone <namespace> --from <ns_orig> is still supported.
+			switch {
+			case len(args) == 2 && from == "":
+				from, to = args[0], args[1]
+			case len(args) == 1 && from != "":
+				to = args[0]
+			default:
+				tools.CheckError(tools.ValidationError("You need to define the origin and the target namespace"))
+			}
+
+			source, err := fetcher.NamespaceFileList(from)
+			tools.CheckError(err)
+			if len(source) == 0 {
+				log.Fatalln("Namespace " + from + " has no artefacts")
+			}
+
+			target, err := fetcher.NamespaceFileList(to)
 			tools.CheckError(err)
+			if len(target) > 0 && !overwrite {
+				log.Fatalf("Namespace %s already has %d artefacts, use --overwrite to replace them\n", to, len(target))
+			}
 
-			ns := args[0]
-			if len(ns) == 0 {
-				log.Fatalln("You need to define a namespace")
+			fmt.Fprintf(os.Stderr, "Cloning %d artefacts from %s to %s...\n", len(source), from, to)
+			res, err := fetcher.NamespaceClone(from, to)
+			tools.CheckError(err)
+			if res.Error != "" {
+				tools.PrintResponse(res)
+				tools.Exit(1)
 			}
 
-			res, err := fetcher.NamespaceClone(ns_orig, ns)
+			target, err = fetcher.NamespaceFileList(to)
 			tools.CheckError(err)
-			tools.PrintResponse(res)
+			fmt.Printf("Cloned %s to %s: %d artefacts\n", from, to, len(target))
 		},
 	}
 
 	var flags = cmd.Flags()
-	flags.StringP("from", "f", "", "Origin namespace to clone")
+	flags.StringP("from", "f", "", "Origin namespace to clone (deprecated, use clone <from> <to>)")
+	flags.Bool("overwrite", false, "Clone also when the target namespace has artefacts")
 
 	return cmd
 }
@@ -34,6 +34,7 @@ func NewNamespaceCommand(config *setting.Config) *cobra.Command {
 
 	cmd.AddCommand(
 		newNamespaceCloneCommand(config),
+		newNamespaceCopyCommand(config),
 		newNamespaceCreateCommand(config),
 		newNamespaceDeleteCommand(config),
 		newNamespaceDownloadCommand(config),
@@ -41,7 +42,9 @@ func NewNamespaceCommand(config *setting.Config) *cobra.Command {
 		newNamespaceShowCommand(config),
 		newNamespaceTagCommand(config),
 		newNamespaceUploadCommand(config),
+		newNamespacePushCommand(config),
 		newNamespaceRemoveCommand(config),
+		newNamespacePruneCommand(config),
 		newNamespaceAppendCommand(config),
 	)
 
@@ -21,8 +21,6 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package namespace
 
 import (
-	"log"
-
 	tools "github.com/MottainaiCI/mottainai-cli/common"
 	client "github.com/MottainaiCI/mottainai-server/pkg/client"
 	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
@@ -43,7 +41,7 @@ func newNamespaceDeleteCommand(config *setting.Config) *cobra.Command {
 
 			ns := args[0]
 			if len(ns) == 0 {
-				log.Fatalln("You need to define a namespace")
+				tools.CheckError(tools.ValidationError("You need to define a namespace"))
 			}
 
 			res, err := fetcher.NamespaceDelete(ns)
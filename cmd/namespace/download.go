@@ -21,8 +21,7 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package namespace
 
 import (
-	"log"
-
+	tools "github.com/MottainaiCI/mottainai-cli/common"
 	client "github.com/MottainaiCI/mottainai-server/pkg/client"
 	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
 	cobra "github.com/spf13/cobra"
@@ -35,25 +34,57 @@ func newNamespaceDownloadCommand(config *setting.Config) *cobra.Command {
 	var cmd = &cobra.Command{
 		Use:   "download <namespace> <target> [OPTIONS]",
 		Short: "Download namespace artefacts",
-		Args:  cobra.RangeArgs(2, 2),
+		Long: `Download the artefacts of a namespace.
+
+The artefacts are selected with glob patterns, matched on the path
+and on the file name, and are downloaded by a pool of workers.
+
+$> mottainai-cli namespace download myrepo ./repo --include 'x86_64/*' --exclude '*.log' --concurrency 8
+`,
+		Args: cobra.RangeArgs(2, 2),
 		Run: func(cmd *cobra.Command, args []string) {
 			var v *viper.Viper = config.Viper
 			fetcher := client.NewTokenClient(v.GetString("master"), v.GetString("apikey"), config)
-			fetcher.SetActiveReport(true)
 
 			ns := args[0]
 			target := args[1]
 			if len(ns) == 0 || len(target) == 0 {
-				log.Fatalln("You need to define a namespace and a target")
+				tools.CheckError(tools.ValidationError("You need to define a namespace and a target"))
 			}
 
-			if err := fetcher.DownloadArtefactsFromNamespace(ns, target, filters); err != nil {
-				log.Fatalln(err)
+			concurrency, err := cmd.Flags().GetInt("concurrency")
+			tools.CheckError(err)
+
+			downloader, err := tools.NewArtefactDownloader(fetcher, v.GetString("apikey"), concurrency, filters)
+			if err != nil {
+				tools.CheckError(err)
+			}
+			downloader.Globs.Include, err = cmd.Flags().GetStringArray("include")
+			tools.CheckError(err)
+			downloader.Globs.Exclude, err = cmd.Flags().GetStringArray("exclude")
+			tools.CheckError(err)
+			tools.CheckError(downloader.Globs.Validate())
+			noVerify, err := cmd.Flags().GetBool("no-verify")
+			tools.CheckError(err)
+			downloader.Verify = !noVerify
+			checksums, err := cmd.Flags().GetString("checksums")
+			tools.CheckError(err)
+			if checksums != "" {
+				downloader.Checksums, err = tools.ReadChecksums(checksums)
+				tools.CheckError(err)
+			}
+			if err := downloader.DownloadNamespace(ns, target); err != nil {
+				tools.CheckError(err)
 			}
 		},
 	}
 
 	cmd.Flags().StringArrayVarP(&filters, "filter", "f", []string{},
 		"Define regex rule for filter artefacts to download.")
+	cmd.Flags().StringArray("include", []string{}, "Download only the artefacts matching the glob pattern ( e.g. 'x86_64/*' )")
+	cmd.Flags().StringArray("exclude", []string{}, "Don't download the artefacts matching the glob pattern")
+	cmd.Flags().IntP("concurrency", "c", 4, "Number of artefacts downloaded at the same time")
+	cmd.Flags().Bool("no-verify", false, "Don't verify the checksums of the downloaded artefacts")
+	cmd.Flags().String("checksums", "", "File with the expected SHA-256 checksums, in the sha256sum format")
 	return cmd
 }
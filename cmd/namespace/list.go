@@ -21,15 +21,12 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package namespace
 
 import (
-	"log"
-	"os"
-
 	schema "github.com/MottainaiCI/mottainai-server/routes/schema"
 
+	tools "github.com/MottainaiCI/mottainai-cli/common"
 	client "github.com/MottainaiCI/mottainai-server/pkg/client"
 	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
 	v1 "github.com/MottainaiCI/mottainai-server/routes/schema/v1"
-	tablewriter "github.com/olekukonko/tablewriter"
 	cobra "github.com/spf13/cobra"
 	viper "github.com/spf13/viper"
 )
@@ -41,7 +38,6 @@ func newNamespaceListCommand(config *setting.Config) *cobra.Command {
 		Args:  cobra.OnlyValidArgs,
 		Run: func(cmd *cobra.Command, args []string) {
 			var tlist []string
-			var ns_table [][]string
 			var v *viper.Viper = config.Viper
 
 			fetcher := client.NewTokenClient(v.GetString("master"), v.GetString("apikey"), config)
@@ -53,21 +49,16 @@ func newNamespaceListCommand(config *setting.Config) *cobra.Command {
 
 			err := fetcher.Handle(req)
 			if err != nil {
-				log.Fatalln("error:", err)
+				tools.CheckError(err)
 			}
 
+			table := tools.NewTable([]string{"Name"})
 			for _, i := range tlist {
-				ns_table = append(ns_table, []string{i})
+				table.Append([]string{i})
 			}
 
-			table := tablewriter.NewWriter(os.Stdout)
-			table.SetHeader([]string{"Name"})
-			table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
-			table.SetCenterSeparator("|")
-			for _, v := range ns_table {
-				table.Append(v)
-			}
-			table.Render()
+			err = tools.NewOutput(v).PrintList(tlist, table)
+			tools.CheckError(err)
 		},
 	}
 
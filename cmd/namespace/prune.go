@@ -0,0 +1,189 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package namespace
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"time"
+
+	tools "github.com/MottainaiCI/mottainai-cli/common"
+	client "github.com/MottainaiCI/mottainai-server/pkg/client"
+	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
+	cobra "github.com/spf13/cobra"
+	viper "github.com/spf13/viper"
+)
+
+// Number of concurrent requests used to fetch the dates of the files.
+const namespaceStatWorkers = 8
+
+type namespacePruneResult struct {
+	*tools.ArtefactGroup
+	Errors []string `json:"errors,omitempty"`
+}
+
+func newNamespacePruneCommand(config *setting.Config) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "prune <namespace> [OPTIONS]",
+		Short: "Remove the old builds of a namespace",
+		Long: `Remove the old builds of a namespace by retention policy.
+
+The artefacts are grouped by build, by default the top level
+directory of every artefact. With --group-by the build is the first
+submatch of the regular expression on the path of the artefacts.
+The artefacts outside a build are never removed.
+
+A build is removed when it isn't one of the --keep-last most recent
+builds and it's older than --older-than, by the date of its newest
+artefact. The builds without a date are never removed, and nothing
+is removed when the date of an artefact can't be fetched.
+
+$> mottainai-cli namespace prune myrepo --keep-last 5 --dry-run
+$> mottainai-cli namespace prune myrepo --keep-last 5 --older-than 90d
+$> mottainai-cli namespace prune myrepo --group-by '-(\d+)\.tar\.gz$' --keep-last 3
+`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var v *viper.Viper = config.Viper
+			var olderThan time.Duration
+			var groupBy *regexp.Regexp
+
+			ns := args[0]
+
+			keepLast, err := cmd.Flags().GetInt("keep-last")
+			tools.CheckError(err)
+			older, err := cmd.Flags().GetString("older-than")
+			tools.CheckError(err)
+			if older != "" {
+				olderThan, err = tools.ParseDuration(older)
+				tools.CheckError(err)
+			}
+			if keepLast <= 0 && olderThan <= 0 {
+				tools.CheckError(errors.New("You need to define --keep-last or --older-than"))
+			}
+			re, err := cmd.Flags().GetString("group-by")
+			tools.CheckError(err)
+			if re != "" {
+				groupBy, err = regexp.Compile(re)
+				tools.CheckError(err)
+			}
+			dryRun, err := cmd.Flags().GetBool("dry-run")
+			tools.CheckError(err)
+			yes, err := cmd.Flags().GetBool("yes")
+			tools.CheckError(err)
+
+			fetcher := client.NewTokenClient(v.GetString("master"), v.GetString("apikey"), config)
+
+			list, err := fetcher.NamespaceFileList(ns)
+			tools.CheckError(err)
+			sort.Strings(list)
+			files := tools.ArtefactSizes(fetcher.GetBaseURL()+"/"+tools.ARTEFACT_NAMESPACE+"/"+ns,
+				v.GetString("apikey"), list, namespaceStatWorkers)
+
+			var unknown int
+			for _, f := range files {
+				if f.Size < 0 {
+					fmt.Fprintf(os.Stderr, "Failed getting the date of %s\n", f.Path)
+					unknown++
+				}
+			}
+			if unknown > 0 && !dryRun {
+				tools.CheckError(fmt.Errorf("Dates of %d artefacts not available, no build removed", unknown))
+			}
+
+			groups := tools.GroupArtefacts(files, groupBy)
+			tools.ApplyRetention(groups, keepLast, olderThan, time.Now())
+
+			var removed []*tools.ArtefactGroup
+			var count int
+			var size int64
+			for _, g := range groups {
+				if g.Action == tools.RETENTION_REMOVE {
+					removed = append(removed, g)
+					count += len(g.Files)
+					size += g.Size
+				}
+			}
+
+			if len(removed) > 0 && !dryRun && !yes {
+				if !tools.Confirm(fmt.Sprintf("Remove %d builds with %d artefacts (%s)?", len(removed), count, tools.HumanSize(size))) {
+					fmt.Fprintln(os.Stderr, "Aborted, use --yes to skip the confirmation or --dry-run to list the builds")
+					tools.Exit(1)
+				}
+			}
+
+			failed := false
+			results := make([]namespacePruneResult, len(groups))
+			for i, g := range groups {
+				results[i].ArtefactGroup = g
+				if dryRun || g.Action != tools.RETENTION_REMOVE {
+					continue
+				}
+				for _, f := range g.Files {
+					res, err := fetcher.NamespaceRemovePath(ns, f.Path)
+					if err == nil && res.Error != "" {
+						err = errors.New(res.Error)
+					}
+					if err != nil {
+						results[i].Errors = append(results[i].Errors, f.Path+": "+err.Error())
+						failed = true
+					}
+				}
+			}
+
+			table := tools.NewTable([]string{"Build", "Artefacts", "Size", "Modified", "Action", "Errors"})
+			for _, r := range results {
+				modified := ""
+				if !r.Modified.IsZero() {
+					modified = r.Modified.Local().Format("2006-01-02 15:04:05")
+				}
+				errs := ""
+				if len(r.Errors) > 0 {
+					errs = fmt.Sprintf("%d", len(r.Errors))
+				}
+				table.Append([]string{r.Name, fmt.Sprintf("%d", len(r.Files)), tools.HumanSize(r.Size),
+					modified, r.Action, errs})
+			}
+			tools.CheckError(tools.NewOutput(v).PrintList(results, table))
+
+			if failed {
+				for _, r := range results {
+					for _, e := range r.Errors {
+						fmt.Fprintln(os.Stderr, e)
+					}
+				}
+				tools.Exit(1)
+			}
+		},
+	}
+
+	var flags = cmd.Flags()
+	flags.Int("keep-last", 0, "Keep the most recent builds")
+	flags.String("older-than", "", "Remove only the builds older than the duration ( e.g. 90d )")
+	flags.String("group-by", "", "Regular expression with the build of an artefact in its first group")
+	flags.Bool("dry-run", false, "Show the builds to remove without removing them")
+	flags.BoolP("yes", "y", false, "Don't ask confirmation")
+
+	return cmd
+}
@@ -0,0 +1,107 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package namespace
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+
+	tools "github.com/MottainaiCI/mottainai-cli/common"
+	client "github.com/MottainaiCI/mottainai-server/pkg/client"
+	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
+	cobra "github.com/spf13/cobra"
+	viper "github.com/spf13/viper"
+)
+
+type namespacePushResult struct {
+	Path  string `json:"path"`
+	Size  int64  `json:"size"`
+	Error string `json:"error,omitempty"`
+}
+
+func newNamespacePushCommand(config *setting.Config) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "push <namespace> <directory> [OPTIONS]",
+		Short: "Upload a local directory to a namespace",
+		Long: `Upload the files of a local directory to a namespace, keeping
+their paths relative to the directory.
+
+$> mottainai-cli namespace push myrepo ./dist
+$> mottainai-cli namespace push myrepo ./dist --prefix /x86_64
+`,
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			var v *viper.Viper = config.Viper
+
+			ns := args[0]
+			dir := args[1]
+
+			prefix, err := cmd.Flags().GetString("prefix")
+			tools.CheckError(err)
+			quiet, err := cmd.Flags().GetBool("quiet")
+			tools.CheckError(err)
+
+			local, err := tools.LocalFiles(dir)
+			tools.CheckError(err)
+			var files []string
+			for f := range local {
+				files = append(files, f)
+			}
+			sort.Strings(files)
+
+			fetcher := client.NewTokenClient(v.GetString("master"), v.GetString("apikey"), config)
+			uploader := tools.NewFileUploader(fetcher, config, v.GetString("apikey"))
+			uploader.Quiet = quiet
+
+			failed := 0
+			results := make([]namespacePushResult, len(files))
+			for i, f := range files {
+				dest := path.Join("/", prefix, f)
+				results[i] = namespacePushResult{Path: dest, Size: local[f]}
+				err := uploader.UploadNamespace(ns, filepath.Join(dir, filepath.FromSlash(f)), path.Dir(dest))
+				if err != nil {
+					results[i].Error = err.Error()
+					failed++
+				}
+			}
+
+			table := tools.NewTable([]string{"Path", "Size", "Error"})
+			for _, r := range results {
+				table.Append([]string{r.Path, tools.HumanSize(r.Size), r.Error})
+			}
+			tools.CheckError(tools.NewOutput(v).PrintList(results, table))
+
+			if failed > 0 {
+				fmt.Fprintf(os.Stderr, "Upload failed for %d of %d files\n", failed, len(results))
+				tools.Exit(1)
+			}
+		},
+	}
+
+	var flags = cmd.Flags()
+	flags.String("prefix", "", "Directory of the namespace where the files are uploaded")
+	flags.BoolP("quiet", "q", false, "Don't show the progress bar")
+
+	return cmd
+}
@@ -21,8 +21,6 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package namespace
 
 import (
-	"log"
-
 	tools "github.com/MottainaiCI/mottainai-cli/common"
 	client "github.com/MottainaiCI/mottainai-server/pkg/client"
 	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
@@ -44,7 +42,7 @@ func newNamespaceRemoveCommand(config *setting.Config) *cobra.Command {
 			ns := args[0]
 			path := args[1]
 			if len(ns) == 0 || len(path) == 0 {
-				log.Fatalln("You need to define a namespace and a path to delete")
+				tools.CheckError(tools.ValidationError("You need to define a namespace and a path to delete"))
 			}
 
 			res, err := fetcher.NamespaceRemovePath(ns, path)
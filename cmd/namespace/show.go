@@ -21,8 +21,6 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package namespace
 
 import (
-	"log"
-
 	schema "github.com/MottainaiCI/mottainai-server/routes/schema"
 
 	tools "github.com/MottainaiCI/mottainai-cli/common"
@@ -46,7 +44,7 @@ func newNamespaceShowCommand(config *setting.Config) *cobra.Command {
 
 			ns := args[0]
 			if len(ns) == 0 {
-				log.Fatalln("You need to define a namespace name")
+				tools.CheckError(tools.ValidationError("You need to define a namespace name"))
 			}
 
 			req := schema.Request{
@@ -59,9 +57,13 @@ func newNamespaceShowCommand(config *setting.Config) *cobra.Command {
 			err := fetcher.Handle(req)
 			tools.CheckError(err)
 
+			table := tools.NewTable([]string{"Artefact"})
 			for _, i := range tlist {
-				log.Println("- " + i)
+				table.Append([]string{i})
 			}
+
+			err = tools.NewOutput(v).PrintList(tlist, table)
+			tools.CheckError(err)
 		},
 	}
 
@@ -21,7 +21,9 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package namespace
 
 import (
-	"log"
+	"fmt"
+	"os"
+	"path"
 
 	tools "github.com/MottainaiCI/mottainai-cli/common"
 	client "github.com/MottainaiCI/mottainai-server/pkg/client"
@@ -32,32 +34,117 @@ import (
 
 func newNamespaceTagCommand(config *setting.Config) *cobra.Command {
 	var cmd = &cobra.Command{
-		Use:   "tag <namespace> --from <task-id> [OPTIONS]",
+		Use:   "tag <task-id> <namespace> [OPTIONS]",
 		Short: "Tag a namespace",
-		Args:  cobra.RangeArgs(1, 1),
+		Long: `Tag a namespace with the artefacts of a task, replacing its content.
+
+With --include and --exclude only the artefacts matching the glob
+patterns are published, matched on the path and on the file name.
+The master can't filter the artefacts of a tag, so the selected
+files are copied from the task and the other artefacts of the
+namespace are removed.
+
+$> mottainai-cli namespace tag <task-id> myrepo --include '*.deb' --exclude '*-dbg*'
+`,
+		Args: cobra.RangeArgs(1, 2),
 		Run: func(cmd *cobra.Command, args []string) {
 			var err error
-			var from string
+			var from, ns string
 			var v *viper.Viper = config.Viper
 
 			fetcher := client.NewTokenClient(v.GetString("master"), v.GetString("apikey"), config)
 
 			from, err = cmd.Flags().GetString("from")
 			tools.CheckError(err)
+			include, err := cmd.Flags().GetStringArray("include")
+			tools.CheckError(err)
+			exclude, err := cmd.Flags().GetStringArray("exclude")
+			tools.CheckError(err)
+			quiet, err := cmd.Flags().GetBool("quiet")
+			tools.CheckError(err)
 
-			ns := args[0]
-			if len(ns) == 0 {
-				log.Fatalln("You need to define a namespace")
+			// tag <namespace> --from <task-id> is still supported.
+			switch {
+			case len(args) == 2 && from == "":
+				from, ns = args[0], args[1]
+			case len(args) == 1 && from != "":
+				ns = args[0]
+			default:
+				tools.CheckError(tools.ValidationError("You need to define a task id and a namespace"))
 			}
 
-			res, err := fetcher.NamespaceTag(from, ns)
+			filter := tools.ArtefactFilter{Include: include, Exclude: exclude}
+			tools.CheckError(filter.Validate())
+			if filter.Empty() {
+				res, err := fetcher.NamespaceTag(from, ns)
+				tools.CheckError(err)
+				tools.PrintResponse(res)
+				return
+			}
+
+			current, err := fetcher.NamespaceFileList(ns)
+			tools.CheckError(err)
+
+			copier := tools.NewArtefactCopier(fetcher, config, v.GetString("apikey"))
+			copier.Filter = filter
+			copier.Quiet = quiet
+			results, err := copier.Copy(tools.ArtefactLocation{Kind: tools.ARTEFACT_TASK, ID: from, Path: "/"},
+				tools.ArtefactLocation{Kind: tools.ARTEFACT_NAMESPACE, ID: ns, Path: "/"})
 			tools.CheckError(err)
-			tools.PrintResponse(res)
+
+			failed := 0
+			published := make(map[string]bool)
+			for _, r := range results {
+				if r.Error != "" {
+					failed++
+				}
+				published[r.Destination] = true
+			}
+
+			// Remove the previous artefacts only when the new ones are
+			// all available.
+			if failed == 0 {
+				for _, f := range current {
+					f = path.Clean("/" + f)
+					if published[f] {
+						continue
+					}
+					res, err := fetcher.NamespaceRemovePath(ns, f)
+					r := tools.ArtefactCopyResult{Source: f, Destination: "removed"}
+					if err != nil {
+						r.Error = err.Error()
+					} else if res.Error != "" {
+						r.Error = res.Error
+					}
+					if r.Error != "" {
+						failed++
+					}
+					results = append(results, r)
+				}
+			}
+
+			table := tools.NewTable([]string{"Source", "Destination", "Size", "Error"})
+			for _, r := range results {
+				size := ""
+				if r.Destination != "removed" {
+					size = tools.HumanSize(r.Size)
+				}
+				table.Append([]string{r.Source, r.Destination, size, r.Error})
+			}
+			tools.CheckError(tools.NewOutput(v).PrintList(results, table))
+
+			if failed > 0 {
+				fmt.Fprintf(os.Stderr, "Tag of %s failed for %d artefacts\n", ns, failed)
+				tools.Exit(1)
+			}
 		},
 	}
 
 	var flags = cmd.Flags()
-	flags.StringP("from", "f", "", "Task Id")
+	flags.StringP("from", "f", "", "Task Id (deprecated, use tag <task-id> <namespace>)")
+	flags.StringArray("include", []string{}, "Publish only the artefacts matching the glob pattern")
+	flags.StringArray("exclude", []string{}, "Don't publish the artefacts matching the glob pattern")
+	flags.BoolP("quiet", "q", false, "Don't show the progress bar")
 
 	return cmd
 }
@@ -33,10 +33,13 @@ func NewNodeCommand(config *setting.Config) *cobra.Command {
 	}
 
 	cmd.AddCommand(
+		newNodeBootstrapCommand(config),
 		newNodeCreateCommand(config),
+		newNodeHealthCommand(config),
 		newNodeListCommand(config),
 		newNodeShowCommand(config),
 		newNodeRemoveCommand(config),
+		newNodeTopCommand(config),
 	)
 
 	return cmd
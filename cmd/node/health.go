@@ -0,0 +1,130 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package node
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	tools "github.com/MottainaiCI/mottainai-cli/common"
+	client "github.com/MottainaiCI/mottainai-server/pkg/client"
+	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
+	cobra "github.com/spf13/cobra"
+	viper "github.com/spf13/viper"
+)
+
+type nodeHealth struct {
+	ID           string   `json:"id"`
+	Hostname     string   `json:"hostname"`
+	HeartbeatAge string   `json:"heartbeat_age"`
+	RunningTasks int      `json:"running_tasks"`
+	Healthy      bool     `json:"healthy"`
+	Problems     []string `json:"problems"`
+}
+
+func newNodeHealthCommand(config *setting.Config) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "health [OPTIONS]",
+		Short: "Check the health of the nodes",
+		Long: `Check the health of the nodes and exit with status 1 when
+a node is unhealthy.
+
+A node is unhealthy when its last heartbeat is older than
+--max-heartbeat-age or when it runs --max-running tasks or
+more, saturating its concurrency.
+
+$> mottainai-cli node health --max-heartbeat-age 5m --max-running 4
+`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			var v *viper.Viper = config.Viper
+
+			maxAge, err := cmd.Flags().GetDuration("max-heartbeat-age")
+			tools.CheckError(err)
+			maxRunning, err := cmd.Flags().GetInt("max-running")
+			tools.CheckError(err)
+			quiet, err := cmd.Flags().GetBool("quiet")
+			tools.CheckError(err)
+
+			fetcher := client.NewTokenClient(v.GetString("master"), v.GetString("apikey"), config)
+
+			list, err := FetchNodes(fetcher, false)
+			tools.CheckError(err)
+
+			report := []nodeHealth{}
+			unhealthy := 0
+			for _, n := range list {
+				h := checkNodeHealth(n, maxAge, maxRunning)
+				if !h.Healthy {
+					unhealthy++
+				}
+				report = append(report, h)
+			}
+
+			if !quiet {
+				table := tools.NewTable([]string{"ID", "Hostname", "Heartbeat", "Running", "Healthy", "Problems"})
+				for _, h := range report {
+					table.Append([]string{h.ID, h.Hostname, h.HeartbeatAge, strconv.Itoa(h.RunningTasks),
+						strconv.FormatBool(h.Healthy), strings.Join(h.Problems, ", ")})
+				}
+				tools.CheckError(tools.NewOutput(v).PrintList(report, table))
+			}
+
+			if unhealthy > 0 {
+				fmt.Fprintf(os.Stderr, "%d of %d nodes are unhealthy\n", unhealthy, len(report))
+				tools.Exit(1)
+			}
+		},
+	}
+
+	var flags = cmd.Flags()
+	flags.Duration("max-heartbeat-age", nodeOfflineAfter, "Max age of the last heartbeat of a healthy node")
+	flags.Int("max-running", 0, "Number of running tasks of a saturated node, 0 to disable the check")
+	flags.BoolP("quiet", "q", false, "Don't print the report, only the exit status")
+
+	return cmd
+}
+
+func checkNodeHealth(n *NodeStatus, maxAge time.Duration, maxRunning int) nodeHealth {
+	h := nodeHealth{
+		ID:           n.ID,
+		Hostname:     n.Hostname,
+		HeartbeatAge: n.HeartbeatAge,
+		RunningTasks: n.RunningTasks,
+		Problems:     []string{},
+	}
+
+	switch {
+	case n.State == NODE_STATE_UNKNOWN:
+		h.Problems = append(h.Problems, "no heartbeats")
+	case n.age > maxAge:
+		h.Problems = append(h.Problems, "last heartbeat "+n.HeartbeatAge+" ago")
+	}
+	if maxRunning > 0 && n.RunningTasks >= maxRunning {
+		h.Problems = append(h.Problems, fmt.Sprintf("saturated, %d running tasks", n.RunningTasks))
+	}
+	h.Healthy = len(h.Problems) == 0
+
+	return h
+}
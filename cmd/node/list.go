@@ -21,16 +21,11 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package node
 
 import (
-	"log"
-	"os"
-
-	schema "github.com/MottainaiCI/mottainai-server/routes/schema"
+	"strconv"
 
+	tools "github.com/MottainaiCI/mottainai-cli/common"
 	client "github.com/MottainaiCI/mottainai-server/pkg/client"
-	nodes "github.com/MottainaiCI/mottainai-server/pkg/nodes"
 	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
-	v1 "github.com/MottainaiCI/mottainai-server/routes/schema/v1"
-	tablewriter "github.com/olekukonko/tablewriter"
 	cobra "github.com/spf13/cobra"
 	viper "github.com/spf13/viper"
 )
@@ -39,39 +34,46 @@ func newNodeListCommand(config *setting.Config) *cobra.Command {
 	var cmd = &cobra.Command{
 		Use:   "list [OPTIONS]",
 		Short: "List nodes",
-		Args:  cobra.OnlyValidArgs,
+		Long: `List the nodes with the age of their last heartbeat, the
+number of running tasks and their state. A node without
+heartbeats for 5 minutes is offline.
+
+The credentials of the nodes are shown with --show-keys.
+`,
+		Args: cobra.OnlyValidArgs,
 		Run: func(cmd *cobra.Command, args []string) {
-			var n []nodes.Node
-			var node_table [][]string
 			var v *viper.Viper = config.Viper
 
-			fetcher := client.NewTokenClient(v.GetString("master"), v.GetString("apikey"), config)
+			showKeys, err := cmd.Flags().GetBool("show-keys")
+			tools.CheckError(err)
 
-			req := schema.Request{
-				Route:  v1.Schema.GetNodeRoute("show_all"),
-				Target: &n,
-			}
+			fetcher := client.NewTokenClient(v.GetString("master"), v.GetString("apikey"), config)
 
-			err := fetcher.Handle(req)
+			n, err := FetchNodes(fetcher, showKeys)
 			if err != nil {
-				log.Fatalln("error:", err)
+				tools.CheckError(err)
 			}
 
+			header := []string{"ID", "Hostname", "Queues", "Heartbeat", "Running", "State"}
+			if showKeys {
+				header = append(header, "User", "Pass", "Key")
+			}
+			table := tools.NewTable(header)
 			for _, i := range n {
-				node_table = append(node_table, []string{i.ID, i.Hostname, i.User, i.Pass, i.Key, i.NodeID})
+				row := []string{i.ID, i.Hostname, joinQueues(i.Queues), i.HeartbeatAge,
+					strconv.Itoa(i.RunningTasks), i.State}
+				if showKeys {
+					row = append(row, i.User, i.Pass, i.Key)
+				}
+				table.Append(row)
 			}
 
-			table := tablewriter.NewWriter(os.Stdout)
-			table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
-			table.SetCenterSeparator("|")
-			table.SetHeader([]string{"ID", "Hostname", "User", "Pass", "Key", "UUID"})
-
-			for _, v := range node_table {
-				table.Append(v)
-			}
-			table.Render()
+			err = tools.NewOutput(v).PrintList(n, table)
+			tools.CheckError(err)
 		},
 	}
 
+	cmd.Flags().Bool("show-keys", false, "Show the credentials of the nodes")
+
 	return cmd
 }
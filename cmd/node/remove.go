@@ -21,7 +21,11 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package node
 
 import (
-	"log"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
 
 	tools "github.com/MottainaiCI/mottainai-cli/common"
 	client "github.com/MottainaiCI/mottainai-server/pkg/client"
@@ -30,25 +34,135 @@ import (
 	viper "github.com/spf13/viper"
 )
 
+// Number of nodes removed at the same time.
+const nodeRemoveWorkers = 4
+
+type nodeRemoveResult struct {
+	ID       string `json:"id"`
+	Hostname string `json:"hostname"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+}
+
 func newNodeRemoveCommand(config *setting.Config) *cobra.Command {
 	var cmd = &cobra.Command{
-		Use:   "remove <node-id> [OPTIONS]",
-		Short: "Remove a node",
-		Args:  cobra.RangeArgs(1, 1),
+		Use:   "remove [node-id...] [OPTIONS]",
+		Short: "Remove nodes",
+		Long: `Remove nodes.
+
+Nodes are selected by id or by filters, for example to remove
+the ephemeral agents without heartbeats for a day:
+
+  $> mottainai-cli node remove --unreachable-for 24h --key-prefix staging-
+`,
 		Run: func(cmd *cobra.Command, args []string) {
 			var v *viper.Viper = config.Viper
 
-			id := args[0]
-			if len(id) == 0 {
-				log.Fatalln("You need to define a node id")
-			}
+			unreachable, err := cmd.Flags().GetDuration("unreachable-for")
+			tools.CheckError(err)
+			prefix, err := cmd.Flags().GetString("key-prefix")
+			tools.CheckError(err)
+			yes, err := cmd.Flags().GetBool("yes")
+			tools.CheckError(err)
 
 			fetcher := client.NewTokenClient(v.GetString("master"), v.GetString("apikey"), config)
-			res, err := fetcher.RemoveNode(id)
-			tools.CheckError(err)
-			tools.PrintResponse(res)
+
+			var selected []*NodeStatus
+			filtered := unreachable > 0 || prefix != ""
+			switch {
+			case len(args) > 0 && filtered:
+				tools.CheckError(errors.New("Node ids and filters can't be used together"))
+			case len(args) > 0:
+				for _, id := range args {
+					selected = append(selected, &NodeStatus{ID: id})
+				}
+			case !filtered:
+				tools.CheckError(errors.New("You need to define a node id or a filter"))
+			default:
+				list, err := FetchNodes(fetcher, true)
+				tools.CheckError(err)
+				for _, n := range list {
+					if prefix != "" && !strings.HasPrefix(n.Key, prefix) {
+						continue
+					}
+					if unreachable > 0 && n.State != NODE_STATE_UNKNOWN && n.age <= unreachable {
+						continue
+					}
+					selected = append(selected, n)
+				}
+			}
+
+			if len(selected) == 0 {
+				fmt.Println("No nodes found")
+				return
+			}
+
+			// Ask confirmation only for nodes selected by filters.
+			if filtered && !yes {
+				names := []string{}
+				for _, n := range selected {
+					names = append(names, n.ID+" ("+n.Hostname+")")
+				}
+				fmt.Fprintf(os.Stderr, "Nodes: %s\n", strings.Join(names, " "))
+				if !tools.Confirm(fmt.Sprintf("Remove %d nodes?", len(selected))) {
+					fmt.Fprintln(os.Stderr, "Aborted, use --yes to skip the confirmation")
+					tools.Exit(1)
+				}
+			}
+
+			results := removeNodes(fetcher, selected)
+
+			failed := false
+			table := tools.NewTable([]string{"ID", "Hostname", "Status", "Error"})
+			for _, r := range results {
+				if r.Error != "" {
+					failed = true
+				}
+				table.Append([]string{r.ID, r.Hostname, r.Status, r.Error})
+			}
+			tools.CheckError(tools.NewOutput(v).PrintList(results, table))
+
+			if failed {
+				tools.Exit(1)
+			}
 		},
 	}
 
+	var flags = cmd.Flags()
+	flags.Duration("unreachable-for", 0,
+		"Select the nodes without heartbeats for the duration ( e.g. 24h )")
+	flags.String("key-prefix", "", "Select the nodes with a key starting with the prefix")
+	flags.BoolP("yes", "y", false, "Don't ask confirmation for nodes selected by filters")
+
 	return cmd
 }
+
+// removeNodes removes the nodes concurrently.
+func removeNodes(fetcher client.HttpClient, list []*NodeStatus) []nodeRemoveResult {
+	results := make([]nodeRemoveResult, len(list))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+
+	for w := 0; w < nodeRemoveWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				res, err := fetcher.RemoveNode(list[i].ID)
+				results[i] = nodeRemoveResult{ID: list[i].ID, Hostname: list[i].Hostname, Status: res.Status}
+				if err != nil {
+					results[i].Error = err.Error()
+				} else if res.Error != "" {
+					results[i].Error = res.Error
+				}
+			}
+		}()
+	}
+	for i := range list {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
@@ -21,10 +21,6 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package node
 
 import (
-	"encoding/json"
-	"fmt"
-	"log"
-
 	schema "github.com/MottainaiCI/mottainai-server/routes/schema"
 	v1 "github.com/MottainaiCI/mottainai-server/routes/schema/v1"
 
@@ -49,7 +45,7 @@ func newNodeShowCommand(config *setting.Config) *cobra.Command {
 
 			id := args[0]
 			if len(id) == 0 {
-				log.Fatalln("You need to define a node id")
+				tools.CheckError(tools.ValidationError("You need to define a node id"))
 			}
 			req := schema.Request{
 				Route: v1.Schema.GetNodeRoute("show"),
@@ -61,11 +57,8 @@ func newNodeShowCommand(config *setting.Config) *cobra.Command {
 			err := fetcher.Handle(req)
 			tools.CheckError(err)
 
-			b, err := json.MarshalIndent(n, "", "  ")
-			if err != nil {
-				log.Fatalln("error:", err)
-			}
-			fmt.Println(string(b))
+			err = tools.NewOutput(v).PrintObject(n)
+			tools.CheckError(err)
 		},
 	}
 
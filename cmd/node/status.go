@@ -0,0 +1,168 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package node
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	schema "github.com/MottainaiCI/mottainai-server/routes/schema"
+	v1 "github.com/MottainaiCI/mottainai-server/routes/schema/v1"
+
+	client "github.com/MottainaiCI/mottainai-server/pkg/client"
+	nodes "github.com/MottainaiCI/mottainai-server/pkg/nodes"
+	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
+	citasks "github.com/MottainaiCI/mottainai-server/pkg/tasks"
+)
+
+const (
+	NODE_STATE_ONLINE  = "online"
+	NODE_STATE_OFFLINE = "offline"
+	NODE_STATE_UNKNOWN = "unknown"
+
+	// A node without heartbeats for this time is offline.
+	nodeOfflineAfter = 5 * time.Minute
+)
+
+// NodeStatus is a node with the age of its last heartbeat and the
+// tasks it's running. The credentials of the node are set only
+// when requested.
+type NodeStatus struct {
+	ID           string   `json:"id"`
+	NodeID       string   `json:"nodeid"`
+	Hostname     string   `json:"hostname"`
+	Queues       []string `json:"queues"`
+	LastReport   string   `json:"last_report"`
+	HeartbeatAge string   `json:"heartbeat_age"`
+	RunningTasks int      `json:"running_tasks"`
+	Tasks        []string `json:"tasks"`
+	State        string   `json:"state"`
+	User         string   `json:"user,omitempty"`
+	Pass         string   `json:"pass,omitempty"`
+	Key          string   `json:"key,omitempty"`
+
+	age time.Duration
+}
+
+// Age returns the time elapsed since the last heartbeat of the node.
+func (n *NodeStatus) Age() time.Duration {
+	return n.age
+}
+
+// node is the node returned by the master. The queues are reported
+// only by recent masters.
+type node struct {
+	nodes.Node
+	Queues json.RawMessage `json:"queues"`
+}
+
+// FetchNodes returns the status of the nodes registered on the master.
+func FetchNodes(fetcher client.HttpClient, showKeys bool) ([]*NodeStatus, error) {
+	var list []node
+
+	req := schema.Request{
+		Route:  v1.Schema.GetNodeRoute("show_all"),
+		Target: &list,
+	}
+	if err := fetcher.Handle(req); err != nil {
+		return nil, err
+	}
+
+	ans := []*NodeStatus{}
+	now := time.Now()
+	for _, n := range list {
+		s := &NodeStatus{
+			ID:         n.ID,
+			NodeID:     n.NodeID,
+			Hostname:   n.Hostname,
+			Queues:     nodeQueues(n.Queues),
+			LastReport: n.LastReport,
+			State:      NODE_STATE_UNKNOWN,
+			Tasks:      []string{},
+		}
+		if showKeys {
+			s.User, s.Pass, s.Key = n.User, n.Pass, n.Key
+		}
+
+		if t, err := time.Parse(setting.Timeformat, n.LastReport); err == nil {
+			s.age = now.Sub(t)
+			if s.age < 0 {
+				s.age = 0
+			}
+			s.HeartbeatAge = s.age.Round(time.Second).String()
+			s.State = NODE_STATE_ONLINE
+			if s.age > nodeOfflineAfter {
+				s.State = NODE_STATE_OFFLINE
+			}
+		}
+
+		if n.Key != "" {
+			var tasks []citasks.Task
+			if err := fetcher.NodesTask(n.Key, &tasks); err == nil {
+				for _, t := range tasks {
+					if t.IsRunning() || t.IsSetup() {
+						s.RunningTasks++
+						s.Tasks = append(s.Tasks, t.ID)
+					}
+				}
+			}
+		}
+
+		ans = append(ans, s)
+	}
+
+	sort.Slice(ans, func(i, j int) bool {
+		return ans[i].Hostname < ans[j].Hostname
+	})
+
+	return ans, nil
+}
+
+// nodeQueues returns the names of the queues as a list or as
+// the keys of a map.
+func nodeQueues(data json.RawMessage) []string {
+	ans := []string{}
+	if len(data) == 0 {
+		return ans
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err == nil {
+		return list
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err == nil {
+		for q := range m {
+			ans = append(ans, q)
+		}
+		sort.Strings(ans)
+	}
+	return ans
+}
+
+func joinQueues(queues []string) string {
+	if len(queues) == 0 {
+		return "-"
+	}
+	return strings.Join(queues, ",")
+}
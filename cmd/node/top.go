@@ -0,0 +1,104 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package node
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	tools "github.com/MottainaiCI/mottainai-cli/common"
+	client "github.com/MottainaiCI/mottainai-server/pkg/client"
+	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
+	cobra "github.com/spf13/cobra"
+	viper "github.com/spf13/viper"
+)
+
+// Max number of running task ids shown for a node.
+const topMaxTasks = 5
+
+func newNodeTopCommand(config *setting.Config) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "top [OPTIONS]",
+		Short: "Show the nodes with their running tasks, refreshed periodically",
+		Long: `Show the nodes with the freshness of their heartbeats and
+their running tasks, refreshing the view every --interval
+until interrupted.
+
+The master doesn't report the resource usage of the nodes,
+so CPU and memory are not shown.
+`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			var v *viper.Viper = config.Viper
+
+			interval, err := cmd.Flags().GetDuration("interval")
+			tools.CheckError(err)
+			iterations, err := cmd.Flags().GetInt("iterations")
+			tools.CheckError(err)
+
+			fetcher := client.NewTokenClient(v.GetString("master"), v.GetString("apikey"), config)
+
+			for i := 0; iterations <= 0 || i < iterations; i++ {
+				if i > 0 {
+					time.Sleep(interval)
+				}
+
+				list, err := FetchNodes(fetcher, false)
+				if err != nil {
+					fmt.Fprintln(os.Stderr, "Error on retrieve nodes: "+err.Error())
+					continue
+				}
+
+				online, running := 0, 0
+				for _, n := range list {
+					if n.State == NODE_STATE_ONLINE {
+						online++
+					}
+					running += n.RunningTasks
+				}
+
+				table := tools.NewTable([]string{"Hostname", "State", "Heartbeat", "Queues", "Running", "Tasks"})
+				for _, n := range list {
+					tasks := n.Tasks
+					if len(tasks) > topMaxTasks {
+						tasks = append(tasks[:topMaxTasks:topMaxTasks], "...")
+					}
+					table.Append([]string{n.Hostname, n.State, n.HeartbeatAge, joinQueues(n.Queues),
+						strconv.Itoa(n.RunningTasks), strings.Join(tasks, ",")})
+				}
+
+				fmt.Print("\033[H\033[2J")
+				fmt.Printf("%s - nodes: %d, online: %d, running tasks: %d\n\n",
+					time.Now().Format("15:04:05"), len(list), online, running)
+				tools.CheckError(tools.NewOutput(v).PrintList(list, table))
+			}
+		},
+	}
+
+	var flags = cmd.Flags()
+	flags.Duration("interval", 5*time.Second, "Interval between the refreshes")
+	flags.IntP("iterations", "n", 0, "Number of refreshes before exiting, 0 to run until interrupted")
+
+	return cmd
+}
@@ -33,9 +33,15 @@ func NewPipelineCommand(config *setting.Config) *cobra.Command {
 	}
 
 	cmd.AddCommand(
+		newPipelineConvertCommand(config),
 		newPipelineCreateCommand(config),
+		newPipelineGraphCommand(config),
+		newPipelineLintCommand(config),
 		newPipelineListCommand(config),
+		newPipelineLogsCommand(config),
 		newPipelineRemoveCommand(config),
+		newPipelineReportCommand(config),
+		newPipelineRetryCommand(config),
 		newPipelineShowCommand(config),
 	)
 
@@ -0,0 +1,312 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	template "github.com/MottainaiCI/mottainai-cli/cmd/task/template"
+	tools "github.com/MottainaiCI/mottainai-cli/common"
+	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
+	citasks "github.com/MottainaiCI/mottainai-server/pkg/tasks"
+	cobra "github.com/spf13/cobra"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	CONVERT_GITHUB_ACTIONS = "github-actions"
+	CONVERT_GITLAB_CI      = "gitlab-ci"
+)
+
+var invalidJobChars = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+func newPipelineConvertCommand(config *setting.Config) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "convert -f <pipeline.yaml> --to <format> [OPTIONS]",
+		Short: "Convert a pipeline manifest to a GitHub Actions or GitLab CI workflow",
+		Long: `Convert a pipeline manifest to a GitHub Actions workflow or
+to a GitLab CI configuration.
+
+Every task is translated to a job running the script of the task
+on its image, with the dependencies defined by the chain, the
+chord and the stages of the pipeline. The fields of the tasks
+without an equivalent on the target format are reported.
+
+$> mottainai-cli pipeline convert -f pipeline.yaml --to github-actions -o .github/workflows/ci.yml
+$> mottainai-cli pipeline convert -f pipeline.yaml --to gitlab-ci -o .gitlab-ci.yml
+`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			file, err := cmd.Flags().GetString("file")
+			tools.CheckError(err)
+			if file == "" {
+				tools.CheckError(errors.New("You need to define the manifest with --file"))
+			}
+			to, err := cmd.Flags().GetString("to")
+			tools.CheckError(err)
+			out, err := cmd.Flags().GetString("output")
+			tools.CheckError(err)
+			values, err := cmd.Flags().GetStringArray("set")
+			tools.CheckError(err)
+			vFile, err := cmd.Flags().GetString("values")
+			tools.CheckError(err)
+			templ, err := template.NewFromValues(values, vFile)
+			tools.CheckError(err)
+
+			p, err := pipelineFromManifest(file, templ)
+			tools.CheckError(err)
+
+			var workflow yaml.MapSlice
+			switch to {
+			case CONVERT_GITHUB_ACTIONS:
+				workflow = githubWorkflow(p)
+			case CONVERT_GITLAB_CI:
+				workflow = gitlabCI(p)
+			default:
+				tools.CheckError(tools.ValidationError("Invalid format %s, use %s or %s",
+					to, CONVERT_GITHUB_ACTIONS, CONVERT_GITLAB_CI))
+			}
+
+			for _, n := range pipelineNodes(p) {
+				t := p.Tasks[n.Name]
+				for _, f := range unconvertedFields(&t) {
+					fmt.Fprintln(os.Stderr, "Warning: field "+f+" of task "+n.Name+" is not converted")
+				}
+			}
+
+			b, err := yaml.Marshal(workflow)
+			tools.CheckError(err)
+			if out == "" || out == "-" {
+				fmt.Print(string(b))
+				return
+			}
+			tools.CheckError(ioutil.WriteFile(out, b, 0644))
+		},
+	}
+
+	var flags = cmd.Flags()
+	flags.StringP("file", "f", "", "Pipeline manifest in YAML or JSON format, - for stdin")
+	flags.String("to", CONVERT_GITHUB_ACTIONS, "Target format: "+CONVERT_GITHUB_ACTIONS+" or "+CONVERT_GITLAB_CI)
+	flags.StringP("output", "o", "", "Output file ( default: stdout )")
+	flags.StringArray("set", []string{},
+		"Set a value used to render the manifest as go template ( e.g. --set arch=amd64 )")
+	flags.String("values", "",
+		"Load the values used to render the manifest from a YAML file with a values: section")
+
+	return cmd
+}
+
+// githubWorkflow returns a GitHub Actions workflow with a job for
+// every task of the pipeline.
+func githubWorkflow(p *citasks.Pipeline) yaml.MapSlice {
+	jobs := yaml.MapSlice{}
+	for _, n := range pipelineNodes(p) {
+		t := p.Tasks[n.Name]
+		job := yaml.MapSlice{{Key: "runs-on", Value: "ubuntu-latest"}}
+		if t.Image != "" {
+			job = append(job, yaml.MapItem{Key: "container", Value: t.Image})
+		}
+		if len(n.DependsOn) > 0 {
+			job = append(job, yaml.MapItem{Key: "needs", Value: jobIDs(n.DependsOn)})
+		}
+		if env := taskEnvironment(&t); len(env) > 0 {
+			job = append(job, yaml.MapItem{Key: "env", Value: env})
+		}
+		if t.TimeOut > 0 {
+			// The timeout of the tasks is in seconds.
+			job = append(job, yaml.MapItem{Key: "timeout-minutes", Value: int(t.TimeOut+59) / 60})
+		}
+
+		steps := []yaml.MapSlice{{{Key: "uses", Value: "actions/checkout@v2"}}}
+		if len(t.Script) > 0 {
+			run := yaml.MapSlice{{Key: "run", Value: strings.Join(t.Script, "\n")}}
+			if t.Directory != "" {
+				run = append(run, yaml.MapItem{Key: "working-directory", Value: t.Directory})
+			}
+			steps = append(steps, run)
+		}
+		if t.ArtefactPath != "" {
+			steps = append(steps, yaml.MapSlice{
+				{Key: "uses", Value: "actions/upload-artifact@v2"},
+				{Key: "with", Value: yaml.MapSlice{
+					{Key: "name", Value: n.Name},
+					{Key: "path", Value: t.ArtefactPath},
+				}},
+			})
+		}
+		job = append(job, yaml.MapItem{Key: "steps", Value: steps})
+
+		jobs = append(jobs, yaml.MapItem{Key: jobID(n.Name), Value: job})
+	}
+
+	return yaml.MapSlice{
+		{Key: "name", Value: pipelineTitle(p)},
+		{Key: "on", Value: []string{"push", "pull_request"}},
+		{Key: "jobs", Value: jobs},
+	}
+}
+
+// gitlabCI returns a GitLab CI configuration with a job for every
+// task of the pipeline. The jobs are assigned to stages following
+// their dependencies.
+func gitlabCI(p *citasks.Pipeline) yaml.MapSlice {
+	nodes := pipelineNodes(p)
+
+	deps := make(map[string][]string)
+	for _, n := range nodes {
+		deps[n.Name] = n.DependsOn
+	}
+
+	// The stage of a job follows the stages of its dependencies.
+	level := make(map[string]int)
+	var depth func(name string, visiting map[string]bool) int
+	depth = func(name string, visiting map[string]bool) int {
+		if l, ok := level[name]; ok {
+			return l
+		}
+		visiting[name] = true
+		l := 1
+		for _, dep := range deps[name] {
+			// Cycles are ignored.
+			if !visiting[dep] {
+				if d := depth(dep, visiting) + 1; d > l {
+					l = d
+				}
+			}
+		}
+		level[name] = l
+		return l
+	}
+
+	stages := []string{}
+	for _, n := range nodes {
+		for l := depth(n.Name, map[string]bool{}); len(stages) < l; {
+			stages = append(stages, fmt.Sprintf("stage%d", len(stages)+1))
+		}
+	}
+
+	ans := yaml.MapSlice{{Key: "stages", Value: stages}}
+	for _, n := range nodes {
+		t := p.Tasks[n.Name]
+		job := yaml.MapSlice{{Key: "stage", Value: stages[level[n.Name]-1]}}
+		if t.Image != "" {
+			job = append(job, yaml.MapItem{Key: "image", Value: t.Image})
+		}
+		if len(n.DependsOn) > 0 {
+			job = append(job, yaml.MapItem{Key: "needs", Value: jobIDs(n.DependsOn)})
+		}
+		if env := taskEnvironment(&t); len(env) > 0 {
+			job = append(job, yaml.MapItem{Key: "variables", Value: env})
+		}
+		if t.TimeOut > 0 {
+			job = append(job, yaml.MapItem{Key: "timeout", Value: fmt.Sprintf("%ds", int(t.TimeOut))})
+		}
+		script := []string{}
+		if t.Directory != "" {
+			script = append(script, "cd "+t.Directory)
+		}
+		script = append(script, t.Script...)
+		if len(script) == 0 {
+			// A GitLab job needs a script.
+			script = []string{"true"}
+		}
+		job = append(job, yaml.MapItem{Key: "script", Value: script})
+		if t.ArtefactPath != "" {
+			job = append(job, yaml.MapItem{Key: "artifacts", Value: yaml.MapSlice{
+				{Key: "paths", Value: []string{t.ArtefactPath}},
+			}})
+		}
+
+		ans = append(ans, yaml.MapItem{Key: jobID(n.Name), Value: job})
+	}
+
+	return ans
+}
+
+// unconvertedFields returns the fields of the task without an
+// equivalent on GitHub Actions and GitLab CI.
+func unconvertedFields(t *citasks.Task) []string {
+	ans := []string{}
+	if t.Type != "" && t.Type != "docker" {
+		ans = append(ans, "type")
+	}
+	for f, v := range map[string]string{
+		"source":        t.Source,
+		"storage":       t.Storage,
+		"tag_namespace": t.TagNamespace,
+		"cache_image":   t.CacheImage,
+		"queue":         t.Queue,
+	} {
+		if v != "" {
+			ans = append(ans, f)
+		}
+	}
+	if len(t.Entrypoint) > 0 {
+		ans = append(ans, "entrypoint")
+	}
+	if len(t.Binds) > 0 {
+		ans = append(ans, "binds")
+	}
+	sort.Strings(ans)
+	return ans
+}
+
+func taskEnvironment(t *citasks.Task) yaml.MapSlice {
+	ans := yaml.MapSlice{}
+	for _, e := range t.Environment {
+		kv := strings.SplitN(e, "=", 2)
+		if len(kv) == 1 {
+			kv = append(kv, "")
+		}
+		ans = append(ans, yaml.MapItem{Key: kv[0], Value: kv[1]})
+	}
+	return ans
+}
+
+func pipelineTitle(p *citasks.Pipeline) string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return "pipeline"
+}
+
+// jobID returns a valid job identifier for the task name.
+func jobID(name string) string {
+	id := invalidJobChars.ReplaceAllString(name, "_")
+	if id == "" || (id[0] >= '0' && id[0] <= '9') || id[0] == '-' {
+		id = "_" + id
+	}
+	return id
+}
+
+func jobIDs(names []string) []string {
+	ans := []string{}
+	for _, n := range names {
+		ans = append(ans, jobID(n))
+	}
+	return ans
+}
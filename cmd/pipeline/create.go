@@ -25,6 +25,7 @@ import (
 	"fmt"
 	"io/ioutil"
 
+	template "github.com/MottainaiCI/mottainai-cli/cmd/task/template"
 	tools "github.com/MottainaiCI/mottainai-cli/common"
 	client "github.com/MottainaiCI/mottainai-server/pkg/client"
 	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
@@ -39,7 +40,28 @@ func newPipelineCreateCommand(config *setting.Config) *cobra.Command {
 	var cmd = &cobra.Command{
 		Use:   "create [OPTIONS]",
 		Short: "Create a new pipeline",
-		Args:  cobra.OnlyValidArgs,
+		Long: `Create a new pipeline.
+
+The manifest supplied with --file defines the tasks by name and
+the stages of the pipeline. A stage is a task or a group of tasks
+executed in parallel:
+
+pipeline_name: release
+tasks:
+  test-amd64:
+    image: foo/builder
+    script: ["make test"]
+  test-arm64:
+    image: foo/builder-arm
+    script: ["make test"]
+  publish:
+    image: foo/builder
+    script: ["make publish"]
+stages:
+  - group: [test-amd64, test-arm64]
+  - publish
+`,
+		Args: cobra.OnlyValidArgs,
 		// TODO: PreRun check of minimal args if --json is not present
 		Run: func(cmd *cobra.Command, args []string) {
 			var err error
@@ -54,8 +76,21 @@ func newPipelineCreateCommand(config *setting.Config) *cobra.Command {
 			tools.CheckError(err)
 			yamlfile, err := cmd.Flags().GetString("yaml")
 			tools.CheckError(err)
+			manifest, err := cmd.Flags().GetString("file")
+			tools.CheckError(err)
 
-			if jsonfile != "" {
+			if manifest != "" {
+				values, err := cmd.Flags().GetStringArray("set")
+				tools.CheckError(err)
+				vFile, err := cmd.Flags().GetString("values")
+				tools.CheckError(err)
+				templ, err := template.NewFromValues(values, vFile)
+				tools.CheckError(err)
+
+				p, err = pipelineFromManifest(manifest, templ)
+				tools.CheckError(err)
+				dat = p.ToMap(false)
+			} else if jsonfile != "" {
 				content, err := ioutil.ReadFile(jsonfile)
 				tools.CheckError(err)
 
@@ -91,6 +126,12 @@ func newPipelineCreateCommand(config *setting.Config) *cobra.Command {
 	}
 
 	var flags = cmd.Flags()
+	flags.StringP("file", "f", "",
+		"Pipeline manifest with tasks and stages in YAML or JSON format, - for stdin ( e.g. pipeline.yaml )")
+	flags.StringArray("set", []string{},
+		"Set a value used to render the manifest as go template ( e.g. --set arch=amd64 )")
+	flags.String("values", "",
+		"Load the values used to render the manifest from a YAML file with a values: section")
 	flags.String("json", "", "Decode parameters from a JSON file ( e.g. /path/to/file.json )")
 	flags.String("yaml", "", "Decode parameters from a YAML file ( e.g. /path/to/file.yaml )")
 
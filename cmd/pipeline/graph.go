@@ -0,0 +1,162 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package pipeline
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	schema "github.com/MottainaiCI/mottainai-server/routes/schema"
+	v1 "github.com/MottainaiCI/mottainai-server/routes/schema/v1"
+
+	tools "github.com/MottainaiCI/mottainai-cli/common"
+	client "github.com/MottainaiCI/mottainai-server/pkg/client"
+	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
+	citasks "github.com/MottainaiCI/mottainai-server/pkg/tasks"
+	cobra "github.com/spf13/cobra"
+	viper "github.com/spf13/viper"
+)
+
+type pipelineNode struct {
+	Name      string   `json:"name"`
+	ID        string   `json:"id"`
+	Status    string   `json:"status"`
+	Result    string   `json:"result"`
+	DependsOn []string `json:"depends_on"`
+}
+
+func newPipelineGraphCommand(config *setting.Config) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "graph <pipeline-id> [OPTIONS]",
+		Short: "Show the dependencies between the tasks of a pipeline",
+		Long: `Show the tasks of a pipeline with their dependencies, or the
+pipeline in Graphviz DOT format with --dot.
+
+The tasks of a chain depend on the previous one, the tasks of a
+group run in parallel and the last task of a chord depends on
+all the other tasks of the chord.
+
+$> mottainai-cli pipeline graph 123 --dot | dot -Tpng > pipeline.png
+`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var p citasks.Pipeline
+			var v *viper.Viper = config.Viper
+
+			dot, err := cmd.Flags().GetBool("dot")
+			tools.CheckError(err)
+
+			fetcher := client.NewTokenClient(v.GetString("master"), v.GetString("apikey"), config)
+
+			req := schema.Request{
+				Route: v1.Schema.GetTaskRoute("pipeline_show"),
+				Options: map[string]interface{}{
+					":id": args[0],
+				},
+				Target: &p,
+			}
+			tools.CheckError(fetcher.Handle(req))
+			if p.ID == "" {
+				tools.CheckError(tools.NotFoundError("Pipeline", args[0]))
+			}
+
+			nodes := pipelineNodes(&p)
+
+			if dot {
+				name := p.Name
+				if name == "" {
+					name = p.ID
+				}
+				g := tools.NewDotGraph(name)
+				for _, n := range nodes {
+					t := p.Tasks[n.Name]
+					g.AddNode(n.Name, n.Name+"\n"+n.ID, tools.TaskDotColor(&t))
+				}
+				for _, n := range nodes {
+					for _, dep := range n.DependsOn {
+						g.AddEdge(dep, n.Name)
+					}
+				}
+				fmt.Print(g.String())
+				return
+			}
+
+			table := tools.NewTable([]string{"Name", "ID", "Status", "Result", "Depends on"})
+			for _, n := range nodes {
+				table.Append([]string{n.Name, n.ID, n.Status, n.Result, strings.Join(n.DependsOn, ", ")})
+			}
+			tools.CheckError(tools.NewOutput(v).PrintList(nodes, table))
+		},
+	}
+
+	cmd.Flags().Bool("dot", false, "Print the pipeline in Graphviz DOT format")
+
+	return cmd
+}
+
+// pipelineNodes returns the tasks of the pipeline with the
+// dependencies defined by chain, group and chord.
+func pipelineNodes(p *citasks.Pipeline) []*pipelineNode {
+	deps := make(map[string][]string)
+
+	for i := 1; i < len(p.Chain); i++ {
+		deps[p.Chain[i]] = append(deps[p.Chain[i]], p.Chain[i-1])
+	}
+	if len(p.Chord) > 1 {
+		callback := p.Chord[len(p.Chord)-1]
+		deps[callback] = append(deps[callback], p.Chord[:len(p.Chord)-1]...)
+	}
+
+	// Tasks are ordered as defined on chain, group and chord.
+	names := []string{}
+	seen := make(map[string]bool)
+	for _, list := range [][]string{p.Chain, p.Group, p.Chord} {
+		for _, name := range list {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	others := []string{}
+	for name := range p.Tasks {
+		if !seen[name] {
+			others = append(others, name)
+		}
+	}
+	sort.Strings(others)
+	names = append(names, others...)
+
+	ans := []*pipelineNode{}
+	for _, name := range names {
+		t := p.Tasks[name]
+		ans = append(ans, &pipelineNode{
+			Name:      name,
+			ID:        t.ID,
+			Status:    t.Status,
+			Result:    t.Result,
+			DependsOn: deps[name],
+		})
+	}
+
+	return ans
+}
@@ -0,0 +1,104 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+
+	template "github.com/MottainaiCI/mottainai-cli/cmd/task/template"
+	tools "github.com/MottainaiCI/mottainai-cli/common"
+	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
+	"github.com/ghodss/yaml"
+	cobra "github.com/spf13/cobra"
+	viper "github.com/spf13/viper"
+)
+
+func newPipelineLintCommand(config *setting.Config) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "lint -f <pipeline.yaml> [OPTIONS]",
+		Short: "Validate a pipeline manifest",
+		Long: `Validate a pipeline manifest before its submission.
+
+The types of the fields of the pipeline and of its tasks, the
+references to undefined tasks and the cycles between the tasks
+of chain, chord and stages are checked. The command exits with
+status 1 when errors are found.
+
+$> mottainai-cli pipeline lint -f pipeline.yaml --set arch=amd64
+`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			var v *viper.Viper = config.Viper
+
+			file, err := cmd.Flags().GetString("file")
+			tools.CheckError(err)
+			if file == "" {
+				tools.CheckError(errors.New("You need to define the manifest with --file"))
+			}
+			values, err := cmd.Flags().GetStringArray("set")
+			tools.CheckError(err)
+			vFile, err := cmd.Flags().GetString("values")
+			tools.CheckError(err)
+			templ, err := template.NewFromValues(values, vFile)
+			tools.CheckError(err)
+
+			content, err := readManifest(file, templ)
+			tools.CheckError(err)
+
+			issues := tools.LintPipeline(content)
+			if tools.LintErrors(issues) == 0 {
+				// Check the stages supported by the pipeline API.
+				m := &PipelineManifest{}
+				if err = yaml.Unmarshal(content, m); err == nil {
+					_, err = m.Compile()
+				}
+				if err != nil {
+					issues = append(issues, tools.LintIssue{Level: tools.LINT_ERROR, Message: err.Error()})
+				}
+			}
+
+			if len(issues) == 0 {
+				fmt.Println(file + ": no issues found")
+				return
+			}
+
+			table := tools.NewTable([]string{"Level", "Field", "Message"})
+			for _, i := range issues {
+				table.Append([]string{i.Level, i.Field, i.Message})
+			}
+			tools.CheckError(tools.NewOutput(v).PrintList(issues, table))
+
+			if tools.LintErrors(issues) > 0 {
+				tools.Exit(1)
+			}
+		},
+	}
+
+	var flags = cmd.Flags()
+	flags.StringP("file", "f", "", "Pipeline manifest in YAML or JSON format, - for stdin")
+	flags.StringArray("set", []string{},
+		"Set a value used to render the manifest as go template ( e.g. --set arch=amd64 )")
+	flags.String("values", "",
+		"Load the values used to render the manifest from a YAML file with a values: section")
+
+	return cmd
+}
@@ -22,8 +22,8 @@ package pipeline
 
 import (
 	"fmt"
-	"os"
 	"sort"
+	"strconv"
 	"time"
 
 	schema "github.com/MottainaiCI/mottainai-server/routes/schema"
@@ -33,7 +33,6 @@ import (
 	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
 	citasks "github.com/MottainaiCI/mottainai-server/pkg/tasks"
 	v1 "github.com/MottainaiCI/mottainai-server/routes/schema/v1"
-	tablewriter "github.com/olekukonko/tablewriter"
 	cobra "github.com/spf13/cobra"
 	viper "github.com/spf13/viper"
 )
@@ -42,16 +41,34 @@ func newPipelineListCommand(config *setting.Config) *cobra.Command {
 	var cmd = &cobra.Command{
 		Use:   "list [OPTIONS]",
 		Short: "List pipelines",
-		Args:  cobra.OnlyValidArgs,
+		Long: `List pipelines.
+
+With --watch the most recent pipelines are shown with the
+number of completed tasks and the elapsed time, refreshing
+the table every --interval:
+
+$> mottainai-cli pipeline list --watch --limit 10
+`,
+		Args: cobra.OnlyValidArgs,
 		Run: func(cmd *cobra.Command, args []string) {
 			var err error
 			var tlist []citasks.Pipeline
-			var task_table [][]string
 			var quiet bool
 			var v *viper.Viper = config.Viper
 
 			fetcher := client.NewTokenClient(v.GetString("master"), v.GetString("apikey"), config)
 
+			watch, err := cmd.Flags().GetBool("watch")
+			tools.CheckError(err)
+			if watch {
+				interval, err := cmd.Flags().GetDuration("interval")
+				tools.CheckError(err)
+				limit, err := cmd.Flags().GetInt("limit")
+				tools.CheckError(err)
+				watchPipelines(v, fetcher, limit, interval)
+				return
+			}
+
 			req := schema.Request{
 				Route:  v1.Schema.GetTaskRoute("pipeline_list"),
 				Target: &tlist,
@@ -73,26 +90,124 @@ func newPipelineListCommand(config *setting.Config) *cobra.Command {
 				return
 			}
 
+			table := tools.NewTable([]string{"ID", "Name", "Created"})
 			for _, i := range tlist {
 				t, _ := time.Parse("20060102150405", i.CreatedTime)
-				task_table = append(task_table, []string{i.ID, i.Name, t.String()})
+				table.Append([]string{i.ID, i.Name, t.String()})
 			}
 
-			table := tablewriter.NewWriter(os.Stdout)
-			table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
-			table.SetCenterSeparator("|")
-			table.SetHeader([]string{"ID", "Name", "Created"})
-
-			for _, v := range task_table {
-				table.Append(v)
-			}
-			table.Render()
+			err = tools.NewOutput(v).PrintList(tlist, table)
+			tools.CheckError(err)
 
 		},
 	}
 
 	var flags = cmd.Flags()
 	flags.BoolP("quiet", "q", false, "Quiet Output")
+	flags.BoolP("watch", "w", false, "Refresh the pipelines with the progress of their tasks")
+	flags.Duration("interval", 5*time.Second, "Interval between the refreshes with --watch")
+	flags.Int("limit", 20, "Number of pipelines shown with --watch")
 
 	return cmd
 }
+
+type pipelineProgress struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Completed int    `json:"completed"`
+	Total     int    `json:"total"`
+	Failed    int    `json:"failed"`
+	Status    string `json:"status"`
+	Elapsed   string `json:"elapsed"`
+}
+
+// watchPipelines prints the progress of the most recent pipelines
+// until interrupted.
+func watchPipelines(v *viper.Viper, fetcher client.HttpClient, limit int, interval time.Duration) {
+	// Completed tasks don't change, they are fetched only once.
+	completed := make(map[string]citasks.Task)
+
+	for {
+		var tlist []citasks.Pipeline
+		req := schema.Request{
+			Route:  v1.Schema.GetTaskRoute("pipeline_list"),
+			Target: &tlist,
+		}
+		tools.CheckError(fetcher.Handle(req))
+
+		sort.Slice(tlist[:], func(i, j int) bool {
+			return tlist[i].CreatedTime > tlist[j].CreatedTime
+		})
+		if limit > 0 && len(tlist) > limit {
+			tlist = tlist[:limit]
+		}
+
+		list := []pipelineProgress{}
+		for _, p := range tlist {
+			for name, t := range p.Tasks {
+				if c, ok := completed[t.ID]; ok {
+					p.Tasks[name] = c
+					continue
+				}
+				if t.ID == "" || fetchTask(fetcher, t.ID, &t) != nil {
+					continue
+				}
+				if t.IsDone() || t.IsStopped() {
+					completed[t.ID] = t
+				}
+				p.Tasks[name] = t
+			}
+			list = append(list, newPipelineProgress(&p, time.Now()))
+		}
+
+		table := tools.NewTable([]string{"ID", "Name", "Tasks", "Failed", "Status", "Elapsed"})
+		for _, p := range list {
+			table.Append([]string{p.ID, p.Name, fmt.Sprintf("%d/%d", p.Completed, p.Total),
+				strconv.Itoa(p.Failed), p.Status, p.Elapsed})
+		}
+
+		fmt.Print("\033[H\033[2J")
+		fmt.Printf("Every %s: pipelines on %s\t%s\n\n", interval, fetcher.GetBaseURL(),
+			time.Now().Format("2006-01-02 15:04:05"))
+		tools.CheckError(tools.NewOutput(v).PrintList(list, table))
+
+		time.Sleep(interval)
+	}
+}
+
+func newPipelineProgress(p *citasks.Pipeline, now time.Time) pipelineProgress {
+	ans := pipelineProgress{ID: p.ID, Name: p.Name, Total: len(p.Tasks)}
+
+	var end time.Time
+	running := false
+	for _, t := range p.Tasks {
+		switch {
+		case t.IsDone() || t.IsStopped():
+			ans.Completed++
+			if e, err := time.Parse(setting.Timeformat, t.EndTime); err == nil && e.After(end) {
+				end = e
+			}
+		default:
+			running = true
+		}
+		if t.Result == setting.TASK_RESULT_FAILED || t.Result == setting.TASK_RESULT_ERROR {
+			ans.Failed++
+		}
+	}
+
+	switch {
+	case running:
+		ans.Status = "running"
+		end = now.UTC()
+	case ans.Failed > 0:
+		ans.Status = setting.TASK_RESULT_FAILED
+	default:
+		ans.Status = setting.TASK_RESULT_SUCCESS
+	}
+
+	if start, err := time.Parse(setting.Timeformat, p.CreatedTime); err == nil && end.After(start) {
+		ans.Elapsed = end.Sub(start).Round(time.Second).String()
+	}
+
+	return ans
+}
@@ -0,0 +1,205 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package pipeline
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	schema "github.com/MottainaiCI/mottainai-server/routes/schema"
+	v1 "github.com/MottainaiCI/mottainai-server/routes/schema/v1"
+
+	tools "github.com/MottainaiCI/mottainai-cli/common"
+	client "github.com/MottainaiCI/mottainai-server/pkg/client"
+	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
+	citasks "github.com/MottainaiCI/mottainai-server/pkg/tasks"
+	"github.com/fatih/color"
+	cobra "github.com/spf13/cobra"
+	viper "github.com/spf13/viper"
+)
+
+// Colors assigned in turn to the tasks of the pipeline.
+var logColors = []color.Attribute{
+	color.FgCyan, color.FgYellow, color.FgGreen, color.FgMagenta, color.FgBlue, color.FgRed,
+	color.FgHiCyan, color.FgHiYellow, color.FgHiGreen, color.FgHiMagenta, color.FgHiBlue, color.FgHiRed,
+}
+
+type pipelineLog struct {
+	Name   string
+	ID     string
+	prefix string
+	pos    int
+	// Last line of the output not terminated yet.
+	partial []byte
+	done    bool
+}
+
+func newPipelineLogsCommand(config *setting.Config) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "logs <pipeline-id> [OPTIONS]",
+		Short: "Show the logs of all the tasks of a pipeline",
+		Long: `Show the logs of all the tasks of a pipeline, every line is
+prefixed with the name of the task.
+
+With --follow the logs are streamed until all the tasks
+of the pipeline are completed:
+
+$> mottainai-cli pipeline logs 123 -f
+`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var p citasks.Pipeline
+			var v *viper.Viper = config.Viper
+
+			follow, err := cmd.Flags().GetBool("follow")
+			tools.CheckError(err)
+			interval, err := cmd.Flags().GetDuration("interval")
+			tools.CheckError(err)
+			noColor, err := cmd.Flags().GetBool("no-color")
+			tools.CheckError(err)
+			if noColor {
+				color.NoColor = true
+			}
+
+			fetcher := client.NewTokenClient(v.GetString("master"), v.GetString("apikey"), config)
+
+			req := schema.Request{
+				Route: v1.Schema.GetTaskRoute("pipeline_show"),
+				Options: map[string]interface{}{
+					":id": args[0],
+				},
+				Target: &p,
+			}
+			tools.CheckError(fetcher.Handle(req))
+			if p.ID == "" {
+				tools.CheckError(tools.NotFoundError("Pipeline", args[0]))
+			}
+
+			logs := pipelineLogs(&p)
+			for {
+				running := false
+				for _, l := range logs {
+					if !l.Update(fetcher, os.Stdout) {
+						running = true
+					}
+				}
+				if !follow || !running {
+					break
+				}
+				time.Sleep(interval)
+			}
+			for _, l := range logs {
+				l.Flush(os.Stdout)
+			}
+		},
+	}
+
+	var flags = cmd.Flags()
+	flags.BoolP("follow", "f", false, "Stream the logs until all the tasks are completed")
+	flags.Duration("interval", 2*time.Second, "Interval between the updates of the logs")
+	flags.Bool("no-color", false, "Disable the colors of the task names")
+
+	return cmd
+}
+
+// pipelineLogs returns the logs of the tasks of the pipeline, with
+// the prefixes aligned and colorized.
+func pipelineLogs(p *citasks.Pipeline) []*pipelineLog {
+	nodes := pipelineNodes(p)
+
+	width := 0
+	for _, n := range nodes {
+		if len(n.Name) > width {
+			width = len(n.Name)
+		}
+	}
+
+	ans := []*pipelineLog{}
+	for i, n := range nodes {
+		c := color.New(logColors[i%len(logColors)]).SprintFunc()
+		ans = append(ans, &pipelineLog{
+			Name:   n.Name,
+			ID:     n.ID,
+			prefix: c(fmt.Sprintf("%-*s |", width, n.Name)) + " ",
+		})
+	}
+
+	return ans
+}
+
+// Update writes the complete lines appended to the log of the task
+// and returns true when the task is completed and the whole log has
+// been written.
+func (l *pipelineLog) Update(fetcher client.HttpClient, w io.Writer) bool {
+	if l.done {
+		return true
+	}
+	if l.ID == "" {
+		// The task has not been created on the master.
+		l.done = true
+		return true
+	}
+
+	var t citasks.Task
+	if err := fetchTask(fetcher, l.ID, &t); err != nil {
+		fmt.Fprintln(os.Stderr, "Error on retrieve task "+l.ID+": "+err.Error())
+		return false
+	}
+	// The status is checked before reading the output, so
+	// with a completed task the whole output is written.
+	completed := t.IsDone() || t.IsStopped()
+
+	buff, err := fetcher.TaskStream(l.ID, strconv.Itoa(l.pos))
+	if err != nil {
+		return false
+	}
+	l.pos += len(buff)
+
+	data := append(l.partial, buff...)
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		fmt.Fprintln(w, l.prefix+string(data[:i]))
+		data = data[i+1:]
+	}
+	l.partial = append([]byte{}, data...)
+
+	if completed {
+		l.Flush(w)
+		l.done = true
+	}
+
+	return l.done
+}
+
+// Flush writes the last line of the log not terminated yet.
+func (l *pipelineLog) Flush(w io.Writer) {
+	if len(l.partial) > 0 {
+		fmt.Fprintln(w, l.prefix+string(l.partial))
+		l.partial = nil
+	}
+}
@@ -0,0 +1,181 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package pipeline
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	template "github.com/MottainaiCI/mottainai-cli/cmd/task/template"
+	citasks "github.com/MottainaiCI/mottainai-server/pkg/tasks"
+
+	"github.com/ghodss/yaml"
+)
+
+// PipelineManifest is a pipeline definition with named tasks and
+// stages. A stage is the name of a task or a group of tasks executed
+// in parallel:
+//
+//	pipeline_name: release
+//	tasks:
+//	  test-amd64: { image: foo/builder, script: ["make test"] }
+//	  test-arm64: { image: foo/builder-arm, script: ["make test"] }
+//	  publish: { image: foo/builder, script: ["make publish"] }
+//	stages:
+//	  - group: [test-amd64, test-arm64]
+//	  - publish
+//
+// The stages are translated to the chain, group and chord of the
+// pipeline API, that can be used directly too.
+type PipelineManifest struct {
+	citasks.Pipeline
+	Stages []PipelineStage `json:"stages"`
+}
+
+type PipelineStage struct {
+	Task  string   `json:"task,omitempty"`
+	Group []string `json:"group,omitempty"`
+}
+
+func (s *PipelineStage) UnmarshalJSON(b []byte) error {
+	var name string
+	if err := json.Unmarshal(b, &name); err == nil {
+		s.Task = name
+		return nil
+	}
+
+	type stage PipelineStage
+	var st stage
+	if err := json.Unmarshal(b, &st); err != nil {
+		return err
+	}
+	if (st.Task == "") == (len(st.Group) == 0) {
+		return errors.New("A stage needs a task or a group")
+	}
+	*s = PipelineStage(st)
+	return nil
+}
+
+func pipelineFromManifest(f string, templ *template.Template) (*citasks.Pipeline, error) {
+	content, err := readManifest(f, templ)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &PipelineManifest{}
+	if err = yaml.Unmarshal(content, m); err != nil {
+		return nil, fmt.Errorf("Invalid pipeline manifest %s: %s", f, err.Error())
+	}
+
+	return m.Compile()
+}
+
+// readManifest returns the content of the manifest, rendered with
+// the template when not nil.
+func readManifest(f string, templ *template.Template) ([]byte, error) {
+	var content []byte
+	var err error
+
+	if f == "-" {
+		content, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		content, err = ioutil.ReadFile(f)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if templ != nil {
+		compiled, err := templ.Draw(string(content))
+		if err != nil {
+			return nil, fmt.Errorf("Error compiling manifest %s: %s", f, err.Error())
+		}
+		content = []byte(compiled)
+	}
+
+	return content, nil
+}
+
+// Compile returns the pipeline with the stages translated to the
+// chain, group or chord of the API. A sequence of tasks is a chain,
+// a single group is a group and a group followed by a task is a chord.
+func (m *PipelineManifest) Compile() (*citasks.Pipeline, error) {
+	p := m.Pipeline
+
+	if len(p.Tasks) == 0 {
+		return nil, errors.New("No tasks defined on the pipeline")
+	}
+
+	if len(m.Stages) > 0 {
+		if len(p.Chain)+len(p.Group)+len(p.Chord) > 0 {
+			return nil, errors.New("stages can't be used with chain, group or chord")
+		}
+
+		groups := 0
+		for _, s := range m.Stages {
+			if len(s.Group) > 0 {
+				groups++
+			}
+		}
+
+		switch {
+		case groups == 0:
+			for _, s := range m.Stages {
+				p.Chain = append(p.Chain, s.Task)
+			}
+		case groups == 1 && len(m.Stages) == 1:
+			p.Group = m.Stages[0].Group
+		case groups == 1 && len(m.Stages) == 2 && len(m.Stages[0].Group) > 0:
+			p.Chord = append(append([]string{}, m.Stages[0].Group...), m.Stages[1].Task)
+		default:
+			return nil, errors.New("Unsupported stages: the pipeline API supports a sequence of tasks, " +
+				"a group or a group followed by a task. Use task submit-graph for complex graphs")
+		}
+	}
+
+	used := make(map[string]bool)
+	for _, list := range [][]string{p.Chain, p.Group, p.Chord} {
+		for _, name := range list {
+			if _, ok := p.Tasks[name]; !ok {
+				return nil, errors.New("Task " + name + " used on the pipeline is not defined")
+			}
+			used[name] = true
+		}
+	}
+	if len(used) == 0 {
+		return nil, errors.New("No stages, chain, group or chord defined on the pipeline")
+	}
+	unused := []string{}
+	for name := range p.Tasks {
+		if !used[name] {
+			unused = append(unused, name)
+		}
+	}
+	if len(unused) > 0 {
+		sort.Strings(unused)
+		return nil, fmt.Errorf("Tasks %v are not used on the pipeline", unused)
+	}
+
+	return &p, nil
+}
@@ -0,0 +1,129 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package pipeline
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+
+	schema "github.com/MottainaiCI/mottainai-server/routes/schema"
+	v1 "github.com/MottainaiCI/mottainai-server/routes/schema/v1"
+
+	tools "github.com/MottainaiCI/mottainai-cli/common"
+	client "github.com/MottainaiCI/mottainai-server/pkg/client"
+	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
+	citasks "github.com/MottainaiCI/mottainai-server/pkg/tasks"
+	cobra "github.com/spf13/cobra"
+	viper "github.com/spf13/viper"
+)
+
+func newPipelineReportCommand(config *setting.Config) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "report <pipeline-id> --junit <file> [OPTIONS]",
+		Short: "Generate a JUnit XML report of a pipeline",
+		Long: `Generate a JUnit XML report with a test suite for the pipeline
+and a test case for every task of the pipeline.
+
+$> mottainai-cli pipeline report 123 --junit report.xml
+`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var p citasks.Pipeline
+			var v *viper.Viper = config.Viper
+
+			junit, err := cmd.Flags().GetString("junit")
+			tools.CheckError(err)
+			if junit == "" {
+				tools.CheckError(errors.New("You need to define the report file with --junit"))
+			}
+			lines, err := cmd.Flags().GetInt("log-lines")
+			tools.CheckError(err)
+
+			fetcher := client.NewTokenClient(v.GetString("master"), v.GetString("apikey"), config)
+
+			req := schema.Request{
+				Route: v1.Schema.GetTaskRoute("pipeline_show"),
+				Options: map[string]interface{}{
+					":id": args[0],
+				},
+				Target: &p,
+			}
+			tools.CheckError(fetcher.Handle(req))
+			if p.ID == "" {
+				tools.CheckError(tools.NotFoundError("Pipeline", args[0]))
+			}
+
+			names := []string{}
+			for name := range p.Tasks {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			tlist := []citasks.Task{}
+			logs := make(map[string]string)
+			for _, name := range names {
+				t := p.Tasks[name]
+				// Refresh the task to get its latest state.
+				tools.CheckError(fetchTask(fetcher, t.ID, &t))
+				if t.Name == "" {
+					t.Name = name
+				}
+				tlist = append(tlist, t)
+
+				if lines > 0 {
+					buff, err := tools.TaskLogTail(fetcher, config, v.GetString("apikey"), t.ID, lines)
+					if err == nil {
+						logs[t.ID] = strings.TrimRight(string(buff), "\n")
+					}
+				}
+			}
+
+			suite := p.Name
+			if suite == "" {
+				suite = p.ID
+			}
+			report := tools.NewJUnitReport(suite)
+			report.AddSuite(suite, tlist, logs)
+			tools.CheckError(report.WriteFile(junit))
+		},
+	}
+
+	var flags = cmd.Flags()
+	flags.String("junit", "", "Write the JUnit XML report on the file, - for stdout")
+	flags.Int("log-lines", 100, "Number of lines of the task log added to the report, 0 to disable")
+
+	return cmd
+}
+
+func fetchTask(f client.HttpClient, id string, t *citasks.Task) error {
+	req := schema.Request{
+		Route: v1.Schema.GetTaskRoute("as_json"),
+		Options: map[string]interface{}{
+			":id": id,
+		},
+	}
+	return f.HandleRaw(req, func(b io.ReadCloser) error {
+		return json.NewDecoder(b).Decode(t)
+	})
+}
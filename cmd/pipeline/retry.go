@@ -0,0 +1,185 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+
+	schema "github.com/MottainaiCI/mottainai-server/routes/schema"
+	v1 "github.com/MottainaiCI/mottainai-server/routes/schema/v1"
+
+	tools "github.com/MottainaiCI/mottainai-cli/common"
+	client "github.com/MottainaiCI/mottainai-server/pkg/client"
+	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
+	citasks "github.com/MottainaiCI/mottainai-server/pkg/tasks"
+	cobra "github.com/spf13/cobra"
+	viper "github.com/spf13/viper"
+)
+
+type retriedTask struct {
+	Name   string `json:"name"`
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	Result string `json:"result"`
+}
+
+func newPipelineRetryCommand(config *setting.Config) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "retry <pipeline-id> [OPTIONS]",
+		Short: "Submit again the tasks of a completed pipeline",
+		Long: `Submit again the tasks of a completed pipeline as a new pipeline
+with the same name.
+
+With --failed-only only the failed tasks and the tasks that
+depend on them are submitted, keeping the chain, group and
+chord of the original pipeline:
+
+$> mottainai-cli pipeline retry 123 --failed-only
+`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var p citasks.Pipeline
+			var v *viper.Viper = config.Viper
+
+			failedOnly, err := cmd.Flags().GetBool("failed-only")
+			tools.CheckError(err)
+
+			fetcher := client.NewTokenClient(v.GetString("master"), v.GetString("apikey"), config)
+
+			req := schema.Request{
+				Route: v1.Schema.GetTaskRoute("pipeline_show"),
+				Options: map[string]interface{}{
+					":id": args[0],
+				},
+				Target: &p,
+			}
+			tools.CheckError(fetcher.Handle(req))
+			if p.ID == "" {
+				tools.CheckError(tools.NotFoundError("Pipeline", args[0]))
+			}
+
+			for name, t := range p.Tasks {
+				// Refresh the task to get its latest state.
+				tools.CheckError(fetchTask(fetcher, t.ID, &t))
+				if t.IsRunning() || t.IsSetup() || t.IsWaiting() {
+					tools.CheckError(errors.New("Pipeline " + p.ID + " is still running, task " + name + " is " + t.Status))
+				}
+				p.Tasks[name] = t
+			}
+
+			retry := retryPipeline(&p, failedOnly)
+			if len(retry.Tasks) == 0 {
+				fmt.Println("No failed tasks on pipeline " + p.ID)
+				return
+			}
+
+			res, err := fetcher.PipelineCreate(retry.ToMap(false))
+			tools.CheckError(err)
+			if res.ID == "" {
+				tools.PrintResponse(res)
+				panic("Failed creating pipeline")
+			}
+
+			retried := []retriedTask{}
+			for _, n := range pipelineNodes(retry) {
+				t := p.Tasks[n.Name]
+				retried = append(retried, retriedTask{Name: n.Name, ID: t.ID, Status: t.Status, Result: t.Result})
+			}
+			table := tools.NewTable([]string{"Name", "ID", "Status", "Result"})
+			for _, t := range retried {
+				table.Append([]string{t.Name, t.ID, t.Status, t.Result})
+			}
+			tools.CheckError(tools.NewOutput(v).PrintList(retried, table))
+
+			fmt.Println("Pipeline " + p.ID + " retried as " + res.ID)
+		},
+	}
+
+	cmd.Flags().Bool("failed-only", false, "Submit only the failed tasks and the tasks depending on them")
+
+	return cmd
+}
+
+// retryPipeline returns a new pipeline with the definitions of the
+// tasks to submit again. With failedOnly the tasks that succeeded
+// and don't depend on a failed task are skipped.
+func retryPipeline(p *citasks.Pipeline, failedOnly bool) *citasks.Pipeline {
+	nodes := pipelineNodes(p)
+
+	retry := make(map[string]bool)
+	for _, n := range nodes {
+		t := p.Tasks[n.Name]
+		if !failedOnly || t.Result == setting.TASK_RESULT_FAILED || t.Result == setting.TASK_RESULT_ERROR {
+			retry[n.Name] = true
+		}
+	}
+	// Add the dependents, the nodes are sorted as defined
+	// on chain, group and chord.
+	for changed := true; changed; {
+		changed = false
+		for _, n := range nodes {
+			for _, dep := range n.DependsOn {
+				if retry[dep] && !retry[n.Name] {
+					retry[n.Name] = true
+					changed = true
+				}
+			}
+		}
+	}
+
+	filter := func(names []string) []string {
+		ans := []string{}
+		for _, name := range names {
+			if retry[name] {
+				ans = append(ans, name)
+			}
+		}
+		return ans
+	}
+
+	ans := &citasks.Pipeline{
+		Name:        p.Name,
+		Queue:       p.Queue,
+		Retry:       p.Retry,
+		Concurrency: p.Concurrency,
+		Chain:       filter(p.Chain),
+		Group:       filter(p.Group),
+		Chord:       filter(p.Chord),
+		Tasks:       make(map[string]citasks.Task),
+	}
+	// A chord with only the callback is a single task.
+	if len(ans.Chord) == 1 {
+		ans.Group = append(ans.Group, ans.Chord...)
+		ans.Chord = []string{}
+	}
+
+	for name := range retry {
+		t := p.Tasks[name]
+		// Runtime data of the original task are not copied.
+		t.ID, t.Status, t.Output, t.Result, t.ExitStatus, t.Node = "", "", "", "", "", ""
+		t.CreatedTime, t.StartTime, t.EndTime, t.UpdatedTime = "", "", "", ""
+		t.PipelineID = ""
+		ans.Tasks[name] = t
+	}
+
+	return ans
+}
@@ -21,13 +21,16 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package pipeline
 
 import (
-	"encoding/json"
 	"fmt"
-	"log"
+	"io"
+	"os"
+	"strings"
+	"time"
 
 	schema "github.com/MottainaiCI/mottainai-server/routes/schema"
 	v1 "github.com/MottainaiCI/mottainai-server/routes/schema/v1"
 
+	tools "github.com/MottainaiCI/mottainai-cli/common"
 	client "github.com/MottainaiCI/mottainai-server/pkg/client"
 	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
 	citasks "github.com/MottainaiCI/mottainai-server/pkg/tasks"
@@ -39,14 +42,21 @@ func newPipelineShowCommand(config *setting.Config) *cobra.Command {
 	var cmd = &cobra.Command{
 		Use:   "show <pipeline-id> [OPTIONS]",
 		Short: "Show a pipeline",
-		Args:  cobra.RangeArgs(1, 1),
+		Long: `Show the tasks of a pipeline as a tree, grouped by chain,
+group and chord, with the status and the duration of every task.
+
+  ✔ success   ✘ failed or error   ● running   ■ stopped   ○ waiting
+
+The pipeline is printed in JSON or YAML format with --output.
+`,
+		Args: cobra.RangeArgs(1, 1),
 		Run: func(cmd *cobra.Command, args []string) {
 			var t citasks.Pipeline
 			var v *viper.Viper = config.Viper
 
 			id := args[0]
 			if len(id) == 0 {
-				log.Fatalln("You need to define a pipeline id")
+				tools.CheckError(tools.ValidationError("You need to define a pipeline id"))
 			}
 
 			fetcher := client.NewTokenClient(v.GetString("master"), v.GetString("apikey"), config)
@@ -61,16 +71,127 @@ func newPipelineShowCommand(config *setting.Config) *cobra.Command {
 
 			err := fetcher.Handle(req)
 			if err != nil {
-				log.Fatalln("error:", err)
+				tools.CheckError(err)
 			}
 
-			b, err := json.MarshalIndent(t, "", "  ")
-			if err != nil {
-				log.Fatalln("error:", err)
+			o := tools.NewOutput(v)
+			if (o.Format == "" || o.Format == tools.OUTPUT_TABLE) && o.Template == "" && o.Query == "" {
+				printPipelineTree(os.Stdout, &t)
+				return
 			}
-			fmt.Println(string(b))
+
+			err = o.PrintObject(t)
+			tools.CheckError(err)
 		},
 	}
 
 	return cmd
 }
+
+// printPipelineTree writes the tasks of the pipeline grouped by
+// chain, group and chord.
+func printPipelineTree(w io.Writer, p *citasks.Pipeline) {
+	name := p.Name
+	if name == "" {
+		name = p.ID
+	}
+	fmt.Fprintf(w, "%s (%s)\n", name, p.ID)
+
+	nodes := pipelineNodes(p)
+	deps := make(map[string][]string)
+	for _, n := range nodes {
+		deps[n.Name] = n.DependsOn
+	}
+
+	type stage struct {
+		title string
+		tasks []string
+		// Show the dependencies, the order of a chain is implicit.
+		after bool
+	}
+	stages := []stage{}
+	if len(p.Chain) > 0 {
+		stages = append(stages, stage{"chain (sequential)", p.Chain, false})
+	}
+	if len(p.Group) > 0 {
+		stages = append(stages, stage{"group (parallel)", p.Group, true})
+	}
+	if len(p.Chord) > 0 {
+		stages = append(stages, stage{"chord (parallel, then callback)", p.Chord, true})
+	}
+	if len(stages) == 0 {
+		// Tasks not referenced by chain, group or chord.
+		names := []string{}
+		for _, n := range nodes {
+			names = append(names, n.Name)
+		}
+		stages = append(stages, stage{"tasks", names, false})
+	}
+
+	for i, s := range stages {
+		branch, indent := "├── ", "│   "
+		if i == len(stages)-1 {
+			branch, indent = "└── ", "    "
+		}
+		fmt.Fprintln(w, branch+s.title)
+
+		for j, name := range s.tasks {
+			leaf := "├── "
+			if j == len(s.tasks)-1 {
+				leaf = "└── "
+			}
+			t := p.Tasks[name]
+			line := fmt.Sprintf("%s %s", taskGlyph(&t), name)
+			if t.ID != "" {
+				line += " [" + t.ID + "]"
+			}
+			if status := taskState(&t); status != "" {
+				line += " " + status
+			}
+			if d := taskElapsed(&t); d > 0 {
+				line += " " + d.String()
+			}
+			if s.after && len(deps[name]) > 0 {
+				line += " (after " + strings.Join(deps[name], ", ") + ")"
+			}
+			fmt.Fprintln(w, indent+leaf+line)
+		}
+	}
+}
+
+func taskGlyph(t *citasks.Task) string {
+	switch {
+	case t.Result == setting.TASK_RESULT_SUCCESS:
+		return "✔"
+	case t.Result == setting.TASK_RESULT_FAILED, t.Result == setting.TASK_RESULT_ERROR:
+		return "✘"
+	case t.IsStopped():
+		return "■"
+	case t.IsRunning(), t.IsSetup():
+		return "●"
+	}
+	return "○"
+}
+
+func taskState(t *citasks.Task) string {
+	if t.Result != "" && t.Result != setting.TASK_RESULT_UNKNOWN {
+		return t.Result
+	}
+	return t.Status
+}
+
+// taskElapsed returns the duration of a completed task or the time
+// elapsed since the start of a running task.
+func taskElapsed(t *citasks.Task) time.Duration {
+	if d := tools.TaskDuration(t); d > 0 {
+		return d
+	}
+	if !t.IsRunning() {
+		return 0
+	}
+	start, err := time.Parse(setting.Timeformat, t.StartTime)
+	if err != nil {
+		return 0
+	}
+	return time.Since(start).Round(time.Second)
+}
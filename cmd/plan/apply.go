@@ -0,0 +1,370 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package plan
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	schema "github.com/MottainaiCI/mottainai-server/routes/schema"
+	v1 "github.com/MottainaiCI/mottainai-server/routes/schema/v1"
+
+	tools "github.com/MottainaiCI/mottainai-cli/common"
+	client "github.com/MottainaiCI/mottainai-server/pkg/client"
+	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
+	citasks "github.com/MottainaiCI/mottainai-server/pkg/tasks"
+	"github.com/ghodss/yaml"
+	cobra "github.com/spf13/cobra"
+	viper "github.com/spf13/viper"
+)
+
+const (
+	PLAN_APPLY_CREATE    = "create"
+	PLAN_APPLY_UPDATE    = "update"
+	PLAN_APPLY_DELETE    = "delete"
+	PLAN_APPLY_UNCHANGED = "unchanged"
+)
+
+type planAction struct {
+	Action string `json:"action"`
+	Name   string `json:"name"`
+	ID     string `json:"id"`
+	File   string `json:"file"`
+	Error  string `json:"error,omitempty"`
+
+	plan *citasks.Plan
+	diff string
+}
+
+func newPlanApplyCommand(config *setting.Config) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "apply -f <file|directory> [OPTIONS]",
+		Short: "Converge the plans of the master to a set of manifests",
+		Long: `Converge the plans of the master to a set of manifests.
+
+Every manifest is a YAML or JSON file with a plan, identified by
+its name. Missing plans are created and the plans with a different
+definition are updated. With --prune the named plans without a
+manifest are deleted too, after a confirmation. Plans without a
+name are never changed.
+
+The master doesn't support the update of a plan: a new plan with
+a new id is created and then the old one is removed.
+
+$> mottainai-cli plan apply -f plans/ --dry-run
+$> mottainai-cli plan apply -f plans/
+$> mottainai-cli plan apply -f plans/ --prune
+`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			var v *viper.Viper = config.Viper
+
+			path, err := cmd.Flags().GetString("file")
+			tools.CheckError(err)
+			if path == "" {
+				tools.CheckError(errors.New("You need to define the manifests with --file"))
+			}
+			dryRun, err := cmd.Flags().GetBool("dry-run")
+			tools.CheckError(err)
+			prune, err := cmd.Flags().GetBool("prune")
+			tools.CheckError(err)
+			yes, err := cmd.Flags().GetBool("yes")
+			tools.CheckError(err)
+			if prune {
+				// A single manifest would prune all the other plans.
+				if fi, err := os.Stat(path); err == nil && !fi.IsDir() {
+					tools.CheckError(tools.ValidationError("--prune needs a directory of manifests"))
+				}
+			}
+
+			manifests, err := loadPlanManifests(path, v.GetString("profile-namespace"))
+			tools.CheckError(err)
+
+			fetcher := client.NewTokenClient(v.GetString("master"), v.GetString("apikey"), config)
+
+			var current []citasks.Plan
+			req := schema.Request{
+				Route:  v1.Schema.GetTaskRoute("plan_list"),
+				Target: &current,
+			}
+			tools.CheckError(fetcher.Handle(req))
+
+			actions, err := planActions(manifests, current, prune)
+			tools.CheckError(err)
+
+			deleted := 0
+			for _, a := range actions {
+				if a.diff != "" {
+					fmt.Print(a.diff)
+				}
+				if a.Action == PLAN_APPLY_DELETE {
+					deleted++
+				}
+			}
+
+			if !dryRun && !yes && deleted > 0 {
+				if !tools.Confirm(fmt.Sprintf("Delete %d plans without a manifest?", deleted)) {
+					fmt.Fprintln(os.Stderr, "Aborted, use --yes to skip the confirmation or --dry-run to list the changes")
+					tools.Exit(1)
+				}
+			}
+
+			failed := false
+			if !dryRun {
+				for _, a := range actions {
+					if err := applyPlanAction(fetcher, a); err != nil {
+						a.Error = err.Error()
+						failed = true
+					}
+				}
+			}
+
+			table := tools.NewTable([]string{"Action", "Name", "ID", "File", "Error"})
+			for _, a := range actions {
+				table.Append([]string{a.Action, a.Name, a.ID, a.File, a.Error})
+			}
+			tools.CheckError(tools.NewOutput(v).PrintList(actions, table))
+
+			if failed {
+				tools.Exit(1)
+			}
+		},
+	}
+
+	var flags = cmd.Flags()
+	flags.StringP("file", "f", "", "Plan manifest or directory with the plan manifests")
+	flags.Bool("dry-run", false, "Show the changes without applying them")
+	flags.Bool("prune", false, "Delete the named plans without a manifest")
+	flags.BoolP("yes", "y", false, "Don't ask confirmation")
+
+	return cmd
+}
+
+// loadPlanManifests reads the plan manifests from a file or from the
+// .yaml, .yml and .json files of a directory.
+func loadPlanManifests(path, namespace string) (map[string]*planAction, error) {
+	files := []string{path}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if fi.IsDir() {
+		files = []string{}
+		for _, ext := range []string{"*.yaml", "*.yml", "*.json"} {
+			matches, err := filepath.Glob(filepath.Join(path, ext))
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, matches...)
+		}
+		sort.Strings(files)
+	}
+
+	ans := make(map[string]*planAction)
+	for _, f := range files {
+		content, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		p := &citasks.Plan{Task: &citasks.Task{}}
+		if err = yaml.Unmarshal(content, p); err != nil {
+			return nil, fmt.Errorf("Invalid plan manifest %s: %s", f, err.Error())
+		}
+		if p.Name == "" {
+			return nil, errors.New("The plan of " + f + " needs a name")
+		}
+		if _, err = parsePlanned(p.Planned); err != nil {
+			return nil, fmt.Errorf("%s: %s", f, err.Error())
+		}
+		if prev, ok := ans[p.Name]; ok {
+			return nil, errors.New("Plan " + p.Name + " defined on " + prev.File + " and " + f)
+		}
+		// Use the default namespace of the active profile
+		if p.Namespace == "" {
+			p.Namespace = namespace
+		}
+		ans[p.Name] = &planAction{Name: p.Name, File: f, plan: p}
+	}
+	if len(ans) == 0 {
+		return nil, errors.New("No plan manifests found on " + path)
+	}
+
+	return ans, nil
+}
+
+// planActions returns the actions to converge the plans of the master
+// to the manifests.
+func planActions(manifests map[string]*planAction, current []citasks.Plan, prune bool) ([]*planAction, error) {
+	ans := []*planAction{}
+
+	existing := make(map[string]*citasks.Plan)
+	for i := range current {
+		p := &current[i]
+		if p.Task == nil || p.Name == "" {
+			continue
+		}
+		if _, ok := existing[p.Name]; ok {
+			return nil, errors.New("More plans named " + p.Name + " on the master, remove the duplicates")
+		}
+		existing[p.Name] = p
+	}
+
+	names := []string{}
+	for name := range manifests {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		a := manifests[name]
+		old, ok := existing[name]
+		if !ok {
+			a.Action = PLAN_APPLY_CREATE
+			ans = append(ans, a)
+			continue
+		}
+
+		a.ID = old.ID
+		before, err := planDefinition(old)
+		if err != nil {
+			return nil, err
+		}
+		after, err := planDefinition(a.plan)
+		if err != nil {
+			return nil, err
+		}
+		a.diff = tools.UnifiedDiff(before, after, "plan "+old.ID, a.File, 3)
+		if a.diff == "" {
+			a.Action = PLAN_APPLY_UNCHANGED
+		} else {
+			a.Action = PLAN_APPLY_UPDATE
+		}
+		ans = append(ans, a)
+	}
+
+	if prune {
+		for _, p := range current {
+			if p.Task == nil || p.Name == "" {
+				continue
+			}
+			if _, ok := manifests[p.Name]; !ok {
+				ans = append(ans, &planAction{Action: PLAN_APPLY_DELETE, Name: p.Name, ID: p.ID})
+			}
+		}
+	}
+
+	return ans, nil
+}
+
+// applyPlanAction applies a on the master. An updated plan is created
+// before removing the old one, so the plan isn't lost when the
+// creation fails.
+func applyPlanAction(fetcher client.HttpClient, a *planAction) error {
+	switch a.Action {
+	case PLAN_APPLY_CREATE, PLAN_APPLY_UPDATE:
+		dat := a.plan.ToMap()
+		// ToMap adds the embedded task without a name.
+		delete(dat, "")
+		res, err := fetcher.PlanCreate(dat)
+		if err != nil {
+			return err
+		}
+		if res.ID == "" {
+			tools.PrintResponse(res)
+			return errors.New("Failed creating plan " + a.Name)
+		}
+		if a.Action == PLAN_APPLY_CREATE {
+			a.ID = res.ID
+			return nil
+		}
+
+		old := a.ID
+		a.ID = res.ID
+		if err := removePlan(fetcher, old); err != nil {
+			return fmt.Errorf("Plan %s created but the old plan %s is still present: %s",
+				res.ID, old, err.Error())
+		}
+	case PLAN_APPLY_DELETE:
+		return removePlan(fetcher, a.ID)
+	}
+	return nil
+}
+
+func removePlan(fetcher client.HttpClient, id string) error {
+	res, err := fetcher.PlanDelete(id)
+	if err != nil {
+		return err
+	}
+	if res.Error != "" {
+		return errors.New("Failed removing plan " + id + ": " + res.Error)
+	}
+	return nil
+}
+
+// Fields of a plan assigned by the master.
+var planRuntimeFields = []string{
+	"ID", "status", "output", "result", "exit_status", "node_id",
+	"created_time", "start_time", "end_time", "last_update_time",
+}
+
+// planDefinition returns the fields of the plan set by the user.
+func planDefinition(p *citasks.Plan) (string, error) {
+	var dat map[string]interface{}
+
+	b, err := json.Marshal(p)
+	if err != nil {
+		return "", err
+	}
+	if err = json.Unmarshal(b, &dat); err != nil {
+		return "", err
+	}
+	for _, f := range append(planRuntimeFields, "owner_id") {
+		delete(dat, f)
+	}
+	for k, value := range dat {
+		switch val := value.(type) {
+		case nil:
+			delete(dat, k)
+		case string:
+			if strings.TrimSpace(val) == "" {
+				delete(dat, k)
+			}
+		case float64:
+			if val == 0 {
+				delete(dat, k)
+			}
+		case []interface{}:
+			if len(val) == 0 {
+				delete(dat, k)
+			}
+		}
+	}
+
+	b, err = yaml.Marshal(dat)
+	return string(b), err
+}
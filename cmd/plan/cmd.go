@@ -33,6 +33,7 @@ func NewPlanCommand(config *setting.Config) *cobra.Command {
 	}
 
 	cmd.AddCommand(
+		newPlanApplyCommand(config),
 		newPlanCreateCommand(config),
 		newPlanListCommand(config),
 		newPlanRemoveCommand(config),
@@ -87,6 +87,15 @@ func newPlanCreateCommand(config *setting.Config) *cobra.Command {
 				}
 			}
 
+			// Use the default namespace of the active profile
+			if ns, _ := dat["namespace"].(string); ns == "" && v.GetString("profile-namespace") != "" {
+				dat["namespace"] = v.GetString("profile-namespace")
+			}
+
+			planned, _ := dat["planned"].(string)
+			schedule, err := parsePlanned(planned)
+			tools.CheckError(err)
+
 			res, err := fetcher.PlanCreate(dat)
 			tools.CheckError(err)
 
@@ -99,6 +108,8 @@ func newPlanCreateCommand(config *setting.Config) *cobra.Command {
 			fmt.Println("-------------------------")
 			fmt.Println("Plan " + tid + " has been created")
 			fmt.Println("-------------------------")
+			printNextRuns(schedule)
+			fmt.Println("-------------------------")
 			fmt.Println("Information: ", tools.BuildCmdArgs(cmd, "plan show "+tid))
 			fmt.Println("-------------------------")
 		},
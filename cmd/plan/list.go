@@ -22,7 +22,6 @@ package plan
 
 import (
 	"fmt"
-	"os"
 	"sort"
 
 	schema "github.com/MottainaiCI/mottainai-server/routes/schema"
@@ -32,7 +31,6 @@ import (
 	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
 	citasks "github.com/MottainaiCI/mottainai-server/pkg/tasks"
 	v1 "github.com/MottainaiCI/mottainai-server/routes/schema/v1"
-	tablewriter "github.com/olekukonko/tablewriter"
 	cobra "github.com/spf13/cobra"
 	viper "github.com/spf13/viper"
 )
@@ -45,7 +43,6 @@ func newPlanListCommand(config *setting.Config) *cobra.Command {
 		Run: func(cmd *cobra.Command, args []string) {
 			var err error
 			var tlist []citasks.Plan
-			var task_table [][]string
 			var quiet bool
 			var v *viper.Viper = config.Viper
 
@@ -72,19 +69,13 @@ func newPlanListCommand(config *setting.Config) *cobra.Command {
 				return
 			}
 
+			table := tools.NewTable([]string{"ID", "Planned", "From Namespace", "Tag to", "Source", "Dir"})
 			for _, i := range tlist {
-				task_table = append(task_table, []string{i.ID, i.Planned, i.Namespace, i.TagNamespace, i.Source, i.Directory})
+				table.Append([]string{i.ID, i.Planned, i.Namespace, i.TagNamespace, i.Source, i.Directory})
 			}
 
-			table := tablewriter.NewWriter(os.Stdout)
-			table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
-			table.SetCenterSeparator("|")
-			table.SetHeader([]string{"ID", "Planned", "From Namespace", "Tag to", "Source", "Dir"})
-
-			for _, v := range task_table {
-				table.Append(v)
-			}
-			table.Render()
+			err = tools.NewOutput(v).PrintList(tlist, table)
+			tools.CheckError(err)
 
 		},
 	}
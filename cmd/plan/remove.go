@@ -21,8 +21,6 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package plan
 
 import (
-	"log"
-
 	tools "github.com/MottainaiCI/mottainai-cli/common"
 	client "github.com/MottainaiCI/mottainai-server/pkg/client"
 	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
@@ -42,7 +40,7 @@ func newPlanRemoveCommand(config *setting.Config) *cobra.Command {
 
 			id := args[0]
 			if len(id) == 0 {
-				log.Fatalln("You need to define a plan id")
+				tools.CheckError(tools.ValidationError("You need to define a plan id"))
 			}
 
 			res, err := fetcher.PlanDelete(id)
@@ -0,0 +1,76 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package plan
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"time"
+
+	cron "gopkg.in/robfig/cron.v2"
+)
+
+// Number of scheduled runs of a plan shown by create and show.
+const planNextRuns = 5
+
+// parsePlanned parses the cron expression of a plan with the same
+// parser of the master.
+func parsePlanned(spec string) (cron.Schedule, error) {
+	if spec == "" {
+		return nil, errors.New("A plan needs a cron expression on planned ( e.g. @every 1h, 0 30 * * * * )")
+	}
+
+	// The parser logs the errors before returning them.
+	log.SetOutput(ioutil.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	s, err := cron.Parse(spec)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid planned expression %q: %s", spec, err.Error())
+	}
+	if s.Next(time.Now()).IsZero() {
+		return nil, fmt.Errorf("Invalid planned expression %q: it never runs", spec)
+	}
+	return s, nil
+}
+
+// nextRuns returns the next n run times of the schedule after t.
+func nextRuns(s cron.Schedule, t time.Time, n int) []time.Time {
+	ans := []time.Time{}
+	for i := 0; i < n; i++ {
+		t = s.Next(t)
+		if t.IsZero() {
+			break
+		}
+		ans = append(ans, t)
+	}
+	return ans
+}
+
+func printNextRuns(s cron.Schedule) {
+	fmt.Println("Next runs:")
+	for _, t := range nextRuns(s, time.Now(), planNextRuns) {
+		fmt.Println("  " + t.Format("2006-01-02 15:04:05 MST"))
+	}
+}
@@ -21,12 +21,12 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package plan
 
 import (
-	"encoding/json"
 	"fmt"
-	"log"
+	"os"
 
 	schema "github.com/MottainaiCI/mottainai-server/routes/schema"
 
+	tools "github.com/MottainaiCI/mottainai-cli/common"
 	client "github.com/MottainaiCI/mottainai-server/pkg/client"
 	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
 	citasks "github.com/MottainaiCI/mottainai-server/pkg/tasks"
@@ -46,7 +46,7 @@ func newPlanShowCommand(config *setting.Config) *cobra.Command {
 
 			id := args[0]
 			if len(id) == 0 {
-				log.Fatalln("You need to define a plan id")
+				tools.CheckError(tools.ValidationError("You need to define a plan id"))
 			}
 
 			fetcher := client.NewTokenClient(v.GetString("master"), v.GetString("apikey"), config)
@@ -61,13 +61,21 @@ func newPlanShowCommand(config *setting.Config) *cobra.Command {
 
 			err := fetcher.Handle(req)
 			if err != nil {
-				log.Fatalln("error:", err)
+				tools.CheckError(err)
 			}
-			b, err := json.MarshalIndent(t, "", "  ")
-			if err != nil {
-				log.Fatalln("error:", err)
+			o := tools.NewOutput(v)
+			err = o.PrintObject(t)
+			tools.CheckError(err)
+
+			// The scheduled runs are added to the default output.
+			if o.Format == "" && o.Template == "" && o.Query == "" {
+				schedule, err := parsePlanned(t.Planned)
+				if err != nil {
+					fmt.Fprintln(os.Stderr, err.Error())
+					return
+				}
+				printNextRuns(schedule)
 			}
-			fmt.Println(string(b))
 		},
 	}
 
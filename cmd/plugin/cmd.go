@@ -0,0 +1,51 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package plugin
+
+import (
+	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
+	"github.com/spf13/cobra"
+)
+
+func NewPluginCommand(config *setting.Config) *cobra.Command {
+
+	var cmd = &cobra.Command{
+		Use:   "plugin [command] [OPTIONS]",
+		Short: "Manage the plugins of the cli",
+		Long: `Plugins are executables on PATH named mottainai-<name>, run as
+the <name> subcommand of the cli with the remaining arguments:
+
+$> mottainai-cli --profile prod report --weekly
+   runs: mottainai-report --weekly
+
+Built-in commands have precedence over the plugins. The settings of
+the active profile are available to the plugin as environment
+variables: MOTTAINAI_CLI_MASTER, MOTTAINAI_CLI_APIKEY,
+MOTTAINAI_CLI_PROFILE, MOTTAINAI_CLI_NAMESPACE, the TLS, proxy and
+auth options and MOTTAINAI_CLI_BIN with the path of the cli.`,
+	}
+
+	cmd.AddCommand(
+		newPluginListCommand(config),
+	)
+
+	return cmd
+}
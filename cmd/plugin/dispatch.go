@@ -0,0 +1,112 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package plugin
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	tools "github.com/MottainaiCI/mottainai-cli/common"
+	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
+	cobra "github.com/spf13/cobra"
+)
+
+// Dispatch runs the plugin named by the first argument of args that
+// isn't a global flag, unless it's a built-in command of root. The
+// flags before the name select the profile supplied to the plugin.
+// It returns false when args don't refer to a plugin, otherwise the
+// exit code of the plugin.
+func Dispatch(root *cobra.Command, config *setting.Config, args []string) (bool, int) {
+	var i int
+	for i = 0; i < len(args); i++ {
+		if args[i] == "--" {
+			return false, 0
+		}
+		if !strings.HasPrefix(args[i], "-") {
+			break
+		}
+		if tools.FlagTakesValue(root.PersistentFlags(), args[i]) {
+			i++
+		}
+	}
+	if i >= len(args) || args[i] == "help" {
+		return false, 0
+	}
+	if c, _, err := root.Find(args[i : i+1]); err != nil || c != root {
+		return false, 0
+	}
+	path, err := tools.FindPlugin(args[i])
+	if err != nil {
+		return false, 0
+	}
+
+	if err := root.ParseFlags(args[:i]); err != nil {
+		fmt.Fprintln(os.Stderr, "Error: "+err.Error())
+		return true, tools.EXIT_VALIDATION
+	}
+	root.PersistentPreRun(root, nil)
+
+	cmd := exec.Command(path, args[i+1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.Env = tools.PluginEnv(config.Viper)
+
+	// The plugin handles the interrupts, SIGINT is received from the
+	// terminal and SIGTERM is forwarded.
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	if err := cmd.Start(); err != nil {
+		fmt.Fprintln(os.Stderr, "Error: "+err.Error())
+		return true, tools.EXIT_FAILURE
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case s := <-signals:
+				if s == syscall.SIGTERM {
+					cmd.Process.Signal(s)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	err = cmd.Wait()
+	if e, ok := err.(*exec.ExitError); ok {
+		if code := e.ExitCode(); code > 0 {
+			return true, code
+		}
+		return true, tools.EXIT_FAILURE
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error: "+err.Error())
+		return true, tools.EXIT_FAILURE
+	}
+	return true, tools.EXIT_SUCCESS
+}
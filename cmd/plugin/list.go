@@ -0,0 +1,74 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package plugin
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	tools "github.com/MottainaiCI/mottainai-cli/common"
+	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
+	cobra "github.com/spf13/cobra"
+	viper "github.com/spf13/viper"
+)
+
+func newPluginListCommand(config *setting.Config) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "list [OPTIONS]",
+		Short: "List the plugins found on PATH",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			var v *viper.Viper = config.Viper
+
+			quiet, err := cmd.Flags().GetBool("quiet")
+			tools.CheckError(err)
+
+			plugins := tools.ListPlugins()
+			for _, p := range plugins {
+				if c, _, err := cmd.Root().Find([]string{p.Name}); err == nil && c != cmd.Root() {
+					fmt.Fprintf(os.Stderr, "Warning: %s is hidden by the built-in command %s\n", p.Path, p.Name)
+				}
+				for _, s := range p.Shadowed {
+					fmt.Fprintf(os.Stderr, "Warning: %s is hidden by %s\n", s, p.Path)
+				}
+			}
+
+			if quiet {
+				for _, p := range plugins {
+					fmt.Println(p.Name)
+				}
+				return
+			}
+
+			table := tools.NewTable([]string{"Name", "Path", "Shadowed"})
+			for _, p := range plugins {
+				table.Append([]string{p.Name, p.Path, strings.Join(p.Shadowed, ", ")})
+			}
+			tools.CheckError(tools.NewOutput(v).PrintList(plugins, table))
+		},
+	}
+
+	var flags = cmd.Flags()
+	flags.BoolP("quiet", "q", false, "Quiet Output")
+
+	return cmd
+}
@@ -36,6 +36,12 @@ func NewProfileCommand(config *setting.Config) *cobra.Command {
 		newProfileListCommand(config),
 		newProfileCreateCommand(config),
 		newProfileRemoveCommand(config),
+		newProfileUseCommand(config),
+		newProfileExportCommand(config),
+		newProfileImportCommand(config),
+		newProfileMigrateCommand(config),
+		newProfileEncryptCommand(config),
+		newProfileDecryptCommand(config),
 	)
 
 	return cmd
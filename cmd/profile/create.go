@@ -22,8 +22,7 @@ package profile
 
 import (
 	"fmt"
-	"os"
-	path "path/filepath"
+	"time"
 
 	common "github.com/MottainaiCI/mottainai-cli/common"
 	tools "github.com/MottainaiCI/mottainai-cli/common"
@@ -39,7 +38,7 @@ func newProfileCreateCommand(config *setting.Config) *cobra.Command {
 		Args:  cobra.RangeArgs(2, 3),
 		Run: func(cmd *cobra.Command, args []string) {
 			var err error
-			var name, master, apikey, f string
+			var name, master, apikey, namespace, f string
 			var conf common.ProfileConf
 			var v *viper.Viper = config.Viper
 
@@ -48,12 +47,16 @@ func newProfileCreateCommand(config *setting.Config) *cobra.Command {
 			if len(args) == 3 {
 				apikey = args[2]
 			}
+			namespace, err = cmd.Flags().GetString("namespace")
+			tools.CheckError(err)
+			useKeyring, err := cmd.Flags().GetBool("use-keyring")
+			tools.CheckError(err)
 
 			if v.Get("profiles") == nil {
 				// POST: No configuration file found
 
 				conf = *common.NewProfileConf()
-				err = conf.AddProfile(name, master, apikey)
+				err = conf.AddProfile(name, master, apikey, namespace)
 				tools.CheckError(err)
 
 			} else {
@@ -68,33 +71,59 @@ func newProfileCreateCommand(config *setting.Config) *cobra.Command {
 					return
 				}
 
-				err = conf.AddProfile(name, master, apikey)
+				err = conf.AddProfile(name, master, apikey, namespace)
 				tools.CheckError(err)
 			}
 
-			// Create new viper configuration to avoid
-			// write of command line arguments/settings
-			viper := viper.New()
-			viper.SetConfigType("yaml")
-			viper.Set("profiles", conf.Profiles)
-
-			if v.ConfigFileUsed() != "" {
-				f = v.ConfigFileUsed()
-			} else {
-
-				f = fmt.Sprintf("%s/%s/%s.yml",
-					common.GetHomeDir(),
-					common.MCLI_HOME_PATH,
-					common.MCLI_CONFIG_NAME)
+			p := conf.Profiles[name]
+			p.CACert, err = cmd.Flags().GetString("cacert")
+			tools.CheckError(err)
+			p.ClientCert, err = cmd.Flags().GetString("cert")
+			tools.CheckError(err)
+			p.ClientKey, err = cmd.Flags().GetString("key")
+			tools.CheckError(err)
+			p.InsecureSkipVerify, err = cmd.Flags().GetBool("insecure")
+			tools.CheckError(err)
+			p.Proxy, err = cmd.Flags().GetString("proxy")
+			tools.CheckError(err)
+			_, err = common.NewProxyFunc(p.Proxy)
+			tools.CheckError(err)
+			p.RequestTimeout, err = cmd.Flags().GetString("request-timeout")
+			tools.CheckError(err)
+			if p.RequestTimeout != "" {
+				_, err = time.ParseDuration(p.RequestTimeout)
+				tools.CheckError(err)
+			}
+			p.Auth, err = cmd.Flags().GetString("auth")
+			tools.CheckError(err)
+			if p.Auth != "" && p.Auth != common.AUTH_TOKEN && p.Auth != common.AUTH_MACAROON && p.Auth != common.AUTH_SSO {
+				tools.CheckError(tools.ValidationError("Invalid auth mode %s, valid modes are: %s, %s, %s",
+					p.Auth, common.AUTH_TOKEN, common.AUTH_MACAROON, common.AUTH_SSO))
+			}
+			p.Retries, err = cmd.Flags().GetInt("retries")
+			tools.CheckError(err)
+			p.RetryDelay, err = cmd.Flags().GetString("retry-delay")
+			tools.CheckError(err)
+			if p.RetryDelay != "" {
+				_, err = time.ParseDuration(p.RetryDelay)
+				tools.CheckError(err)
+			}
 
-				// Create directory where save file if doesn't exists
-				if _, err := os.Stat(path.Dir(f)); os.IsNotExist(err) {
-					err = os.MkdirAll(path.Dir(f), 0760)
-					tools.CheckError(err)
+			if useKeyring {
+				if apikey == "" {
+					fmt.Println("An api-key is needed to use the keyring.")
+					return
 				}
+				store := common.NewKeyringStore(common.MCLI_KEYRING_SERVICE)
+				err = store.Set(name, apikey)
+				tools.CheckError(err)
+
+				p.ApiKey = ""
+				p.CredentialStore = common.CREDENTIAL_STORE_KEYRING
 			}
+			conf.Profiles[name] = p
 
-			err = viper.WriteConfigAs(f)
+			f, err = conf.Write(v.ConfigFileUsed())
 			tools.CheckError(err)
 
 			fmt.Printf("Profile %s with url %s added on file %s.\n",
@@ -102,5 +131,18 @@ func newProfileCreateCommand(config *setting.Config) *cobra.Command {
 		},
 	}
 
+	var flags = cmd.Flags()
+	flags.StringP("namespace", "n", "", "Default namespace used by the profile")
+	flags.Bool("use-keyring", false, "Store the api-key on the OS keyring instead of the profiles file")
+	flags.String("cacert", "", "CA certificate used to verify the master")
+	flags.String("cert", "", "Client certificate used to authenticate with the master")
+	flags.String("key", "", "Client key used to authenticate with the master")
+	flags.Bool("insecure", false, "Skip verification of the master certificate")
+	flags.String("proxy", "", "HTTP or SOCKS5 proxy used to reach the master")
+	flags.String("request-timeout", "", "Timeout of the connection and of the master response headers ( e.g. 30s )")
+	flags.String("auth", "", "Authentication mode: token ( default ), macaroon or sso")
+	flags.Int("retries", 0, "Number of retries of the failed API calls")
+	flags.String("retry-delay", "", "Delay before the first retry of a failed API call ( e.g. 2s )")
+
 	return cmd
 }
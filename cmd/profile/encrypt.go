@@ -0,0 +1,111 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package profile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	common "github.com/MottainaiCI/mottainai-cli/common"
+	tools "github.com/MottainaiCI/mottainai-cli/common"
+	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
+	cobra "github.com/spf13/cobra"
+	viper "github.com/spf13/viper"
+)
+
+func newProfileEncryptCommand(config *setting.Config) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "encrypt [OPTIONS]",
+		Short: "Encrypt profiles file with a passphrase",
+		Long: `Encrypt profiles file with a passphrase.
+
+The passphrase is read from MOTTAINAI_CLI_PASSPHRASE variable,
+from the file defined by MOTTAINAI_CLI_PASSPHRASE_FILE variable
+or from terminal.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var conf common.ProfileConf
+			var v *viper.Viper = config.Viper
+
+			f := v.ConfigFileUsed()
+			if v.Get("profiles") == nil || f == "" {
+				fmt.Println("No profiles available.")
+				return
+			}
+			if common.IsEncryptedFile(f) {
+				fmt.Printf("Profiles file %s is already encrypted.\n", f)
+				return
+			}
+
+			err = v.Unmarshal(&conf)
+			tools.CheckError(err)
+
+			// Confirmed here, Write uses the same passphrase.
+			_, err = common.NewPassphrase()
+			tools.CheckError(err)
+
+			enc, err := conf.Write(common.EncryptedProfilesFile(filepath.Dir(f)))
+			tools.CheckError(err)
+
+			err = os.Remove(f)
+			tools.CheckError(err)
+
+			fmt.Printf("Profiles encrypted on file %s.\n", enc)
+		},
+	}
+
+	return cmd
+}
+
+func newProfileDecryptCommand(config *setting.Config) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "decrypt [OPTIONS]",
+		Short: "Decrypt profiles file",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var conf common.ProfileConf
+			var v *viper.Viper = config.Viper
+
+			f := v.ConfigFileUsed()
+			if !common.IsEncryptedFile(f) {
+				fmt.Println("No encrypted profiles available.")
+				return
+			}
+
+			err = v.Unmarshal(&conf)
+			tools.CheckError(err)
+
+			plain, err := conf.Write(strings.TrimSuffix(f, common.MCLI_ENCRYPTED_EXT))
+			tools.CheckError(err)
+
+			err = os.Remove(f)
+			tools.CheckError(err)
+
+			fmt.Printf("Profiles decrypted on file %s.\n", plain)
+		},
+	}
+
+	return cmd
+}
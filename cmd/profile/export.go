@@ -0,0 +1,128 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package profile
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+
+	common "github.com/MottainaiCI/mottainai-cli/common"
+	tools "github.com/MottainaiCI/mottainai-cli/common"
+	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
+	cobra "github.com/spf13/cobra"
+	viper "github.com/spf13/viper"
+)
+
+func newProfileExportCommand(config *setting.Config) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "export [file.yaml] [OPTIONS]",
+		Short: "Export profiles to a YAML file",
+		Args:  cobra.RangeArgs(0, 1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var conf common.ProfileConf
+			var v *viper.Viper = config.Viper
+
+			if v.Get("profiles") == nil {
+				fmt.Println("No profiles available.")
+				return
+			}
+
+			err = v.Unmarshal(&conf)
+			tools.CheckError(err)
+
+			secrets, err := cmd.Flags().GetBool("with-secrets")
+			tools.CheckError(err)
+
+			exported, err := conf.Export(secrets)
+			tools.CheckError(err)
+
+			data, err := exported.ToYaml()
+			tools.CheckError(err)
+
+			if len(args) == 0 {
+				fmt.Print(string(data))
+				return
+			}
+
+			err = ioutil.WriteFile(args[0], data, 0600)
+			tools.CheckError(err)
+
+			fmt.Printf("Exported %d profiles on file %s.\n", len(exported.Profiles), args[0])
+		},
+	}
+
+	var flags = cmd.Flags()
+	flags.Bool("with-secrets", false, "Include api-keys on exported file")
+
+	return cmd
+}
+
+func newProfileImportCommand(config *setting.Config) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "import <file.yaml> [OPTIONS]",
+		Short: "Import profiles from a YAML file",
+		Args:  cobra.RangeArgs(1, 1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var conf, imported common.ProfileConf
+			var v *viper.Viper = config.Viper
+
+			overwrite, err := cmd.Flags().GetBool("overwrite")
+			tools.CheckError(err)
+
+			// Read file with a new viper instance to support
+			// the same syntax of the profiles file.
+			in := viper.New()
+			in.SetConfigFile(args[0])
+			err = in.ReadInConfig()
+			tools.CheckError(err)
+			err = in.Unmarshal(&imported)
+			tools.CheckError(err)
+			imported.Migrate()
+
+			if v.Get("profiles") != nil {
+				err = v.Unmarshal(&conf)
+				tools.CheckError(err)
+			}
+
+			names := conf.Import(&imported, overwrite)
+			if len(names) == 0 {
+				fmt.Println("No profiles imported.")
+				return
+			}
+
+			f, err := conf.Write(v.ConfigFileUsed())
+			tools.CheckError(err)
+
+			sort.Strings(names)
+			for _, name := range names {
+				fmt.Printf("Profile %s imported on file %s.\n", name, f)
+			}
+		},
+	}
+
+	var flags = cmd.Flags()
+	flags.Bool("overwrite", false, "Replace existing profiles with the same name")
+
+	return cmd
+}
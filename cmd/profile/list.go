@@ -22,16 +22,22 @@ package profile
 
 import (
 	"fmt"
-	"os"
+	"sort"
 
 	common "github.com/MottainaiCI/mottainai-cli/common"
 	tools "github.com/MottainaiCI/mottainai-cli/common"
 	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
-	tablewriter "github.com/olekukonko/tablewriter"
 	cobra "github.com/spf13/cobra"
 	viper "github.com/spf13/viper"
 )
 
+// profileListItem is a profile with its name, as printed by profile list.
+type profileListItem struct {
+	Name   string `json:"name"`
+	Active bool   `json:"active"`
+	common.Profile
+}
+
 func newProfileListCommand(config *setting.Config) *cobra.Command {
 	var cmd = &cobra.Command{
 		Use:   "list [OPTIONS]",
@@ -54,20 +60,31 @@ func newProfileListCommand(config *setting.Config) *cobra.Command {
 				fmt.Println("No profiles available.")
 				return
 			}
-			table := tablewriter.NewWriter(os.Stdout)
-			table.SetBorders(tablewriter.Border{
-				Left:   true,
-				Top:    true,
-				Right:  true,
-				Bottom: true})
-			table.SetCenterSeparator("|")
-			table.SetHeader([]string{"Name", "Master URL", "ApiKey"})
-
-			for k, val := range conf.Profiles {
-				table.Append([]string{k, val.GetMaster(), val.GetApiKey()})
+			names := []string{}
+			for k := range conf.Profiles {
+				names = append(names, k)
+			}
+			sort.Strings(names)
+
+			profiles := []profileListItem{}
+			table := tools.NewTable([]string{"Name", "Master URL", "ApiKey", "Namespace", "Active"})
+			for _, k := range names {
+				val := conf.Profiles[k]
+				profiles = append(profiles, profileListItem{Name: k, Active: k == conf.GetCurrent(), Profile: val})
+
+				apikey := val.GetApiKey()
+				if val.CredentialStore != common.CREDENTIAL_STORE_PROFILE {
+					apikey = "<" + val.CredentialStore + ">"
+				}
+				active := ""
+				if k == conf.GetCurrent() {
+					active = "*"
+				}
+				table.Append([]string{k, val.GetMaster(), apikey, val.GetNamespace(), active})
 			}
 
-			table.Render()
+			err = tools.NewOutput(v).PrintList(profiles, table)
+			tools.CheckError(err)
 		},
 	}
 
@@ -0,0 +1,67 @@
+/*
+
+Copyright (C) 2017-2018  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package profile
+
+import (
+	"fmt"
+
+	common "github.com/MottainaiCI/mottainai-cli/common"
+	tools "github.com/MottainaiCI/mottainai-cli/common"
+	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
+	cobra "github.com/spf13/cobra"
+	viper "github.com/spf13/viper"
+)
+
+func newProfileMigrateCommand(config *setting.Config) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "migrate [OPTIONS]",
+		Short: "Convert profiles file to the current syntax",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var conf common.ProfileConf
+			var v *viper.Viper = config.Viper
+
+			if v.Get("profiles") == nil {
+				fmt.Println("No profiles available.")
+				return
+			}
+
+			err = v.Unmarshal(&conf)
+			tools.CheckError(err)
+
+			migrated := conf.Migrate()
+			if len(migrated) == 0 {
+				fmt.Println("Profiles are already up to date.")
+				return
+			}
+
+			_, err = conf.Write(v.ConfigFileUsed())
+			tools.CheckError(err)
+
+			for _, name := range migrated {
+				fmt.Printf("Profile %s migrated on file %s.\n", name, v.ConfigFileUsed())
+			}
+		},
+	}
+
+	return cmd
+}
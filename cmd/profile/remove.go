@@ -37,7 +37,7 @@ func newProfileRemoveCommand(config *setting.Config) *cobra.Command {
 		Args:  cobra.RangeArgs(1, 1),
 		Run: func(cmd *cobra.Command, args []string) {
 			var err error
-			var name, f string
+			var name string
 			var conf common.ProfileConf
 			var p *common.Profile
 			var v *viper.Viper = config.Viper
@@ -63,17 +63,17 @@ func newProfileRemoveCommand(config *setting.Config) *cobra.Command {
 				}
 
 				p = conf.RemoveProfile(name)
-			}
-
-			// Create new viper configuration to avoid
-			// write of command line arguments/settings
-			viper := viper.New()
-			viper.SetConfigType("yaml")
-			viper.Set("profiles", conf.Profiles)
 
-			f = v.ConfigFileUsed()
+				store, err := common.NewCredentialStore(p.CredentialStore)
+				tools.CheckError(err)
+				if store != nil {
+					if err = store.Delete(name); err != nil {
+						fmt.Println("Error on remove api-key from credential store: ", err)
+					}
+				}
+			}
 
-			err = viper.WriteConfigAs(f)
+			_, err = conf.Write(v.ConfigFileUsed())
 			tools.CheckError(err)
 
 			fmt.Printf("Profile %s with master %s removed correctly.\n",
@@ -0,0 +1,83 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package profile
+
+import (
+	"fmt"
+
+	common "github.com/MottainaiCI/mottainai-cli/common"
+	tools "github.com/MottainaiCI/mottainai-cli/common"
+	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
+	cobra "github.com/spf13/cobra"
+	viper "github.com/spf13/viper"
+)
+
+func newProfileUseCommand(config *setting.Config) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "use <profile-name> [OPTIONS]",
+		Short: "Set the profile used by default",
+		Args:  cobra.RangeArgs(0, 1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var conf common.ProfileConf
+			var v *viper.Viper = config.Viper
+
+			if v.Get("profiles") == nil {
+				fmt.Println("No profiles available.")
+				return
+			}
+
+			err = v.Unmarshal(&conf)
+			tools.CheckError(err)
+
+			unset, err := cmd.Flags().GetBool("unset")
+			tools.CheckError(err)
+
+			if unset {
+				conf.Current = ""
+			} else if len(args) == 0 {
+				if conf.GetCurrent() == "" {
+					fmt.Println("No active profile.")
+				} else {
+					fmt.Println(conf.GetCurrent())
+				}
+				return
+			} else {
+				err = conf.SetCurrent(args[0])
+				tools.CheckError(err)
+			}
+
+			_, err = conf.Write(v.ConfigFileUsed())
+			tools.CheckError(err)
+
+			if unset {
+				fmt.Println("Active profile removed.")
+			} else {
+				fmt.Printf("Profile %s is now active.\n", conf.GetCurrent())
+			}
+		},
+	}
+
+	var flags = cmd.Flags()
+	flags.Bool("unset", false, "Remove the active profile")
+
+	return cmd
+}
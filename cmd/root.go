@@ -24,6 +24,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -36,8 +37,13 @@ import (
 	settingcmd "github.com/MottainaiCI/mottainai-cli/cmd/settings"
 	webhookcmd "github.com/MottainaiCI/mottainai-cli/cmd/webhook"
 
+	completion "github.com/MottainaiCI/mottainai-cli/cmd/completion"
 	debug "github.com/MottainaiCI/mottainai-cli/cmd/debug"
+	login "github.com/MottainaiCI/mottainai-cli/cmd/login"
+	metrics "github.com/MottainaiCI/mottainai-cli/cmd/metrics"
+	plugin "github.com/MottainaiCI/mottainai-cli/cmd/plugin"
 	simulate "github.com/MottainaiCI/mottainai-cli/cmd/simulate"
+	stats "github.com/MottainaiCI/mottainai-cli/cmd/stats"
 	storage "github.com/MottainaiCI/mottainai-cli/cmd/storage"
 	task "github.com/MottainaiCI/mottainai-cli/cmd/task"
 	token "github.com/MottainaiCI/mottainai-cli/cmd/token"
@@ -53,6 +59,23 @@ const (
 
 $> mottainai-cli -m http://127.0.0.1:8080 namespace list
 `
+
+	cliExitCodes = `
+
+Exit codes:
+  0    success
+  1    generic failure
+  2    invalid arguments, flags or input
+  3    authentication or authorization failure
+  4    object not found
+  5    server error
+  6    network error (connection, DNS, TLS, timeout)
+  7    task stopped (task monitor)
+  8    timeout waiting a task (task wait, task monitor)
+  130  interrupted by SIGINT or SIGTERM
+
+Commands that report a result, e.g. task wait or node health,
+document their own exit codes.`
 )
 
 func initConfig(config *setting.Config) {
@@ -88,10 +111,58 @@ func initCommand(rootCmd *cobra.Command, config *setting.Config) {
 	pflags.StringP("apikey", "k", "fb4h3bhgv4421355", "Mottainai API key")
 
 	pflags.StringP("profile", "p", "", "Use specific profile for call API.")
+	pflags.String("cacert", "", "CA certificate used to verify the master")
+	pflags.String("cert", "", "Client certificate used to authenticate with the master")
+	pflags.String("key", "", "Client key used to authenticate with the master")
+	pflags.Bool("insecure", false, "Skip verification of the master certificate")
+	pflags.String("proxy", "", "HTTP or SOCKS5 proxy used to reach the master ( e.g. socks5://127.0.0.1:1080 )")
+	pflags.Duration("timeout", 0, "Timeout of the connection and of the master response headers ( e.g. 30s )")
+	pflags.Bool("no-compress", false, "Don't request gzip compressed responses from the master")
+	pflags.String("record", "", "Record the API interactions on a session file")
+	pflags.String("replay", "", "Replay the API interactions of a session file instead of contacting the master")
+	pflags.Bool("cache", false, "Cache the responses of the master under ~/"+common.MCLI_CACHE_PATH+
+		", readable only by the user. Responses with tasks may contain their secrets")
+	pflags.Bool("debug", false, "Log the HTTP requests to the master on stderr")
+	pflags.Bool("debug-body", false, "Log the HTTP requests with their payloads, secrets are redacted")
+	pflags.String("rate-limit", "", "Maximum rate of the API calls ( e.g. 10/s, 100/m )")
+	pflags.String("auth", "", "Authentication mode: token ( default ), macaroon or sso")
+	pflags.Int("retries", 0, "Number of retries of the API calls failed for a connection error or a 5xx response")
+	pflags.Duration("retry-delay", time.Second, "Delay before the first retry, doubled after every attempt")
+	pflags.String("output", "",
+		"Output format ( table, json, yaml, csv ). Default depends on the command.")
+	pflags.String("format", "",
+		"Go template applied to every result ( e.g. '{{.ID}} {{.Status}}' )")
+	pflags.StringSlice("columns", []string{},
+		"Columns showed in tabular output ( e.g. id,status,created )")
+	pflags.String("sort-by", "",
+		"Sort tabular output by column, prefix with - for descending order ( e.g. created )")
+	pflags.String("query", "",
+		"JMESPath expression used to filter the result ( e.g. \"[?status=='running'].ID\" )")
 
 	v.BindPFlag("master", rootCmd.PersistentFlags().Lookup("master"))
 	v.BindPFlag("apikey", rootCmd.PersistentFlags().Lookup("apikey"))
 	v.BindPFlag("profile", rootCmd.PersistentFlags().Lookup("profile"))
+	v.BindPFlag("cacert", rootCmd.PersistentFlags().Lookup("cacert"))
+	v.BindPFlag("cert", rootCmd.PersistentFlags().Lookup("cert"))
+	v.BindPFlag("key", rootCmd.PersistentFlags().Lookup("key"))
+	v.BindPFlag("insecure", rootCmd.PersistentFlags().Lookup("insecure"))
+	v.BindPFlag("proxy", rootCmd.PersistentFlags().Lookup("proxy"))
+	v.BindPFlag("timeout", rootCmd.PersistentFlags().Lookup("timeout"))
+	v.BindPFlag("no-compress", rootCmd.PersistentFlags().Lookup("no-compress"))
+	v.BindPFlag("cache", rootCmd.PersistentFlags().Lookup("cache"))
+	v.BindPFlag("record", rootCmd.PersistentFlags().Lookup("record"))
+	v.BindPFlag("replay", rootCmd.PersistentFlags().Lookup("replay"))
+	v.BindPFlag("debug", rootCmd.PersistentFlags().Lookup("debug"))
+	v.BindPFlag("debug-body", rootCmd.PersistentFlags().Lookup("debug-body"))
+	v.BindPFlag("rate-limit", rootCmd.PersistentFlags().Lookup("rate-limit"))
+	v.BindPFlag("auth", rootCmd.PersistentFlags().Lookup("auth"))
+	v.BindPFlag("retries", rootCmd.PersistentFlags().Lookup("retries"))
+	v.BindPFlag("retry-delay", rootCmd.PersistentFlags().Lookup("retry-delay"))
+	v.BindPFlag("output", rootCmd.PersistentFlags().Lookup("output"))
+	v.BindPFlag("format", rootCmd.PersistentFlags().Lookup("format"))
+	v.BindPFlag("columns", rootCmd.PersistentFlags().Lookup("columns"))
+	v.BindPFlag("sort-by", rootCmd.PersistentFlags().Lookup("sort-by"))
+	v.BindPFlag("query", rootCmd.PersistentFlags().Lookup("query"))
 
 	rootCmd.AddCommand(
 		task.NewTaskCommand(config),
@@ -108,17 +179,152 @@ func initCommand(rootCmd *cobra.Command, config *setting.Config) {
 		webhookcmd.NewWebHookCommand(config),
 		secret.NewSecretCommand(config),
 		debug.NewDebugCommand(config),
+		metrics.NewMetricsCommand(config),
+		stats.NewStatsCommand(config),
+		login.NewLoginCommand(config),
+		login.NewLogoutCommand(config),
+		login.NewWhoamiCommand(config),
+		completion.NewCompletionCommand(config),
+		completion.NewCompleteCommand(config),
+		newShellCommand(config),
+		plugin.NewPluginCommand(config),
 	)
 }
 
+func loadProfile(cmd *cobra.Command, v *viper.Viper) {
+	var err error
+	var conf common.ProfileConf
+	var profile *common.Profile
+
+	if v.Get("profiles") != nil {
+		// PRE: profiles contains a map
+		//      map[
+		//        <NAME_PROFILE1>:<PROFILE INTERFACE>
+		//        <NAME_PROFILE2>:<PROFILE INTERFACE>
+		//     ]
+		if err = v.Unmarshal(&conf); err != nil {
+			fmt.Println("Ignore config: ", err)
+		}
+	}
+
+	local, err := common.FindLocalProfile()
+	if err != nil {
+		fmt.Println("Ignore local profile: ", err)
+	}
+
+	// Profile selection order: --profile, project local
+	// profile and then the active profile.
+	name := v.GetString("profile")
+	if name == "" && local != nil {
+		name = local.Name
+	}
+	if name == "" {
+		name = conf.GetCurrent()
+	}
+
+	if name != "" {
+		v.Set("profile", name)
+		profile, _ = conf.GetProfile(name)
+		if profile == nil {
+			fmt.Printf("No profile with name %s. I use default value.\n", name)
+		}
+	}
+
+	if local != nil {
+		if local.ChangesEndpoint(profile) && local.GetApiKey() == "" {
+			fmt.Fprintln(os.Stderr,
+				"The local profile changes the master or its connection, the API key of the profile isn't used")
+		}
+		profile = local.MergeOver(profile)
+	}
+	if profile == nil {
+		return
+	}
+
+	if profile.GetMaster() != "" {
+		v.Set("master", profile.GetMaster())
+	}
+	if !cmd.Flag("apikey").Changed {
+		apikey, err := profile.ResolveApiKey(name)
+		if err != nil {
+			fmt.Println("Ignore profile apikey: ", err)
+		} else if apikey != "" {
+			v.Set("apikey", apikey)
+		}
+	}
+	if profile.GetNamespace() != "" {
+		v.Set("profile-namespace", profile.GetNamespace())
+	}
+	// TLS flags override the profile options.
+	insecure := v.GetBool("insecure")
+	profile.SetTLSOptions(v)
+	if cmd.Flag("insecure").Changed {
+		v.Set("insecure", insecure)
+	}
+	if !cmd.Flag("proxy").Changed && profile.Proxy != "" {
+		v.Set("proxy", profile.Proxy)
+	}
+	if !cmd.Flag("timeout").Changed && profile.RequestTimeout != "" {
+		d, err := time.ParseDuration(profile.RequestTimeout)
+		if err != nil {
+			fmt.Println("Ignore profile request_timeout: ", err)
+		} else {
+			v.Set("timeout", d)
+		}
+	}
+	if !cmd.Flag("auth").Changed && profile.Auth != "" {
+		v.Set("auth", profile.Auth)
+	}
+	if !cmd.Flag("retries").Changed && profile.Retries > 0 {
+		v.Set("retries", profile.Retries)
+	}
+	if !cmd.Flag("retry-delay").Changed && profile.RetryDelay != "" {
+		d, err := time.ParseDuration(profile.RetryDelay)
+		if err != nil {
+			fmt.Println("Ignore profile retry_delay: ", err)
+		} else {
+			v.Set("retry-delay", d)
+		}
+	}
+}
+
 func Execute() {
 	// Create Main Instance Config object
-	var config *setting.Config = setting.NewConfig(nil)
+	config := newConfig()
+	rootCmd := newRootCommand(config)
+
+	// The errors of CheckError are reported with their exit code.
+	defer func() {
+		if r := recover(); r != nil {
+			err, ok := r.(error)
+			if !ok {
+				panic(r)
+			}
+			fmt.Fprintln(os.Stderr, "Error: "+err.Error())
+			os.Exit(common.ExitCode(err))
+		}
+	}()
+
+	// Unknown subcommands are run by the plugins, which handle
+	// the interrupts on their own.
+	if ok, code := plugin.Dispatch(rootCmd, config, os.Args[1:]); ok {
+		os.Exit(code)
+	}
+	common.HandleInterrupt()
 
-	initConfig(config)
+	// Start command execution
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Println(err)
+		// Errors returned by cobra are about the arguments and flags.
+		os.Exit(common.EXIT_VALIDATION)
+	}
+}
 
+// newRootCommand returns the command tree of the cli.
+func newRootCommand(config *setting.Config) *cobra.Command {
 	var rootCmd = &cobra.Command{
 		Short:        common.MCLI_HEADER,
+		Long:         common.MCLI_HEADER + cliExitCodes,
 		Version:      setting.MOTTAINAI_VERSION,
 		Example:      cliExamples,
 		Args:         cobra.OnlyValidArgs,
@@ -126,7 +332,7 @@ func Execute() {
 		PreRun: func(cmd *cobra.Command, args []string) {
 			if len(args) == 0 {
 				cmd.Help()
-				os.Exit(0)
+				common.Exit(0)
 			}
 		},
 		Run: func(cmd *cobra.Command, args []string) {
@@ -142,43 +348,26 @@ func Execute() {
 			//	fmt.Println(err)
 			//}
 
-			// Load profile data and override master if not present.
-			if v.Get("profiles") != nil && !cmd.Flag("master").Changed {
-
-				// PRE: profiles contains a map
-				//      map[
-				//        <NAME_PROFILE1>:<PROFILE INTERFACE>
-				//        <NAME_PROFILE2>:<PROFILE INTERFACE>
-				//     ]
-
-				var conf common.ProfileConf
-				var profile *common.Profile
-				if err = v.Unmarshal(&conf); err != nil {
-					fmt.Println("Ignore config: ", err)
-				} else {
-					if v.GetString("profile") != "" {
-						profile, err = conf.GetProfile(v.GetString("profile"))
-
-						if profile != nil {
-							v.Set("master", profile.GetMaster())
-							if profile.GetApiKey() != "" && !cmd.Flag("apikey").Changed {
-								v.Set("apikey", profile.GetApiKey())
-							}
-						} else {
-							fmt.Printf("No profile with name %s. I use default value.\n", v.GetString("profile"))
-						}
+			// Try with encrypted profiles if plain file is not present.
+			if v.ConfigFileUsed() == "" {
+				if f := common.FindEncryptedProfiles(); f != "" {
+					if err = common.LoadEncryptedProfiles(v, f); err != nil {
+						fmt.Println("Ignore encrypted config: ", err)
 					}
 				}
+			}
 
+			// Load profile data and override master if not present.
+			if !cmd.Flag("master").Changed {
+				loadProfile(cmd, v)
 			}
+
+			err = common.SetupTransport(v)
+			common.CheckError(err)
 		},
 	}
 
 	initCommand(rootCmd, config)
 
-	// Start command execution
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
-	}
+	return rootCmd
 }
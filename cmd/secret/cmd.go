@@ -35,6 +35,7 @@ func NewSecretCommand(config *setting.Config) *cobra.Command {
 	cmd.AddCommand(
 		newSecretCreateCommand(config),
 		newSecretListCommand(config),
+		newSecretShowCommand(config),
 		newSecretRemoveCommand(config),
 		newSecretEditCommand(config),
 	)
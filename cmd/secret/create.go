@@ -21,8 +21,6 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package secret
 
 import (
-	"log"
-
 	tools "github.com/MottainaiCI/mottainai-cli/common"
 	client "github.com/MottainaiCI/mottainai-server/pkg/client"
 	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
@@ -32,10 +30,19 @@ import (
 
 func newSecretCreateCommand(config *setting.Config) *cobra.Command {
 	var cmd = &cobra.Command{
-		Use:   "create [OPTIONS]",
+		Use:   "create <name> [OPTIONS]",
 		Short: "Create a new secret",
-		Args:  cobra.RangeArgs(1, 1),
-		// TODO: PreRun check of minimal args if --json is not present
+		Long: `Create a new secret.
+
+The value is read from a file with --from-file, use - to read it from
+stdin so that it doesn't appear on the shell history.
+
+# Store a deploy key
+$> mottainai-cli secret create deploy-key -f ~/.ssh/deploy_rsa
+
+# Type the value without echo
+$> mottainai-cli secret create registry-password -f -`,
+		Args: cobra.RangeArgs(1, 1),
 		Run: func(cmd *cobra.Command, args []string) {
 			var err error
 			var v *viper.Viper = config.Viper
@@ -43,13 +50,32 @@ func newSecretCreateCommand(config *setting.Config) *cobra.Command {
 			fetcher := client.NewTokenClient(v.GetString("master"), v.GetString("apikey"), config)
 			name := args[0]
 			if len(name) == 0 {
-				log.Fatalln("You need to define a secret name type, e.g. foo")
+				tools.CheckError(tools.ValidationError("You need to define a secret name type, e.g. foo"))
 			}
+			file, err := cmd.Flags().GetString("from-file")
+			tools.CheckError(err)
+			var value string
+			if file != "" {
+				value, err = tools.ReadSecretInput(file, "Secret value: ")
+				tools.CheckError(err)
+			}
+
 			res, err := fetcher.SecretCreate(name)
 			tools.CheckError(err)
+			if file != "" && len(res.Error) == 0 && len(res.ID) > 0 {
+				res, err = fetcher.SecretEdit(map[string]interface{}{
+					"id":    res.ID,
+					"key":   "secret",
+					"value": value,
+				})
+				tools.CheckError(err)
+			}
 			tools.PrintResponse(res)
 		},
 	}
 
+	var flags = cmd.Flags()
+	flags.StringP("from-file", "f", "", "Read the secret value from file (- for stdin)")
+
 	return cmd
 }
@@ -21,9 +21,7 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package secret
 
 import (
-	"io/ioutil"
 	"log"
-	"os"
 
 	tools "github.com/MottainaiCI/mottainai-cli/common"
 	client "github.com/MottainaiCI/mottainai-server/pkg/client"
@@ -43,12 +41,11 @@ func newSecretEditCommand(config *setting.Config) *cobra.Command {
 				log.Fatalln("Missing value or --from-file option")
 			} else if len(args) < 2 {
 				cmd.Help()
-				os.Exit(0)
+				tools.Exit(0)
 			}
 		},
 		Run: func(cmd *cobra.Command, args []string) {
 			var err error
-			var content []byte
 			var v *viper.Viper = config.Viper
 			var value string
 
@@ -58,12 +55,11 @@ func newSecretEditCommand(config *setting.Config) *cobra.Command {
 			if len(args) > 2 {
 				value = args[2]
 			} else {
-				// Read value from file
-				content, err = ioutil.ReadFile(cmd.Flag("from-file").Value.String())
+				// Read value from file or stdin
+				value, err = tools.ReadSecretInput(cmd.Flag("from-file").Value.String(), "Value: ")
 				if err != nil {
 					log.Fatalln("Error on read file ", err)
 				}
-				value = string(content)
 			}
 
 			fetcher := client.NewTokenClient(v.GetString("master"), v.GetString("apikey"), config)
@@ -80,7 +76,7 @@ func newSecretEditCommand(config *setting.Config) *cobra.Command {
 	}
 
 	var pflags = cmd.PersistentFlags()
-	pflags.StringP("from-file", "f", "", "Read value from file.")
+	pflags.StringP("from-file", "f", "", "Read value from file (- for stdin).")
 
 	return cmd
 }
@@ -22,7 +22,6 @@ package secret
 
 import (
 	"fmt"
-	"os"
 
 	tools "github.com/MottainaiCI/mottainai-cli/common"
 	client "github.com/MottainaiCI/mottainai-server/pkg/client"
@@ -30,7 +29,6 @@ import (
 	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
 	"github.com/MottainaiCI/mottainai-server/routes/schema"
 	"github.com/MottainaiCI/mottainai-server/routes/schema/v1"
-	tablewriter "github.com/olekukonko/tablewriter"
 	cobra "github.com/spf13/cobra"
 	viper "github.com/spf13/viper"
 )
@@ -43,7 +41,6 @@ func newSecretListCommand(config *setting.Config) *cobra.Command {
 		Run: func(cmd *cobra.Command, args []string) {
 			var err error
 			var tlist []secret.Secret
-			var task_table [][]string
 			var quiet bool
 			var v *viper.Viper = config.Viper
 
@@ -66,19 +63,13 @@ func newSecretListCommand(config *setting.Config) *cobra.Command {
 				return
 			}
 
+			table := tools.NewTable([]string{"ID", "Name", "Secret"})
 			for _, i := range tlist {
-				task_table = append(task_table, []string{i.ID, i.Name, i.Secret})
+				table.Append([]string{i.ID, i.Name, i.Secret})
 			}
 
-			table := tablewriter.NewWriter(os.Stdout)
-			table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
-			table.SetCenterSeparator("|")
-			table.SetHeader([]string{"ID", "Name", "Secret"})
-
-			for _, v := range task_table {
-				table.Append(v)
-			}
-			table.Render()
+			err = tools.NewOutput(v).PrintList(tlist, table)
+			tools.CheckError(err)
 
 		},
 	}
@@ -0,0 +1,83 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package secret
+
+import (
+	"fmt"
+
+	tools "github.com/MottainaiCI/mottainai-cli/common"
+	client "github.com/MottainaiCI/mottainai-server/pkg/client"
+	secret "github.com/MottainaiCI/mottainai-server/pkg/secret"
+	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
+	schema "github.com/MottainaiCI/mottainai-server/routes/schema"
+	v1 "github.com/MottainaiCI/mottainai-server/routes/schema/v1"
+	cobra "github.com/spf13/cobra"
+	viper "github.com/spf13/viper"
+)
+
+func newSecretShowCommand(config *setting.Config) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "show <id|name> [OPTIONS]",
+		Short: "Show a secret",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var s secret.Secret
+			var v *viper.Viper = config.Viper
+
+			fetcher := client.NewTokenClient(v.GetString("master"), v.GetString("apikey"), config)
+
+			req := schema.Request{
+				Route:   v1.Schema.GetSecretRoute("show"),
+				Options: map[string]interface{}{":id": args[0]},
+				Target:  &s,
+			}
+			err := fetcher.Handle(req)
+			if err != nil || s.ID == "" {
+				s = secret.Secret{}
+				req = schema.Request{
+					Route:   v1.Schema.GetSecretRoute("show_by_name"),
+					Options: map[string]interface{}{":name": args[0]},
+					Target:  &s,
+				}
+				err = fetcher.Handle(req)
+			}
+			if err == nil && s.ID == "" {
+				err = tools.NotFoundError("Secret", args[0])
+			}
+			tools.CheckError(err)
+
+			valueOnly, err := cmd.Flags().GetBool("value")
+			tools.CheckError(err)
+			if valueOnly {
+				fmt.Print(s.Secret)
+				return
+			}
+
+			err = tools.NewOutput(v).PrintObject(s)
+			tools.CheckError(err)
+		},
+	}
+
+	var flags = cmd.Flags()
+	flags.Bool("value", false, "Print only the secret value")
+
+	return cmd
+}
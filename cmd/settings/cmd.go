@@ -28,13 +28,16 @@ import (
 func NewSettingCommand(config *setting.Config) *cobra.Command {
 
 	var cmd = &cobra.Command{
-		Use:   "setting [command] [OPTIONS]",
-		Short: "Manage Infrastructure settings",
+		Use:     "setting [command] [OPTIONS]",
+		Aliases: []string{"settings"},
+		Short:   "Manage Infrastructure settings",
 	}
 
 	cmd.AddCommand(
 		newSettingCreateCommand(config),
 		newSettingListCommand(config),
+		newSettingGetCommand(config),
+		newSettingSetCommand(config),
 		newSettingRemoveCommand(config),
 		newSettingUpdateCommand(config),
 	)
@@ -0,0 +1,59 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package settingcmd
+
+import (
+	"fmt"
+	"os"
+
+	tools "github.com/MottainaiCI/mottainai-cli/common"
+	client "github.com/MottainaiCI/mottainai-server/pkg/client"
+	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
+	cobra "github.com/spf13/cobra"
+	viper "github.com/spf13/viper"
+)
+
+func newSettingGetCommand(config *setting.Config) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "get <key> [OPTIONS]",
+		Short: "Print the value of a setting",
+		Long: `Print the value of a setting.
+
+The command exits with status 4 when the setting isn't defined.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var v *viper.Viper = config.Viper
+
+			fetcher := client.NewTokenClient(v.GetString("master"), v.GetString("apikey"), config)
+			tlist, err := listSettings(fetcher)
+			tools.CheckError(err)
+
+			s := findSetting(tlist, args[0])
+			if s == nil {
+				fmt.Fprintf(os.Stderr, "Setting %s is not defined.\n", args[0])
+				tools.Exit(tools.EXIT_NOT_FOUND)
+			}
+			fmt.Println(s.Value)
+		},
+	}
+
+	return cmd
+}
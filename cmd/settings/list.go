@@ -22,8 +22,6 @@ package settingcmd
 
 import (
 	"fmt"
-	"log"
-	"os"
 
 	schema "github.com/MottainaiCI/mottainai-server/routes/schema"
 
@@ -31,11 +29,29 @@ import (
 	client "github.com/MottainaiCI/mottainai-server/pkg/client"
 	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
 	v1 "github.com/MottainaiCI/mottainai-server/routes/schema/v1"
-	tablewriter "github.com/olekukonko/tablewriter"
 	cobra "github.com/spf13/cobra"
 	viper "github.com/spf13/viper"
 )
 
+func listSettings(fetcher client.HttpClient) ([]setting.Setting, error) {
+	var tlist []setting.Setting
+	req := schema.Request{
+		Route:  v1.Schema.GetSettingRoute("show_all"),
+		Target: &tlist,
+	}
+	err := fetcher.Handle(req)
+	return tlist, err
+}
+
+func findSetting(tlist []setting.Setting, key string) *setting.Setting {
+	for i := range tlist {
+		if tlist[i].Key == key {
+			return &tlist[i]
+		}
+	}
+	return nil
+}
+
 func newSettingListCommand(config *setting.Config) *cobra.Command {
 	var cmd = &cobra.Command{
 		Use:   "list [OPTIONS]",
@@ -43,19 +59,13 @@ func newSettingListCommand(config *setting.Config) *cobra.Command {
 		Args:  cobra.OnlyValidArgs,
 		Run: func(cmd *cobra.Command, args []string) {
 			var tlist []setting.Setting
-			var setting_table [][]string
 			var quiet bool
 			var v *viper.Viper = config.Viper
 
 			fetcher := client.NewTokenClient(v.GetString("master"), v.GetString("apikey"), config)
-			req := schema.Request{
-				Route:  v1.Schema.GetSettingRoute("show_all"),
-				Target: &tlist,
-			}
-
-			err := fetcher.Handle(req)
+			tlist, err := listSettings(fetcher)
 			if err != nil {
-				log.Fatalln("error:", err)
+				tools.CheckError(err)
 			}
 
 			quiet, err = cmd.Flags().GetBool("quiet")
@@ -68,19 +78,13 @@ func newSettingListCommand(config *setting.Config) *cobra.Command {
 				return
 			}
 
+			table := tools.NewTable([]string{"ID", "Key", "Value"})
 			for _, i := range tlist {
-				setting_table = append(setting_table, []string{i.ID, i.Key, i.Value})
+				table.Append([]string{i.ID, i.Key, i.Value})
 			}
 
-			table := tablewriter.NewWriter(os.Stdout)
-			table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
-			table.SetCenterSeparator("|")
-			table.SetHeader([]string{"ID", "Key", "Value"})
-
-			for _, v := range setting_table {
-				table.Append(v)
-			}
-			table.Render()
+			err = tools.NewOutput(v).PrintList(tlist, table)
+			tools.CheckError(err)
 
 		},
 	}
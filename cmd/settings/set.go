@@ -0,0 +1,89 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package settingcmd
+
+import (
+	"fmt"
+	"strings"
+
+	tools "github.com/MottainaiCI/mottainai-cli/common"
+	client "github.com/MottainaiCI/mottainai-server/pkg/client"
+	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
+	cobra "github.com/spf13/cobra"
+	viper "github.com/spf13/viper"
+)
+
+// Settings read by the master with their values.
+var knownSettings = [][2]string{
+	{setting.SYSTEM_SIGNUP_ENABLED, "yes|no"},
+	{setting.SYSTEM_SIGNIN_ONLY_USERVALIDATION, "yes|no"},
+	{setting.SYSTEM_THIRDPARTY_INTEGRATION_ENABLED, "yes|no"},
+	{setting.SYSTEM_WEBHOOK_ENABLED, "yes|no"},
+	{setting.SYSTEM_WEBHOOK_PR_ENABLED, "yes|no"},
+	{setting.SYSTEM_WEBHOOK_INTERNAL_ONLY, "yes|no"},
+	{setting.SYSTEM_WEBHOOK_DEFAULT_QUEUE, "<queue>"},
+	{setting.SYSTEM_PROTECT_NAMESPACE_OVERWRITE, "yes|no"},
+	{setting.SYSTEM_PROTECT_NAMESPACE_PARALLEL_APPEND, "yes|no"},
+}
+
+func knownSettingsUsage() string {
+	var ans []string
+	for _, s := range knownSettings {
+		ans = append(ans, fmt.Sprintf("  %-52s %s", s[0], s[1]))
+	}
+	return strings.Join(ans, "\n")
+}
+
+func newSettingSetCommand(config *setting.Config) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "set <key> <value> [OPTIONS]",
+		Short: "Create or update a setting",
+		Long: `Create or update a setting.
+
+Settings used by the master are:
+
+` + knownSettingsUsage(),
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			var v *viper.Viper = config.Viper
+
+			fetcher := client.NewTokenClient(v.GetString("master"), v.GetString("apikey"), config)
+			tlist, err := listSettings(fetcher)
+			tools.CheckError(err)
+
+			dat := map[string]interface{}{
+				"key":   args[0],
+				"value": args[1],
+			}
+			if findSetting(tlist, args[0]) != nil {
+				res, err := fetcher.SettingUpdate(dat)
+				tools.CheckError(err)
+				tools.PrintResponse(res)
+				return
+			}
+			res, err := fetcher.SettingCreate(dat)
+			tools.CheckError(err)
+			tools.PrintResponse(res)
+		},
+	}
+
+	return cmd
+}
@@ -21,8 +21,6 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package settingcmd
 
 import (
-	"log"
-
 	tools "github.com/MottainaiCI/mottainai-cli/common"
 	client "github.com/MottainaiCI/mottainai-server/pkg/client"
 	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
@@ -41,7 +39,7 @@ func newSettingUpdateCommand(config *setting.Config) *cobra.Command {
 			dat := make(map[string]interface{})
 
 			if len(args) != 2 {
-				log.Fatalln("You need to define akey and a value to create")
+				tools.CheckError(tools.ValidationError("You need to define akey and a value to create"))
 			}
 			dat["key"] = args[0]
 			dat["value"] = args[1]
@@ -0,0 +1,271 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	completion "github.com/MottainaiCI/mottainai-cli/cmd/completion"
+	plugin "github.com/MottainaiCI/mottainai-cli/cmd/plugin"
+	common "github.com/MottainaiCI/mottainai-cli/common"
+	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
+	cobra "github.com/spf13/cobra"
+	pflag "github.com/spf13/pflag"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// Key of Ctrl-C, received by the prompt in raw mode.
+const keyCtrlC = 3
+
+func newShellCommand(config *setting.Config) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "shell [OPTIONS]",
+		Short: "Start an interactive shell",
+		Long: `Start a prompt that runs the commands of the cli without starting
+a new process for each of them. The connections to the master are
+kept open while the settings of the commands don't change.
+
+The prompt has the history of the commands ( arrows up and down ) and
+completes with TAB commands, flags and the identifiers of tasks,
+nodes, namespaces and storages. Ctrl-C interrupts the running command,
+exit or Ctrl-D leaves the shell.
+
+The global flags of the shell apply to all the commands:
+
+$> mottainai-cli --profile prod shell
+mottainai-cli(prod)> task list --status running
+mottainai-cli(prod)> task log <TAB>
+
+Without a terminal the commands are read one per line from stdin.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if common.ShellMode() {
+				common.CheckError(common.ValidationError("The shell is already running"))
+			}
+			common.SetShellMode(true)
+			defer common.SetShellMode(false)
+
+			s := &shell{config: config, flags: changedFlags(cmd.InheritedFlags())}
+			common.CheckError(s.run())
+		},
+	}
+
+	return cmd
+}
+
+type shell struct {
+	// Settings of the shell, every command loads its own.
+	config *setting.Config
+	// Global flags of the shell, supplied to every command.
+	flags []string
+	term  *terminal.Terminal
+}
+
+func (s *shell) run() error {
+	fd := int(os.Stdin.Fd())
+	if !terminal.IsTerminal(fd) {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			if !s.exec(scanner.Text()) {
+				return nil
+			}
+		}
+		return scanner.Err()
+	}
+
+	s.term = terminal.NewTerminal(struct {
+		io.Reader
+		io.Writer
+	}{os.Stdin, os.Stdout}, s.prompt())
+	s.term.AutoCompleteCallback = s.complete
+
+	fmt.Println("Type help for the commands, exit or Ctrl-D to leave the shell.")
+	for {
+		line, err := s.readLine(fd)
+		if err == io.EOF {
+			fmt.Println()
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !s.exec(line) {
+			return nil
+		}
+	}
+}
+
+// readLine reads a line with the terminal in raw mode, restored
+// before running the command.
+func (s *shell) readLine(fd int) (string, error) {
+	state, err := terminal.MakeRaw(fd)
+	if err != nil {
+		return "", err
+	}
+	defer terminal.Restore(fd, state)
+
+	if w, h, err := terminal.GetSize(fd); err == nil && w > 0 {
+		s.term.SetSize(w, h)
+	}
+	line, err := s.term.ReadLine()
+	if err == terminal.ErrPasteIndicator {
+		err = nil
+	}
+	return line, err
+}
+
+func (s *shell) prompt() string {
+	name := s.config.Viper.GetString("profile")
+	if name == "" {
+		name = s.config.Viper.GetString("current")
+	}
+	if name == "" {
+		return "mottainai-cli> "
+	}
+	return "mottainai-cli(" + name + ")> "
+}
+
+// exec runs the command line, it returns false to leave the shell.
+func (s *shell) exec(line string) (cont bool) {
+	args, err := common.SplitCommandLine(line)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error: "+err.Error())
+		return true
+	}
+	if len(args) == 0 || strings.HasPrefix(args[0], "#") {
+		return true
+	}
+	switch args[0] {
+	case "exit", "quit":
+		return false
+	}
+
+	common.ResetInterrupt()
+	defer func() {
+		if r := recover(); r != nil {
+			err, ok := r.(error)
+			if !ok {
+				panic(r)
+			}
+			cont = true
+			// The command printed already its result.
+			if _, ok := err.(*common.ExitError); !ok {
+				fmt.Fprintln(os.Stderr, "Error: "+err.Error())
+			}
+		}
+	}()
+
+	// Settings and flags of the previous commands aren't inherited.
+	config := newConfig()
+	root := newRootCommand(config)
+	args = append(append([]string{}, s.flags...), args...)
+	if ok, _ := plugin.Dispatch(root, config, args); ok {
+		return true
+	}
+	root.SetArgs(args)
+	if err := root.Execute(); err != nil {
+		fmt.Println(err)
+	}
+	return true
+}
+
+// complete is the AutoCompleteCallback of the terminal: TAB completes
+// the word under the cursor, Ctrl-C discards the line.
+func (s *shell) complete(line string, pos int, key rune) (string, int, bool) {
+	switch key {
+	case keyCtrlC:
+		return "", 0, true
+	case '\t':
+	default:
+		return "", 0, false
+	}
+
+	prefix := line[:pos]
+	words := strings.Fields(prefix)
+	current := ""
+	if len(words) > 0 && !strings.HasSuffix(prefix, " ") {
+		current = words[len(words)-1]
+		words = words[:len(words)-1]
+	}
+
+	candidates := s.candidates(append(append([]string{}, s.flags...), words...), current)
+	if len(candidates) == 0 {
+		return line, pos, true
+	}
+	replace := candidates[0] + " "
+	if len(candidates) > 1 {
+		replace = commonPrefix(candidates)
+		if replace == current {
+			fmt.Fprintln(s.term, strings.Join(candidates, "  "))
+			return line, pos, true
+		}
+	}
+
+	start := pos - len(current)
+	return line[:start] + replace + line[pos:], start + len(replace), true
+}
+
+func (s *shell) candidates(words []string, current string) (ans []string) {
+	// Failures to reach the master leave the line as is.
+	defer func() {
+		if r := recover(); r != nil {
+			ans = nil
+		}
+	}()
+	config := newConfig()
+	return completion.Candidates(config, newRootCommand(config), words, current)
+}
+
+func newConfig() *setting.Config {
+	config := setting.NewConfig(nil)
+	initConfig(config)
+	return config
+}
+
+func commonPrefix(values []string) string {
+	prefix := values[0]
+	for _, v := range values[1:] {
+		for !strings.HasPrefix(v, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
+
+// changedFlags returns the flags set on the command line as arguments.
+func changedFlags(flags *pflag.FlagSet) []string {
+	var ans []string
+	flags.VisitAll(func(f *pflag.Flag) {
+		if !f.Changed {
+			return
+		}
+		value := f.Value.String()
+		if strings.HasSuffix(f.Value.Type(), "Slice") {
+			value = strings.Trim(value, "[]")
+		}
+		ans = append(ans, "--"+f.Name+"="+value)
+	})
+	return ans
+}
@@ -0,0 +1,153 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package stats
+
+import (
+	"fmt"
+	"sort"
+
+	node "github.com/MottainaiCI/mottainai-cli/cmd/node"
+	tools "github.com/MottainaiCI/mottainai-cli/common"
+	client "github.com/MottainaiCI/mottainai-server/pkg/client"
+	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
+	storageci "github.com/MottainaiCI/mottainai-server/pkg/storage"
+	schema "github.com/MottainaiCI/mottainai-server/routes/schema"
+	v1 "github.com/MottainaiCI/mottainai-server/routes/schema/v1"
+	cobra "github.com/spf13/cobra"
+	viper "github.com/spf13/viper"
+)
+
+// Concurrent requests used to read the size of the storage files.
+const storageSizeWorkers = 8
+
+func NewStatsCommand(config *setting.Config) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "stats [OPTIONS]",
+		Short: "Show the dashboard counters of the master",
+		Long: `Show the counters of the tasks reported by the master with
+the number of online nodes, as on the dashboard of the web interface.
+
+With --storage the usage of the storages is computed too, reading
+the size of every stored file.
+
+$> mottainai-cli stats --output json`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			var v *viper.Viper = config.Viper
+			var info map[string]interface{}
+
+			withStorage, err := cmd.Flags().GetBool("storage")
+			tools.CheckError(err)
+
+			fetcher := client.NewTokenClient(v.GetString("master"), v.GetString("apikey"), config)
+			req := schema.Request{
+				Route:  v1.Schema.GetStatsRoute("info"),
+				Target: &info,
+			}
+			tools.CheckError(fetcher.Handle(req))
+
+			stats := make(map[string]interface{})
+			keys := make([]string, 0, len(info))
+			for k, val := range info {
+				stats[k] = val
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+
+			nodes, err := node.FetchNodes(fetcher, false)
+			tools.CheckError(err)
+			online := 0
+			for _, n := range nodes {
+				if n.State == node.NODE_STATE_ONLINE {
+					online++
+				}
+			}
+			stats["nodes"] = len(nodes)
+			stats["nodes_online"] = online
+			keys = append(keys, "nodes", "nodes_online")
+
+			if withStorage {
+				storages, files, size, err := storageUsage(fetcher, v.GetString("apikey"))
+				tools.CheckError(err)
+				stats["storages"] = storages
+				stats["storage_files"] = files
+				stats["storage_bytes"] = size
+				keys = append(keys, "storages", "storage_files", "storage_bytes")
+			}
+
+			table := tools.NewTable([]string{"Counter", "Value"})
+			for _, k := range keys {
+				value := fmt.Sprintf("%v", stats[k])
+				if k == "storage_bytes" {
+					value = tools.HumanSize(stats[k].(int64))
+				}
+				table.Append([]string{k, value})
+			}
+
+			err = tools.NewOutput(v).PrintList(stats, table)
+			tools.CheckError(err)
+		},
+	}
+
+	var flags = cmd.Flags()
+	flags.Bool("storage", false, "Compute the usage of the storages")
+
+	return cmd
+}
+
+// storageUsage returns the number of storages with the number and
+// the total size of their files. Files with an unknown size
+// are not counted on the size.
+func storageUsage(fetcher client.HttpClient, token string) (int, int, int64, error) {
+	var storages []storageci.Storage
+	var files int
+	var size int64
+
+	req := schema.Request{
+		Route:  v1.Schema.GetStorageRoute("show_all"),
+		Target: &storages,
+	}
+	if err := fetcher.Handle(req); err != nil {
+		return 0, 0, 0, err
+	}
+
+	for _, s := range storages {
+		var list []string
+		req := schema.Request{
+			Route:   v1.Schema.GetStorageRoute("show_artefacts"),
+			Options: map[string]interface{}{":id": s.ID},
+			Target:  &list,
+		}
+		if err := fetcher.Handle(req); err != nil {
+			return 0, 0, 0, err
+		}
+
+		for _, f := range tools.ArtefactSizes(fetcher.GetBaseURL()+"/"+tools.ARTEFACT_STORAGE+"/"+s.Path,
+			token, list, storageSizeWorkers) {
+			if f.Size > 0 {
+				size += f.Size
+			}
+		}
+		files += len(list)
+	}
+
+	return len(storages), files, size, nil
+}
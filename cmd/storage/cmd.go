@@ -40,6 +40,9 @@ func NewStorageCommand(config *setting.Config) *cobra.Command {
 		newStorageListCommand(config),
 		newStorageUploadCommand(config),
 		newStorageRemoveCommand(config),
+		newStorageSyncCommand(config),
+		newStoragePruneCommand(config),
+		newStorageCopyCommand(config),
 	)
 
 	return cmd
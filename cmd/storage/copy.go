@@ -0,0 +1,87 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package storage
+
+import (
+	"fmt"
+	"os"
+
+	tools "github.com/MottainaiCI/mottainai-cli/common"
+	client "github.com/MottainaiCI/mottainai-server/pkg/client"
+	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
+	cobra "github.com/spf13/cobra"
+	viper "github.com/spf13/viper"
+)
+
+func newStorageCopyCommand(config *setting.Config) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "copy <src-id>:<path> <dst-id>:<path> [OPTIONS]",
+		Short: "Copy artefacts between storages",
+		Long: `Copy the artefacts of a storage in another storage.
+
+The source is a file or a directory and the destination is the
+directory where it's copied. The master can't copy the files, so
+every file is streamed from the download to the upload, without
+a local copy.
+
+$> mottainai-cli storage copy <src-id>:/build/app.tar.gz <dst-id>:/releases
+$> mottainai-cli storage copy <src-id>:/build <dst-id>:/
+`,
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			var v *viper.Viper = config.Viper
+
+			src, err := tools.ParseArtefactLocation(tools.ARTEFACT_STORAGE, args[0])
+			tools.CheckError(err)
+			dst, err := tools.ParseArtefactLocation(tools.ARTEFACT_STORAGE, args[1])
+			tools.CheckError(err)
+			quiet, err := cmd.Flags().GetBool("quiet")
+			tools.CheckError(err)
+
+			fetcher := client.NewTokenClient(v.GetString("master"), v.GetString("apikey"), config)
+
+			copier := tools.NewArtefactCopier(fetcher, config, v.GetString("apikey"))
+			copier.Quiet = quiet
+			results, err := copier.Copy(src, dst)
+			tools.CheckError(err)
+
+			failed := 0
+			table := tools.NewTable([]string{"Source", "Destination", "Size", "Error"})
+			for _, r := range results {
+				if r.Error != "" {
+					failed++
+				}
+				table.Append([]string{r.Source, r.Destination, tools.HumanSize(r.Size), r.Error})
+			}
+			tools.CheckError(tools.NewOutput(v).PrintList(results, table))
+
+			if failed > 0 {
+				fmt.Fprintf(os.Stderr, "Copy failed for %d of %d files\n", failed, len(results))
+				tools.Exit(1)
+			}
+		},
+	}
+
+	var flags = cmd.Flags()
+	flags.BoolP("quiet", "q", false, "Don't show the progress bar")
+
+	return cmd
+}
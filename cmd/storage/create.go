@@ -21,8 +21,6 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package storage
 
 import (
-	"log"
-
 	tools "github.com/MottainaiCI/mottainai-cli/common"
 	client "github.com/MottainaiCI/mottainai-server/pkg/client"
 	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
@@ -41,7 +39,7 @@ func newStorageCreateCommand(config *setting.Config) *cobra.Command {
 
 			storage := args[0]
 			if len(storage) == 0 {
-				log.Fatalln("You need to define a storage name")
+				tools.CheckError(tools.ValidationError("You need to define a storage name"))
 			}
 
 			fetcher := client.NewTokenClient(v.GetString("master"), v.GetString("apikey"), config)
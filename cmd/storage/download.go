@@ -21,8 +21,7 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package storage
 
 import (
-	"log"
-
+	tools "github.com/MottainaiCI/mottainai-cli/common"
 	client "github.com/MottainaiCI/mottainai-server/pkg/client"
 	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
 	cobra "github.com/spf13/cobra"
@@ -38,19 +37,36 @@ func newStorageDownloadCommand(config *setting.Config) *cobra.Command {
 			var v *viper.Viper = config.Viper
 
 			fetcher := client.NewTokenClient(v.GetString("master"), v.GetString("apikey"), config)
-			fetcher.SetActiveReport(true)
 
 			storage := args[0]
 			target := args[1]
 			if len(storage) == 0 || len(target) == 0 {
-				log.Fatalln("You need to define a storage id and a target")
+				tools.CheckError(tools.ValidationError("You need to define a storage id and a target"))
 			}
+			concurrency, err := cmd.Flags().GetInt("concurrency")
+			tools.CheckError(err)
 
-			if err := fetcher.DownloadArtefactsFromStorage(storage, target); err != nil {
-				log.Fatalln(err)
+			downloader, err := tools.NewArtefactDownloader(fetcher, v.GetString("apikey"), concurrency, nil)
+			if err != nil {
+				tools.CheckError(err)
+			}
+			noVerify, err := cmd.Flags().GetBool("no-verify")
+			tools.CheckError(err)
+			downloader.Verify = !noVerify
+			checksums, err := cmd.Flags().GetString("checksums")
+			tools.CheckError(err)
+			if checksums != "" {
+				downloader.Checksums, err = tools.ReadChecksums(checksums)
+				tools.CheckError(err)
+			}
+			if err := downloader.DownloadStorage(storage, target); err != nil {
+				tools.CheckError(err)
 			}
 		},
 	}
 
+	cmd.Flags().IntP("concurrency", "c", 4, "Number of artefacts downloaded at the same time")
+	cmd.Flags().Bool("no-verify", false, "Don't verify the checksums of the downloaded artefacts")
+	cmd.Flags().String("checksums", "", "File with the expected SHA-256 checksums, in the sha256sum format")
 	return cmd
 }
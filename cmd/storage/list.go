@@ -22,15 +22,14 @@ package storage
 
 import (
 	"log"
-	"os"
 
 	schema "github.com/MottainaiCI/mottainai-server/routes/schema"
 	v1 "github.com/MottainaiCI/mottainai-server/routes/schema/v1"
 
+	tools "github.com/MottainaiCI/mottainai-cli/common"
 	client "github.com/MottainaiCI/mottainai-server/pkg/client"
 	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
 	storage "github.com/MottainaiCI/mottainai-server/pkg/storage"
-	tablewriter "github.com/olekukonko/tablewriter"
 	cobra "github.com/spf13/cobra"
 	viper "github.com/spf13/viper"
 )
@@ -42,7 +41,6 @@ func newStorageListCommand(config *setting.Config) *cobra.Command {
 		Args:  cobra.OnlyValidArgs,
 		Run: func(cmd *cobra.Command, args []string) {
 			var n []storage.Storage
-			var storage_table [][]string
 			var v *viper.Viper = config.Viper
 
 			fetcher := client.NewTokenClient(v.GetString("master"), v.GetString("apikey"), config)
@@ -53,23 +51,18 @@ func newStorageListCommand(config *setting.Config) *cobra.Command {
 
 			err := fetcher.Handle(req)
 			if err != nil {
-				log.Fatalln("error:", err)
+				tools.CheckError(err)
 			}
 
 			log.Println("Available storages: ")
 
+			table := tools.NewTable([]string{"ID", "Name", "Path"})
 			for _, i := range n {
-				storage_table = append(storage_table, []string{i.ID, i.Name, i.Path})
+				table.Append([]string{i.ID, i.Name, i.Path})
 			}
 
-			table := tablewriter.NewWriter(os.Stdout)
-			table.SetHeader([]string{"ID", "Name", "Path"})
-			table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
-			table.SetCenterSeparator("|")
-			for _, v := range storage_table {
-				table.Append(v)
-			}
-			table.Render()
+			err = tools.NewOutput(v).PrintList(n, table)
+			tools.CheckError(err)
 
 		},
 	}
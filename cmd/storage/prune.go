@@ -0,0 +1,177 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	tools "github.com/MottainaiCI/mottainai-cli/common"
+	client "github.com/MottainaiCI/mottainai-server/pkg/client"
+	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
+	cobra "github.com/spf13/cobra"
+	viper "github.com/spf13/viper"
+)
+
+type storagePruneResult struct {
+	Path     string    `json:"path"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+	Status   string    `json:"status"`
+	Error    string    `json:"error,omitempty"`
+}
+
+func newStoragePruneCommand(config *setting.Config) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "prune <storage-id> [OPTIONS]",
+		Short: "Remove the artefacts of a storage by age or pattern",
+		Long: `Remove the artefacts of a storage by age or pattern.
+
+The artefacts are selected by their modification time with
+--older-than and by glob patterns with --match, matched on the
+path and on the file name. When both are used, an artefact is
+removed if it's older than the duration and matches a pattern.
+
+$> mottainai-cli storage prune <storage-id> --older-than 30d --match '*.log' --dry-run
+`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var v *viper.Viper = config.Viper
+			var olderThan time.Duration
+
+			storage := args[0]
+
+			older, err := cmd.Flags().GetString("older-than")
+			tools.CheckError(err)
+			if older != "" {
+				olderThan, err = tools.ParseDuration(older)
+				tools.CheckError(err)
+			}
+			patterns, err := cmd.Flags().GetStringArray("match")
+			tools.CheckError(err)
+			filter := tools.ArtefactFilter{Include: patterns}
+			tools.CheckError(filter.Validate())
+			if olderThan <= 0 && filter.Empty() {
+				tools.CheckError(errors.New("You need to define --older-than or --match"))
+			}
+			dryRun, err := cmd.Flags().GetBool("dry-run")
+			tools.CheckError(err)
+			yes, err := cmd.Flags().GetBool("yes")
+			tools.CheckError(err)
+
+			fetcher := client.NewTokenClient(v.GetString("master"), v.GetString("apikey"), config)
+
+			list, err := fetcher.StorageFileList(storage)
+			tools.CheckError(err)
+			var matched []string
+			for _, f := range list {
+				if filter.Match(f) {
+					matched = append(matched, f)
+				}
+			}
+			sort.Strings(matched)
+
+			storagePath, err := tools.StoragePath(fetcher, storage)
+			tools.CheckError(err)
+			files := tools.ArtefactSizes(fetcher.GetBaseURL()+"/"+tools.ARTEFACT_STORAGE+"/"+storagePath,
+				v.GetString("apikey"), matched, storageSizeWorkers)
+
+			var selected []tools.ArtefactFile
+			now := time.Now()
+			for _, f := range files {
+				if olderThan > 0 {
+					if f.Modified.IsZero() {
+						fmt.Fprintf(os.Stderr, "Skipping %s: modification time not available\n", f.Path)
+						continue
+					}
+					if now.Sub(f.Modified) <= olderThan {
+						continue
+					}
+				}
+				selected = append(selected, f)
+			}
+
+			if len(selected) == 0 {
+				fmt.Println("No artefacts found")
+				return
+			}
+
+			results := make([]storagePruneResult, len(selected))
+			for i, f := range selected {
+				results[i] = storagePruneResult{Path: f.Path, Size: f.Size, Modified: f.Modified, Status: "selected"}
+			}
+
+			if !dryRun && !yes {
+				var total int64
+				for _, f := range selected {
+					if f.Size > 0 {
+						total += f.Size
+					}
+				}
+				if !tools.Confirm(fmt.Sprintf("Remove %d artefacts (%s)?", len(selected), tools.HumanSize(total))) {
+					fmt.Fprintln(os.Stderr, "Aborted, use --yes to skip the confirmation or --dry-run to list the artefacts")
+					tools.Exit(1)
+				}
+			}
+
+			failed := false
+			if !dryRun {
+				for i := range results {
+					res, err := fetcher.StorageRemovePath(storage, results[i].Path)
+					results[i].Status = res.Status
+					if err != nil {
+						results[i].Error = err.Error()
+					} else if res.Error != "" {
+						results[i].Error = res.Error
+					}
+					if results[i].Error != "" {
+						failed = true
+					}
+				}
+			}
+
+			table := tools.NewTable([]string{"Path", "Size", "Modified", "Status", "Error"})
+			for _, r := range results {
+				modified := ""
+				if !r.Modified.IsZero() {
+					modified = r.Modified.Local().Format("2006-01-02 15:04:05")
+				}
+				table.Append([]string{r.Path, tools.HumanSize(r.Size), modified, r.Status, r.Error})
+			}
+			tools.CheckError(tools.NewOutput(v).PrintList(results, table))
+
+			if failed {
+				tools.Exit(1)
+			}
+		},
+	}
+
+	var flags = cmd.Flags()
+	flags.String("older-than", "", "Select the artefacts modified before the duration ( e.g. 30d, 12h )")
+	flags.StringArray("match", []string{}, "Select the artefacts matching the glob pattern ( e.g. '*.log' )")
+	flags.Bool("dry-run", false, "List the selected artefacts without removing them")
+	flags.BoolP("yes", "y", false, "Don't ask confirmation")
+
+	return cmd
+}
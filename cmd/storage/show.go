@@ -21,10 +21,16 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package storage
 
 import (
-	"log"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
 
 	schema "github.com/MottainaiCI/mottainai-server/routes/schema"
 
+	tools "github.com/MottainaiCI/mottainai-cli/common"
 	client "github.com/MottainaiCI/mottainai-server/pkg/client"
 	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
 	v1 "github.com/MottainaiCI/mottainai-server/routes/schema/v1"
@@ -36,14 +42,23 @@ func newStorageShowCommand(config *setting.Config) *cobra.Command {
 	var cmd = &cobra.Command{
 		Use:   "show <storage-id> [OPTIONS]",
 		Short: "Show artefacts belonging to a storage",
-		Args:  cobra.RangeArgs(1, 1),
+		Long: `Show the artefacts of a storage.
+
+With --tree the artefacts are printed as a directory tree with the
+size of every file and directory, and with --human the sizes are
+in a human readable format. The master doesn't return the sizes in
+the listing, so they are fetched for every file.
+
+$> mottainai-cli storage show <storage-id> --tree --human
+`,
+		Args: cobra.RangeArgs(1, 1),
 		Run: func(cmd *cobra.Command, args []string) {
 			var tlist []string
 			var v *viper.Viper = config.Viper
 
 			storage := args[0]
 			if len(storage) == 0 {
-				log.Fatalln("You need to define a storage id")
+				tools.CheckError(tools.ValidationError("You need to define a storage id"))
 			}
 
 			fetcher := client.NewTokenClient(v.GetString("master"), v.GetString("apikey"), config)
@@ -58,14 +73,137 @@ func newStorageShowCommand(config *setting.Config) *cobra.Command {
 
 			err := fetcher.Handle(req)
 			if err != nil {
-				log.Fatalln("error:", err)
+				tools.CheckError(err)
+			}
+
+			tree, err := cmd.Flags().GetBool("tree")
+			tools.CheckError(err)
+			human, err := cmd.Flags().GetBool("human")
+			tools.CheckError(err)
+
+			if !tree && !human {
+				table := tools.NewTable([]string{"Artefact"})
+				for _, i := range tlist {
+					table.Append([]string{i})
+				}
+
+				err = tools.NewOutput(v).PrintList(tlist, table)
+				tools.CheckError(err)
+				return
+			}
+
+			path, err := tools.StoragePath(fetcher, storage)
+			tools.CheckError(err)
+			sort.Strings(tlist)
+			files := tools.ArtefactSizes(fetcher.GetBaseURL()+"/"+tools.ARTEFACT_STORAGE+"/"+path,
+				v.GetString("apikey"), tlist, storageSizeWorkers)
+
+			o := tools.NewOutput(v)
+			if tree && (o.Format == "" || o.Format == tools.OUTPUT_TABLE) && o.Template == "" && o.Query == "" {
+				printStorageTree(os.Stdout, files, human)
+				return
 			}
 
-			for _, i := range tlist {
-				log.Println("- " + i)
+			table := tools.NewTable([]string{"Artefact", "Size"})
+			for _, f := range files {
+				table.Append([]string{f.Path, formatSize(f.Size, human)})
 			}
+			tools.CheckError(o.PrintList(files, table))
 		},
 	}
 
+	var flags = cmd.Flags()
+	flags.Bool("tree", false, "Show the artefacts as a directory tree with their sizes")
+	flags.Bool("human", false, "Show the sizes in a human readable format")
+
 	return cmd
 }
+
+// Number of concurrent requests used to fetch the sizes of the files.
+const storageSizeWorkers = 8
+
+// storageDir is a directory of the tree of a storage.
+type storageDir struct {
+	dirs  map[string]*storageDir
+	files map[string]int64
+	size  int64
+	count int
+}
+
+func newStorageDir() *storageDir {
+	return &storageDir{dirs: map[string]*storageDir{}, files: map[string]int64{}}
+}
+
+func (d *storageDir) add(path string, size int64) {
+	d.count++
+	if size > 0 {
+		d.size += size
+	}
+
+	parts := strings.SplitN(strings.TrimLeft(path, "/"), "/", 2)
+	if len(parts) == 1 {
+		d.files[parts[0]] = size
+		return
+	}
+	sub, ok := d.dirs[parts[0]]
+	if !ok {
+		sub = newStorageDir()
+		d.dirs[parts[0]] = sub
+	}
+	sub.add(parts[1], size)
+}
+
+// printStorageTree prints the files as a tree, with the size of
+// every file and the total size of every directory.
+func printStorageTree(w io.Writer, files []tools.ArtefactFile, human bool) {
+	root := newStorageDir()
+	for _, f := range files {
+		root.add(f.Path, f.Size)
+	}
+
+	fmt.Fprintln(w, "/")
+	root.print(w, "", human)
+
+	total := formatSize(root.size, human)
+	if !human {
+		total += " bytes"
+	}
+	fmt.Fprintf(w, "\n%d files, %s\n", root.count, total)
+}
+
+func (d *storageDir) print(w io.Writer, prefix string, human bool) {
+	var names []string
+	for n := range d.dirs {
+		names = append(names, n+"/")
+	}
+	for n := range d.files {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	for i, n := range names {
+		branch, indent := "├── ", "│   "
+		if i == len(names)-1 {
+			branch, indent = "└── ", "    "
+		}
+
+		if strings.HasSuffix(n, "/") {
+			sub := d.dirs[strings.TrimSuffix(n, "/")]
+			fmt.Fprintf(w, "%s%s%s (%s)\n", prefix, branch, n, formatSize(sub.size, human))
+			sub.print(w, prefix+indent, human)
+			continue
+		}
+		fmt.Fprintf(w, "%s%s%s (%s)\n", prefix, branch, n, formatSize(d.files[n], human))
+	}
+}
+
+func formatSize(size int64, human bool) string {
+	switch {
+	case size < 0:
+		return "?"
+	case human:
+		return tools.HumanSize(size)
+	default:
+		return strconv.FormatInt(size, 10)
+	}
+}
@@ -0,0 +1,96 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package storage
+
+import (
+	"fmt"
+	"os"
+
+	tools "github.com/MottainaiCI/mottainai-cli/common"
+	client "github.com/MottainaiCI/mottainai-server/pkg/client"
+	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
+	cobra "github.com/spf13/cobra"
+	viper "github.com/spf13/viper"
+)
+
+func newStorageSyncCommand(config *setting.Config) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "sync <storage-id> <directory> [OPTIONS]",
+		Short: "Synchronize a local directory to a storage",
+		Long: `Synchronize a local directory to a storage.
+
+The files of the directory are uploaded when they are missing
+on the storage or when their size is different. With --checksum
+the remote files with the same size are downloaded and compared
+by SHA-256. With --delete the remote files missing in the
+directory are removed.
+
+$> mottainai-cli storage sync <storage-id> ./build --dry-run
+$> mottainai-cli storage sync <storage-id> ./build --delete
+`,
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			var v *viper.Viper = config.Viper
+
+			storage := args[0]
+			dir := args[1]
+
+			dryRun, err := cmd.Flags().GetBool("dry-run")
+			tools.CheckError(err)
+
+			fetcher := client.NewTokenClient(v.GetString("master"), v.GetString("apikey"), config)
+			sync := tools.NewStorageSync(fetcher, config, v.GetString("apikey"))
+			sync.Delete, err = cmd.Flags().GetBool("delete")
+			tools.CheckError(err)
+			sync.Checksum, err = cmd.Flags().GetBool("checksum")
+			tools.CheckError(err)
+			sync.Quiet, err = cmd.Flags().GetBool("quiet")
+			tools.CheckError(err)
+
+			changes, err := sync.Changes(storage, dir)
+			tools.CheckError(err)
+
+			var failed []string
+			if !dryRun {
+				failed = sync.Apply(storage, dir, changes)
+			}
+
+			table := tools.NewTable([]string{"Action", "Path", "Reason"})
+			for _, c := range changes {
+				table.Append([]string{c.Action, c.Path, c.Reason})
+			}
+			tools.CheckError(tools.NewOutput(v).PrintList(changes, table))
+
+			if len(failed) > 0 {
+				fmt.Fprintf(os.Stderr, "Synchronization failed for %d of %d files\n", len(failed), len(changes))
+				tools.Exit(1)
+			}
+		},
+	}
+
+	var flags = cmd.Flags()
+	flags.Bool("delete", false, "Remove the remote files missing in the directory")
+	flags.Bool("checksum", false, "Compare the content of the files with the same size")
+	flags.Bool("dry-run", false, "Show the changes without applying them")
+	flags.BoolP("quiet", "q", false, "Don't show the progress bar")
+
+	return cmd
+}
@@ -21,8 +21,7 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package storage
 
 import (
-	"log"
-
+	tools "github.com/MottainaiCI/mottainai-cli/common"
 	client "github.com/MottainaiCI/mottainai-server/pkg/client"
 	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
 	cobra "github.com/spf13/cobra"
@@ -41,13 +40,19 @@ func newStorageUploadCommand(config *setting.Config) *cobra.Command {
 			file := args[1]
 			path := args[2]
 			if len(storage) == 0 || len(file) == 0 || len(path) == 0 {
-				log.Fatalln("You need to define a storage id, a file and a target storage path.")
+				tools.CheckError(tools.ValidationError("You need to define a storage id, a file and a target storage path."))
 			}
 
 			fetcher := client.NewTokenClient(v.GetString("master"), v.GetString("apikey"), config)
-			fetcher.UploadStorageFile(storage, file, path)
+			uploader := tools.NewFileUploader(fetcher, config, v.GetString("apikey"))
+			uploader.Quiet, _ = cmd.Flags().GetBool("quiet")
+			err := uploader.UploadStorage(storage, file, path)
+			tools.CheckError(err)
 		},
 	}
 
+	flags := cmd.Flags()
+	flags.BoolP("quiet", "q", false, "Don't show the progress bar")
+
 	return cmd
 }
@@ -22,7 +22,6 @@ package task
 
 import (
 	"fmt"
-	"log"
 
 	schema "github.com/MottainaiCI/mottainai-server/routes/schema"
 
@@ -45,7 +44,7 @@ func newTaskArtefactsCommand(config *setting.Config) *cobra.Command {
 
 			id := args[0]
 			if len(id) == 0 {
-				log.Fatalln("You need to define a task id")
+				tools.CheckError(tools.ValidationError("You need to define a task id"))
 			}
 
 			fmt.Println("Artefacts for:", id)
@@ -24,7 +24,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
 	"strconv"
 	"time"
 
@@ -51,7 +50,7 @@ func newTaskAttachCommand(config *setting.Config) *cobra.Command {
 
 			id := args[0]
 			if len(id) == 0 {
-				log.Fatalln("You need to define a task id")
+				tools.CheckError(tools.ValidationError("You need to define a task id"))
 			}
 			var pos = 0
 
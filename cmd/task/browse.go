@@ -0,0 +1,499 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package task
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	tools "github.com/MottainaiCI/mottainai-cli/common"
+	client "github.com/MottainaiCI/mottainai-server/pkg/client"
+	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
+	citasks "github.com/MottainaiCI/mottainai-server/pkg/tasks"
+	v1 "github.com/MottainaiCI/mottainai-server/routes/schema/v1"
+
+	cobra "github.com/spf13/cobra"
+	viper "github.com/spf13/viper"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+const (
+	BROWSE_VIEW_TASKS = iota
+	BROWSE_VIEW_LOG
+
+	// Number of lines of the log loaded by the log view.
+	browseLogLines = 1000
+
+	ansiClear       = "\033[H\033[2J"
+	ansiAltScreen   = "\033[?1049h"
+	ansiMainScreen  = "\033[?1049l"
+	ansiHideCursor  = "\033[?25l"
+	ansiShowCursor  = "\033[?25h"
+	ansiReverse     = "\033[7m"
+	ansiBold        = "\033[1m"
+	ansiReset       = "\033[0m"
+	browseTasksHelp = "↑/↓ move  enter logs  s stop  r retry  d download  R refresh  q quit"
+	browseLogHelp   = "↑/↓ scroll  pgup/pgdn page  g/G top/bottom  esc back  q quit"
+)
+
+// taskBrowser is a terminal UI with the list of the tasks refreshed
+// periodically and the log of the selected task.
+type taskBrowser struct {
+	fetcher client.HttpClient
+	config  *setting.Config
+	filter  *TaskFilter
+	limit   int
+	out     *bytes.Buffer
+
+	mutex     sync.Mutex
+	view      int
+	tasks     []citasks.Task
+	cursor    int
+	offset    int
+	log       []string
+	logTask   string
+	logOffset int
+	logFollow bool
+	message   string
+	updated   time.Time
+	confirm   func()
+}
+
+func newTaskBrowser(fetcher client.HttpClient, config *setting.Config, filter *TaskFilter, limit int) *taskBrowser {
+	return &taskBrowser{
+		fetcher: fetcher,
+		config:  config,
+		filter:  filter,
+		limit:   limit,
+		out:     &bytes.Buffer{},
+	}
+}
+
+func (b *taskBrowser) setMessage(format string, args ...interface{}) {
+	b.mutex.Lock()
+	b.message = fmt.Sprintf(format, args...)
+	b.mutex.Unlock()
+}
+
+func (b *taskBrowser) selected() *citasks.Task {
+	if b.cursor < 0 || b.cursor >= len(b.tasks) {
+		return nil
+	}
+	t := b.tasks[b.cursor]
+	return &t
+}
+
+// refresh reloads the tasks, keeping the cursor on the selected task,
+// and the log when the log view is active.
+func (b *taskBrowser) refresh() {
+	pager := tools.NewPager(b.fetcher, v1.Schema.GetTaskRoute("show_all"), 1, b.limit)
+	b.filter.Apply(pager)
+	pager.Sort = sortTasks
+
+	var tlist []citasks.Task
+	_, err := pager.Next(&tlist)
+	if err != nil {
+		b.setMessage("Error on loading tasks: %s", err.Error())
+		return
+	}
+	sortTasks(&tlist)
+
+	b.mutex.Lock()
+	var id string
+	if t := b.selected(); t != nil {
+		id = t.ID
+	}
+	b.tasks = tlist
+	b.cursor = 0
+	for i := range tlist {
+		if tlist[i].ID == id {
+			b.cursor = i
+			break
+		}
+	}
+	b.updated = time.Now()
+	view, logTask := b.view, b.logTask
+	b.mutex.Unlock()
+
+	if view == BROWSE_VIEW_LOG {
+		b.loadLog(logTask)
+	}
+}
+
+func (b *taskBrowser) loadLog(id string) {
+	buff, err := tools.TaskLogTail(b.fetcher, b.config, b.config.Viper.GetString("apikey"), id, browseLogLines)
+	if err != nil {
+		b.setMessage("Error on loading the log of %s: %s", id, err.Error())
+		return
+	}
+	lines := strings.Split(strings.TrimRight(string(buff), "\n"), "\n")
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.logTask != id {
+		b.logTask = id
+		b.logFollow = true
+	}
+	b.log = lines
+	if b.logFollow {
+		b.logOffset = len(lines)
+	}
+}
+
+// stop asks to stop the selected task.
+func (b *taskBrowser) stop(t *citasks.Task) {
+	b.message = "Stop task " + t.ID + "? (y/n)"
+	b.confirm = func() {
+		if _, err := b.fetcher.StopTask(t.ID); err != nil {
+			b.setMessage("Error on stopping task %s: %s", t.ID, err.Error())
+			return
+		}
+		b.setMessage("Task %s stopped", t.ID)
+		b.refresh()
+	}
+}
+
+// retry asks to start again the selected task.
+func (b *taskBrowser) retry(t *citasks.Task) {
+	b.message = "Start again task " + t.ID + "? (y/n)"
+	b.confirm = func() {
+		if _, err := b.fetcher.StartTask(t.ID); err != nil {
+			b.setMessage("Error on starting task %s: %s", t.ID, err.Error())
+			return
+		}
+		b.setMessage("Task %s started", t.ID)
+		b.refresh()
+	}
+}
+
+// download downloads the artefacts of the selected task on a directory
+// named as the task in the current directory.
+func (b *taskBrowser) download(t *citasks.Task) {
+	target, _ := filepath.Abs(t.ID)
+	b.message = "Download artefacts of " + t.ID + " on " + target + "? (y/n)"
+	b.confirm = func() {
+		d, err := tools.NewArtefactDownloader(b.fetcher, b.config.Viper.GetString("apikey"), 4, nil)
+		if err != nil {
+			b.setMessage("%s", err.Error())
+			return
+		}
+		d.Quiet = true
+		b.setMessage("Downloading artefacts of %s...", t.ID)
+		go func() {
+			if err := d.DownloadTask(t.ID, target); err != nil {
+				b.setMessage("Error on downloading artefacts of %s: %s", t.ID, err.Error())
+				return
+			}
+			b.setMessage("Artefacts of %s downloaded on %s", t.ID, target)
+		}()
+	}
+}
+
+// handleKey processes a key and returns false to quit.
+func (b *taskBrowser) handleKey(key string, height int) bool {
+	b.mutex.Lock()
+	page := height - 4
+	if page < 1 {
+		page = 1
+	}
+
+	if b.confirm != nil {
+		confirm := b.confirm
+		b.confirm = nil
+		b.message = ""
+		b.mutex.Unlock()
+		if key == "y" || key == "Y" {
+			go confirm()
+		}
+		return true
+	}
+
+	if key == "q" || key == "\x03" {
+		b.mutex.Unlock()
+		return false
+	}
+
+	if b.view == BROWSE_VIEW_LOG {
+		switch key {
+		case "\x1b", "h", "\x1b[D":
+			b.view = BROWSE_VIEW_TASKS
+		case "k", "\x1b[A":
+			b.logOffset--
+		case "j", "\x1b[B":
+			b.logOffset++
+		case "b", "\x1b[5~":
+			b.logOffset -= page
+		case " ", "\x1b[6~":
+			b.logOffset += page
+		case "g":
+			b.logOffset = 0
+		case "G":
+			b.logOffset = len(b.log)
+		}
+		b.mutex.Unlock()
+		return true
+	}
+
+	t := b.selected()
+	switch key {
+	case "k", "\x1b[A":
+		b.cursor--
+	case "j", "\x1b[B":
+		b.cursor++
+	case "\x1b[5~":
+		b.cursor -= page
+	case "\x1b[6~":
+		b.cursor += page
+	case "g":
+		b.cursor = 0
+	case "G":
+		b.cursor = len(b.tasks) - 1
+	case "\r", "l", "\x1b[C":
+		if t != nil {
+			b.view = BROWSE_VIEW_LOG
+			b.logTask = ""
+			b.log = []string{"Loading..."}
+			b.mutex.Unlock()
+			go b.loadLog(t.ID)
+			return true
+		}
+	case "s":
+		if t != nil {
+			b.stop(t)
+		}
+	case "r":
+		if t != nil {
+			b.retry(t)
+		}
+	case "d":
+		if t != nil {
+			b.download(t)
+		}
+	case "R":
+		b.mutex.Unlock()
+		go b.refresh()
+		return true
+	}
+	if b.cursor >= len(b.tasks) {
+		b.cursor = len(b.tasks) - 1
+	}
+	if b.cursor < 0 {
+		b.cursor = 0
+	}
+	b.mutex.Unlock()
+	return true
+}
+
+func fitLine(s string, width int) string {
+	s = strings.Replace(s, "\t", "    ", -1)
+	r := []rune(s)
+	if len(r) > width {
+		return string(r[:width])
+	}
+	return s + strings.Repeat(" ", width-len(r))
+}
+
+// render draws the current view on a buffer written at once
+// on the terminal to avoid flickering.
+func (b *taskBrowser) render(width, height int) []byte {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.out.Reset()
+	b.out.WriteString("\033[H")
+	body := height - 3
+	if body < 1 {
+		body = 1
+	}
+	lines := []string{}
+
+	if b.view == BROWSE_VIEW_LOG {
+		title := fmt.Sprintf("Log of task %s", b.logTask)
+		b.out.WriteString(ansiBold + fitLine(title, width) + ansiReset + "\r\n")
+
+		if b.logOffset > len(b.log)-body {
+			b.logOffset = len(b.log) - body
+		}
+		if b.logOffset < 0 {
+			b.logOffset = 0
+		}
+		b.logFollow = b.logOffset >= len(b.log)-body
+		for i := b.logOffset; i < len(b.log) && len(lines) < body; i++ {
+			lines = append(lines, fitLine(b.log[i], width))
+		}
+	} else {
+		title := fmt.Sprintf("Tasks on %s ( %d ) - updated at %s",
+			b.config.Viper.GetString("master"), len(b.tasks), b.updated.Format("15:04:05"))
+		b.out.WriteString(ansiBold + fitLine(title, width) + ansiReset + "\r\n")
+
+		header := fmt.Sprintf("%-10s %-25s %-8s %-8s %-30s %s",
+			"ID", "NAME", "STATUS", "RESULT", "IMAGE", "CREATED")
+		lines = append(lines, ansiReverse+fitLine(header, width)+ansiReset)
+
+		if b.cursor < b.offset {
+			b.offset = b.cursor
+		}
+		if b.cursor >= b.offset+body-1 {
+			b.offset = b.cursor - body + 2
+		}
+		for i := b.offset; i < len(b.tasks) && len(lines) < body; i++ {
+			t := b.tasks[i]
+			created, _ := time.Parse(taskTimeFormat, t.CreatedTime)
+			row := fitLine(fmt.Sprintf("%-10s %-25s %-8s %-8s %-30s %s",
+				t.ID, t.Name, t.Status, t.Result, t.Image, created.Local().Format("2006-01-02 15:04:05")), width)
+			if i == b.cursor {
+				row = ansiReverse + row + ansiReset
+			}
+			lines = append(lines, row)
+		}
+	}
+
+	for len(lines) < body {
+		lines = append(lines, fitLine("", width))
+	}
+	for _, l := range lines {
+		b.out.WriteString(l + "\r\n")
+	}
+
+	help := browseTasksHelp
+	if b.view == BROWSE_VIEW_LOG {
+		help = browseLogHelp
+	}
+	b.out.WriteString(fitLine(b.message, width) + "\r\n")
+	b.out.WriteString(ansiReverse + fitLine(help, width) + ansiReset)
+
+	return b.out.Bytes()
+}
+
+// Run starts the browser until the user quits.
+func (b *taskBrowser) Run(interval time.Duration) error {
+	fd := int(os.Stdin.Fd())
+	if !terminal.IsTerminal(fd) || !terminal.IsTerminal(int(os.Stdout.Fd())) {
+		return errors.New("The task browser requires a terminal")
+	}
+
+	state, err := terminal.MakeRaw(fd)
+	if err != nil {
+		return err
+	}
+	defer terminal.Restore(fd, state)
+	fmt.Print(ansiAltScreen + ansiHideCursor + ansiClear)
+	defer fmt.Print(ansiShowCursor + ansiMainScreen)
+	defer tools.OnInterrupt(func() {
+		fmt.Print(ansiShowCursor + ansiMainScreen)
+		terminal.Restore(fd, state)
+	})()
+
+	keys := make(chan string)
+	go func() {
+		buf := make([]byte, 16)
+		for {
+			n, err := os.Stdin.Read(buf)
+			if err != nil {
+				close(keys)
+				return
+			}
+			keys <- string(buf[:n])
+		}
+	}()
+
+	draw := func() int {
+		width, height, err := terminal.GetSize(int(os.Stdout.Fd()))
+		if err != nil || width <= 0 || height <= 0 {
+			width, height = 80, 24
+		}
+		os.Stdout.Write(b.render(width, height))
+		return height
+	}
+
+	b.setMessage("Loading tasks...")
+	go func() {
+		b.refresh()
+		b.setMessage("")
+	}()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	redraw := time.NewTicker(250 * time.Millisecond)
+	defer redraw.Stop()
+
+	height := draw()
+	for {
+		select {
+		case key, ok := <-keys:
+			if !ok || !b.handleKey(key, height) {
+				return nil
+			}
+		case <-ticker.C:
+			go b.refresh()
+		case <-redraw.C:
+		}
+		height = draw()
+	}
+}
+
+func newTaskBrowseCommand(config *setting.Config) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:     "browse [OPTIONS]",
+		Aliases: []string{"ui"},
+		Short:   "Browse tasks with an interactive terminal UI",
+		Long: `Browse the tasks with an interactive terminal UI.
+
+The list of the tasks is refreshed periodically. Select a task
+with the arrows to show its log, stop it, start it again or
+download its artefacts on the current directory.
+
+$> mottainai-cli task browse
+$> mottainai-cli task browse --status running,waiting --interval 10s
+`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			var v *viper.Viper = config.Viper
+
+			fetcher := client.NewTokenClient(v.GetString("master"), v.GetString("apikey"), config)
+
+			filter, err := newTaskFilter(cmd)
+			tools.CheckError(err)
+			interval, err := cmd.Flags().GetDuration("interval")
+			tools.CheckError(err)
+			if interval <= 0 {
+				tools.CheckError(tools.ValidationError("Invalid interval %s", interval))
+			}
+			limit, err := cmd.Flags().GetInt("limit")
+			tools.CheckError(err)
+
+			tools.CheckError(newTaskBrowser(fetcher, config, filter, limit).Run(interval))
+		},
+	}
+
+	var flags = cmd.Flags()
+	flags.Duration("interval", 5*time.Second, "Refresh interval of the tasks")
+	flags.Int("limit", 200, "Number of tasks loaded")
+	addTaskFilterFlags(cmd)
+
+	return cmd
+}
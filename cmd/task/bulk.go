@@ -0,0 +1,256 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package task
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	template "github.com/MottainaiCI/mottainai-cli/cmd/task/template"
+	tools "github.com/MottainaiCI/mottainai-cli/common"
+	client "github.com/MottainaiCI/mottainai-server/pkg/client"
+	event "github.com/MottainaiCI/mottainai-server/pkg/event"
+	citasks "github.com/MottainaiCI/mottainai-server/pkg/tasks"
+	v1 "github.com/MottainaiCI/mottainai-server/routes/schema/v1"
+	cobra "github.com/spf13/cobra"
+	viper "github.com/spf13/viper"
+)
+
+// Max number of tasks submitted at the same time.
+const bulkCreateWorkers = 4
+
+type bulkCreateResult struct {
+	Manifest string `json:"manifest"`
+	ID       string `json:"id"`
+	Error    string `json:"error,omitempty"`
+}
+
+// findManifests returns the YAML manifests available in dir.
+func findManifests(dir string, recursive bool) ([]string, error) {
+	var ans []string
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path != dir && !recursive {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		if ext == ".yaml" || ext == ".yml" {
+			ans = append(ans, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(ans)
+	return ans, nil
+}
+
+// createTasksFromDir submits concurrently all manifests of dir and
+// prints a summary of the created tasks. env, as returned by
+// taskEnvironment, is merged on the environment of every task.
+// failed is true when any manifest isn't submitted.
+func createTasksFromDir(cmd *cobra.Command, v *viper.Viper, fetcher client.HttpClient,
+	dir string, templ *template.Template, recursive bool, env []string) (created map[string]bool, failed bool) {
+	manifests, err := findManifests(dir, recursive)
+	tools.CheckError(err)
+	if len(manifests) == 0 {
+		tools.CheckError(errors.New("No manifests found on " + dir))
+	}
+
+	results := make([]bulkCreateResult, len(manifests))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+
+	for w := 0; w < bulkCreateWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = createTaskFromManifest(cmd, v, fetcher, manifests[i], templ, env)
+			}
+		}()
+	}
+	for i := range manifests {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	created = make(map[string]bool)
+	table := tools.NewTable([]string{"Manifest", "Task ID", "Error"})
+	for _, r := range results {
+		if r.ID != "" {
+			created[r.ID] = false
+		}
+		if r.Error != "" {
+			failed = true
+		}
+		table.Append([]string{r.Manifest, r.ID, r.Error})
+	}
+	err = tools.NewOutput(v).PrintList(results, table)
+	tools.CheckError(err)
+
+	return created, failed
+}
+
+func createTaskFromManifest(cmd *cobra.Command, v *viper.Viper, fetcher client.HttpClient,
+	manifest string, templ *template.Template, env []string) bulkCreateResult {
+	ans := bulkCreateResult{Manifest: manifest}
+
+	t, err := taskFromManifest(manifest, templ)
+	if err != nil {
+		ans.Error = err.Error()
+		return ans
+	}
+
+	dat := t.ToMap()
+	if err := applyTaskFlags(cmd, v, dat, env); err != nil {
+		ans.Error = err.Error()
+		return ans
+	}
+
+	res, err := fetcher.CreateTask(dat)
+	if err != nil {
+		ans.Error = err.Error()
+	} else if res.ID == "" {
+		ans.Error = "Failed creating task"
+		if res.Error != "" {
+			ans.Error += ": " + res.Error
+		}
+	}
+	ans.ID = res.ID
+
+	return ans
+}
+
+type bulkOperationResult struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// resolveTaskIDs returns the tasks ids supplied as arguments or
+// the ids of the tasks matching the filter flags.
+func resolveTaskIDs(cmd *cobra.Command, fetcher client.HttpClient, args []string) ([]string, error) {
+	filter, err := newTaskFilter(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(args) > 0 {
+		if !filter.IsEmpty() {
+			return nil, errors.New("Task ids and filters can't be used together")
+		}
+		return args, nil
+	}
+	if filter.IsEmpty() {
+		return nil, errors.New("You need to define a task id or a filter")
+	}
+
+	var tlist []citasks.Task
+	pager := tools.NewPager(fetcher, v1.Schema.GetTaskRoute("show_all"), 1, 0)
+	pager.Options = filter.Options()
+	if err = pager.All(&tlist); err != nil {
+		return nil, err
+	}
+
+	ids := []string{}
+	for _, t := range filter.Filter(tlist) {
+		ids = append(ids, t.ID)
+	}
+	return ids, nil
+}
+
+// runTaskOperation confirms and executes op concurrently on
+// the tasks resolved from the arguments and the filters.
+func runTaskOperation(cmd *cobra.Command, v *viper.Viper, fetcher client.HttpClient, args []string,
+	action string, op func(string) (event.APIResponse, error)) {
+	ids, err := resolveTaskIDs(cmd, fetcher, args)
+	tools.CheckError(err)
+
+	if len(ids) == 0 {
+		fmt.Println("No tasks found")
+		return
+	}
+
+	// Ask confirmation only for tasks selected by filters.
+	yes, _ := cmd.Flags().GetBool("yes")
+	if len(args) == 0 && !yes {
+		fmt.Fprintf(os.Stderr, "Tasks: %s\n", strings.Join(ids, " "))
+		if !tools.Confirm(fmt.Sprintf("%s %d tasks?", action, len(ids))) {
+			fmt.Fprintln(os.Stderr, "Aborted, use --yes to skip the confirmation")
+			tools.Exit(1)
+		}
+	}
+
+	results := make([]bulkOperationResult, len(ids))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+
+	for w := 0; w < bulkCreateWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				res, err := op(ids[i])
+				results[i] = bulkOperationResult{ID: ids[i], Status: res.Status}
+				if err != nil {
+					results[i].Error = err.Error()
+				} else if res.Error != "" {
+					results[i].Error = res.Error
+				}
+			}
+		}()
+	}
+	for i := range ids {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	failed := false
+	table := tools.NewTable([]string{"Task ID", "Status", "Error"})
+	for _, r := range results {
+		if r.Error != "" {
+			failed = true
+		}
+		table.Append([]string{r.ID, r.Status, r.Error})
+	}
+	err = tools.NewOutput(v).PrintList(results, table)
+	tools.CheckError(err)
+
+	if failed {
+		tools.Exit(1)
+	}
+}
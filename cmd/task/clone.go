@@ -21,11 +21,17 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package task
 
 import (
-	"log"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
 
 	tools "github.com/MottainaiCI/mottainai-cli/common"
 	client "github.com/MottainaiCI/mottainai-server/pkg/client"
 	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
+	citasks "github.com/MottainaiCI/mottainai-server/pkg/tasks"
+	schema "github.com/MottainaiCI/mottainai-server/routes/schema"
+	v1 "github.com/MottainaiCI/mottainai-server/routes/schema/v1"
 	cobra "github.com/spf13/cobra"
 	viper "github.com/spf13/viper"
 )
@@ -34,21 +40,140 @@ func newTaskCloneCommand(config *setting.Config) *cobra.Command {
 	var cmd = &cobra.Command{
 		Use:   "clone <taskid> [OPTIONS]",
 		Short: "clone a task",
-		Args:  cobra.RangeArgs(1, 1),
+		Long: `Clone a task.
+
+With --set the task definition is fetched, the fields are
+overridden and a new task is submitted:
+
+  $> mottainai-cli task clone <taskid> --set image=foo/bar:latest --set environment.FOO=bar
+`,
+		Args: cobra.RangeArgs(1, 1),
 		Run: func(cmd *cobra.Command, args []string) {
 			var v *viper.Viper = config.Viper
 
 			id := args[0]
 			if len(id) == 0 {
-				log.Fatalln("You need to define a task id")
+				tools.CheckError(tools.ValidationError("You need to define a task id"))
 			}
 
 			fetcher := client.NewTokenClient(v.GetString("master"), v.GetString("apikey"), config)
-			res, err := fetcher.CloneTask(id)
+
+			overrides, err := cmd.Flags().GetStringArray("set")
 			tools.CheckError(err)
-			tools.PrintResponse(res)
+			if len(overrides) == 0 {
+				res, err := fetcher.CloneTask(id)
+				tools.CheckError(err)
+				tools.PrintResponse(res)
+				return
+			}
+
+			var t citasks.Task
+			req := schema.Request{
+				Route: v1.Schema.GetTaskRoute("as_json"),
+				Options: map[string]interface{}{
+					":id": id,
+				},
+			}
+			err = fetcher.HandleRaw(req, func(b io.ReadCloser) error {
+				return json.NewDecoder(b).Decode(&t)
+			})
+			tools.CheckError(err)
+			if t.ID == "" {
+				tools.CheckError(tools.NotFoundError("Task", id))
+			}
+
+			clone, err := overrideTask(&t, overrides)
+			tools.CheckError(err)
+
+			res, err := fetcher.CreateTask(clone.ToMap())
+			tools.CheckError(err)
+			if res.ID == "" {
+				tools.PrintResponse(res)
+				panic("Failed creating task")
+			}
+			fmt.Println("Task " + res.ID + " has been created from " + id)
 		},
 	}
 
+	var flags = cmd.Flags()
+	flags.StringArray("set", []string{},
+		"Override a field of the task ( e.g. --set image=foo/bar --set environment.FOO=bar ).\n"+
+			"List fields accept comma separated values")
+
 	return cmd
 }
+
+// Fields of a task updated by the master during its execution.
+var taskRuntimeFields = []string{
+	"ID", "status", "output", "result", "exit_status", "node_id",
+	"created_time", "start_time", "end_time", "last_update_time",
+}
+
+// overrideTask returns a new task definition from t with
+// the fields overridden by the key=value pairs. Runtime data
+// of the original task are not copied.
+func overrideTask(t *citasks.Task, overrides []string) (*citasks.Task, error) {
+	var dat map[string]interface{}
+
+	b, err := json.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(b, &dat); err != nil {
+		return nil, err
+	}
+
+	for _, f := range taskRuntimeFields {
+		delete(dat, f)
+	}
+
+	for _, o := range overrides {
+		item := strings.SplitN(o, "=", 2)
+		if len(item) != 2 {
+			return nil, tools.ValidationError("Invalid override: %s", o)
+		}
+		key, value := item[0], item[1]
+
+		if strings.HasPrefix(key, "environment.") {
+			name := strings.TrimPrefix(key, "environment.")
+			env := []interface{}{}
+			if e, ok := dat["environment"].([]interface{}); ok {
+				for _, kv := range e {
+					if s, ok := kv.(string); ok && !strings.HasPrefix(s, name+"=") {
+						env = append(env, s)
+					}
+				}
+			}
+			dat["environment"] = append(env, name+"="+value)
+			continue
+		}
+
+		current, ok := dat[key]
+		if !ok {
+			return nil, tools.ValidationError("Invalid task field: %s", key)
+		}
+		switch current.(type) {
+		case []interface{}, nil:
+			dat[key] = strings.Split(value, ",")
+		case float64:
+			var n float64
+			if _, err := fmt.Sscanf(value, "%g", &n); err != nil {
+				return nil, tools.ValidationError("Invalid number for %s: %s", key, value)
+			}
+			dat[key] = n
+		default:
+			dat[key] = value
+		}
+	}
+
+	b, err = json.Marshal(dat)
+	if err != nil {
+		return nil, err
+	}
+	ans := &citasks.Task{}
+	if err = json.Unmarshal(b, ans); err != nil {
+		return nil, err
+	}
+
+	return ans, nil
+}
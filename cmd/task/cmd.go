@@ -35,17 +35,29 @@ func NewTaskCommand(config *setting.Config) *cobra.Command {
 	cmd.AddCommand(
 		newTaskArtefactsCommand(config),
 		newTaskAttachCommand(config),
+		newTaskBrowseCommand(config),
 		newTaskCloneCommand(config),
 		newTaskCreateCommand(config),
+		newTaskDiffCommand(config),
 		newTaskDownloadCommand(config),
 		newTaskExecuteCommand(config),
+		newTaskExportCommand(config),
+		newTaskImportCommand(config),
+		newTaskGraphCommand(config),
 		newTaskListCommand(config),
 		newTaskLogCommand(config),
 		newTaskRemoveCommand(config),
+		newTaskReportCommand(config),
+		newTaskRunLocalCommand(config),
+		newTaskSearchCommand(config),
 		newTaskShowCommand(config),
 		newTaskStartCommand(config),
+		newTaskStatsCommand(config),
 		newTaskStopCommand(config),
+		newTaskValidateCommand(config),
 		newTaskMonitorCommand(config),
+		newTaskSubmitGraphCommand(config),
+		newTaskWaitCommand(config),
 		//newTaskPlayCommand(),
 		newCompileCommand(config),
 	)
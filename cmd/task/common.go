@@ -34,7 +34,6 @@ import (
 	v1 "github.com/MottainaiCI/mottainai-server/routes/schema/v1"
 
 	"fmt"
-	"os"
 
 	"github.com/mudler/anagent"
 )
@@ -78,7 +77,31 @@ func GenerateTasks(c client.HttpClient, dat map[string]interface{}, hostreg stri
 	}
 	return created
 }
+
+// fetchTask returns the task with the given id.
+func fetchTask(f client.HttpClient, id string) (citasks.Task, error) {
+	var t citasks.Task
+
+	req := schema.Request{
+		Route: v1.Schema.GetTaskRoute("as_json"),
+		Options: map[string]interface{}{
+			":id": id,
+		},
+	}
+	err := f.HandleRaw(req, func(b io.ReadCloser) error {
+		return json.NewDecoder(b).Decode(&t)
+	})
+
+	return t, err
+}
+
 func MonitorTasks(f client.HttpClient, created map[string]bool) {
+	tools.Exit(monitorTasks(f, created))
+}
+
+// monitorTasks waits the completion of the created tasks and returns
+// 1 when any of them fails.
+func monitorTasks(f client.HttpClient, created map[string]bool) int {
 	agent := anagent.New()
 	var done int
 	var res = 0
@@ -130,5 +153,5 @@ func MonitorTasks(f client.HttpClient, created map[string]bool) {
 	})
 
 	agent.Start()
-	os.Exit(res)
+	return res
 }
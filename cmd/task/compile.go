@@ -25,6 +25,7 @@ import (
 	"strings"
 
 	template "github.com/MottainaiCI/mottainai-cli/cmd/task/template"
+	tools "github.com/MottainaiCI/mottainai-cli/common"
 	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
 	cobra "github.com/spf13/cobra"
 )
@@ -39,7 +40,7 @@ func newCompileCommand(config *setting.Config) *cobra.Command {
 		Run: func(cmd *cobra.Command, args []string) {
 			if len(args) == 0 {
 				fmt.Println("Not enough arguments to compile")
-				os.Exit(1)
+				tools.Exit(1)
 			}
 
 			va := map[string]interface{}{}
@@ -48,7 +49,7 @@ func newCompileCommand(config *setting.Config) *cobra.Command {
 				item := strings.Split(v, "=")
 				if len(item) != 2 {
 					fmt.Println("Invalid value: ", item)
-					os.Exit(1)
+					tools.Exit(1)
 				}
 				va[item[0]] = item[1]
 			}
@@ -59,7 +60,7 @@ func newCompileCommand(config *setting.Config) *cobra.Command {
 			vFile, err := cmd.Flags().GetString("load")
 			if err != nil {
 				fmt.Println("Error loading values file: ", err.Error())
-				os.Exit(1)
+				tools.Exit(1)
 			}
 
 			if vFile != "" {
@@ -73,7 +74,7 @@ func newCompileCommand(config *setting.Config) *cobra.Command {
 			compiled, err := templ.DrawFromFile(args[0])
 			if err != nil {
 				fmt.Println("Error compiling template: ", err.Error())
-				os.Exit(1)
+				tools.Exit(1)
 			}
 
 			oFile, err := cmd.Flags().GetString("output")
@@ -85,14 +86,14 @@ func newCompileCommand(config *setting.Config) *cobra.Command {
 			f, err := os.Create(oFile)
 			if err != nil {
 				fmt.Println("Error creating output file: ", err.Error())
-				os.Exit(1)
+				tools.Exit(1)
 			}
 			defer f.Close()
 
 			bytesConsumed, err := f.WriteString(compiled)
 			if err != nil {
 				fmt.Println("Error writing to output file: ", err.Error())
-				os.Exit(1)
+				tools.Exit(1)
 			}
 
 			fmt.Printf("wrote %d bytes\n", bytesConsumed)
@@ -24,7 +24,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"os"
+	"strings"
+	"time"
 
+	template "github.com/MottainaiCI/mottainai-cli/cmd/task/template"
 	tools "github.com/MottainaiCI/mottainai-cli/common"
 	client "github.com/MottainaiCI/mottainai-server/pkg/client"
 	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
@@ -39,7 +43,15 @@ func newTaskCreateCommand(config *setting.Config) *cobra.Command {
 	var cmd = &cobra.Command{
 		Use:   "create [OPTIONS]",
 		Short: "Create a new task",
-		Args:  cobra.OnlyValidArgs,
+		Long: `Create a new task.
+
+A failed task can be submitted again with --resubmit and
+--resubmit-delay. They are not named --retries and --retry-delay
+because the global flags with those names retry the failed API
+calls, and they can be used with task create too.
+
+$> mottainai-cli task create --yaml task.yaml --resubmit 2 --retries 3`,
+		Args: cobra.OnlyValidArgs,
 		// TODO: PreRun check of minimal args if --json is not present
 		Run: func(cmd *cobra.Command, args []string) {
 
@@ -54,8 +66,46 @@ func newTaskCreateCommand(config *setting.Config) *cobra.Command {
 			tools.CheckError(err)
 			yamlfile, err := cmd.Flags().GetString("yaml")
 			tools.CheckError(err)
+			manifest, err := cmd.Flags().GetString("file")
+			tools.CheckError(err)
+			templ, err := manifestTemplate(cmd)
+			tools.CheckError(err)
+			retries, err := cmd.Flags().GetInt("resubmit")
+			tools.CheckError(err)
+			retryDelay, err := cmd.Flags().GetDuration("resubmit-delay")
+			tools.CheckError(err)
+			// Resolved once, secrets from commands aren't executed
+			// for every manifest.
+			env, err := taskEnvironment(cmd)
+			tools.CheckError(err)
 
-			if jsonfile != "" {
+			if info, err := os.Stat(manifest); err == nil && info.IsDir() {
+				recursive, _ := cmd.Flags().GetBool("recursive")
+				monitor, _ := cmd.Flags().GetBool("monitor")
+				if len(to) > 0 {
+					panic("--to can't be used with a directory of manifests")
+				}
+				if retries > 0 {
+					panic("--resubmit can't be used with a directory of manifests")
+				}
+				created, failed := createTasksFromDir(cmd, v, fetcher, manifest, templ, recursive, env)
+				res := 0
+				if monitor && len(created) > 0 {
+					fmt.Println("Monitoring task state")
+					res = monitorTasks(fetcher, created)
+				}
+				if failed {
+					res = 1
+				}
+				if res != 0 {
+					tools.Exit(res)
+				}
+				return
+			} else if manifest != "" {
+				t, err = taskFromManifest(manifest, templ)
+				tools.CheckError(err)
+				dat = t.ToMap()
+			} else if jsonfile != "" {
 				content, err := ioutil.ReadFile(jsonfile)
 				if err != nil {
 					panic(err)
@@ -75,22 +125,13 @@ func newTaskCreateCommand(config *setting.Config) *cobra.Command {
 				dat = t.ToMap()
 			}
 
-			var value string
-			var flagsName []string = []string{
-				"name", "script", "storage", "source", "directory", "type", "image",
-				"namespace", "storage_path", "artefact_path", "tag_namespace",
-				"prune", "queue", "cache_image",
-			}
+			tools.CheckError(applyTaskFlags(cmd, v, dat, env))
 
-			for _, n := range flagsName {
-				if cmd.Flag(n).Changed {
-					value, err = cmd.Flags().GetString(n)
-					tools.CheckError(err)
-					dat[n] = value
-				}
-			}
 			var created = make(map[string]bool)
 			if len(to) > 0 {
+				if retries > 0 {
+					panic("--resubmit can't be used with --to")
+				}
 				created = GenerateTasks(fetcher, dat, to)
 			} else {
 				res, err := fetcher.CreateTask(dat)
@@ -111,6 +152,10 @@ func newTaskCreateCommand(config *setting.Config) *cobra.Command {
 				fmt.Println("URL:", " "+fetcher.GetBaseURL()+"/tasks/display/"+tid)
 				fmt.Println("Build Log:", " "+fetcher.GetBaseURL()+"/artefact/"+tid+"/build_"+tid+".log")
 				fmt.Println("-------------------------")
+
+				if retries > 0 {
+					tools.Exit(retryTask(v, fetcher, tid, retries, retryDelay))
+				}
 			}
 			if monitor, err := cmd.Flags().GetBool("monitor"); err == nil && monitor {
 				fmt.Println("Monitoring task state")
@@ -121,9 +166,24 @@ func newTaskCreateCommand(config *setting.Config) *cobra.Command {
 	}
 
 	var flags = cmd.Flags()
+	flags.StringP("file", "f", "",
+		"Task manifest in YAML or JSON format, - for stdin ( e.g. /path/to/task.yaml ).\n"+
+			"With a directory all YAML manifests inside are submitted")
+	flags.BoolP("recursive", "r", false, "Search manifests in subdirectories too when --file is a directory")
+	flags.StringArray("set", []string{},
+		"Set a value used to render the manifest as go template ( e.g. --set arch=amd64 )")
+	flags.String("values", "",
+		"Load the values used to render the manifest from a YAML file with a values: section")
 	flags.String("json", "", "Decode parameters from a JSON file ( e.g. /path/to/file.json )")
 	flags.String("yaml", "", "Decode parameters from a YAML file ( e.g. /path/to/file.yaml )")
 	flags.String("script", "", "Entrypoint script")
+	flags.StringArray("env-file", []string{},
+		"Add the variables of a dotenv file to the task environment ( e.g. --env-file .env )")
+	flags.StringArrayP("env", "e", []string{},
+		"Set a variable of the task environment, it overrides --env-file ( e.g. --env FOO=bar )")
+	flags.StringArray("secret", []string{},
+		"Set a variable of the task environment from a secret resolved at submit time\n"+
+			"( e.g. --secret TOKEN=keyring:ci-token ). Backends: keyring, env, file, cmd")
 	flags.String("storage", "", "Storage ID")
 	flags.StringP("source", "s", "", "Repository url ( e.g. https://github.com/foo/bar.git )")
 	flags.StringP("directory", "d", "", "Directory inside repository url ( e.g. /test )")
@@ -138,9 +198,166 @@ func newTaskCreateCommand(config *setting.Config) *cobra.Command {
 	flags.StringP("queue", "q", "", "Queue where to send the task to")
 	flags.String("to", "", "Regex match pattern for nodes, it will create a task for each one")
 	flags.Bool("monitor", false, "Monitor task after creation (returns same exit status as task)")
+	flags.Int("resubmit", 0,
+		"Wait the task and submit a clone of it up to N times when it fails (returns the exit status of the last attempt).\n"+
+			"Unlike the global --retries it doesn't retry the API calls")
+	flags.Duration("resubmit-delay", 30*time.Second, "Delay before submitting a failed task again ( e.g. 2m ).\n"+
+		"Unlike the global --retry-delay it doesn't apply to the API calls")
 
 	flags.StringP("cache_image", "C", "yes",
 		"Cache image after execution inside the host for later reuse.")
 
 	return cmd
 }
+
+// applyTaskFlags overrides the task parameters with the flags
+// supplied by the user and merges env, as returned by
+// taskEnvironment, on the task environment.
+func applyTaskFlags(cmd *cobra.Command, v *viper.Viper, dat map[string]interface{}, env []string) error {
+	var flagsName []string = []string{
+		"name", "script", "storage", "source", "directory", "type", "image",
+		"namespace", "storage_path", "artefact_path", "tag_namespace",
+		"prune", "queue", "cache_image",
+	}
+
+	for _, n := range flagsName {
+		if f := cmd.Flag(n); f != nil && f.Changed {
+			value, err := cmd.Flags().GetString(n)
+			if err != nil {
+				return err
+			}
+			dat[n] = value
+		}
+	}
+
+	if len(env) > 0 {
+		var base []string
+		switch e := dat["environment"].(type) {
+		case []string:
+			base = e
+		case []interface{}:
+			for _, kv := range e {
+				if s, ok := kv.(string); ok {
+					base = append(base, s)
+				}
+			}
+		}
+		dat["environment"] = tools.MergeEnvironment(base, env)
+	}
+
+	// Use the default namespace of the active profile
+	if ns, _ := dat["namespace"].(string); ns == "" && v.GetString("profile-namespace") != "" {
+		dat["namespace"] = v.GetString("profile-namespace")
+	}
+
+	return nil
+}
+
+// taskEnvironment returns the variables of the --env-file files,
+// the --env flags and the resolved --secret references, merged in
+// this order. It returns nil when none of them is supplied.
+func taskEnvironment(cmd *cobra.Command) ([]string, error) {
+	var overrides [][]string
+
+	if f := cmd.Flag("env-file"); f != nil && f.Changed {
+		files, err := cmd.Flags().GetStringArray("env-file")
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			env, err := tools.LoadDotEnv(file)
+			if err != nil {
+				return nil, err
+			}
+			overrides = append(overrides, env)
+		}
+	}
+	if f := cmd.Flag("env"); f != nil && f.Changed {
+		env, err := cmd.Flags().GetStringArray("env")
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range env {
+			if !strings.Contains(e, "=") {
+				return nil, tools.ValidationError("Invalid environment variable %s, use KEY=VALUE", e)
+			}
+		}
+		overrides = append(overrides, env)
+	}
+	if f := cmd.Flag("secret"); f != nil && f.Changed {
+		secrets, err := cmd.Flags().GetStringArray("secret")
+		if err != nil {
+			return nil, err
+		}
+		env, err := tools.ResolveSecrets(secrets)
+		if err != nil {
+			return nil, err
+		}
+		overrides = append(overrides, env)
+	}
+	if len(overrides) == 0 {
+		return nil, nil
+	}
+
+	return tools.MergeEnvironment(nil, overrides...), nil
+}
+
+// manifestTemplate returns the template used to render the manifests
+// or nil when no values are supplied. Values defined with --set
+// have priority over the values file.
+func manifestTemplate(cmd *cobra.Command) (*template.Template, error) {
+	values, err := cmd.Flags().GetStringArray("set")
+	if err != nil {
+		return nil, err
+	}
+	vFile, err := cmd.Flags().GetString("values")
+	if err != nil {
+		return nil, err
+	}
+
+	return template.NewFromValues(values, vFile)
+}
+
+// taskFromManifest reads a task definition from a YAML or JSON file.
+// JSON is decoded through the YAML parser as it's a subset of YAML.
+// When templ is not nil the manifest is rendered as go template.
+func taskFromManifest(f string, templ *template.Template) (*task.Task, error) {
+	t := &task.Task{}
+
+	content, err := readManifest(f, templ)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = yaml.Unmarshal(content, t); err != nil {
+		return nil, fmt.Errorf("Invalid task manifest %s: %s", f, err.Error())
+	}
+
+	return t, nil
+}
+
+// readManifest returns the content of the manifest file, rendered
+// as go template when templ is not nil.
+func readManifest(f string, templ *template.Template) ([]byte, error) {
+	var content []byte
+	var err error
+
+	if f == "-" {
+		content, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		content, err = ioutil.ReadFile(f)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if templ != nil {
+		compiled, err := templ.Draw(string(content))
+		if err != nil {
+			return nil, fmt.Errorf("Error compiling manifest %s: %s", f, err.Error())
+		}
+		content = []byte(compiled)
+	}
+
+	return content, nil
+}
@@ -0,0 +1,106 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package task
+
+import (
+	"encoding/json"
+	"fmt"
+
+	tools "github.com/MottainaiCI/mottainai-cli/common"
+	client "github.com/MottainaiCI/mottainai-server/pkg/client"
+	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
+	citasks "github.com/MottainaiCI/mottainai-server/pkg/tasks"
+
+	"github.com/fatih/color"
+	"github.com/ghodss/yaml"
+	cobra "github.com/spf13/cobra"
+	viper "github.com/spf13/viper"
+)
+
+func newTaskDiffCommand(config *setting.Config) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "diff <taskid1> <taskid2> [OPTIONS]",
+		Short: "Show differences between two tasks",
+		Long: `Show differences between the definitions of two tasks.
+
+The exit status is 0 when the tasks are equal and 1 otherwise.`,
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			var v *viper.Viper = config.Viper
+
+			all, err := cmd.Flags().GetBool("all")
+			tools.CheckError(err)
+			noColor, err := cmd.Flags().GetBool("no-color")
+			tools.CheckError(err)
+			if noColor {
+				color.NoColor = true
+			}
+
+			fetcher := client.NewTokenClient(v.GetString("master"), v.GetString("apikey"), config)
+
+			var defs []string
+			for _, id := range args {
+				t, err := fetchTask(fetcher, id)
+				tools.CheckError(err)
+				if t.ID == "" {
+					tools.CheckError(tools.NotFoundError("Task", id))
+				}
+				def, err := taskDefinition(&t, all)
+				tools.CheckError(err)
+				defs = append(defs, def)
+			}
+
+			diff := tools.UnifiedDiff(defs[0], defs[1], "task "+args[0], "task "+args[1], 3)
+			if diff == "" {
+				return
+			}
+			fmt.Print(diff)
+			tools.Exit(1)
+		},
+	}
+
+	var flags = cmd.Flags()
+	flags.Bool("all", false, "Compare also the fields updated during the execution ( status, result, times )")
+	flags.Bool("no-color", false, "Disable colorized output")
+
+	return cmd
+}
+
+// taskDefinition returns the YAML representation of the task.
+func taskDefinition(t *citasks.Task, all bool) (string, error) {
+	var dat map[string]interface{}
+
+	b, err := json.Marshal(t)
+	if err != nil {
+		return "", err
+	}
+	if err = json.Unmarshal(b, &dat); err != nil {
+		return "", err
+	}
+	if !all {
+		for _, f := range taskRuntimeFields {
+			delete(dat, f)
+		}
+	}
+
+	b, err = yaml.Marshal(dat)
+	return string(b), err
+}
@@ -51,7 +51,7 @@ func newTaskExecuteCommand(config *setting.Config) *cobra.Command {
 			fetcher.SetActiveReport(true)
 			id := args[0]
 			if len(id) == 0 {
-				log.Fatalln("You need to define a task id")
+				tools.CheckError(tools.ValidationError("You need to define a task id"))
 			}
 
 			var t citasks.Task
@@ -67,13 +67,33 @@ func newTaskExecuteCommand(config *setting.Config) *cobra.Command {
 				return json.NewDecoder(b).Decode(&t)
 			})
 			tools.CheckError(err)
-			var fn func(string) (int, error)
-
 			config.GetWeb().AppURL = v.GetString("master")
-			fn = manager.DefaultTaskHandler(config).Handler(t.Type)
-			fn(id)
+
+			executor, err := cmd.Flags().GetString("executor")
+			tools.CheckError(err)
+			opts, err := newExecutorOptions(cmd)
+			tools.CheckError(err)
+
+			if executor == "" {
+				opts.Apply(config, executor)
+
+				var fn func(string) (int, error)
+				fn = manager.DefaultTaskHandler(config).Handler(t.Type)
+				fn(id)
+				return
+			}
+
+			e, err := newTaskExecutor(config, executor, opts, fetcher)
+			tools.CheckError(err)
+			res, err := manager.NewPlayer(id).Start(e)
+			if err != nil {
+				log.Println(err)
+			}
+			tools.Exit(res)
 		},
 	}
 
+	addExecutorFlags(cmd)
+
 	return cmd
 }
@@ -0,0 +1,153 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package task
+
+import (
+	"errors"
+	"strings"
+
+	client "github.com/MottainaiCI/mottainai-server/pkg/client"
+	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
+	executors "github.com/MottainaiCI/mottainai-server/pkg/tasks/executors"
+	cobra "github.com/spf13/cobra"
+)
+
+const (
+	EXECUTOR_DOCKER     = "docker"
+	EXECUTOR_PODMAN     = "podman"
+	EXECUTOR_LXD        = "lxd"
+	EXECUTOR_KUBERNETES = "kubernetes"
+	EXECUTOR_LIBVIRT    = "libvirt"
+	EXECUTOR_VIRTUALBOX = "virtualbox"
+	EXECUTOR_HOST       = "host"
+
+	// Docker compatible API socket of podman.
+	PODMAN_DEFAULT_ENDPOINT = "unix:///run/podman/podman.sock"
+)
+
+var taskExecutors = []string{
+	EXECUTOR_DOCKER, EXECUTOR_PODMAN, EXECUTOR_LXD,
+	EXECUTOR_KUBERNETES, EXECUTOR_LIBVIRT, EXECUTOR_VIRTUALBOX,
+}
+
+// ExecutorOptions are the options of the local executors.
+type ExecutorOptions struct {
+	DockerEndpoint string
+	LxdEndpoint    string
+	LxdConfigDir   string
+	LxdRemote      string
+	LxdProfiles    []string
+}
+
+func addExecutorFlags(cmd *cobra.Command) {
+	var flags = cmd.Flags()
+	flags.String("executor", "",
+		"Executor used to run the task ( "+strings.Join(taskExecutors, ", ")+" ).\n"+
+			"By default it's selected from the task type")
+	flags.String("docker-endpoint", "",
+		"Docker socket used by docker and podman executors ( e.g. unix:///var/run/docker.sock )")
+	flags.String("lxd-endpoint", "", "Path of the LXD unix socket")
+	flags.String("lxd-config-dir", "", "Directory with the LXD client configuration")
+	flags.String("lxd-remote", "", "LXD remote of the client configuration used to run the task")
+	flags.StringSlice("lxd-profile", []string{}, "LXD profiles applied to the container")
+}
+
+func newExecutorOptions(cmd *cobra.Command) (*ExecutorOptions, error) {
+	var err error
+	ans := &ExecutorOptions{}
+
+	if ans.DockerEndpoint, err = cmd.Flags().GetString("docker-endpoint"); err != nil {
+		return nil, err
+	}
+	if ans.LxdEndpoint, err = cmd.Flags().GetString("lxd-endpoint"); err != nil {
+		return nil, err
+	}
+	if ans.LxdConfigDir, err = cmd.Flags().GetString("lxd-config-dir"); err != nil {
+		return nil, err
+	}
+	if ans.LxdRemote, err = cmd.Flags().GetString("lxd-remote"); err != nil {
+		return nil, err
+	}
+	if ans.LxdProfiles, err = cmd.Flags().GetStringSlice("lxd-profile"); err != nil {
+		return nil, err
+	}
+	if ans.LxdRemote != "" && ans.LxdEndpoint != "" {
+		return nil, errors.New("--lxd-remote and --lxd-endpoint can't be used together")
+	}
+
+	return ans, nil
+}
+
+// Apply stores the options on the agent configuration read by the executors.
+func (o *ExecutorOptions) Apply(config *setting.Config, executor string) {
+	agent := config.GetAgent()
+
+	if o.DockerEndpoint != "" {
+		agent.DockerEndpoint = o.DockerEndpoint
+	} else if executor == EXECUTOR_PODMAN {
+		agent.DockerEndpoint = PODMAN_DEFAULT_ENDPOINT
+	}
+	if o.LxdEndpoint != "" {
+		agent.LxdEndpoint = o.LxdEndpoint
+	}
+	if o.LxdConfigDir != "" {
+		agent.LxdConfigDir = o.LxdConfigDir
+	}
+	if len(o.LxdProfiles) > 0 {
+		agent.LxdProfiles = o.LxdProfiles
+	}
+}
+
+// newTaskExecutor returns the executor with the given name.
+func newTaskExecutor(config *setting.Config, name string, opts *ExecutorOptions,
+	fetcher client.HttpClient) (executors.Executor, error) {
+	opts.Apply(config, name)
+
+	var base *executors.TaskExecutor
+	var ans executors.Executor
+
+	switch name {
+	case EXECUTOR_DOCKER, EXECUTOR_PODMAN:
+		e := executors.NewDockerExecutor(config)
+		base, ans = e.TaskExecutor, e
+	case EXECUTOR_LXD:
+		var err error
+		base, ans, err = newLxdExecutor(config, opts)
+		if err != nil {
+			return nil, err
+		}
+	case EXECUTOR_KUBERNETES:
+		e := executors.NewKubernetesExecutor(config)
+		base, ans = e.TaskExecutor, e
+	case EXECUTOR_LIBVIRT, EXECUTOR_VIRTUALBOX:
+		e := executors.NewVagrantExecutor(config)
+		e.Provider = name
+		base, ans = e.TaskExecutor, e
+	case EXECUTOR_HOST:
+		return nil, errors.New("The host executor is not available on this build")
+	default:
+		return nil, errors.New("Invalid executor " + name + ", valid executors are: " +
+			strings.Join(taskExecutors, ", "))
+	}
+	base.MottainaiClient = fetcher
+
+	return ans, nil
+}
@@ -0,0 +1,64 @@
+//go:build lxd
+// +build lxd
+
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package task
+
+import (
+	"errors"
+
+	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
+	executors "github.com/MottainaiCI/mottainai-server/pkg/tasks/executors"
+)
+
+func newLxdExecutor(config *setting.Config, opts *ExecutorOptions) (*executors.TaskExecutor, executors.Executor, error) {
+	e := &lxdRemoteExecutor{LxdExecutor: executors.NewLxdExecutor(config), Remote: opts.LxdRemote}
+	return e.TaskExecutor, e, nil
+}
+
+// lxdRemoteExecutor is an LXD executor that runs the containers
+// on a remote of the LXD client configuration.
+type lxdRemoteExecutor struct {
+	*executors.LxdExecutor
+	Remote string
+}
+
+func (l *lxdRemoteExecutor) Setup(docID string) error {
+	if err := l.LxdExecutor.Setup(docID); err != nil {
+		return err
+	}
+	if l.Remote == "" {
+		return nil
+	}
+
+	if _, ok := l.LxdConfig.Remotes[l.Remote]; !ok {
+		return errors.New("LXD remote " + l.Remote + " not found")
+	}
+	c, err := l.LxdConfig.GetContainerServer(l.Remote)
+	if err != nil {
+		return errors.New("Error on connect to LXD remote " + l.Remote + ": " + err.Error())
+	}
+	l.LxdConfig.DefaultRemote = l.Remote
+	l.LxdClient = c
+
+	return nil
+}
@@ -0,0 +1,35 @@
+//go:build !lxd
+// +build !lxd
+
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package task
+
+import (
+	"errors"
+
+	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
+	executors "github.com/MottainaiCI/mottainai-server/pkg/tasks/executors"
+)
+
+func newLxdExecutor(config *setting.Config, opts *ExecutorOptions) (*executors.TaskExecutor, executors.Executor, error) {
+	return nil, nil, errors.New("The LXD executor requires a build with the lxd tag ( make build EXTENSIONS=lxd )")
+}
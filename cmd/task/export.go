@@ -0,0 +1,250 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package task
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tools "github.com/MottainaiCI/mottainai-cli/common"
+	client "github.com/MottainaiCI/mottainai-server/pkg/client"
+	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
+	cobra "github.com/spf13/cobra"
+	viper "github.com/spf13/viper"
+)
+
+const (
+	// Entries of a task archive.
+	TASK_ARCHIVE_DEFINITION = "task.yaml"
+	TASK_ARCHIVE_ARTEFACTS  = "artefacts"
+)
+
+func newTaskExportCommand(config *setting.Config) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "export <taskid> [OPTIONS]",
+		Short: "Export a task definition and its artefacts",
+		Long: `Export the definition of a task and its artefacts on a tar archive,
+compressed with gzip when the file name ends with .gz or .tgz.
+
+The archive can be imported on another master with task import.
+
+$> mottainai-cli task export 123 -o task.tar
+$> mottainai-cli task import task.tar --profile other-master
+`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var v *viper.Viper = config.Viper
+
+			id := args[0]
+			out, err := cmd.Flags().GetString("output")
+			tools.CheckError(err)
+			if out == "" {
+				out = id + ".tar"
+			}
+			skip, err := cmd.Flags().GetBool("skip-artefacts")
+			tools.CheckError(err)
+			concurrency, err := cmd.Flags().GetInt("concurrency")
+			tools.CheckError(err)
+
+			fetcher := client.NewTokenClient(v.GetString("master"), v.GetString("apikey"), config)
+
+			t, err := fetchTask(fetcher, id)
+			tools.CheckError(err)
+			if t.ID == "" {
+				tools.CheckError(tools.NotFoundError("Task", id))
+			}
+			def, err := taskDefinition(&t, false)
+			tools.CheckError(err)
+
+			dir, err := ioutil.TempDir("", "mottainai-export")
+			tools.CheckError(err)
+			defer os.RemoveAll(dir)
+			defer tools.OnInterrupt(func() { os.RemoveAll(dir) })()
+
+			artefacts := filepath.Join(dir, TASK_ARCHIVE_ARTEFACTS)
+			if !skip {
+				downloader, err := tools.NewArtefactDownloader(fetcher, v.GetString("apikey"), concurrency, nil)
+				tools.CheckError(err)
+				tools.CheckError(downloader.DownloadTask(id, artefacts))
+			}
+
+			tools.CheckError(writeTaskArchive(out, []byte(def), artefacts))
+			fmt.Println("Task " + id + " exported to " + out)
+		},
+	}
+
+	var flags = cmd.Flags()
+	flags.StringP("output", "o", "", "Archive file ( default: <taskid>.tar )")
+	flags.Bool("skip-artefacts", false, "Export only the task definition")
+	flags.IntP("concurrency", "c", 4, "Number of artefacts downloaded at the same time")
+
+	return cmd
+}
+
+func isGzipArchive(f string) bool {
+	return strings.HasSuffix(f, ".gz") || strings.HasSuffix(f, ".tgz")
+}
+
+// writeTaskArchive writes the archive f with the task definition and
+// the files of the artefacts directory, when it exists.
+func writeTaskArchive(f string, def []byte, artefacts string) (err error) {
+	file, err := os.Create(f)
+	if err != nil {
+		return err
+	}
+	// An interrupted archive is incomplete.
+	defer tools.OnInterrupt(func() { os.Remove(f) })()
+	defer func() {
+		if e := file.Close(); err == nil {
+			err = e
+		}
+	}()
+
+	var w io.Writer = file
+	if isGzipArchive(f) {
+		gz := gzip.NewWriter(file)
+		defer func() {
+			if e := gz.Close(); err == nil {
+				err = e
+			}
+		}()
+		w = gz
+	}
+
+	tw := tar.NewWriter(w)
+	defer func() {
+		if e := tw.Close(); err == nil {
+			err = e
+		}
+	}()
+
+	err = tw.WriteHeader(&tar.Header{
+		Name:    TASK_ARCHIVE_DEFINITION,
+		Mode:    0644,
+		Size:    int64(len(def)),
+		ModTime: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	if _, err = tw.Write(def); err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(artefacts); os.IsNotExist(err) {
+		return nil
+	}
+	return filepath.Walk(artefacts, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.Mode().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(filepath.Dir(artefacts), path)
+		if err != nil {
+			return err
+		}
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if err = tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		_, err = io.Copy(tw, in)
+		return err
+	})
+}
+
+// readTaskArchive extracts the artefacts of the archive f on dir
+// and returns the task definition.
+func readTaskArchive(f, dir string) ([]byte, error) {
+	file, err := os.Open(f)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var r io.Reader = bufio.NewReader(file)
+	if magic, err := r.(*bufio.Reader).Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	var def []byte
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		name := filepath.Clean(filepath.FromSlash(hdr.Name))
+		switch {
+		case name == TASK_ARCHIVE_DEFINITION:
+			if def, err = ioutil.ReadAll(tr); err != nil {
+				return nil, err
+			}
+		case hdr.Typeflag == tar.TypeReg && strings.HasPrefix(name, TASK_ARCHIVE_ARTEFACTS+string(filepath.Separator)):
+			if strings.Contains(name, "..") {
+				return nil, errors.New("Invalid path on archive: " + hdr.Name)
+			}
+			dest := filepath.Join(dir, name)
+			if err := os.MkdirAll(filepath.Dir(dest), os.ModePerm); err != nil {
+				return nil, err
+			}
+			out, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(hdr.Mode)&os.ModePerm)
+			if err != nil {
+				return nil, err
+			}
+			_, err = io.Copy(out, tr)
+			out.Close()
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if def == nil {
+		return nil, errors.New("No " + TASK_ARCHIVE_DEFINITION + " found on " + f)
+	}
+	return def, nil
+}
@@ -0,0 +1,227 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package task
+
+import (
+	"path/filepath"
+	"time"
+
+	tools "github.com/MottainaiCI/mottainai-cli/common"
+	citasks "github.com/MottainaiCI/mottainai-server/pkg/tasks"
+	cobra "github.com/spf13/cobra"
+)
+
+// Time format used by the server for the task timestamps.
+const taskTimeFormat = "20060102150405"
+
+// TaskFilter selects the tasks matching all the supplied fields.
+type TaskFilter struct {
+	Status    []string
+	Owner     string
+	Image     string
+	Namespace string
+	Queue     string
+	Since     time.Time
+	Before    time.Time
+}
+
+func addTaskFilterFlags(cmd *cobra.Command) {
+	var flags = cmd.Flags()
+	flags.StringSlice("status", []string{}, "Filter tasks by status ( e.g. running,waiting )")
+	flags.String("owner", "", "Filter tasks by owner id")
+	flags.String("image", "", "Filter tasks by image, shell patterns are supported ( e.g. 'sabayon/*' )")
+	flags.String("namespace", "", "Filter tasks by namespace")
+	flags.String("queue", "", "Filter tasks by queue")
+	flags.String("since", "", "Filter tasks created after a duration or a date ( e.g. 24h, 7d, 2019-01-31 )")
+	flags.String("before", "", "Filter tasks created before a date ( e.g. 2019-01-31 )")
+	flags.Duration("older-than", 0, "Filter tasks created more than the duration ago ( e.g. 6h )")
+}
+
+func newTaskFilter(cmd *cobra.Command) (*TaskFilter, error) {
+	var err error
+	ans := &TaskFilter{}
+
+	if ans.Status, err = cmd.Flags().GetStringSlice("status"); err != nil {
+		return nil, err
+	}
+	if ans.Owner, err = cmd.Flags().GetString("owner"); err != nil {
+		return nil, err
+	}
+	if ans.Image, err = cmd.Flags().GetString("image"); err != nil {
+		return nil, err
+	}
+	if _, err = filepath.Match(ans.Image, ""); err != nil {
+		return nil, tools.ValidationError("Invalid image pattern %s", ans.Image)
+	}
+	if ans.Namespace, err = cmd.Flags().GetString("namespace"); err != nil {
+		return nil, err
+	}
+	if ans.Queue, err = cmd.Flags().GetString("queue"); err != nil {
+		return nil, err
+	}
+	since, err := cmd.Flags().GetString("since")
+	if err != nil {
+		return nil, err
+	}
+	if since != "" {
+		if ans.Since, err = parseSince(since); err != nil {
+			return nil, err
+		}
+	}
+	before, err := cmd.Flags().GetString("before")
+	if err != nil {
+		return nil, err
+	}
+	if before != "" {
+		if ans.Before, err = parseSince(before); err != nil {
+			return nil, err
+		}
+	}
+	olderThan, err := cmd.Flags().GetDuration("older-than")
+	if err != nil {
+		return nil, err
+	}
+	if olderThan > 0 {
+		limit := time.Now().Add(-olderThan)
+		if ans.Before.IsZero() || limit.Before(ans.Before) {
+			ans.Before = limit
+		}
+	}
+
+	return ans, nil
+}
+
+// parseSince parses a duration relative to now or a date.
+func parseSince(s string) (time.Time, error) {
+	if d, err := tools.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, tools.ValidationError("Invalid since value %s", s)
+}
+
+// Options returns the filters as API query parameters.
+func (f *TaskFilter) Options() map[string]interface{} {
+	ans := make(map[string]interface{})
+
+	if len(f.Status) > 0 {
+		ans["status"] = f.Status
+	}
+	if f.Owner != "" {
+		ans["owner_id"] = f.Owner
+	}
+	if f.Image != "" {
+		ans["image"] = f.Image
+	}
+	if f.Namespace != "" {
+		ans["namespace"] = f.Namespace
+	}
+	if f.Queue != "" {
+		ans["queue"] = f.Queue
+	}
+	if !f.Since.IsZero() {
+		ans["since"] = f.Since.UTC().Format(taskTimeFormat)
+	}
+	if !f.Before.IsZero() {
+		ans["before"] = f.Before.UTC().Format(taskTimeFormat)
+	}
+
+	return ans
+}
+
+// Match returns true if the task matches the filter. It's used
+// to filter the tasks when the master ignores the query parameters.
+func (f *TaskFilter) Match(t *citasks.Task) bool {
+	if len(f.Status) > 0 {
+		found := false
+		for _, s := range f.Status {
+			if s == t.Status {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.Owner != "" && f.Owner != t.Owner {
+		return false
+	}
+	if f.Image != "" {
+		if ok, _ := filepath.Match(f.Image, t.Image); !ok {
+			return false
+		}
+	}
+	if f.Namespace != "" && f.Namespace != t.Namespace {
+		return false
+	}
+	if f.Queue != "" && f.Queue != t.Queue {
+		return false
+	}
+	if !f.Since.IsZero() || !f.Before.IsZero() {
+		created, err := time.Parse(taskTimeFormat, t.CreatedTime)
+		if err != nil {
+			return false
+		}
+		if !f.Since.IsZero() && created.Before(f.Since) {
+			return false
+		}
+		if !f.Before.IsZero() && !created.Before(f.Before) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// IsEmpty returns true when no filter is defined.
+func (f *TaskFilter) IsEmpty() bool {
+	return len(f.Status) == 0 && f.Owner == "" && f.Image == "" &&
+		f.Namespace == "" && f.Queue == "" && f.Since.IsZero() && f.Before.IsZero()
+}
+
+func (f *TaskFilter) Filter(tasks []citasks.Task) []citasks.Task {
+	ans := []citasks.Task{}
+	for i := range tasks {
+		if f.Match(&tasks[i]) {
+			ans = append(ans, tasks[i])
+		}
+	}
+	return ans
+}
+
+// Apply sets the filter on a pager of tasks. The tasks are matched
+// on the whole list before slicing the pages, so older tasks aren't
+// lost when the master ignores the query parameters.
+func (f *TaskFilter) Apply(pager *tools.Pager) {
+	pager.Options = f.Options()
+	if f.IsEmpty() {
+		return
+	}
+	pager.Match = func(item interface{}) bool {
+		t := item.(citasks.Task)
+		return f.Match(&t)
+	}
+}
@@ -0,0 +1,400 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package task
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	template "github.com/MottainaiCI/mottainai-cli/cmd/task/template"
+	tools "github.com/MottainaiCI/mottainai-cli/common"
+	client "github.com/MottainaiCI/mottainai-server/pkg/client"
+	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
+	citasks "github.com/MottainaiCI/mottainai-server/pkg/tasks"
+
+	"github.com/ghodss/yaml"
+	cobra "github.com/spf13/cobra"
+	viper "github.com/spf13/viper"
+)
+
+const (
+	GRAPH_NODE_PENDING = "pending"
+	GRAPH_NODE_SUBMIT  = "submitted"
+	GRAPH_NODE_SUCCESS = "success"
+	GRAPH_NODE_FAILED  = "failed"
+	GRAPH_NODE_SKIPPED = "skipped"
+)
+
+// TaskGraph is a set of tasks with dependencies between them.
+type TaskGraph struct {
+	Tasks map[string]*TaskGraphNode `json:"tasks"`
+}
+
+type TaskGraphNode struct {
+	citasks.Task
+	DependsOn []string `json:"depends_on"`
+
+	id     string
+	status string
+}
+
+type graphResult struct {
+	Name   string `json:"name"`
+	ID     string `json:"id"`
+	Status string `json:"status"`
+}
+
+func newTaskSubmitGraphCommand(config *setting.Config) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "submit-graph -f graph.yaml [OPTIONS]",
+		Short: "Submit a graph of tasks with dependencies",
+		Long: `Submit a graph of tasks with dependencies.
+
+Every task is submitted when all the tasks defined on its
+depends_on are completed with success:
+
+tasks:
+  build:
+    image: foo/builder
+    script: ["make"]
+  test:
+    image: foo/builder
+    script: ["make test"]
+    depends_on: ["build"]
+`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			var v *viper.Viper = config.Viper
+
+			file, err := cmd.Flags().GetString("file")
+			tools.CheckError(err)
+			if file == "" {
+				fmt.Println("You need to define the graph file")
+				tools.Exit(1)
+			}
+			interval, err := cmd.Flags().GetDuration("interval")
+			tools.CheckError(err)
+			templ, err := manifestTemplate(cmd)
+			tools.CheckError(err)
+
+			graph, err := taskGraphFromFile(file, templ)
+			tools.CheckError(err)
+			order, err := graph.Sort()
+			tools.CheckError(err)
+
+			fetcher := client.NewTokenClient(v.GetString("master"), v.GetString("apikey"), config)
+
+			graph.Run(fetcher, order, interval, func(name string, dat map[string]interface{}) {
+				tools.CheckError(applyTaskFlags(cmd, v, dat, nil))
+			})
+
+			table := tools.NewTable([]string{"Name", "Task ID", "Status"})
+			results := []graphResult{}
+			var res = 0
+			for _, name := range order {
+				n := graph.Tasks[name]
+				if n.status != GRAPH_NODE_SUCCESS {
+					res = 1
+				}
+				results = append(results, graphResult{Name: name, ID: n.id, Status: n.status})
+				table.Append([]string{name, n.id, n.status})
+			}
+			err = tools.NewOutput(v).PrintList(results, table)
+			tools.CheckError(err)
+
+			tools.Exit(res)
+		},
+	}
+
+	var flags = cmd.Flags()
+	flags.StringP("file", "f", "", "Graph manifest in YAML or JSON format")
+	flags.Duration("interval", 5*time.Second, "Interval between task status checks")
+	flags.StringArray("set", []string{},
+		"Set a value used to render the manifest as go template ( e.g. --set arch=amd64 )")
+	flags.String("values", "",
+		"Load the values used to render the manifest from a YAML file with a values: section")
+	flags.StringP("namespace", "n", "", "Specify a namespace the tasks will be started on")
+	flags.StringP("queue", "q", "", "Queue where to send the tasks to")
+
+	return cmd
+}
+
+func newTaskGraphCommand(config *setting.Config) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "graph -f graph.yaml [OPTIONS]",
+		Short: "Show the dependencies of a graph of tasks",
+		Long: `Show the tasks of a graph in submission order with their
+dependencies, or the graph in Graphviz DOT format with --dot.
+
+$> mottainai-cli task graph -f graph.yaml --dot | dot -Tsvg > graph.svg
+`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			var v *viper.Viper = config.Viper
+
+			file, err := cmd.Flags().GetString("file")
+			tools.CheckError(err)
+			if file == "" {
+				fmt.Println("You need to define the graph file")
+				tools.Exit(1)
+			}
+			dot, err := cmd.Flags().GetBool("dot")
+			tools.CheckError(err)
+			templ, err := manifestTemplate(cmd)
+			tools.CheckError(err)
+
+			graph, err := taskGraphFromFile(file, templ)
+			tools.CheckError(err)
+			order, err := graph.Sort()
+			tools.CheckError(err)
+
+			if dot {
+				fmt.Print(graph.Dot(strings.TrimSuffix(filepath.Base(file), filepath.Ext(file)), order).String())
+				return
+			}
+
+			table := tools.NewTable([]string{"Name", "Image", "Depends on"})
+			nodes := []*TaskGraphNode{}
+			for _, name := range order {
+				n := graph.Tasks[name]
+				nodes = append(nodes, n)
+				table.Append([]string{name, n.Image, strings.Join(n.DependsOn, ", ")})
+			}
+			err = tools.NewOutput(v).PrintList(nodes, table)
+			tools.CheckError(err)
+		},
+	}
+
+	var flags = cmd.Flags()
+	flags.StringP("file", "f", "", "Graph manifest in YAML or JSON format")
+	flags.Bool("dot", false, "Print the graph in Graphviz DOT format")
+	flags.StringArray("set", []string{},
+		"Set a value used to render the manifest as go template ( e.g. --set arch=amd64 )")
+	flags.String("values", "",
+		"Load the values used to render the manifest from a YAML file with a values: section")
+
+	return cmd
+}
+
+func taskGraphFromFile(f string, templ *template.Template) (*TaskGraph, error) {
+	graph := &TaskGraph{}
+
+	content, err := ioutil.ReadFile(f)
+	if err != nil {
+		return nil, err
+	}
+	if templ != nil {
+		compiled, err := templ.Draw(string(content))
+		if err != nil {
+			return nil, fmt.Errorf("Error compiling graph %s: %s", f, err.Error())
+		}
+		content = []byte(compiled)
+	}
+	if err = yaml.Unmarshal(content, graph); err != nil {
+		return nil, fmt.Errorf("Invalid graph %s: %s", f, err.Error())
+	}
+	if len(graph.Tasks) == 0 {
+		return nil, errors.New("No tasks defined on graph " + f)
+	}
+
+	for name, n := range graph.Tasks {
+		if n == nil {
+			return nil, errors.New("Empty definition for task " + name)
+		}
+		n.status = GRAPH_NODE_PENDING
+		if n.Name == "" {
+			n.Name = name
+		}
+	}
+
+	return graph, nil
+}
+
+// Dot returns the DOT representation of the graph with the
+// nodes in the given order.
+func (g *TaskGraph) Dot(name string, order []string) *tools.DotGraph {
+	ans := tools.NewDotGraph(name)
+	for _, id := range order {
+		n := g.Tasks[id]
+		label := id
+		if n.Image != "" {
+			label += "\n" + n.Image
+		}
+		ans.AddNode(id, label, "")
+	}
+	for _, id := range order {
+		for _, dep := range g.Tasks[id].DependsOn {
+			ans.AddEdge(dep, id)
+		}
+	}
+	return ans
+}
+
+// Sort returns the tasks names in topological order. It returns an
+// error when a dependency is missing or the graph contains a cycle.
+func (g *TaskGraph) Sort() ([]string, error) {
+	var ans, ready []string
+	indegree := make(map[string]int)
+	dependents := make(map[string][]string)
+
+	for name, n := range g.Tasks {
+		indegree[name] += 0
+		for _, dep := range n.DependsOn {
+			if _, ok := g.Tasks[dep]; !ok {
+				return nil, fmt.Errorf("Task %s depends on unknown task %s", name, dep)
+			}
+			indegree[name]++
+			dependents[dep] = append(dependents[dep], name)
+		}
+	}
+
+	for name, d := range indegree {
+		if d == 0 {
+			ready = append(ready, name)
+		}
+	}
+
+	for len(ready) > 0 {
+		sort.Strings(ready)
+		name := ready[0]
+		ready = ready[1:]
+		ans = append(ans, name)
+		for _, dep := range dependents[name] {
+			indegree[dep]--
+			if indegree[dep] == 0 {
+				ready = append(ready, dep)
+			}
+		}
+	}
+
+	if len(ans) != len(g.Tasks) {
+		var cycle []string
+		for name, d := range indegree {
+			if d > 0 {
+				cycle = append(cycle, name)
+			}
+		}
+		sort.Strings(cycle)
+		return nil, errors.New("Dependency cycle between tasks: " + strings.Join(cycle, ", "))
+	}
+
+	return ans, nil
+}
+
+// Run submits the tasks following order and waits for their
+// completion. Tasks with a failed dependency are skipped.
+func (g *TaskGraph) Run(fetcher client.HttpClient, order []string, interval time.Duration,
+	customize func(string, map[string]interface{})) {
+	for {
+		var running int
+
+		for _, name := range order {
+			n := g.Tasks[name]
+			if n.status != GRAPH_NODE_PENDING {
+				continue
+			}
+
+			ready := true
+			for _, dep := range n.DependsOn {
+				switch g.Tasks[dep].status {
+				case GRAPH_NODE_FAILED, GRAPH_NODE_SKIPPED:
+					n.status = GRAPH_NODE_SKIPPED
+					fmt.Printf("[%s] skipped: dependency %s not completed\n", name, dep)
+				case GRAPH_NODE_SUCCESS:
+				default:
+					ready = false
+				}
+			}
+			if !ready || n.status == GRAPH_NODE_SKIPPED {
+				continue
+			}
+
+			dat := n.Task.ToMap()
+			customize(name, dat)
+			res, err := fetcher.CreateTask(dat)
+			if err != nil || res.ID == "" {
+				n.status = GRAPH_NODE_FAILED
+				if err == nil {
+					err = errors.New(res.Error)
+				}
+				fmt.Printf("[%s] failed submitting task: %s\n", name, err.Error())
+				continue
+			}
+			n.id = res.ID
+			n.status = GRAPH_NODE_SUBMIT
+			fmt.Printf("[%s] submitted task %s\n", name, n.id)
+		}
+
+		for _, name := range order {
+			n := g.Tasks[name]
+			if n.status != GRAPH_NODE_SUBMIT {
+				continue
+			}
+
+			t, err := fetchTask(fetcher, n.id)
+			if err != nil {
+				fmt.Printf("[%s] error on retrieve task %s: %s\n", name, n.id, err.Error())
+				running++
+				continue
+			}
+			if t.IsDone() || t.IsStopped() {
+				if t.IsDone() && t.IsSuccess() {
+					n.status = GRAPH_NODE_SUCCESS
+				} else {
+					n.status = GRAPH_NODE_FAILED
+				}
+				fmt.Printf("[%s] task %s completed: %s\n", name, n.id, n.status)
+				continue
+			}
+			running++
+		}
+
+		if running == 0 && !g.hasReady() {
+			return
+		}
+		time.Sleep(interval)
+	}
+}
+
+// hasReady returns true when a pending task can still be submitted.
+func (g *TaskGraph) hasReady() bool {
+	for _, n := range g.Tasks {
+		if n.status != GRAPH_NODE_PENDING {
+			continue
+		}
+		ready := true
+		for _, dep := range n.DependsOn {
+			if s := g.Tasks[dep].status; s != GRAPH_NODE_SUCCESS &&
+				s != GRAPH_NODE_FAILED && s != GRAPH_NODE_SKIPPED {
+				ready = false
+			}
+		}
+		if ready {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package task
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tools "github.com/MottainaiCI/mottainai-cli/common"
+	client "github.com/MottainaiCI/mottainai-server/pkg/client"
+	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
+	citasks "github.com/MottainaiCI/mottainai-server/pkg/tasks"
+
+	"github.com/ghodss/yaml"
+	cobra "github.com/spf13/cobra"
+	viper "github.com/spf13/viper"
+)
+
+func newTaskImportCommand(config *setting.Config) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "import <archive> [OPTIONS]",
+		Short: "Import a task exported with task export",
+		Long: `Create a task from an archive generated by task export and
+upload the exported artefacts as artefacts of the new task.
+
+Fields of the definition can be overridden with --set
+( e.g. --set queue=amd64 --set environment.ARCH=amd64 ).
+
+$> mottainai-cli task import task.tar --profile other-master
+`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var v *viper.Viper = config.Viper
+			var t citasks.Task
+
+			overrides, err := cmd.Flags().GetStringArray("set")
+			tools.CheckError(err)
+			skip, err := cmd.Flags().GetBool("skip-artefacts")
+			tools.CheckError(err)
+
+			dir, err := ioutil.TempDir("", "mottainai-import")
+			tools.CheckError(err)
+			defer os.RemoveAll(dir)
+			defer tools.OnInterrupt(func() { os.RemoveAll(dir) })()
+
+			def, err := readTaskArchive(args[0], dir)
+			tools.CheckError(err)
+			if err := yaml.Unmarshal(def, &t); err != nil {
+				tools.CheckError(fmt.Errorf("Invalid task definition on %s: %s", args[0], err.Error()))
+			}
+			clone, err := overrideTask(&t, overrides)
+			tools.CheckError(err)
+
+			fetcher := client.NewTokenClient(v.GetString("master"), v.GetString("apikey"), config)
+
+			res, err := fetcher.CreateTask(clone.ToMap())
+			tools.CheckError(err)
+			if res.ID == "" {
+				tools.PrintResponse(res)
+				tools.CheckError(errors.New("Failed creating task"))
+			}
+			fmt.Println("Task " + res.ID + " has been created")
+
+			artefacts := filepath.Join(dir, TASK_ARCHIVE_ARTEFACTS)
+			if _, err := os.Stat(artefacts); skip || os.IsNotExist(err) {
+				return
+			}
+
+			fetcher.Doc(res.ID)
+			err = filepath.Walk(artefacts, func(path string, info os.FileInfo, err error) error {
+				if err != nil || !info.Mode().IsRegular() {
+					return err
+				}
+				// The relative path is the directory of the artefact.
+				rel := strings.TrimPrefix(filepath.Dir(path), artefacts) + "/"
+				fmt.Println("Uploading " + strings.TrimPrefix(path, artefacts))
+				return fetcher.UploadArtefactRetry(path, filepath.ToSlash(rel), 5)
+			})
+			tools.CheckError(err)
+		},
+	}
+
+	var flags = cmd.Flags()
+	flags.StringArray("set", []string{},
+		"Override a field of the task definition ( e.g. --set queue=amd64 )")
+	flags.Bool("skip-artefacts", false, "Don't upload the exported artefacts")
+
+	return cmd
+}
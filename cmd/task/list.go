@@ -30,10 +30,8 @@ import (
 	client "github.com/MottainaiCI/mottainai-server/pkg/client"
 	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
 	citasks "github.com/MottainaiCI/mottainai-server/pkg/tasks"
-	schema "github.com/MottainaiCI/mottainai-server/routes/schema"
 	"github.com/MottainaiCI/mottainai-server/routes/schema/v1"
 
-	tablewriter "github.com/olekukonko/tablewriter"
 	cobra "github.com/spf13/cobra"
 	viper "github.com/spf13/viper"
 )
@@ -50,49 +48,75 @@ func newTaskListCommand(config *setting.Config) *cobra.Command {
 
 			fetcher := client.NewTokenClient(v.GetString("master"), v.GetString("apikey"), config)
 
-			var tlist []citasks.Task
-			req := schema.Request{
-				Route:  v1.Schema.GetTaskRoute("show_all"),
-				Target: &tlist,
-			}
-			err = fetcher.Handle(req)
+			filter, err := newTaskFilter(cmd)
 			tools.CheckError(err)
 
-			sort.Slice(tlist[:], func(i, j int) bool {
-				return tlist[i].CreatedTime > tlist[j].CreatedTime
-			})
-			quiet, err = cmd.Flags().GetBool("quiet")
+			page, err := cmd.Flags().GetInt("page")
+			tools.CheckError(err)
+			limit, err := cmd.Flags().GetInt("limit")
+			tools.CheckError(err)
+			all, err := cmd.Flags().GetBool("all")
 			tools.CheckError(err)
 
-			if quiet {
-				for _, i := range tlist {
-					fmt.Println(i.ID)
-				}
-				return
-			}
-
-			var task_table [][]string
+			pager := tools.NewPager(fetcher, v1.Schema.GetTaskRoute("show_all"), page, limit)
+			filter.Apply(pager)
+			pager.Sort = sortTasks
 
-			for _, i := range tlist {
-				t, _ := time.Parse("20060102150405", i.CreatedTime)
-				t2, _ := time.Parse("20060102150405", i.EndTime)
-				task_table = append(task_table, []string{i.ID, i.Name, i.Type, i.Status, i.Result, t.String(), t2.String(), i.Source, i.Directory})
+			var tlist []citasks.Task
+			if all {
+				err = pager.All(&tlist)
+			} else {
+				_, err = pager.Next(&tlist)
 			}
+			tools.CheckError(err)
+			sortTasks(&tlist)
 
-			table := tablewriter.NewWriter(os.Stdout)
-			table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
-			table.SetCenterSeparator("|")
-			table.SetHeader([]string{"ID", "Name", "Type", "Status", "Result", "Created", "End", "Source", "Dir"})
-
-			for _, v := range task_table {
-				table.Append(v)
+			if !all && pager.HasNext() {
+				fmt.Fprintf(os.Stderr, "More tasks available: use --page %d or --all\n", pager.Page())
 			}
-			table.Render()
+			quiet, err = cmd.Flags().GetBool("quiet")
+			tools.CheckError(err)
+
+			printTasks(v, tlist, quiet)
 		},
 	}
 
 	var flags = cmd.Flags()
 	flags.BoolP("quiet", "q", false, "Quiet Output")
+	flags.Int("page", 1, "Page of tasks to show")
+	flags.Int("limit", 100, "Number of tasks for page")
+	flags.Bool("all", false, "Show all tasks")
+	addTaskFilterFlags(cmd)
 
 	return cmd
 }
+
+// sortTasks sorts a pointer to a list of tasks from the newest.
+func sortTasks(l interface{}) {
+	tasks := *l.(*[]citasks.Task)
+	sort.Slice(tasks, func(i, j int) bool {
+		if tasks[i].CreatedTime == tasks[j].CreatedTime {
+			return tasks[i].ID > tasks[j].ID
+		}
+		return tasks[i].CreatedTime > tasks[j].CreatedTime
+	})
+}
+
+func printTasks(v *viper.Viper, tlist []citasks.Task, quiet bool) {
+	if quiet {
+		for _, i := range tlist {
+			fmt.Println(i.ID)
+		}
+		return
+	}
+
+	table := tools.NewTable([]string{"ID", "Name", "Type", "Status", "Result", "Queue", "Created", "End", "Source", "Dir"})
+	for _, i := range tlist {
+		t, _ := time.Parse("20060102150405", i.CreatedTime)
+		t2, _ := time.Parse("20060102150405", i.EndTime)
+		table.Append([]string{i.ID, i.Name, i.Type, i.Status, i.Result, i.Queue, t.String(), t2.String(), i.Source, i.Directory})
+	}
+
+	err := tools.NewOutput(v).PrintList(tlist, table)
+	tools.CheckError(err)
+}
@@ -21,7 +21,9 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package task
 
 import (
-	"log"
+	"errors"
+	"strconv"
+	"time"
 
 	tools "github.com/MottainaiCI/mottainai-cli/common"
 	client "github.com/MottainaiCI/mottainai-server/pkg/client"
@@ -32,24 +34,79 @@ import (
 
 func newTaskLogCommand(config *setting.Config) *cobra.Command {
 	var cmd = &cobra.Command{
-		Use:   "log <taskid> [OPTIONS]",
-		Short: "Show log of a task",
-		Args:  cobra.RangeArgs(1, 1),
+		Use:     "log <taskid> [OPTIONS]",
+		Aliases: []string{"logs"},
+		Short:   "Show log of a task",
+		Long: `Show the log of a task.
+
+The size of the log is stored on a local state file every time
+it's fetched: with --since only the content appended after the
+given time is retrieved, starting from the offset reached at
+that time. Use --since last to get the content appended after
+the previous fetch.
+
+$> mottainai-cli task log 123 --tail 20
+$> mottainai-cli task log 123 --since last
+$> mottainai-cli task log 123 --since 10m
+`,
+		Args: cobra.RangeArgs(1, 1),
 		Run: func(cmd *cobra.Command, args []string) {
 			var v *viper.Viper = config.Viper
 
 			id := args[0]
 			if len(id) == 0 {
-				log.Fatalln("You need to define a task id")
+				tools.CheckError(tools.ValidationError("You need to define a task id"))
 			}
 			fetcher := client.NewTokenClient(v.GetString("master"), v.GetString("apikey"), config)
-			buff, err := fetcher.TaskLog(id)
+			var buff []byte
+			var err error
+			tail, _ := cmd.Flags().GetInt("tail")
+			since, _ := cmd.Flags().GetString("since")
+			stateFile, _ := cmd.Flags().GetString("state-file")
+			if tail > 0 && since != "" {
+				tools.CheckError(errors.New("--tail and --since can't be used together"))
+			}
+			if tail > 0 {
+				buff, err = tools.TaskLogTail(fetcher, config, v.GetString("apikey"), id, tail)
+				if err != nil {
+					panic(err)
+				}
+				tools.PrintBuff(buff)
+				return
+			}
+
+			state, err := tools.LoadLogState(stateFile)
+			tools.CheckError(err)
+			key := fetcher.GetBaseURL() + "/" + id
+			now := time.Now()
+
+			offset := 0
+			switch since {
+			case "":
+			case "last":
+				offset, _ = state.Last(key)
+			default:
+				t, err := parseSince(since)
+				tools.CheckError(err)
+				offset, _ = state.Offset(key, t)
+			}
+
+			buff, err = fetcher.TaskStream(id, strconv.Itoa(offset))
 			if err != nil {
 				panic(err)
 			}
 			tools.PrintBuff(buff)
+
+			state.Add(key, now, offset+len(buff))
+			tools.CheckError(state.Save())
 		},
 	}
 
+	var flags = cmd.Flags()
+	flags.IntP("tail", "t", 0, "Show only the last N lines of the log")
+	flags.StringP("since", "s", "",
+		"Show only the log appended after a duration, a date or the last fetch ( e.g. 10m, 2019-01-31, last )")
+	flags.String("state-file", tools.DefaultLogStateFile(), "File with the offsets of the fetched logs")
+
 	return cmd
 }
@@ -21,21 +21,64 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package task
 
 import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	tools "github.com/MottainaiCI/mottainai-cli/common"
 	client "github.com/MottainaiCI/mottainai-server/pkg/client"
 	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
 	cobra "github.com/spf13/cobra"
 	viper "github.com/spf13/viper"
 )
 
+const (
+	MONITOR_EXIT_SUCCESS = 0
+	MONITOR_EXIT_FAILED  = 1
+	MONITOR_EXIT_STOPPED = tools.EXIT_TASK_STOPPED
+	MONITOR_EXIT_TIMEOUT = tools.EXIT_TIMEOUT
+)
+
 func newTaskMonitorCommand(config *setting.Config) *cobra.Command {
 	var cmd = &cobra.Command{
 		Use:   "monitor id1 id2 id3 ...",
 		Short: "Monitor tasks and propagate exit status",
+		Long: `Monitor tasks and propagate exit status.
+
+With a single task the output of the task is streamed until it
+completes and the exit status maps the task result:
+
+  0  the task is completed with success
+  1  the task fails
+  7  the task is stopped
+  8  timeout
+
+Errors use the exit codes of mottainai-cli --help.
+
+The wait of a single task is bounded by --max-wait. The global
+--timeout bounds only the connection and the response headers
+of each request, not the whole wait.
+
+$> mottainai-cli task monitor 123 --max-wait 1h && make deploy
+`,
+		Args: cobra.MinimumNArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
 			var v *viper.Viper = config.Viper
 
 			fetcher := client.NewTokenClient(v.GetString("master"), v.GetString("apikey"), config)
 
+			if len(args) == 1 {
+				timeout, err := cmd.Flags().GetDuration("max-wait")
+				tools.CheckError(err)
+				interval, err := cmd.Flags().GetDuration("interval")
+				tools.CheckError(err)
+				quiet, err := cmd.Flags().GetBool("quiet")
+				tools.CheckError(err)
+
+				tools.Exit(monitorTask(fetcher, args[0], timeout, interval, !quiet))
+			}
+
 			var tasks = make(map[string]bool)
 			for _, id := range args {
 				tasks[id] = false
@@ -44,5 +87,66 @@ func newTaskMonitorCommand(config *setting.Config) *cobra.Command {
 		},
 	}
 
+	var flags = cmd.Flags()
+	flags.Duration("max-wait", 0, "Max time to wait a single task ( e.g. 30m ). 0 means no timeout")
+	flags.Duration("interval", 2*time.Second, "Interval between task output and status checks")
+	flags.BoolP("quiet", "q", false, "Don't stream the output of a single task")
+
 	return cmd
 }
+
+// monitorTask streams the output of the task, when output is true,
+// until it reaches a terminal state and returns the exit status
+// of the command.
+func monitorTask(fetcher client.HttpClient, id string, timeout, interval time.Duration, output bool) int {
+	var deadline time.Time
+	var pos int
+
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+
+	for {
+		t, err := fetchTask(fetcher, id)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error on retrieve task "+id+": "+err.Error())
+			if !tools.IsTemporaryError(err) {
+				return tools.ExitCode(err)
+			}
+		} else if t.ID == "" {
+			fmt.Fprintln(os.Stderr, "No task associated with id "+id)
+			return MONITOR_EXIT_FAILED
+		} else {
+			// The status is checked before reading the output, so
+			// with a completed task the whole output is printed.
+			if output {
+				buff, err := fetcher.TaskStream(id, strconv.Itoa(pos))
+				if err == nil {
+					pos += len(buff)
+					tools.PrintBuff(buff)
+				}
+			}
+
+			switch {
+			case t.IsDone() && t.IsSuccess():
+				return MONITOR_EXIT_SUCCESS
+			case t.IsDone():
+				fmt.Fprintln(os.Stderr, "Task "+id+" failed with exit status "+t.ExitStatus)
+				return MONITOR_EXIT_FAILED
+			case t.IsStopped():
+				fmt.Fprintln(os.Stderr, "Task "+id+" stopped")
+				return MONITOR_EXIT_STOPPED
+			}
+		}
+
+		if !deadline.IsZero() && !time.Now().Before(deadline) {
+			fmt.Fprintln(os.Stderr, "Timeout monitoring task "+id)
+			return MONITOR_EXIT_TIMEOUT
+		}
+		if wait := time.Until(deadline); !deadline.IsZero() && wait < interval {
+			time.Sleep(wait)
+			continue
+		}
+		time.Sleep(interval)
+	}
+}
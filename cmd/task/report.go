@@ -0,0 +1,94 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package task
+
+import (
+	"errors"
+	"strings"
+
+	tools "github.com/MottainaiCI/mottainai-cli/common"
+	client "github.com/MottainaiCI/mottainai-server/pkg/client"
+	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
+	citasks "github.com/MottainaiCI/mottainai-server/pkg/tasks"
+
+	cobra "github.com/spf13/cobra"
+	viper "github.com/spf13/viper"
+)
+
+func newTaskReportCommand(config *setting.Config) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "report <taskid> [taskid...] --junit <file> [OPTIONS]",
+		Short: "Generate a JUnit XML report of tasks",
+		Long: `Generate a JUnit XML report with a test case for every task.
+
+Successful tasks pass, failed tasks are failures, errored tasks
+are errors and tasks not completed are skipped. The last lines
+of the log of every task are stored as output of the test case.
+
+$> mottainai-cli task report 123 124 --junit report.xml
+`,
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var v *viper.Viper = config.Viper
+
+			junit, err := cmd.Flags().GetString("junit")
+			tools.CheckError(err)
+			if junit == "" {
+				tools.CheckError(errors.New("You need to define the report file with --junit"))
+			}
+			lines, err := cmd.Flags().GetInt("log-lines")
+			tools.CheckError(err)
+			name, err := cmd.Flags().GetString("name")
+			tools.CheckError(err)
+
+			fetcher := client.NewTokenClient(v.GetString("master"), v.GetString("apikey"), config)
+
+			tlist := []citasks.Task{}
+			logs := make(map[string]string)
+			for _, id := range args {
+				t, err := fetchTask(fetcher, id)
+				tools.CheckError(err)
+				if t.ID == "" {
+					tools.CheckError(tools.NotFoundError("Task", id))
+				}
+				tlist = append(tlist, t)
+
+				if lines > 0 {
+					buff, err := tools.TaskLogTail(fetcher, config, v.GetString("apikey"), id, lines)
+					if err == nil {
+						logs[id] = strings.TrimRight(string(buff), "\n")
+					}
+				}
+			}
+
+			report := tools.NewJUnitReport(name)
+			report.AddSuite(name, tlist, logs)
+			tools.CheckError(report.WriteFile(junit))
+		},
+	}
+
+	var flags = cmd.Flags()
+	flags.String("junit", "", "Write the JUnit XML report on the file, - for stdout")
+	flags.String("name", "mottainai", "Name of the test suite")
+	flags.Int("log-lines", 100, "Number of lines of the task log added to the report, 0 to disable")
+
+	return cmd
+}
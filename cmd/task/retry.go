@@ -0,0 +1,99 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package task
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	tools "github.com/MottainaiCI/mottainai-cli/common"
+	client "github.com/MottainaiCI/mottainai-server/pkg/client"
+	citasks "github.com/MottainaiCI/mottainai-server/pkg/tasks"
+	viper "github.com/spf13/viper"
+)
+
+// Interval between the status checks of the retried tasks.
+const retryCheckInterval = 5 * time.Second
+
+type retryAttempt struct {
+	Attempt int    `json:"attempt"`
+	ID      string `json:"id"`
+	Status  string `json:"status"`
+	Result  string `json:"result"`
+	Exit    string `json:"exit_status"`
+}
+
+// retryTask waits the task and submits a clone of it, up to retries
+// times, when it ends with a failure. Stopped tasks aren't retried.
+// It prints the outcome of all the attempts and returns the exit
+// status of the command, like waitTask.
+func retryTask(v *viper.Viper, fetcher client.HttpClient, id string, retries int, delay time.Duration) int {
+	var attempts []retryAttempt
+	var ans int
+
+	for i := 0; ; i++ {
+		ans = waitTask(fetcher, id, 0, retryCheckInterval, false)
+
+		t, err := fetchTask(fetcher, id)
+		if err != nil {
+			t = citasks.Task{ID: id}
+		}
+		attempts = append(attempts, retryAttempt{
+			Attempt: i + 1,
+			ID:      id,
+			Status:  t.Status,
+			Result:  t.Result,
+			Exit:    t.ExitStatus,
+		})
+
+		if ans == WAIT_EXIT_SUCCESS || t.IsStopped() || i >= retries {
+			break
+		}
+
+		fmt.Printf("Task %s failed, retry %d of %d in %s\n", id, i+1, retries, delay)
+		time.Sleep(delay)
+
+		res, err := fetcher.CloneTask(id)
+		if err != nil || res.ID == "" {
+			tools.PrintResponse(res)
+			fmt.Fprintln(os.Stderr, "Failed cloning task "+id)
+			break
+		}
+		fmt.Println("Task " + id + " cloned as " + res.ID)
+		id = res.ID
+	}
+
+	table := tools.NewTable([]string{"Attempt", "ID", "Status", "Result", "Exit status"})
+	for _, a := range attempts {
+		table.Append([]string{strconv.Itoa(a.Attempt), a.ID, a.Status, a.Result, a.Exit})
+	}
+	tools.CheckError(tools.NewOutput(v).PrintList(attempts, table))
+
+	if ans == WAIT_EXIT_SUCCESS {
+		fmt.Printf("Task succeeded after %d attempt(s)\n", len(attempts))
+	} else {
+		fmt.Printf("Task failed after %d attempt(s)\n", len(attempts))
+	}
+
+	return ans
+}
@@ -0,0 +1,276 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package task
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	tools "github.com/MottainaiCI/mottainai-cli/common"
+	client "github.com/MottainaiCI/mottainai-server/pkg/client"
+	event "github.com/MottainaiCI/mottainai-server/pkg/event"
+	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
+	citasks "github.com/MottainaiCI/mottainai-server/pkg/tasks"
+	manager "github.com/MottainaiCI/mottainai-server/pkg/tasks/manager"
+
+	cobra "github.com/spf13/cobra"
+	viper "github.com/spf13/viper"
+)
+
+const LOCAL_TASK_ID = "local"
+
+// localFetcher replaces the master client of the executors when
+// a task is executed locally: the task is read from the manifest,
+// the output is left to the standard output of the executor and the
+// artefacts are copied on a local directory. Only the download of the artefacts of namespaces,
+// tasks and storages required by the task reaches the master.
+type localFetcher struct {
+	client.HttpClient
+
+	mutex       sync.Mutex
+	task        citasks.Task
+	artefactDir string
+}
+
+func newLocalFetcher(fetcher client.HttpClient, t citasks.Task, artefactDir string) *localFetcher {
+	t.ID = LOCAL_TASK_ID
+	return &localFetcher{HttpClient: fetcher, task: t, artefactDir: artefactDir}
+}
+
+func (f *localFetcher) setField(field, value string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	switch field {
+	case "status":
+		f.task.Status = value
+	case "result":
+		f.task.Result = value
+	case "exit_status":
+		f.task.ExitStatus = value
+	}
+}
+
+func (f *localFetcher) ok() (event.APIResponse, error) {
+	return event.APIResponse{Status: "ok", ID: LOCAL_TASK_ID}, nil
+}
+
+func (f *localFetcher) Doc(string)             {}
+func (f *localFetcher) SetUploadChunkSize(int) {}
+
+func (f *localFetcher) GetTask() ([]byte, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return json.Marshal(f.task)
+}
+
+func (f *localFetcher) AppendTaskOutput(output string) (event.APIResponse, error) {
+	return f.ok()
+}
+
+func (f *localFetcher) StreamOutput(r io.Reader) {
+	go io.Copy(os.Stdout, r)
+}
+
+func (f *localFetcher) SetTaskField(field, value string) (event.APIResponse, error) {
+	f.setField(field, value)
+	return f.ok()
+}
+
+func (f *localFetcher) SetTaskStatus(status string) (event.APIResponse, error) {
+	return f.SetTaskField("status", status)
+}
+
+func (f *localFetcher) SetTaskResult(result string) (event.APIResponse, error) {
+	return f.SetTaskField("result", result)
+}
+
+func (f *localFetcher) SetTaskOutput(output string) (event.APIResponse, error) {
+	return f.ok()
+}
+
+func (f *localFetcher) SetupTask() (event.APIResponse, error) {
+	return f.SetTaskStatus(setting.TASK_STATE_SETUP)
+}
+
+func (f *localFetcher) RunTask() {
+	f.SetTaskStatus(setting.TASK_STATE_RUNNING)
+}
+
+func (f *localFetcher) FinishTask() {
+	f.SetTaskStatus(setting.TASK_STATE_DONE)
+}
+
+func (f *localFetcher) AbortTask() {
+	f.SetTaskStatus(setting.TASK_STATE_STOPPED)
+}
+
+func (f *localFetcher) SuccessTask() {
+	f.SetTaskResult(setting.TASK_RESULT_SUCCESS)
+}
+
+func (f *localFetcher) ErrorTask() {
+	f.SetTaskResult(setting.TASK_RESULT_ERROR)
+}
+
+func (f *localFetcher) FailTask(e string) {
+	fmt.Fprintln(os.Stderr, e)
+	f.SetTaskResult(setting.TASK_RESULT_FAILED)
+}
+
+// UploadFile copies the artefact on the local artefacts directory.
+func (f *localFetcher) UploadFile(path, art string) error {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !fi.Mode().IsRegular() {
+		return nil
+	}
+
+	rel, err := filepath.Rel(art, path)
+	if err != nil {
+		return err
+	}
+	dest := filepath.Join(f.artefactDir, rel)
+	log.Println("Copying " + path + " to " + dest)
+
+	if err := os.MkdirAll(filepath.Dir(dest), os.ModePerm); err != nil {
+		return err
+	}
+	return copyFile(path, dest, fi.Mode())
+}
+
+func copyFile(src, dest string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func newTaskRunLocalCommand(config *setting.Config) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "run-local -f <task.yaml> [OPTIONS]",
+		Short: "Run a task definition locally without the master",
+		Long: `Run a task definition locally with the selected executor.
+
+The task is not registered on the master: the output is printed
+on stdout and the artefacts are copied on the directory defined
+by --artefacts. The master is contacted only to download the
+artefacts of the namespaces, tasks and storages used by the task.
+
+The exit code is the exit status of the task.
+
+$> mottainai-cli task run-local -f task.yaml
+$> mottainai-cli task run-local -f task.yaml --executor podman --set arch=amd64
+`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			var v *viper.Viper = config.Viper
+
+			f, err := cmd.Flags().GetString("file")
+			tools.CheckError(err)
+			if f == "" {
+				tools.CheckError(errors.New("You need to define a task manifest with --file"))
+			}
+			artefactDir, err := cmd.Flags().GetString("artefacts")
+			tools.CheckError(err)
+			buildDir, err := cmd.Flags().GetString("build-dir")
+			tools.CheckError(err)
+			executor, err := cmd.Flags().GetString("executor")
+			tools.CheckError(err)
+			opts, err := newExecutorOptions(cmd)
+			tools.CheckError(err)
+
+			templ, err := manifestTemplate(cmd)
+			tools.CheckError(err)
+			t, err := taskFromManifest(f, templ)
+			tools.CheckError(err)
+
+			if executor == "" {
+				executor = t.Type
+			}
+			if executor == "" {
+				executor = EXECUTOR_DOCKER
+			}
+
+			artefactDir, err = filepath.Abs(artefactDir)
+			tools.CheckError(err)
+			tmpDir := buildDir == ""
+			if tmpDir {
+				buildDir, err = ioutil.TempDir("", "mottainai-run-local")
+				tools.CheckError(err)
+				tools.OnInterrupt(func() { os.RemoveAll(buildDir) })
+			}
+			config.GetAgent().BuildPath = buildDir
+
+			fetcher := newLocalFetcher(
+				client.NewTokenClient(v.GetString("master"), v.GetString("apikey"), config),
+				*t, artefactDir)
+
+			e, err := newTaskExecutor(config, strings.ToLower(executor), opts, fetcher)
+			tools.CheckError(err)
+
+			res, err := manager.NewPlayer(LOCAL_TASK_ID).Start(e)
+			if err != nil {
+				log.Println(err)
+				if res == 0 {
+					res = 1
+				}
+			}
+			if tmpDir {
+				os.RemoveAll(buildDir)
+			}
+			tools.Exit(res)
+		},
+	}
+
+	var flags = cmd.Flags()
+	flags.StringP("file", "f", "", "Task manifest to run ( - for stdin )")
+	flags.String("artefacts", "artefacts", "Directory where the artefacts of the task are copied")
+	flags.String("build-dir", "", "Directory used to build the task ( a temporary directory by default )")
+	flags.StringArray("set", []string{},
+		"Set a value used to render the manifest as go template ( e.g. --set arch=amd64 )")
+	flags.String("values", "",
+		"Load the values used to render the manifest from a YAML file with a values: section")
+	addExecutorFlags(cmd)
+
+	return cmd
+}
@@ -0,0 +1,91 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package task
+
+import (
+	tools "github.com/MottainaiCI/mottainai-cli/common"
+	client "github.com/MottainaiCI/mottainai-server/pkg/client"
+	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
+	citasks "github.com/MottainaiCI/mottainai-server/pkg/tasks"
+	v1 "github.com/MottainaiCI/mottainai-server/routes/schema/v1"
+
+	cobra "github.com/spf13/cobra"
+	viper "github.com/spf13/viper"
+)
+
+// Short names of the task fields usable on queries.
+var taskQueryAliases = map[string]string{
+	"id":      "ID",
+	"created": "created_time",
+	"started": "start_time",
+	"ended":   "end_time",
+	"updated": "last_update_time",
+	"owner":   "owner_id",
+	"node":    "node_id",
+}
+
+func newTaskSearchCommand(config *setting.Config) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "search <query> [OPTIONS]",
+		Short: "Search tasks",
+		Long: `Search tasks matching a query.
+
+Conditions are in the form <field><operator><value> and are joined
+with AND, OR and negated with NOT. Supported operators are =, !=,
+~ (regex match), !~, >, >=, < and <=. Dates are compared as dates:
+
+  $> mottainai-cli task search 'status=done AND exit_status!=0 AND image~alpine AND created>2019-01-01'
+`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var v *viper.Viper = config.Viper
+
+			query, err := tools.ParseQuery(args[0])
+			tools.CheckError(err)
+			query.Aliases = taskQueryAliases
+
+			quiet, err := cmd.Flags().GetBool("quiet")
+			tools.CheckError(err)
+
+			fetcher := client.NewTokenClient(v.GetString("master"), v.GetString("apikey"), config)
+			pager := tools.NewPager(fetcher, v1.Schema.GetTaskRoute("show_all"), 1, 0)
+
+			var ans []citasks.Task
+			err = pager.Each(&[]citasks.Task{}, func(item interface{}) error {
+				t := item.(citasks.Task)
+				match, err := query.Match(t)
+				if match {
+					ans = append(ans, t)
+				}
+				return err
+			})
+			tools.CheckError(err)
+			sortTasks(&ans)
+
+			printTasks(v, ans, quiet)
+		},
+	}
+
+	var flags = cmd.Flags()
+	flags.BoolP("quiet", "q", false, "Quiet Output")
+
+	return cmd
+}
@@ -21,12 +21,9 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package task
 
 import (
-	"encoding/json"
-	"fmt"
-	"log"
-
 	schema "github.com/MottainaiCI/mottainai-server/routes/schema"
 
+	tools "github.com/MottainaiCI/mottainai-cli/common"
 	client "github.com/MottainaiCI/mottainai-server/pkg/client"
 	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
 	citasks "github.com/MottainaiCI/mottainai-server/pkg/tasks"
@@ -47,7 +44,7 @@ func newTaskShowCommand(config *setting.Config) *cobra.Command {
 
 			id := args[0]
 			if len(id) == 0 {
-				log.Fatalln("You need to define a task id")
+				tools.CheckError(tools.ValidationError("You need to define a task id"))
 			}
 			var t citasks.Task
 
@@ -63,11 +60,8 @@ func newTaskShowCommand(config *setting.Config) *cobra.Command {
 			if err != nil {
 				panic(err)
 			}
-			b, err := json.MarshalIndent(t, "", "  ")
-			if err != nil {
-				fmt.Println("error:", err)
-			}
-			fmt.Println(string(b))
+			err = tools.NewOutput(v).PrintObject(t)
+			tools.CheckError(err)
 			//for _, i := range tlist {
 			//	fmt.Println(strconv.Itoa(i.ID) + " " + i.Status)
 			//}
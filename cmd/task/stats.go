@@ -0,0 +1,192 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package task
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	tools "github.com/MottainaiCI/mottainai-cli/common"
+	client "github.com/MottainaiCI/mottainai-server/pkg/client"
+	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
+	citasks "github.com/MottainaiCI/mottainai-server/pkg/tasks"
+	v1 "github.com/MottainaiCI/mottainai-server/routes/schema/v1"
+
+	cobra "github.com/spf13/cobra"
+	viper "github.com/spf13/viper"
+)
+
+const STATS_TOTAL = "TOTAL"
+
+var taskStatsStates = []string{
+	setting.TASK_STATE_WAIT, setting.TASK_STATE_SETUP, setting.TASK_STATE_RUNNING,
+	setting.TASK_STATE_DONE, setting.TASK_STATE_STOPPED,
+}
+
+// TaskStats contains the statistics of a group of tasks.
+type TaskStats struct {
+	Group           string         `json:"group"`
+	Total           int            `json:"total"`
+	Status          map[string]int `json:"status"`
+	Success         int            `json:"success"`
+	Failed          int            `json:"failed"`
+	Error           int            `json:"error"`
+	FailureRate     float64        `json:"failure_rate"`
+	AverageDuration float64        `json:"average_duration"`
+
+	durations time.Duration
+	completed int
+}
+
+func newTaskStats(group string) *TaskStats {
+	return &TaskStats{Group: group, Status: make(map[string]int)}
+}
+
+func (s *TaskStats) Add(t *citasks.Task) {
+	s.Total++
+	s.Status[t.Status]++
+
+	switch t.Result {
+	case setting.TASK_RESULT_SUCCESS:
+		s.Success++
+	case setting.TASK_RESULT_FAILED:
+		s.Failed++
+	case setting.TASK_RESULT_ERROR:
+		s.Error++
+	}
+
+	start, err := time.Parse(taskTimeFormat, t.StartTime)
+	if err != nil {
+		return
+	}
+	end, err := time.Parse(taskTimeFormat, t.EndTime)
+	if err != nil || end.Before(start) {
+		return
+	}
+	s.durations += end.Sub(start)
+	s.completed++
+}
+
+// Compute updates the failure rate and the average duration.
+func (s *TaskStats) Compute() {
+	if results := s.Success + s.Failed + s.Error; results > 0 {
+		s.FailureRate = float64(s.Failed+s.Error) / float64(results)
+	}
+	if s.completed > 0 {
+		s.AverageDuration = (s.durations / time.Duration(s.completed)).Seconds()
+	}
+}
+
+// taskStats groups the tasks by namespace or image and returns the
+// statistics of every group followed by the total.
+func taskStats(tasks []citasks.Task, by string) []*TaskStats {
+	groups := make(map[string]*TaskStats)
+	total := newTaskStats(STATS_TOTAL)
+
+	for i := range tasks {
+		key := tasks[i].Namespace
+		if by == "image" {
+			key = tasks[i].Image
+		}
+		if _, ok := groups[key]; !ok {
+			groups[key] = newTaskStats(key)
+		}
+		groups[key].Add(&tasks[i])
+		total.Add(&tasks[i])
+	}
+
+	ans := []*TaskStats{}
+	for _, s := range groups {
+		s.Compute()
+		ans = append(ans, s)
+	}
+	sort.Slice(ans, func(i, j int) bool {
+		return ans[i].Group < ans[j].Group
+	})
+	total.Compute()
+
+	return append(ans, total)
+}
+
+func newTaskStatsCommand(config *setting.Config) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "stats [OPTIONS]",
+		Short: "Show statistics of the tasks",
+		Long: `Show the number of tasks by status, the average duration
+and the failure rate of the tasks grouped by namespace or image.
+
+The failure rate is the ratio of failed and errored tasks over
+the tasks with a result. The average duration is in seconds.
+
+$> mottainai-cli task stats --since 7d
+$> mottainai-cli task stats --since 24h --by image --output json
+`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			var v *viper.Viper = config.Viper
+
+			fetcher := client.NewTokenClient(v.GetString("master"), v.GetString("apikey"), config)
+
+			filter, err := newTaskFilter(cmd)
+			tools.CheckError(err)
+			by, err := cmd.Flags().GetString("by")
+			tools.CheckError(err)
+			if by != "namespace" && by != "image" {
+				tools.CheckError(tools.ValidationError("Invalid --by value %s, valid values are: namespace, image", by))
+			}
+
+			pager := tools.NewPager(fetcher, v1.Schema.GetTaskRoute("show_all"), 1, 100)
+			filter.Apply(pager)
+			pager.Sort = sortTasks
+
+			var tlist []citasks.Task
+			tools.CheckError(pager.All(&tlist))
+
+			stats := taskStats(tlist, by)
+
+			header := []string{by, "Total"}
+			header = append(header, taskStatsStates...)
+			header = append(header, "Success", "Failed", "Error", "Failure rate", "Avg duration")
+			table := tools.NewTable(header)
+			for _, s := range stats {
+				row := []string{s.Group, strconv.Itoa(s.Total)}
+				for _, state := range taskStatsStates {
+					row = append(row, strconv.Itoa(s.Status[state]))
+				}
+				row = append(row,
+					strconv.Itoa(s.Success), strconv.Itoa(s.Failed), strconv.Itoa(s.Error),
+					fmt.Sprintf("%.1f%%", s.FailureRate*100),
+					(time.Duration(s.AverageDuration) * time.Second).String())
+				table.Append(row)
+			}
+
+			err = tools.NewOutput(v).PrintList(stats, table)
+			tools.CheckError(err)
+		},
+	}
+
+	cmd.Flags().String("by", "namespace", "Group the tasks by namespace or image")
+	addTaskFilterFlags(cmd)
+
+	return cmd
+}
@@ -21,9 +21,6 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package task
 
 import (
-	"log"
-
-	tools "github.com/MottainaiCI/mottainai-cli/common"
 	client "github.com/MottainaiCI/mottainai-server/pkg/client"
 	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
 	cobra "github.com/spf13/cobra"
@@ -32,23 +29,25 @@ import (
 
 func newTaskStopCommand(config *setting.Config) *cobra.Command {
 	var cmd = &cobra.Command{
-		Use:   "stop <taskid> [OPTIONS]",
-		Short: "Stop a task",
-		Args:  cobra.RangeArgs(1, 1),
+		Use:   "stop [taskid...] [OPTIONS]",
+		Short: "Stop tasks",
+		Long: `Stop tasks.
+
+Tasks are selected by id or by filters, for example:
+
+  $> mottainai-cli task stop --status running --older-than 6h
+`,
 		Run: func(cmd *cobra.Command, args []string) {
 			var v *viper.Viper = config.Viper
 
 			fetcher := client.NewTokenClient(v.GetString("master"), v.GetString("apikey"), config)
-
-			id := args[0]
-			if len(id) == 0 {
-				log.Fatalln("You need to define a task id")
-			}
-			res, err := fetcher.StopTask(id)
-			tools.CheckError(err)
-			tools.PrintResponse(res)
+			runTaskOperation(cmd, v, fetcher, args, "Stop", fetcher.StopTask)
 		},
 	}
 
+	var flags = cmd.Flags()
+	flags.BoolP("yes", "y", false, "Don't ask confirmation for tasks selected by filters")
+	addTaskFilterFlags(cmd)
+
 	return cmd
 }
@@ -35,6 +35,31 @@ type Template struct {
 
 func New() *Template { return &Template{Values: map[string]interface{}{}} }
 
+// NewFromValues returns a template with the key=value pairs and the
+// values file, or nil when no values are supplied. The key=value
+// pairs have priority over the values file.
+func NewFromValues(values []string, file string) (*Template, error) {
+	if len(values) == 0 && file == "" {
+		return nil, nil
+	}
+
+	tem := New()
+	for _, v := range values {
+		item := strings.SplitN(v, "=", 2)
+		if len(item) != 2 {
+			return nil, errors.New("Invalid value: " + v)
+		}
+		tem.Values[item[0]] = item[1]
+	}
+	if file != "" {
+		if err := tem.LoadValuesFromFile(file); err != nil {
+			return nil, fmt.Errorf("Error loading values from file %s: %s", file, err.Error())
+		}
+	}
+
+	return tem, nil
+}
+
 func (tem *Template) DrawFromFile(file string) (string, error) {
 	dat, err := ioutil.ReadFile(file)
 	if err != nil {
@@ -0,0 +1,215 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package task
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	tools "github.com/MottainaiCI/mottainai-cli/common"
+	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
+	citasks "github.com/MottainaiCI/mottainai-server/pkg/tasks"
+
+	"github.com/ghodss/yaml"
+	cobra "github.com/spf13/cobra"
+)
+
+// Task types handled by the agents.
+var taskTypes = []string{
+	"docker_execute", "docker", "kubernetes",
+	"libvirt_execute", "libvirt_vagrant",
+	"virtualbox_execute", "virtualbox_vagrant",
+}
+
+// Valid names of namespaces and queues.
+var taskNameRegex = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+type taskValidation struct {
+	Errors   []string
+	Warnings []string
+}
+
+func (v *taskValidation) errorf(format string, args ...interface{}) {
+	v.Errors = append(v.Errors, fmt.Sprintf(format, args...))
+}
+
+func (v *taskValidation) warnf(format string, args ...interface{}) {
+	v.Warnings = append(v.Warnings, fmt.Sprintf(format, args...))
+}
+
+func newTaskValidateCommand(config *setting.Config) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "validate -f <task.yaml> [OPTIONS]",
+		Short: "Validate task manifests",
+		Long: `Validate task manifests without submitting them.
+
+The exit status is 1 when a manifest contains errors.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			file, err := cmd.Flags().GetString("file")
+			tools.CheckError(err)
+			if file == "" {
+				fmt.Println("You need to define the manifest file")
+				tools.Exit(1)
+			}
+			recursive, err := cmd.Flags().GetBool("recursive")
+			tools.CheckError(err)
+			templ, err := manifestTemplate(cmd)
+			tools.CheckError(err)
+
+			manifests := []string{file}
+			if info, err := os.Stat(file); err == nil && info.IsDir() {
+				manifests, err = findManifests(file, recursive)
+				tools.CheckError(err)
+			}
+
+			res := 0
+			for _, m := range manifests {
+				var v *taskValidation
+				content, err := readManifest(m, templ)
+				if err != nil {
+					v = &taskValidation{Errors: []string{err.Error()}}
+				} else {
+					v = validateTask(content)
+				}
+
+				for _, w := range v.Warnings {
+					fmt.Printf("%s: warning: %s\n", m, w)
+				}
+				for _, e := range v.Errors {
+					fmt.Printf("%s: error: %s\n", m, e)
+				}
+				if len(v.Errors) > 0 {
+					res = 1
+				} else {
+					fmt.Printf("%s: OK\n", m)
+				}
+			}
+
+			tools.Exit(res)
+		},
+	}
+
+	var flags = cmd.Flags()
+	flags.StringP("file", "f", "", "Task manifest or directory of manifests to validate, - for stdin")
+	flags.BoolP("recursive", "r", false, "Search manifests in subdirectories too when --file is a directory")
+	flags.StringArray("set", []string{},
+		"Set a value used to render the manifest as go template ( e.g. --set arch=amd64 )")
+	flags.String("values", "",
+		"Load the values used to render the manifest from a YAML file with a values: section")
+
+	return cmd
+}
+
+// validateTask checks the manifest against the task schema.
+func validateTask(content []byte) *taskValidation {
+	ans := &taskValidation{}
+	t := &citasks.Task{}
+
+	data, err := yaml.YAMLToJSON(content)
+	if err != nil {
+		ans.errorf("invalid YAML: %s", err.Error())
+		return ans
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err = dec.Decode(t); err != nil {
+		ans.errorf("invalid task: %s", err.Error())
+		return ans
+	}
+
+	if t.Type == "" {
+		ans.warnf("type is not defined, the default of the master is used")
+	} else if !isTaskType(t.Type) {
+		ans.errorf("invalid type %s, valid types are: %s", t.Type, strings.Join(taskTypes, ", "))
+	}
+	if t.Image == "" {
+		ans.errorf("image is required")
+	}
+	if len(t.Script) == 0 && len(t.Entrypoint) == 0 {
+		ans.warnf("neither script nor entrypoint are defined")
+	}
+	if t.Directory != "" && t.Source == "" {
+		ans.warnf("directory is defined without a source")
+	}
+
+	for _, f := range []struct{ name, value string }{
+		{"namespace", t.Namespace},
+		{"tag_namespace", t.TagNamespace},
+		{"queue", t.Queue},
+	} {
+		if f.value != "" && !taskNameRegex.MatchString(f.value) {
+			ans.errorf("invalid %s %s: only letters, numbers, '.', '_' and '-' are allowed", f.name, f.value)
+		}
+	}
+
+	for _, f := range []struct{ name, value string }{
+		{"prune", t.Prune},
+		{"cache_image", t.CacheImage},
+		{"cache_clean", t.CacheClean},
+		{"namespace_merged", t.NamespaceMerged},
+	} {
+		switch f.value {
+		case "", "yes", "no", "true", "false":
+		default:
+			ans.errorf("invalid %s %s: expected yes or no", f.name, f.value)
+		}
+	}
+
+	if t.PublishMode != "" && t.PublishMode != setting.TASK_PUBLISH_MODE_APPEND {
+		ans.errorf("invalid publish_mode %s: only %s is supported", t.PublishMode, setting.TASK_PUBLISH_MODE_APPEND)
+	}
+	if t.Retry != "" {
+		if n, err := strconv.Atoi(t.Retry); err != nil || n < 0 {
+			ans.errorf("invalid retry %s: expected a positive number", t.Retry)
+		}
+	}
+	if t.TimeOut < 0 {
+		ans.errorf("invalid timeout %v: expected a positive number", t.TimeOut)
+	}
+
+	for _, e := range t.Environment {
+		if i := strings.Index(e, "="); i <= 0 {
+			ans.errorf("invalid environment %s: expected KEY=VALUE", e)
+		}
+	}
+	for _, b := range t.Binds {
+		if parts := strings.Split(b, ":"); len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+			ans.errorf("invalid bind %s: expected SOURCE:TARGET[:MODE]", b)
+		}
+	}
+
+	return ans
+}
+
+func isTaskType(t string) bool {
+	for _, tt := range taskTypes {
+		if tt == t {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,131 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package task
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	tools "github.com/MottainaiCI/mottainai-cli/common"
+	client "github.com/MottainaiCI/mottainai-server/pkg/client"
+	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
+	cobra "github.com/spf13/cobra"
+	viper "github.com/spf13/viper"
+)
+
+const (
+	WAIT_EXIT_SUCCESS = 0
+	WAIT_EXIT_FAILED  = 1
+	WAIT_EXIT_TIMEOUT = tools.EXIT_TIMEOUT
+)
+
+func newTaskWaitCommand(config *setting.Config) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "wait <taskid> [OPTIONS]",
+		Short: "Wait the completion of a task",
+		Long: `Wait the completion of a task.
+
+The exit status is 0 when the task is completed with success,
+1 when the task fails or is stopped and 8 on timeout. Errors
+use the exit codes of mottainai-cli --help.
+
+The wait is bounded by --max-wait. The global --timeout bounds
+only the connection and the response headers of each status
+check, not the whole wait.
+
+$> mottainai-cli task wait 123 --max-wait 30m`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var v *viper.Viper = config.Viper
+
+			id := args[0]
+			timeout, err := cmd.Flags().GetDuration("max-wait")
+			tools.CheckError(err)
+			interval, err := cmd.Flags().GetDuration("interval")
+			tools.CheckError(err)
+			quiet, err := cmd.Flags().GetBool("quiet")
+			tools.CheckError(err)
+
+			fetcher := client.NewTokenClient(v.GetString("master"), v.GetString("apikey"), config)
+			tools.Exit(waitTask(fetcher, id, timeout, interval, quiet))
+		},
+	}
+
+	var flags = cmd.Flags()
+	flags.Duration("max-wait", 0, "Max time to wait the task ( e.g. 30m ). 0 means no timeout")
+	flags.Duration("interval", 5*time.Second, "Interval between task status checks")
+	flags.BoolP("quiet", "q", false, "Don't print the task status changes")
+
+	return cmd
+}
+
+// waitTask polls the task until it reaches a terminal state and
+// returns the exit status of the command.
+func waitTask(fetcher client.HttpClient, id string, timeout, interval time.Duration, quiet bool) int {
+	var deadline time.Time
+	var status string
+
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+
+	for {
+		t, err := fetchTask(fetcher, id)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error on retrieve task "+id+": "+err.Error())
+			if !tools.IsTemporaryError(err) {
+				return tools.ExitCode(err)
+			}
+		} else if t.ID == "" {
+			fmt.Fprintln(os.Stderr, "No task associated with id "+id)
+			return WAIT_EXIT_FAILED
+		} else {
+			if t.Status != status && !quiet {
+				fmt.Println("Task " + id + " status: " + t.Status)
+			}
+			status = t.Status
+
+			if t.IsDone() {
+				if t.IsSuccess() {
+					return WAIT_EXIT_SUCCESS
+				}
+				if !quiet {
+					fmt.Println("Task " + id + " failed with exit status " + t.ExitStatus)
+				}
+				return WAIT_EXIT_FAILED
+			}
+			if t.IsStopped() {
+				return WAIT_EXIT_FAILED
+			}
+		}
+
+		if !deadline.IsZero() && time.Now().Add(interval).After(deadline) {
+			if wait := time.Until(deadline); wait > 0 {
+				time.Sleep(wait)
+				continue
+			}
+			fmt.Fprintln(os.Stderr, "Timeout waiting task "+id)
+			return WAIT_EXIT_TIMEOUT
+		}
+		time.Sleep(interval)
+	}
+}
@@ -36,6 +36,7 @@ func NewTokenCommand(config *setting.Config) *cobra.Command {
 		newTokenCreateCommand(config),
 		newTokenListCommand(config),
 		newTokenRemoveCommand(config),
+		newTokenRotateCommand(config),
 	)
 
 	return cmd
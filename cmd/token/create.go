@@ -21,6 +21,8 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package token
 
 import (
+	"fmt"
+
 	tools "github.com/MottainaiCI/mottainai-cli/common"
 	client "github.com/MottainaiCI/mottainai-server/pkg/client"
 	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
@@ -32,8 +34,11 @@ func newTokenCreateCommand(config *setting.Config) *cobra.Command {
 	var cmd = &cobra.Command{
 		Use:   "create [OPTIONS]",
 		Short: "Create a new token",
-		Args:  cobra.OnlyValidArgs,
-		// TODO: PreRun check of minimal args if --json is not present
+		Long: `Create a new API token for the current user.
+
+The new token is printed once created, use --quiet to print only
+its key, e.g. to store it in a profile or a CI secret.`,
+		Args: cobra.OnlyValidArgs,
 		Run: func(cmd *cobra.Command, args []string) {
 			var err error
 			var v *viper.Viper = config.Viper
@@ -42,9 +47,33 @@ func newTokenCreateCommand(config *setting.Config) *cobra.Command {
 
 			res, err := fetcher.TokenCreate()
 			tools.CheckError(err)
-			tools.PrintResponse(res)
+			if len(res.Error) > 0 || len(res.ID) == 0 {
+				tools.PrintResponse(res)
+				return
+			}
+
+			// The key is only exposed by the token list
+			tlist, err := listTokens(fetcher)
+			tools.CheckError(err)
+			t := findToken(tlist, res.ID)
+			if t == nil {
+				tools.PrintResponse(res)
+				return
+			}
+
+			quiet, err := cmd.Flags().GetBool("quiet")
+			tools.CheckError(err)
+			if quiet {
+				fmt.Println(t.Key)
+				return
+			}
+			err = tools.NewOutput(v).PrintObject(t)
+			tools.CheckError(err)
 		},
 	}
 
+	var flags = cmd.Flags()
+	flags.BoolP("quiet", "q", false, "Print only the new token key")
+
 	return cmd
 }
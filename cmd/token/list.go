@@ -22,7 +22,6 @@ package token
 
 import (
 	"fmt"
-	"os"
 
 	schema "github.com/MottainaiCI/mottainai-server/routes/schema"
 
@@ -31,11 +30,29 @@ import (
 	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
 	token "github.com/MottainaiCI/mottainai-server/pkg/token"
 	"github.com/MottainaiCI/mottainai-server/routes/schema/v1"
-	tablewriter "github.com/olekukonko/tablewriter"
 	cobra "github.com/spf13/cobra"
 	viper "github.com/spf13/viper"
 )
 
+func listTokens(fetcher client.HttpClient) ([]token.Token, error) {
+	var tlist []token.Token
+	req := schema.Request{
+		Route:  v1.Schema.GetTokenRoute("show"),
+		Target: &tlist,
+	}
+	err := fetcher.Handle(req)
+	return tlist, err
+}
+
+func findToken(tlist []token.Token, id string) *token.Token {
+	for i := range tlist {
+		if tlist[i].ID == id {
+			return &tlist[i]
+		}
+	}
+	return nil
+}
+
 func newTokenListCommand(config *setting.Config) *cobra.Command {
 	var cmd = &cobra.Command{
 		Use:   "list [OPTIONS]",
@@ -44,16 +61,11 @@ func newTokenListCommand(config *setting.Config) *cobra.Command {
 		Run: func(cmd *cobra.Command, args []string) {
 			var err error
 			var tlist []token.Token
-			var task_table [][]string
 			var quiet bool
 			var v *viper.Viper = config.Viper
 
 			fetcher := client.NewTokenClient(v.GetString("master"), v.GetString("apikey"), config)
-			req := schema.Request{
-				Route:  v1.Schema.GetTokenRoute("show"),
-				Target: &tlist,
-			}
-			err = fetcher.Handle(req)
+			tlist, err = listTokens(fetcher)
 			tools.CheckError(err)
 
 			quiet, err = cmd.Flags().GetBool("quiet")
@@ -66,19 +78,13 @@ func newTokenListCommand(config *setting.Config) *cobra.Command {
 				return
 			}
 
+			table := tools.NewTable([]string{"ID", "Key", "UserId"})
 			for _, i := range tlist {
-				task_table = append(task_table, []string{i.ID, i.Key, i.UserId})
+				table.Append([]string{i.ID, i.Key, i.UserId})
 			}
 
-			table := tablewriter.NewWriter(os.Stdout)
-			table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
-			table.SetCenterSeparator("|")
-			table.SetHeader([]string{"ID", "Key", "UserId"})
-
-			for _, v := range task_table {
-				table.Append(v)
-			}
-			table.Render()
+			err = tools.NewOutput(v).PrintList(tlist, table)
+			tools.CheckError(err)
 
 		},
 	}
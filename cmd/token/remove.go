@@ -21,8 +21,6 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package token
 
 import (
-	"log"
-
 	tools "github.com/MottainaiCI/mottainai-cli/common"
 	client "github.com/MottainaiCI/mottainai-server/pkg/client"
 	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
@@ -32,9 +30,10 @@ import (
 
 func newTokenRemoveCommand(config *setting.Config) *cobra.Command {
 	var cmd = &cobra.Command{
-		Use:   "remove <token-id> [OPTIONS]",
-		Short: "Remove a token",
-		Args:  cobra.RangeArgs(1, 1),
+		Use:     "delete <token-id> [OPTIONS]",
+		Short:   "Delete a token",
+		Aliases: []string{"remove"},
+		Args:    cobra.RangeArgs(1, 1),
 		Run: func(cmd *cobra.Command, args []string) {
 			var v *viper.Viper = config.Viper
 
@@ -42,7 +41,7 @@ func newTokenRemoveCommand(config *setting.Config) *cobra.Command {
 
 			id := args[0]
 			if len(id) == 0 {
-				log.Fatalln("You need to define a plan id")
+				tools.CheckError(tools.ValidationError("You need to define a token id"))
 			}
 
 			res, err := fetcher.TokenDelete(id)
@@ -0,0 +1,144 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package token
+
+import (
+	"errors"
+	"fmt"
+
+	tools "github.com/MottainaiCI/mottainai-cli/common"
+	client "github.com/MottainaiCI/mottainai-server/pkg/client"
+	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
+	cobra "github.com/spf13/cobra"
+	viper "github.com/spf13/viper"
+)
+
+func newTokenRotateCommand(config *setting.Config) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "rotate [OPTIONS]",
+		Short: "Replace the token of the active profile with a new one",
+		Long: `Replace the token of the active profile with a new one.
+
+A new token is created and checked with a test API call, then it is
+saved on the profile (or on its credential store, e.g. the keyring)
+and only at the end the old token is revoked.
+
+If any step before the revocation fails the new token is removed and
+the profile is left untouched, so the old token keeps working.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			var conf tools.ProfileConf
+			var v *viper.Viper = config.Viper
+
+			name := v.GetString("profile")
+			if name == "" || v.Get("profiles") == nil {
+				tools.CheckError(errors.New("token rotate needs an active profile"))
+			}
+			err := v.Unmarshal(&conf)
+			tools.CheckError(err)
+			profile, err := conf.GetProfile(name)
+			tools.CheckError(err)
+			if profile == nil {
+				tools.CheckError(errors.New("No profile with name " + name))
+			}
+
+			// Rotating a key that comes from --apikey or from a project
+			// local profile would leave the profile with a stale key.
+			oldKey := v.GetString("apikey")
+			stored, err := profile.ResolveApiKey(name)
+			tools.CheckError(err)
+			if stored != oldKey {
+				tools.CheckError(fmt.Errorf("The API key in use isn't the one of profile %s", name))
+			}
+
+			fetcher := client.NewTokenClient(v.GetString("master"), oldKey, config)
+			tlist, err := listTokens(fetcher)
+			tools.CheckError(err)
+			var old string
+			for _, t := range tlist {
+				if t.Key == oldKey {
+					old = t.ID
+				}
+			}
+			if old == "" {
+				tools.CheckError(errors.New("The API key of the profile isn't a token of the current user"))
+			}
+
+			res, err := fetcher.TokenCreate()
+			tools.CheckError(err)
+			if len(res.Error) > 0 || len(res.ID) == 0 {
+				tools.PrintResponse(res)
+				tools.CheckError(errors.New("Token creation failed"))
+			}
+
+			rollback := func(err error) {
+				if _, derr := fetcher.TokenDelete(res.ID); derr != nil {
+					fmt.Printf("Failed to remove the new token %s: %s\n", res.ID, derr)
+				}
+				tools.CheckError(err)
+			}
+
+			tlist, err = listTokens(fetcher)
+			if err != nil {
+				rollback(err)
+			}
+			t := findToken(tlist, res.ID)
+			if t == nil {
+				rollback(errors.New("The new token " + res.ID + " isn't available"))
+			}
+
+			// Test API call with the new key before saving it.
+			check := client.NewTokenClient(v.GetString("master"), t.Key, config)
+			tlist, err = listTokens(check)
+			if err == nil && findToken(tlist, res.ID) == nil {
+				err = errors.New("The new token isn't accepted by the master")
+			}
+			if err != nil {
+				rollback(err)
+			}
+
+			err = conf.SaveApiKey(name, t.Key)
+			if err != nil {
+				rollback(err)
+			}
+			f, err := conf.Write(v.ConfigFileUsed())
+			if err != nil {
+				if rerr := conf.SaveApiKey(name, oldKey); rerr != nil {
+					fmt.Printf("Failed to restore the old key of profile %s: %s\n", name, rerr)
+				}
+				rollback(err)
+			}
+			fmt.Printf("Profile %s updated on file %s with token %s.\n", name, f, t.ID)
+
+			dres, err := check.TokenDelete(old)
+			if err == nil && len(dres.Error) > 0 {
+				err = errors.New(dres.Error)
+			}
+			if err != nil {
+				fmt.Printf("The old token %s is still valid, remove it with: token delete %s\n", old, old)
+				tools.CheckError(err)
+			}
+			fmt.Printf("Token %s revoked.\n", old)
+		},
+	}
+
+	return cmd
+}
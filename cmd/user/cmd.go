@@ -35,10 +35,13 @@ func NewUserCommand(config *setting.Config) *cobra.Command {
 	cmd.AddCommand(
 		newUserCreateCommand(config),
 		newUserSetCommand(config),
+		newUserSetAdminCommand(config),
+		newUserSetManagerCommand(config),
 		newUserListCommand(config),
 		newUserRemoveCommand(config),
 		newUserShowCommand(config),
 		newUserEditCommand(config),
+		newUserSetPasswordCommand(config),
 	)
 
 	return cmd
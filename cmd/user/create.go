@@ -21,8 +21,6 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package user
 
 import (
-	"log"
-
 	tools "github.com/MottainaiCI/mottainai-cli/common"
 	client "github.com/MottainaiCI/mottainai-server/pkg/client"
 	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
@@ -51,13 +49,13 @@ func newUserCreateCommand(config *setting.Config) *cobra.Command {
 			tools.CheckError(err)
 
 			if name == "" {
-				log.Fatalln("Missing mandatory parameter name")
+				tools.CheckError(tools.ValidationError("Missing mandatory parameter name"))
 			}
 			if email == "" {
-				log.Fatalln("Missing mandatory parameter email")
+				tools.CheckError(tools.ValidationError("Missing mandatory parameter email"))
 			}
 			if password == "" {
-				log.Fatalln("Missing mandatory parameter password")
+				tools.CheckError(tools.ValidationError("Missing mandatory parameter password"))
 			}
 
 			u.Name = name
@@ -21,8 +21,6 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package user
 
 import (
-	"log"
-
 	user "github.com/MottainaiCI/mottainai-server/pkg/user"
 
 	tools "github.com/MottainaiCI/mottainai-cli/common"
@@ -43,12 +41,12 @@ func newUserEditCommand(config *setting.Config) *cobra.Command {
 			dat := make(map[string]interface{})
 
 			if len(args) == 0 {
-				log.Fatalln("You need to define a user id")
+				tools.CheckError(tools.ValidationError("You need to define a user id"))
 			}
 
 			id := args[0]
 			if len(id) == 0 {
-				log.Fatalln("You need to define a user id")
+				tools.CheckError(tools.ValidationError("You need to define a user id"))
 			}
 			name, err := cmd.Flags().GetString("name")
 			tools.CheckError(err)
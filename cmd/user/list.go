@@ -22,7 +22,6 @@ package user
 
 import (
 	"fmt"
-	"os"
 
 	schema "github.com/MottainaiCI/mottainai-server/routes/schema"
 	v1 "github.com/MottainaiCI/mottainai-server/routes/schema/v1"
@@ -31,7 +30,6 @@ import (
 	client "github.com/MottainaiCI/mottainai-server/pkg/client"
 	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
 	user "github.com/MottainaiCI/mottainai-server/pkg/user"
-	tablewriter "github.com/olekukonko/tablewriter"
 	cobra "github.com/spf13/cobra"
 	viper "github.com/spf13/viper"
 )
@@ -44,7 +42,6 @@ func newUserListCommand(config *setting.Config) *cobra.Command {
 		Run: func(cmd *cobra.Command, args []string) {
 			var err error
 			var tlist []user.User
-			var task_table [][]string
 			var quiet bool
 			var v *viper.Viper = config.Viper
 
@@ -67,19 +64,13 @@ func newUserListCommand(config *setting.Config) *cobra.Command {
 				return
 			}
 
+			table := tools.NewTable([]string{"ID", "Name", "Email", "Admin", "Manager"})
 			for _, i := range tlist {
-				task_table = append(task_table, []string{i.ID, i.Name, i.Email, i.Admin, i.Manager})
+				table.Append([]string{i.ID, i.Name, i.Email, i.Admin, i.Manager})
 			}
 
-			table := tablewriter.NewWriter(os.Stdout)
-			table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
-			table.SetCenterSeparator("|")
-			table.SetHeader([]string{"ID", "Name", "Email", "Admin", "Manager"})
-
-			for _, v := range task_table {
-				table.Append(v)
-			}
-			table.Render()
+			err = tools.NewOutput(v).PrintList(tlist, table)
+			tools.CheckError(err)
 
 		},
 	}
@@ -0,0 +1,77 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package user
+
+import (
+	"fmt"
+
+	tools "github.com/MottainaiCI/mottainai-cli/common"
+	client "github.com/MottainaiCI/mottainai-server/pkg/client"
+	event "github.com/MottainaiCI/mottainai-server/pkg/event"
+	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
+	cobra "github.com/spf13/cobra"
+	viper "github.com/spf13/viper"
+)
+
+const (
+	USER_ROLE_ADMIN   = "admin"
+	USER_ROLE_MANAGER = "manager"
+)
+
+func newUserSetAdminCommand(config *setting.Config) *cobra.Command {
+	return newUserRoleCommand(config, USER_ROLE_ADMIN)
+}
+
+func newUserSetManagerCommand(config *setting.Config) *cobra.Command {
+	return newUserRoleCommand(config, USER_ROLE_MANAGER)
+}
+
+// newUserRoleCommand returns the command that grants or revokes a role.
+func newUserRoleCommand(config *setting.Config, role string) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   fmt.Sprintf("set-%s <user-id>... [OPTIONS]", role),
+		Short: fmt.Sprintf("Grant or revoke the %s role to users", role),
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var v *viper.Viper = config.Viper
+			var res event.APIResponse
+
+			unset, err := cmd.Flags().GetBool("unset")
+			tools.CheckError(err)
+
+			fetcher := client.NewTokenClient(v.GetString("master"), v.GetString("apikey"), config)
+			for _, id := range args {
+				if unset {
+					res, err = fetcher.UserUnset(id, role)
+				} else {
+					res, err = fetcher.UserSet(id, role)
+				}
+				tools.CheckError(err)
+				tools.PrintResponse(res)
+			}
+		},
+	}
+
+	var flags = cmd.Flags()
+	flags.Bool("unset", false, fmt.Sprintf("Revoke the %s role", role))
+
+	return cmd
+}
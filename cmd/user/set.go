@@ -21,8 +21,6 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package user
 
 import (
-	"log"
-
 	tools "github.com/MottainaiCI/mottainai-cli/common"
 	client "github.com/MottainaiCI/mottainai-server/pkg/client"
 	event "github.com/MottainaiCI/mottainai-server/pkg/event"
@@ -44,12 +42,12 @@ func newUserSetCommand(config *setting.Config) *cobra.Command {
 			t, err := cmd.Flags().GetString("type")
 
 			if len(args) == 0 {
-				log.Fatalln("You need to define a user id")
+				tools.CheckError(tools.ValidationError("You need to define a user id"))
 			}
 
 			id := args[0]
 			if len(id) == 0 {
-				log.Fatalln("You need to define a user id")
+				tools.CheckError(tools.ValidationError("You need to define a user id"))
 			}
 			fetcher := client.NewTokenClient(v.GetString("master"), v.GetString("apikey"), config)
 
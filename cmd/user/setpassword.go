@@ -0,0 +1,78 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package user
+
+import (
+	"errors"
+	"os"
+
+	tools "github.com/MottainaiCI/mottainai-cli/common"
+	client "github.com/MottainaiCI/mottainai-server/pkg/client"
+	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
+	user "github.com/MottainaiCI/mottainai-server/pkg/user"
+	cobra "github.com/spf13/cobra"
+	viper "github.com/spf13/viper"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+func newUserSetPasswordCommand(config *setting.Config) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "set-password <user-id> [OPTIONS]",
+		Short: "Change the password of a user",
+		Long: `Change the password of a user.
+
+The password is asked twice on the terminal, or it's read from
+the first line of stdin:
+
+$> echo "$PASSWORD" | mottainai-cli user set-password <user-id>
+`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var v *viper.Viper = config.Viper
+
+			id := args[0]
+
+			password, err := tools.ReadPassword("New password: ")
+			tools.CheckError(err)
+			if password == "" {
+				tools.CheckError(errors.New("The password can't be empty"))
+			}
+			if terminal.IsTerminal(int(os.Stdin.Fd())) {
+				confirm, err := tools.ReadPassword("Repeat the password: ")
+				tools.CheckError(err)
+				if confirm != password {
+					tools.CheckError(errors.New("The passwords don't match"))
+				}
+			}
+
+			us := &user.User{Password: password}
+			tools.CheckError(us.SaltPassword())
+			u := &user.UserForm{Password: us.Password}
+
+			fetcher := client.NewTokenClient(v.GetString("master"), v.GetString("apikey"), config)
+			res, err := fetcher.UserUpdate(id, u.ToMap())
+			tools.CheckError(err)
+			tools.PrintResponse(res)
+		},
+	}
+
+	return cmd
+}
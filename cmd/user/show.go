@@ -21,14 +21,11 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package user
 
 import (
-	"encoding/json"
-	"fmt"
-	"log"
-
 	user "github.com/MottainaiCI/mottainai-server/pkg/user"
 	schema "github.com/MottainaiCI/mottainai-server/routes/schema"
 	v1 "github.com/MottainaiCI/mottainai-server/routes/schema/v1"
 
+	tools "github.com/MottainaiCI/mottainai-cli/common"
 	client "github.com/MottainaiCI/mottainai-server/pkg/client"
 	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
 	cobra "github.com/spf13/cobra"
@@ -45,11 +42,11 @@ func newUserShowCommand(config *setting.Config) *cobra.Command {
 			var v *viper.Viper = config.Viper
 
 			if len(args) == 0 {
-				log.Fatalln("You need to define a user id")
+				tools.CheckError(tools.ValidationError("You need to define a user id"))
 			}
 			id := args[0]
 			if len(id) == 0 {
-				log.Fatalln("You need to define a user id")
+				tools.CheckError(tools.ValidationError("You need to define a user id"))
 			}
 
 			fetcher := client.NewTokenClient(v.GetString("master"), v.GetString("apikey"), config)
@@ -65,13 +62,10 @@ func newUserShowCommand(config *setting.Config) *cobra.Command {
 
 			err := fetcher.Handle(req)
 			if err != nil {
-				log.Fatalln("error:", err)
-			}
-			b, err := json.MarshalIndent(t, "", "  ")
-			if err != nil {
-				log.Fatalln("error:", err)
+				tools.CheckError(err)
 			}
-			fmt.Println(string(b))
+			err = tools.NewOutput(v).PrintObject(t)
+			tools.CheckError(err)
 		},
 	}
 	var flags = cmd.Flags()
@@ -39,6 +39,7 @@ func NewWebHookCommand(config *setting.Config) *cobra.Command {
 		newWebHookDeleteCommand(config),
 		newWebHookUpdateCommand(config),
 		newWebHookEditCommand(config),
+		newWebHookTestCommand(config),
 	)
 
 	return cmd
@@ -21,9 +21,6 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package webhook
 
 import (
-	"log"
-	"os"
-
 	tools "github.com/MottainaiCI/mottainai-cli/common"
 	client "github.com/MottainaiCI/mottainai-server/pkg/client"
 	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
@@ -42,17 +39,17 @@ func newWebHookCreateCommand(config *setting.Config) *cobra.Command {
 
 			fetcher := client.NewTokenClient(v.GetString("master"), v.GetString("apikey"), config)
 			if len(args) == 0 {
-				log.Fatalln("You need to define a webhook type: github|gitlab")
+				tools.CheckError(tools.ValidationError("You need to define a webhook type: github|gitlab"))
 			}
 			webtype := args[0]
 			res, err := fetcher.WebHookCreate(webtype)
 			tools.PrintResponse(res)
 			if err != nil {
-				log.Fatalln(err)
+				tools.CheckError(err)
 			}
 
 			if len(res.Error) > 0 {
-				os.Exit(1)
+				tools.Exit(1)
 			}
 		},
 	}
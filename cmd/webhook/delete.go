@@ -45,7 +45,7 @@ func newWebHookDeleteCommand(config *setting.Config) *cobra.Command {
 
 			id := args[0]
 			if len(id) == 0 {
-				log.Fatalln("You need to define a webhook id")
+				tools.CheckError(tools.ValidationError("You need to define a webhook id"))
 			}
 			mytype := args[1]
 			if mytype != "task" && mytype != "pipeline" {
@@ -21,8 +21,6 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package webhook
 
 import (
-	"log"
-
 	tools "github.com/MottainaiCI/mottainai-cli/common"
 	client "github.com/MottainaiCI/mottainai-server/pkg/client"
 	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
@@ -50,7 +48,7 @@ func newWebHookEditCommand(config *setting.Config) *cobra.Command {
 			dat := make(map[string]interface{})
 
 			if len(args) != 3 {
-				log.Fatalln("You need to define a webhook id and a key and a value to update")
+				tools.CheckError(tools.ValidationError("You need to define a webhook id and a key and a value to update"))
 			}
 			dat["key"] = key
 			dat["value"] = value
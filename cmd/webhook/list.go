@@ -22,7 +22,6 @@ package webhook
 
 import (
 	"fmt"
-	"os"
 	"strconv"
 
 	tools "github.com/MottainaiCI/mottainai-cli/common"
@@ -31,7 +30,6 @@ import (
 	webhook "github.com/MottainaiCI/mottainai-server/pkg/webhook"
 	schema "github.com/MottainaiCI/mottainai-server/routes/schema"
 	"github.com/MottainaiCI/mottainai-server/routes/schema/v1"
-	tablewriter "github.com/olekukonko/tablewriter"
 	cobra "github.com/spf13/cobra"
 	viper "github.com/spf13/viper"
 )
@@ -44,7 +42,6 @@ func newWebHookListCommand(config *setting.Config) *cobra.Command {
 		Run: func(cmd *cobra.Command, args []string) {
 			var err error
 			var tlist []webhook.WebHook
-			var task_table [][]string
 			var quiet bool
 			var v *viper.Viper = config.Viper
 
@@ -69,27 +66,21 @@ func newWebHookListCommand(config *setting.Config) *cobra.Command {
 				return
 			}
 
+			table := tools.NewTable([]string{"ID", "Name", "Key", "URL", "Type", "Owner", "Pipeline", "Task", "Filter", "Auth"})
 			for _, i := range tlist {
 				if !all {
-					task_table = append(task_table, []string{i.ID, i.Name, i.Key, i.URL, i.Type, i.OwnerId, strconv.FormatBool(i.HasPipeline()), strconv.FormatBool(i.HasTask()), i.Filter, i.Auth})
+					table.Append([]string{i.ID, i.Name, i.Key, i.URL, i.Type, i.OwnerId, strconv.FormatBool(i.HasPipeline()), strconv.FormatBool(i.HasTask()), i.Filter, i.Auth})
 				} else {
 					t, _ := i.ReadTask()
 					p, _ := i.ReadPipeline()
 					tstr := fmt.Sprintf("%#v", t)
 					pstr := fmt.Sprintf("%#v", p)
-					task_table = append(task_table, []string{i.ID, i.Name, i.Key, i.URL, i.Type, i.OwnerId, pstr, tstr, i.Filter, i.Auth})
+					table.Append([]string{i.ID, i.Name, i.Key, i.URL, i.Type, i.OwnerId, pstr, tstr, i.Filter, i.Auth})
 				}
 			}
 
-			table := tablewriter.NewWriter(os.Stdout)
-			table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
-			table.SetCenterSeparator("|")
-			table.SetHeader([]string{"ID", "Name", "Key", "URL", "Type", "Owner", "Pipeline", "Task", "Filter", "Auth"})
-
-			for _, v := range task_table {
-				table.Append(v)
-			}
-			table.Render()
+			err = tools.NewOutput(v).PrintList(tlist, table)
+			tools.CheckError(err)
 
 		},
 	}
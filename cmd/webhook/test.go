@@ -0,0 +1,227 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package webhook
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"time"
+
+	tools "github.com/MottainaiCI/mottainai-cli/common"
+	client "github.com/MottainaiCI/mottainai-server/pkg/client"
+	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
+	citasks "github.com/MottainaiCI/mottainai-server/pkg/tasks"
+	webhook "github.com/MottainaiCI/mottainai-server/pkg/webhook"
+	schema "github.com/MottainaiCI/mottainai-server/routes/schema"
+	v1 "github.com/MottainaiCI/mottainai-server/routes/schema/v1"
+	cobra "github.com/spf13/cobra"
+	viper "github.com/spf13/viper"
+)
+
+type spawnedItem struct {
+	Kind   string `json:"kind"`
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Status string `json:"status"`
+}
+
+func newWebHookTestCommand(config *setting.Config) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "test <id> [OPTIONS]",
+		Short: "Send a test payload to a webhook",
+		Long: `Send a webhook payload to the master and report the tasks and
+pipelines spawned by it.
+
+The payload is read from --file (- for stdin), e.g. one recorded
+from the deliveries of the git hosting service, or it's a push event
+crafted from --repo, --ref and --commit.
+
+# Replay a recorded GitHub pull request event
+$> mottainai-cli webhook test <id> -f pr.json --event pull_request --secret <github_secret>`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var hook webhook.WebHook
+			var payload []byte
+			var v *viper.Viper = config.Viper
+
+			flags := cmd.Flags()
+			file, err := flags.GetString("file")
+			tools.CheckError(err)
+			event, err := flags.GetString("event")
+			tools.CheckError(err)
+			secret, err := flags.GetString("secret")
+			tools.CheckError(err)
+			repo, err := flags.GetString("repo")
+			tools.CheckError(err)
+			ref, err := flags.GetString("ref")
+			tools.CheckError(err)
+			commit, err := flags.GetString("commit")
+			tools.CheckError(err)
+			wait, err := flags.GetDuration("wait")
+			tools.CheckError(err)
+
+			fetcher := client.NewTokenClient(v.GetString("master"), v.GetString("apikey"), config)
+			req := schema.Request{
+				Route:   v1.Schema.GetWebHookRoute("show"),
+				Options: map[string]interface{}{":id": args[0]},
+				Target:  &hook,
+			}
+			tools.CheckError(fetcher.Handle(req))
+			if hook.Key == "" {
+				tools.CheckError(tools.NotFoundError("Webhook", args[0]))
+			}
+
+			switch file {
+			case "":
+				payload, err = tools.WebHookPayload(hook.Type, repo, ref, commit)
+			case "-":
+				payload, err = ioutil.ReadAll(os.Stdin)
+			default:
+				payload, err = ioutil.ReadFile(file)
+			}
+			tools.CheckError(err)
+
+			tasks, pipelines, err := listSpawnable(fetcher)
+			tools.CheckError(err)
+
+			request, err := tools.NewWebHookRequest(v.GetString("master")+config.GetWeb().BuildURI(""),
+				hook.Type, hook.Key, event, secret, payload)
+			tools.CheckError(err)
+			response, err := http.DefaultClient.Do(request)
+			tools.CheckError(err)
+			body, _ := ioutil.ReadAll(response.Body)
+			response.Body.Close()
+
+			fmt.Printf("Webhook %s replied: %s\n", hook.ID, response.Status)
+			if response.StatusCode != http.StatusOK {
+				if len(body) > 0 {
+					fmt.Println(string(body))
+				}
+				tools.Exit(1)
+			}
+
+			// Tasks are spawned asynchronously by the master.
+			var spawned []spawnedItem
+			deadline := time.Now().Add(wait)
+			for {
+				spawned, err = newSpawned(fetcher, tasks, pipelines)
+				tools.CheckError(err)
+				if len(spawned) > 0 || !time.Now().Before(deadline) {
+					break
+				}
+				time.Sleep(time.Second)
+			}
+
+			if len(spawned) == 0 {
+				fmt.Println("No tasks or pipelines spawned.")
+				return
+			}
+
+			table := tools.NewTable([]string{"Kind", "ID", "Name", "Status"})
+			for _, i := range spawned {
+				table.Append([]string{i.Kind, i.ID, i.Name, i.Status})
+			}
+			err = tools.NewOutput(v).PrintList(spawned, table)
+			tools.CheckError(err)
+		},
+	}
+
+	var flags = cmd.Flags()
+	flags.StringP("file", "f", "", "File with the payload to send (- for stdin)")
+	flags.String("event", "push", "Event of the payload")
+	flags.String("secret", "", "Secret used to sign the payload")
+	flags.String("repo", "", "Repository of the crafted payload (e.g. owner/project)")
+	flags.String("ref", "master", "Branch or ref of the crafted payload")
+	flags.String("commit", "", "Commit of the crafted payload")
+	flags.Duration("wait", 10*time.Second, "How long to wait for spawned tasks")
+
+	return cmd
+}
+
+// listSpawnable returns the ids of the existing tasks and pipelines.
+func listSpawnable(fetcher client.HttpClient) (map[string]bool, map[string]bool, error) {
+	tlist, plist, err := fetchSpawnable(fetcher)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tasks := make(map[string]bool)
+	for _, t := range tlist {
+		tasks[t.ID] = true
+	}
+	pipelines := make(map[string]bool)
+	for _, p := range plist {
+		pipelines[p.ID] = true
+	}
+	return tasks, pipelines, nil
+}
+
+// newSpawned returns the tasks and pipelines that aren't
+// in the input sets.
+func newSpawned(fetcher client.HttpClient, tasks, pipelines map[string]bool) ([]spawnedItem, error) {
+	var ans []spawnedItem
+
+	tlist, plist, err := fetchSpawnable(fetcher)
+	if err != nil {
+		return nil, err
+	}
+
+	// Tasks of new pipelines are reported with the pipeline.
+	inPipeline := make(map[string]bool)
+	for _, p := range plist {
+		if pipelines[p.ID] {
+			continue
+		}
+		for _, t := range p.Tasks {
+			inPipeline[t.ID] = true
+		}
+		ans = append(ans, spawnedItem{Kind: "pipeline", ID: p.ID, Name: p.Name})
+	}
+	for _, t := range tlist {
+		if tasks[t.ID] || inPipeline[t.ID] {
+			continue
+		}
+		ans = append(ans, spawnedItem{Kind: "task", ID: t.ID, Name: t.Name, Status: t.Status})
+	}
+
+	return ans, nil
+}
+
+func fetchSpawnable(fetcher client.HttpClient) ([]citasks.Task, []citasks.Pipeline, error) {
+	var tlist []citasks.Task
+	var plist []citasks.Pipeline
+
+	pager := tools.NewPager(fetcher, v1.Schema.GetTaskRoute("show_all"), 1, 0)
+	if err := pager.All(&tlist); err != nil {
+		return nil, nil, err
+	}
+
+	req := schema.Request{
+		Route:  v1.Schema.GetTaskRoute("pipeline_list"),
+		Target: &plist,
+	}
+	if err := fetcher.Handle(req); err != nil {
+		return nil, nil, err
+	}
+	return tlist, plist, nil
+}
@@ -24,7 +24,6 @@ import (
 	"encoding/json"
 	"io/ioutil"
 	"log"
-	"os"
 
 	event "github.com/MottainaiCI/mottainai-server/pkg/event"
 
@@ -54,7 +53,7 @@ func newWebHookUpdateCommand(config *setting.Config) *cobra.Command {
 			mytype := args[1]
 
 			if len(id) == 0 {
-				log.Fatalln("You need to define a webhook id")
+				tools.CheckError(tools.ValidationError("You need to define a webhook id"))
 			}
 			if mytype != "task" && mytype != "pipeline" {
 				log.Fatalln("You can delete a task or a pipeline associated to a webhook")
@@ -117,7 +116,7 @@ func newWebHookUpdateCommand(config *setting.Config) *cobra.Command {
 
 			tools.PrintResponse(res)
 			if len(res.Error) > 0 {
-				os.Exit(1)
+				tools.Exit(1)
 			}
 		},
 	}
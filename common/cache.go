@@ -0,0 +1,215 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package common
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// NOTE: relative to the home directory as MCLI_HOME_PATH.
+	MCLI_CACHE_PATH = ".cache/mottainai"
+
+	// Responses bigger than this size, e.g. artefacts, aren't cached.
+	cacheMaxBody = 1 << 20
+)
+
+// Only the responses of the API are cached, artefacts are
+// downloaded on the target directories.
+const cacheRoutes = "/api/"
+
+// Routes never stored on disk because their responses contain
+// credentials, e.g. the user and the keys of the nodes.
+var cacheExcluded = []string{"/api/token", "/api/secret", "/api/user", "/api/nodes"}
+
+// CacheDir returns the directory of the response cache.
+func CacheDir() string {
+	if dir := os.Getenv("XDG_CACHE_HOME"); dir != "" {
+		return filepath.Join(dir, "mottainai")
+	}
+	return filepath.Join(GetHomeDir(), MCLI_CACHE_PATH)
+}
+
+// CacheTransport stores on Dir the responses of the GET requests
+// with an ETag or a Last-Modified header, on files readable only
+// by the user. The cached responses are
+// always revalidated with a conditional request and returned when
+// the master replies 304 Not Modified.
+type CacheTransport struct {
+	Next http.RoundTripper
+	Dir  string
+}
+
+func (t *CacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.cacheable(req) {
+		return t.Next.RoundTrip(req)
+	}
+
+	file := filepath.Join(t.Dir, cacheKey(req))
+	cached := readCachedResponse(file, req)
+	if cached != nil {
+		r := new(http.Request)
+		*r = *req
+		r.Header = cloneHeader(req.Header)
+		if etag := cached.Header.Get("ETag"); etag != "" {
+			r.Header.Set("If-None-Match", etag)
+		}
+		if lm := cached.Header.Get("Last-Modified"); lm != "" {
+			r.Header.Set("If-Modified-Since", lm)
+		}
+		req = r
+	}
+
+	resp, err := t.Next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		resp.Body.Close()
+		return cached, nil
+	}
+	if resp.StatusCode != http.StatusOK ||
+		(resp.Header.Get("ETag") == "" && resp.Header.Get("Last-Modified") == "") ||
+		resp.ContentLength > cacheMaxBody {
+		if resp.StatusCode == http.StatusOK {
+			os.Remove(file)
+		}
+		return resp, nil
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, cacheMaxBody+1))
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	if len(body) > cacheMaxBody {
+		resp.Body = &multiReadCloser{Reader: io.MultiReader(bytes.NewReader(body), resp.Body), Closer: resp.Body}
+		return resp, nil
+	}
+	resp.Body.Close()
+
+	writeCachedResponse(file, resp, body)
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	return resp, nil
+}
+
+func (t *CacheTransport) cacheable(req *http.Request) bool {
+	if req.Method != "GET" || req.Header.Get("Range") != "" ||
+		strings.Contains(req.Header.Get("Cache-Control"), "no-store") ||
+		!strings.Contains(req.URL.Path, cacheRoutes) {
+		return false
+	}
+	for _, p := range cacheExcluded {
+		if strings.Contains(req.URL.Path, p) {
+			return false
+		}
+	}
+	return true
+}
+
+// cacheKey identifies the response of an URL for a credential, so
+// users with different permissions don't share the responses.
+func cacheKey(req *http.Request) string {
+	h := sha256.New()
+	io.WriteString(h, req.URL.String()+"\n"+req.Header.Get("Authorization"))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func readCachedResponse(file string, req *http.Request) *http.Response {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil
+	}
+	data, err := ioutil.ReadAll(f)
+	f.Close()
+	if err != nil {
+		return nil
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(data)), req)
+	if err != nil {
+		os.Remove(file)
+		return nil
+	}
+	return resp
+}
+
+func writeCachedResponse(file string, resp *http.Response, body []byte) {
+	// The body is stored decoded with its length.
+	r := new(http.Response)
+	*r = *resp
+	r.Header = cloneHeader(resp.Header)
+	r.Header.Del("Content-Encoding")
+	r.TransferEncoding = nil
+	r.ContentLength = int64(len(body))
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	data, err := httputil.DumpResponse(r, true)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return
+	}
+
+	// Written on a temporary file so concurrent commands never
+	// read a partial response.
+	tmp, err := ioutil.TempFile(filepath.Dir(file), ".response")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+	if err := os.Rename(tmp.Name(), file); err != nil {
+		os.Remove(tmp.Name())
+	}
+}
+
+func cloneHeader(h http.Header) http.Header {
+	ans := make(http.Header, len(h))
+	for k, v := range h {
+		ans[k] = append([]string(nil), v...)
+	}
+	return ans
+}
+
+type multiReadCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,98 @@
+// Copyright © 2019 Ettore Di Giacinto <mudler@gentoo.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package common_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/MottainaiCI/mottainai-cli/common"
+)
+
+var _ = Describe("CacheTransport", func() {
+	var dir string
+	var server *httptest.Server
+	var body string
+	var sent, notModified int
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "mcli-cache")
+		Expect(err).ToNot(HaveOccurred())
+
+		body = `{"id":"1"}`
+		sent, notModified = 0, 0
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			etag := `"` + body + `"`
+			if r.Header.Get("If-None-Match") == etag {
+				notModified++
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			sent++
+			w.Header().Set("ETag", etag)
+			w.Write([]byte(body))
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+		os.RemoveAll(dir)
+	})
+
+	get := func(path string) string {
+		client := &http.Client{Transport: &CacheTransport{Next: &http.Transport{}, Dir: dir}}
+		req, _ := http.NewRequest("GET", server.URL+path, nil)
+		req.Header.Set("Authorization", "token t1")
+		resp, err := client.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		data, err := ioutil.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		return string(data)
+	}
+
+	It("returns the cached response when not modified", func() {
+		Expect(get("/api/tasks")).To(Equal(`{"id":"1"}`))
+		Expect(get("/api/tasks")).To(Equal(`{"id":"1"}`))
+		Expect(sent).To(Equal(1))
+		Expect(notModified).To(Equal(1))
+
+		body = `{"id":"2"}`
+		Expect(get("/api/tasks")).To(Equal(`{"id":"2"}`))
+		Expect(sent).To(Equal(2))
+
+		files, err := ioutil.ReadDir(dir)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(files).To(HaveLen(1))
+		Expect(files[0].Mode().Perm()).To(Equal(os.FileMode(0600)))
+	})
+
+	It("doesn't store the credentials and the artefacts", func() {
+		get("/api/token")
+		get("/api/nodes")
+		get("/artefact/1/file")
+		files, err := ioutil.ReadDir(dir)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(files).To(BeEmpty())
+	})
+})
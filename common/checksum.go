@@ -0,0 +1,110 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package common
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+)
+
+const (
+	// Name of the manifests with the checksums of the files of
+	// their directory, in the sha256sum format.
+	CHECKSUM_MANIFEST = "SHA256SUMS"
+	// Extension of the files with the checksum of a single file.
+	CHECKSUM_EXT = ".sha256"
+)
+
+// FileChecksum returns the hex encoded SHA-256 of a file.
+func FileChecksum(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// ParseChecksums parses the output of sha256sum, returning the
+// checksums indexed by file name.
+func ParseChecksums(data []byte) (map[string]string, error) {
+	sums := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		sum := strings.ToLower(fields[0])
+		if len(sum) != sha256.Size*2 {
+			return nil, fmt.Errorf("Invalid checksum at line %d", n)
+		}
+		if _, err := hex.DecodeString(sum); err != nil {
+			return nil, fmt.Errorf("Invalid checksum at line %d", n)
+		}
+
+		name := ""
+		if len(fields) > 1 {
+			// Binary mode files are prefixed by *.
+			name = strings.TrimPrefix(strings.TrimSpace(line[len(fields[0]):]), "*")
+		}
+		sums[name] = sum
+	}
+	return sums, scanner.Err()
+}
+
+// ReadChecksums reads a sha256sum manifest. The paths are relative
+// to the root of the downloaded artefacts.
+func ReadChecksums(file string) (map[string]string, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	sums, err := ParseChecksums(data)
+	if err != nil {
+		return nil, err
+	}
+
+	ans := make(map[string]string)
+	for name, sum := range sums {
+		ans[artefactPath(name)] = sum
+	}
+	return ans, nil
+}
+
+// artefactPath normalizes a path as in the artefacts listings.
+func artefactPath(p string) string {
+	return path.Clean("/" + p)
+}
@@ -0,0 +1,46 @@
+// Copyright © 2019 Ettore Di Giacinto <mudler@gentoo.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package common_test
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/MottainaiCI/mottainai-cli/common"
+)
+
+var _ = Describe("ParseChecksums", func() {
+	sum := strings.Repeat("ab", 32)
+
+	It("reads the sha256sum format", func() {
+		sums, err := ParseChecksums([]byte("# comment\n" + sum + "  a file.txt\n\n" + strings.ToUpper(sum) + " *bin/b\n"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(sums).To(Equal(map[string]string{"a file.txt": sum, "bin/b": sum}))
+	})
+
+	It("accepts a checksum without file name", func() {
+		sums, err := ParseChecksums([]byte(sum + "\n"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(sums).To(Equal(map[string]string{"": sum}))
+	})
+
+	It("rejects invalid checksums", func() {
+		_, err := ParseChecksums([]byte("abc  file\n"))
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,165 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package common
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	pflag "github.com/spf13/pflag"
+)
+
+const (
+	// Resources with identifiers completed by the shell completion.
+	COMPLETE_TASK      = "task"
+	COMPLETE_NODE      = "node"
+	COMPLETE_NAMESPACE = "namespace"
+	COMPLETE_STORAGE   = "storage"
+
+	// Directory under CacheDir with the completion candidates.
+	MCLI_COMPLETION_DIR = "completion"
+	// How long the candidates read from the API are reused.
+	MCLI_COMPLETION_TTL = 30 * time.Second
+)
+
+// Argument placeholders of the command usages and their resource.
+var completionArgs = map[string]string{
+	"taskid":     COMPLETE_TASK,
+	"task-id":    COMPLETE_TASK,
+	"node-id":    COMPLETE_NODE,
+	"namespace":  COMPLETE_NAMESPACE,
+	"storage-id": COMPLETE_STORAGE,
+	"storageid":  COMPLETE_STORAGE,
+}
+
+var usageArg = regexp.MustCompile(`^[<\[]([a-z_-]+?)\d*(\.\.\.)?[>\]]`)
+
+// CompletionKind returns the resource of the positional argument pos
+// described by the usage line of a command ( e.g. "show <taskid>" ),
+// or an empty string when the argument isn't a resource identifier.
+// A variadic last argument ( e.g. "[taskid...]" ) applies to all the
+// following positions.
+func CompletionKind(use string, pos int) string {
+	var kinds []string
+	var variadic bool
+
+	fields := strings.Fields(use)
+	for i := 1; i < len(fields); i++ {
+		// Flags and their value aren't positional arguments.
+		if strings.HasPrefix(fields[i], "-") {
+			i++
+			continue
+		}
+		m := usageArg.FindStringSubmatch(fields[i])
+		if m == nil {
+			continue
+		}
+		kinds = append(kinds, completionArgs[m[1]])
+		variadic = m[2] != ""
+	}
+
+	switch {
+	case pos < len(kinds):
+		return kinds[pos]
+	case variadic:
+		return kinds[len(kinds)-1]
+	}
+	return ""
+}
+
+// CompletionCache keeps the completion candidates read from the API
+// for TTL, so repeated completions don't wait for the master.
+type CompletionCache struct {
+	Dir string
+	TTL time.Duration
+}
+
+func NewCompletionCache() *CompletionCache {
+	return &CompletionCache{
+		Dir: filepath.Join(CacheDir(), MCLI_COMPLETION_DIR),
+		TTL: MCLI_COMPLETION_TTL,
+	}
+}
+
+// Get returns the candidates stored with key, or the ones returned
+// by fetch when they are missing or expired. The key must identify
+// the master and the credential used.
+func (c *CompletionCache) Get(key string, fetch func() ([]string, error)) ([]string, error) {
+	h := sha256.New()
+	io.WriteString(h, key)
+	file := filepath.Join(c.Dir, hex.EncodeToString(h.Sum(nil)))
+
+	if info, err := os.Stat(file); err == nil && time.Since(info.ModTime()) < c.TTL {
+		if data, err := ioutil.ReadFile(file); err == nil {
+			return strings.Fields(string(data)), nil
+		}
+	}
+
+	ans, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+	c.write(file, ans)
+	return ans, nil
+}
+
+func (c *CompletionCache) write(file string, candidates []string) {
+	if err := os.MkdirAll(c.Dir, 0700); err != nil {
+		return
+	}
+	tmp, err := ioutil.TempFile(c.Dir, ".candidates")
+	if err != nil {
+		return
+	}
+	_, err = io.WriteString(tmp, strings.Join(candidates, "\n"))
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+	if err := os.Rename(tmp.Name(), file); err != nil {
+		os.Remove(tmp.Name())
+	}
+}
+
+// FlagTakesValue returns true if word is a flag of flags that requires
+// a value supplied as the next word.
+func FlagTakesValue(flags *pflag.FlagSet, word string) bool {
+	var f *pflag.Flag
+	switch {
+	case strings.Contains(word, "="):
+		return false
+	case strings.HasPrefix(word, "--"):
+		f = flags.Lookup(word[2:])
+	case strings.HasPrefix(word, "-") && len(word) == 2:
+		f = flags.ShorthandLookup(word[1:])
+	}
+	return f != nil && f.NoOptDefVal == ""
+}
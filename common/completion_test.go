@@ -0,0 +1,86 @@
+// Copyright © 2019 Ettore Di Giacinto <mudler@gentoo.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package common_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/MottainaiCI/mottainai-cli/common"
+)
+
+var _ = Describe("Completion", func() {
+	Context("CompletionKind", func() {
+		It("maps the arguments of the usage to resources", func() {
+			Expect(CompletionKind("show <taskid> [OPTIONS]", 0)).To(Equal(COMPLETE_TASK))
+			Expect(CompletionKind("show <taskid> [OPTIONS]", 1)).To(Equal(""))
+			Expect(CompletionKind("tag <task-id> <namespace> [OPTIONS]", 1)).To(Equal(COMPLETE_NAMESPACE))
+			Expect(CompletionKind("remove <storageid> <absolute_path> [OPTIONS]", 1)).To(Equal(""))
+			Expect(CompletionKind("append <namespace> --from <task-id> [OPTIONS]", 1)).To(Equal(""))
+			Expect(CompletionKind("list [OPTIONS]", 0)).To(Equal(""))
+		})
+
+		It("repeats variadic arguments", func() {
+			Expect(CompletionKind("remove [node-id...] [OPTIONS]", 3)).To(Equal(COMPLETE_NODE))
+			Expect(CompletionKind("diff <taskid1> <taskid2> [OPTIONS]", 2)).To(Equal(""))
+		})
+	})
+
+	Context("CompletionCache", func() {
+		var cache *CompletionCache
+		var calls int
+		fetch := func() ([]string, error) {
+			calls++
+			return []string{"a", "b"}, nil
+		}
+
+		BeforeEach(func() {
+			dir, err := ioutil.TempDir("", "mcli-completion")
+			Expect(err).ToNot(HaveOccurred())
+			cache = &CompletionCache{Dir: dir, TTL: time.Minute}
+			calls = 0
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(cache.Dir)
+		})
+
+		It("reuses the candidates until they expire", func() {
+			Expect(cache.Get("key", fetch)).To(Equal([]string{"a", "b"}))
+			Expect(cache.Get("key", fetch)).To(Equal([]string{"a", "b"}))
+			Expect(calls).To(Equal(1))
+
+			Expect(cache.Get("other", fetch)).To(HaveLen(2))
+			Expect(calls).To(Equal(2))
+
+			cache.TTL = 0
+			cache.Get("key", fetch)
+			Expect(calls).To(Equal(3))
+		})
+
+		It("doesn't store failures", func() {
+			_, err := cache.Get("key", func() ([]string, error) { return nil, errors.New("offline") })
+			Expect(err).To(HaveOccurred())
+			Expect(cache.Get("key", fetch)).To(HaveLen(2))
+			Expect(calls).To(Equal(1))
+		})
+	})
+})
@@ -0,0 +1,187 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package common
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+
+	client "github.com/MottainaiCI/mottainai-server/pkg/client"
+	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
+	utils "github.com/MottainaiCI/mottainai-server/pkg/utils"
+)
+
+// ArtefactLocation is a path in a storage or in a namespace.
+type ArtefactLocation struct {
+	Kind string
+	ID   string
+	Path string
+}
+
+// ParseArtefactLocation parses a location in the <id>:<path> format.
+// The path is optional and defaults to the root.
+func ParseArtefactLocation(kind, s string) (ArtefactLocation, error) {
+	parts := strings.SplitN(s, ":", 2)
+	if parts[0] == "" {
+		return ArtefactLocation{}, errors.New("Invalid location " + s + ": missing the " + kind)
+	}
+	ans := ArtefactLocation{Kind: kind, ID: parts[0], Path: "/"}
+	if len(parts) == 2 {
+		ans.Path = artefactPath(parts[1])
+	}
+	return ans, nil
+}
+
+func (l ArtefactLocation) String() string {
+	return l.ID + ":" + l.Path
+}
+
+// ArtefactCopyResult is the result of the copy of a file.
+type ArtefactCopyResult struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+	Size        int64  `json:"size"`
+	Error       string `json:"error,omitempty"`
+}
+
+// ArtefactCopier copies the artefacts of a task, a storage or a
+// namespace on a storage or a namespace. The master can't copy single
+// files, so every file is streamed from its download to the upload,
+// without a local copy.
+type ArtefactCopier struct {
+	Fetcher client.HttpClient
+	Config  *setting.Config
+	Token   string
+	Filter  ArtefactFilter
+	// Quiet disables the progress bar.
+	Quiet bool
+}
+
+func NewArtefactCopier(fetcher client.HttpClient, config *setting.Config, token string) *ArtefactCopier {
+	return &ArtefactCopier{
+		Fetcher: fetcher,
+		Config:  config,
+		Token:   token,
+	}
+}
+
+// Copy copies the file or the directory src in the directory dst.
+// The errors of the single files don't stop the copy and are
+// reported in the results.
+func (c *ArtefactCopier) Copy(src, dst ArtefactLocation) ([]ArtefactCopyResult, error) {
+	var list []string
+	var baseURL string
+	var err error
+
+	switch src.Kind {
+	case ARTEFACT_STORAGE:
+		list, err = c.Fetcher.StorageFileList(src.ID)
+		if err != nil {
+			return nil, errors.New("Failed getting storage file list: " + err.Error())
+		}
+		storagePath, err := StoragePath(c.Fetcher, src.ID)
+		if err != nil {
+			return nil, err
+		}
+		baseURL = c.Fetcher.GetBaseURL() + "/" + ARTEFACT_STORAGE + "/" + storagePath
+	case ARTEFACT_NAMESPACE:
+		list, err = c.Fetcher.NamespaceFileList(src.ID)
+		if err != nil {
+			return nil, errors.New("Failed getting namespace artefacts list: " + err.Error())
+		}
+		baseURL = c.Fetcher.GetBaseURL() + "/" + ARTEFACT_NAMESPACE + "/" + src.ID
+	case ARTEFACT_TASK:
+		list, err = c.Fetcher.TaskFileList(src.ID)
+		if err != nil {
+			return nil, errors.New("Failed getting task artefacts list: " + err.Error())
+		}
+		baseURL = c.Fetcher.GetBaseURL() + "/" + ARTEFACT_TASK + "/" + src.ID
+	default:
+		return nil, errors.New("Copy not supported from " + src.Kind)
+	}
+
+	// Relative path of the selected files in the destination.
+	files := make(map[string]string)
+	for _, f := range list {
+		f = artefactPath(f)
+		if !c.Filter.Match(f) {
+			continue
+		}
+		switch {
+		case f == src.Path:
+			files[f] = path.Base(f)
+		case src.Path == "/":
+			files[f] = f
+		case strings.HasPrefix(f, src.Path+"/"):
+			files[f] = strings.TrimPrefix(f, src.Path)
+		}
+	}
+	if len(files) == 0 {
+		return nil, errors.New("No artefacts found in " + src.String())
+	}
+
+	var sources []string
+	for f := range files {
+		sources = append(sources, f)
+	}
+	sort.Strings(sources)
+
+	uploader := NewFileUploader(c.Fetcher, c.Config, c.Token)
+	uploader.Quiet = c.Quiet
+
+	results := make([]ArtefactCopyResult, len(sources))
+	for i, f := range sources {
+		dest := path.Join(dst.Path, files[f])
+		results[i] = ArtefactCopyResult{Source: f, Destination: dest}
+		size, err := c.copyFile(uploader, baseURL+utils.PathEscape(f), dst, dest)
+		results[i].Size = size
+		if err != nil {
+			results[i].Error = err.Error()
+		}
+	}
+
+	return results, nil
+}
+
+// copyFile streams the file available on url in dest.
+func (c *ArtefactCopier) copyFile(uploader *FileUploader, url string, dst ArtefactLocation, dest string) (int64, error) {
+	request, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return 0, err
+	}
+	setAuthHeader(request, c.Token)
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("Download failed: %s", response.Status)
+	}
+
+	err = uploader.UploadReader(dst.Kind, dst.ID, path.Base(dest), path.Dir(dest), response.Body, response.ContentLength)
+	return response.ContentLength, err
+}
@@ -0,0 +1,42 @@
+// Copyright © 2019 Ettore Di Giacinto <mudler@gentoo.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package common_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/MottainaiCI/mottainai-cli/common"
+)
+
+var _ = Describe("ParseArtefactLocation", func() {
+	It("parses the id and the path", func() {
+		l, err := ParseArtefactLocation(ARTEFACT_STORAGE, "st1:build/dir/")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(l).To(Equal(ArtefactLocation{Kind: ARTEFACT_STORAGE, ID: "st1", Path: "/build/dir"}))
+	})
+
+	It("defaults to the root", func() {
+		l, err := ParseArtefactLocation(ARTEFACT_NAMESPACE, "ns")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(l.Path).To(Equal("/"))
+	})
+
+	It("requires the id", func() {
+		_, err := ParseArtefactLocation(ARTEFACT_STORAGE, ":/dir")
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,113 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package common
+
+import (
+	"errors"
+)
+
+const (
+	CREDENTIAL_STORE_PROFILE = ""
+	CREDENTIAL_STORE_KEYRING = "keyring"
+
+	// Service name used to store API keys on OS keyring.
+	MCLI_KEYRING_SERVICE = "mottainai-cli"
+)
+
+// CredentialStore permits to save API keys outside of
+// the profiles file. Keys are indexed by profile name.
+type CredentialStore interface {
+	Get(profile string) (string, error)
+	Set(profile, apikey string) error
+	Delete(profile string) error
+}
+
+// NewCredentialStore returns the store with the input name.
+// The profile store is managed directly by the profiles file,
+// so nil is returned.
+func NewCredentialStore(name string) (CredentialStore, error) {
+	switch name {
+	case CREDENTIAL_STORE_PROFILE:
+		return nil, nil
+	case CREDENTIAL_STORE_KEYRING:
+		return NewKeyringStore(MCLI_KEYRING_SERVICE), nil
+	}
+	return nil, errors.New("Invalid credential store " + name)
+}
+
+// ResolveApiKey returns the API key of the profile name
+// reading it from the credential store of the profile.
+func (p *Profile) ResolveApiKey(name string) (string, error) {
+	store, err := NewCredentialStore(p.CredentialStore)
+	if err != nil {
+		return "", err
+	}
+	if store == nil {
+		return p.GetApiKey(), nil
+	}
+	return store.Get(name)
+}
+
+// SaveApiKey stores apikey as the API key of the profile name
+// on the credential store of the profile. Profiles that keep the
+// key on the profiles file must be written to persist it.
+func (p *ProfileConf) SaveApiKey(name, apikey string) error {
+	profile, ok := p.Profiles[name]
+	if !ok {
+		return errors.New("No profile with name " + name)
+	}
+
+	store, err := NewCredentialStore(profile.CredentialStore)
+	if err != nil {
+		return err
+	}
+	if store != nil {
+		return store.Set(name, apikey)
+	}
+
+	profile.ApiKey = apikey
+	profile.LegacyApiKey = ""
+	p.Profiles[name] = profile
+	return nil
+}
+
+// DeleteApiKey removes the API key of the profile name from the
+// credential store of the profile. Profiles that keep the key on
+// the profiles file must be written to persist the change.
+func (p *ProfileConf) DeleteApiKey(name string) error {
+	profile, ok := p.Profiles[name]
+	if !ok {
+		return errors.New("No profile with name " + name)
+	}
+
+	profile.ApiKey = ""
+	profile.LegacyApiKey = ""
+	p.Profiles[name] = profile
+
+	store, err := NewCredentialStore(profile.CredentialStore)
+	if err != nil {
+		return err
+	}
+	if store != nil {
+		return store.Delete(name)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+// Copyright © 2019 Ettore Di Giacinto <mudler@gentoo.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package common_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/MottainaiCI/mottainai-cli/common"
+)
+
+var _ = Describe("Credentials", func() {
+	var conf *ProfileConf
+
+	BeforeEach(func() {
+		conf = NewProfileConf()
+		Expect(conf.AddProfile("local", "http://127.0.0.1:8080", "", "")).To(Succeed())
+	})
+
+	It("saves and deletes the API key on the profiles file", func() {
+		Expect(conf.SaveApiKey("local", "secret")).To(Succeed())
+		p, _ := conf.GetProfile("local")
+		Expect(p.ResolveApiKey("local")).To(Equal("secret"))
+
+		Expect(conf.DeleteApiKey("local")).To(Succeed())
+		p, _ = conf.GetProfile("local")
+		Expect(p.GetApiKey()).To(BeEmpty())
+		Expect(p.Master).To(Equal("http://127.0.0.1:8080"))
+	})
+
+	It("fails on missing profiles", func() {
+		Expect(conf.DeleteApiKey("missing")).ToNot(Succeed())
+	})
+
+	Context("local profiles", func() {
+		global := &Profile{Master: "https://mottainai.example.com/", ApiKey: "secret"}
+
+		It("keeps the API key on the same master", func() {
+			local := &LocalProfile{Profile: Profile{Master: "https://mottainai.example.com", Namespace: "team"}}
+			p := local.MergeOver(global)
+			Expect(p.ResolveApiKey("local")).To(Equal("secret"))
+			Expect(p.Namespace).To(Equal("team"))
+		})
+
+		It("drops the API key when the endpoint changes", func() {
+			for _, local := range []*LocalProfile{
+				{Profile: Profile{Master: "https://attacker.example.com"}},
+				{Profile: Profile{Proxy: "http://attacker.example.com:3128"}},
+				{Profile: Profile{InsecureSkipVerify: true}},
+				{Profile: Profile{Master: "https://attacker.example.com", CredentialStore: CREDENTIAL_STORE_KEYRING}},
+			} {
+				Expect(local.ChangesEndpoint(global)).To(BeTrue())
+				p := local.MergeOver(global)
+				Expect(p.ResolveApiKey("local")).To(BeEmpty())
+			}
+		})
+
+		It("uses the API key of the local profile", func() {
+			local := &LocalProfile{Profile: Profile{Master: "https://other.example.com", ApiKey: "other"}}
+			Expect(local.MergeOver(global).ResolveApiKey("local")).To(Equal("other"))
+		})
+	})
+})
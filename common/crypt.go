@@ -0,0 +1,244 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package common
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	secretbox "golang.org/x/crypto/nacl/secretbox"
+	scrypt "golang.org/x/crypto/scrypt"
+	terminal "golang.org/x/crypto/ssh/terminal"
+
+	v "github.com/spf13/viper"
+)
+
+const (
+	MCLI_ENCRYPTED_EXT = ".enc"
+
+	encryptedMagic = "MCLIENC1"
+	saltSize       = 16
+	nonceSize      = 24
+	keySize        = 32
+)
+
+// Passphrase read from environment or terminal, cached
+// to avoid multiple prompts on the same command.
+var profilesPassphrase []byte
+
+// GetPassphrase returns the passphrase used to encrypt the profiles
+// file. It is read from the MOTTAINAI_CLI_PASSPHRASE variable, from
+// the file defined by MOTTAINAI_CLI_PASSPHRASE_FILE or from terminal.
+func GetPassphrase() ([]byte, error) {
+	if profilesPassphrase != nil {
+		return profilesPassphrase, nil
+	}
+
+	if p := os.Getenv(MCLI_ENV_PREFIX + "_PASSPHRASE"); p != "" {
+		profilesPassphrase = []byte(p)
+	} else if f := os.Getenv(MCLI_ENV_PREFIX + "_PASSPHRASE_FILE"); f != "" {
+		data, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		profilesPassphrase = bytes.TrimRight(data, "\r\n")
+	} else if terminal.IsTerminal(int(os.Stdin.Fd())) {
+		data, err := readPassphrase("Profiles passphrase: ")
+		if err != nil {
+			return nil, err
+		}
+		profilesPassphrase = data
+	}
+
+	if len(profilesPassphrase) == 0 {
+		profilesPassphrase = nil
+		return nil, errors.New("No passphrase available for encrypted profiles")
+	}
+
+	return profilesPassphrase, nil
+}
+
+// NewPassphrase returns the passphrase used to encrypt the profiles
+// file for the first time. When it's read from terminal it's asked
+// twice, as a typo would make the file unrecoverable.
+func NewPassphrase() ([]byte, error) {
+	if profilesPassphrase != nil || !terminal.IsTerminal(int(os.Stdin.Fd())) ||
+		os.Getenv(MCLI_ENV_PREFIX+"_PASSPHRASE") != "" ||
+		os.Getenv(MCLI_ENV_PREFIX+"_PASSPHRASE_FILE") != "" {
+		return GetPassphrase()
+	}
+
+	data, err := readPassphrase("New profiles passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	confirm, err := readPassphrase("Repeat the passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(data, confirm) {
+		return nil, ValidationError("The passphrases don't match")
+	}
+	if len(data) == 0 {
+		return nil, errors.New("No passphrase available for encrypted profiles")
+	}
+	profilesPassphrase = data
+
+	return profilesPassphrase, nil
+}
+
+func readPassphrase(prompt string) ([]byte, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	data, err := terminal.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	return data, err
+}
+
+func deriveKey(passphrase, salt []byte) (*[keySize]byte, error) {
+	var key [keySize]byte
+
+	k, err := scrypt.Key(passphrase, salt, 32768, 8, 1, keySize)
+	if err != nil {
+		return nil, err
+	}
+	copy(key[:], k)
+
+	return &key, nil
+}
+
+// Encrypt seals data with a key derived from passphrase.
+func Encrypt(data, passphrase []byte) ([]byte, error) {
+	var nonce [nonceSize]byte
+
+	salt := make([]byte, saltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+	if _, err := io.ReadFull(rand.Reader, nonce[:]); err != nil {
+		return nil, err
+	}
+
+	key, err := deriveKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	ans := append([]byte(encryptedMagic), salt...)
+	ans = append(ans, nonce[:]...)
+
+	return secretbox.Seal(ans, data, &nonce, key), nil
+}
+
+// Decrypt opens data sealed by Encrypt.
+func Decrypt(data, passphrase []byte) ([]byte, error) {
+	var nonce [nonceSize]byte
+
+	header := len(encryptedMagic) + saltSize + nonceSize
+	if len(data) < header || string(data[:len(encryptedMagic)]) != encryptedMagic {
+		return nil, errors.New("Invalid encrypted file")
+	}
+
+	salt := data[len(encryptedMagic) : len(encryptedMagic)+saltSize]
+	copy(nonce[:], data[len(encryptedMagic)+saltSize:header])
+
+	key, err := deriveKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	ans, ok := secretbox.Open(nil, data[header:], &nonce, key)
+	if !ok {
+		return nil, errors.New("Invalid passphrase or corrupted file")
+	}
+
+	return ans, nil
+}
+
+// IsEncryptedFile returns true if f is an encrypted profiles file.
+func IsEncryptedFile(f string) bool {
+	return strings.HasSuffix(f, MCLI_ENCRYPTED_EXT)
+}
+
+// EncryptedProfilesFile returns the encrypted profiles file of
+// directory dir. The encrypted content is always YAML, whatever
+// the format of the plain file.
+func EncryptedProfilesFile(dir string) string {
+	return fmt.Sprintf("%s/%s.yml%s", dir, MCLI_CONFIG_NAME, MCLI_ENCRYPTED_EXT)
+}
+
+// FindEncryptedProfiles searches the encrypted profiles file
+// on the same paths used for the plain one.
+func FindEncryptedProfiles() string {
+	paths := []string{
+		MCLI_LOCAL_PATH,
+		fmt.Sprintf("%s/%s", GetHomeDir(), MCLI_HOME_PATH),
+	}
+
+	for _, p := range paths {
+		files := []string{EncryptedProfilesFile(p)}
+		// Older versions of profile encrypt kept the extension
+		// of the plain file.
+		for _, ext := range v.SupportedExts {
+			files = append(files, fmt.Sprintf("%s/%s.%s%s", p, MCLI_CONFIG_NAME, ext, MCLI_ENCRYPTED_EXT))
+		}
+		for _, f := range files {
+			if _, err := os.Stat(f); err == nil {
+				return f
+			}
+		}
+	}
+
+	return ""
+}
+
+// LoadEncryptedProfiles decrypts the profiles file f and
+// loads its content on viper.
+func LoadEncryptedProfiles(viper *v.Viper, f string) error {
+	data, err := ioutil.ReadFile(f)
+	if err != nil {
+		return err
+	}
+
+	passphrase, err := GetPassphrase()
+	if err != nil {
+		return err
+	}
+
+	plain, err := Decrypt(data, passphrase)
+	if err != nil {
+		return err
+	}
+
+	viper.SetConfigType("yaml")
+	if err = viper.MergeConfig(bytes.NewReader(plain)); err != nil {
+		return err
+	}
+	// Permit to profile commands to write the same file.
+	viper.SetConfigFile(f)
+
+	return nil
+}
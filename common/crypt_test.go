@@ -0,0 +1,92 @@
+// Copyright © 2019 Ettore Di Giacinto <mudler@gentoo.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package common_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/MottainaiCI/mottainai-cli/common"
+	v "github.com/spf13/viper"
+)
+
+var _ = Describe("Crypt", func() {
+	Describe("Encrypt", func() {
+		Context("Using a passphrase", func() {
+			It("can be decrypted only with the same passphrase", func() {
+				data, err := Encrypt([]byte("profiles: {}"), []byte("secret"))
+				Expect(err).ToNot(HaveOccurred())
+
+				plain, err := Decrypt(data, []byte("secret"))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(plain)).To(Equal("profiles: {}"))
+
+				_, err = Decrypt(data, []byte("wrong"))
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("FindEncryptedProfiles", func() {
+		var dir, cwd, home string
+
+		BeforeEach(func() {
+			var err error
+			cwd, err = os.Getwd()
+			Expect(err).ToNot(HaveOccurred())
+			dir, err = ioutil.TempDir("", "mcli-crypt")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(os.Chdir(dir)).To(Succeed())
+			home = os.Getenv("HOME")
+			os.Setenv("HOME", dir)
+			os.Setenv(MCLI_ENV_PREFIX+"_PASSPHRASE", "secret")
+		})
+
+		AfterEach(func() {
+			os.Chdir(cwd)
+			os.Setenv("HOME", home)
+			os.Unsetenv(MCLI_ENV_PREFIX + "_PASSPHRASE")
+			os.RemoveAll(dir)
+		})
+
+		It("finds the profiles encrypted from a plain file that isn't .yml", func() {
+			plain := filepath.Join(MCLI_LOCAL_PATH, MCLI_CONFIG_NAME+".json")
+			conf := NewProfileConf()
+			Expect(conf.AddProfile("ci", "http://localhost:9090", "key", "")).To(Succeed())
+
+			enc, err := conf.Write(EncryptedProfilesFile(filepath.Dir(plain)))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(FindEncryptedProfiles()).To(Equal(enc))
+
+			viper := v.New()
+			Expect(LoadEncryptedProfiles(viper, FindEncryptedProfiles())).To(Succeed())
+			Expect(viper.GetString("profiles.ci.master")).To(Equal("http://localhost:9090"))
+		})
+
+		It("finds the profiles encrypted keeping the extension of the plain file", func() {
+			conf := NewProfileConf()
+			Expect(conf.AddProfile("ci", "http://localhost:9090", "key", "")).To(Succeed())
+
+			enc, err := conf.Write(filepath.Join(MCLI_LOCAL_PATH, MCLI_CONFIG_NAME+".json"+MCLI_ENCRYPTED_EXT))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(FindEncryptedProfiles()).To(Equal(enc))
+		})
+	})
+})
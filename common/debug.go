@@ -0,0 +1,177 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package common
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Bytes of the payloads printed by the debug transport.
+const debugBodySize = 4096
+
+const debugRedacted = "REDACTED"
+
+// DebugTransport logs the method, the URL, the status and the duration
+// of every request on Writer. With Body the request and response
+// payloads are logged too, without the values of secret fields.
+type DebugTransport struct {
+	Next   http.RoundTripper
+	Writer io.Writer
+	Body   bool
+}
+
+func (t *DebugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	fmt.Fprintf(t.Writer, "> %s %s\n", req.Method, redactURL(req.URL))
+	if t.Body && req.Body != nil && req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			data, _ := ioutil.ReadAll(io.LimitReader(body, debugBodySize))
+			body.Close()
+			t.printBody(">", req.Header.Get("Content-Type"), data)
+		}
+	}
+
+	start := time.Now()
+	resp, err := t.Next.RoundTrip(req)
+	elapsed := time.Since(start).Round(time.Millisecond)
+	if err != nil {
+		fmt.Fprintf(t.Writer, "< %s %s: %s (%s)\n", req.Method, redactURL(req.URL), err, elapsed)
+		return resp, err
+	}
+	fmt.Fprintf(t.Writer, "< %s %s: %s (%s)\n", req.Method, redactURL(req.URL), resp.Status, elapsed)
+
+	if t.Body && resp.Body != nil {
+		// The peeked data is left on the body for the caller.
+		buf := bufio.NewReaderSize(resp.Body, debugBodySize)
+		data, _ := buf.Peek(debugBodySize)
+		t.printBody("<", resp.Header.Get("Content-Type"), data)
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{buf, resp.Body}
+	}
+	return resp, err
+}
+
+func (t *DebugTransport) printBody(prefix, contentType string, data []byte) {
+	if len(data) == 0 {
+		return
+	}
+	fmt.Fprintf(t.Writer, "%s %s\n", prefix, RedactPayload(contentType, data))
+}
+
+// RedactPayload returns a printable version of a JSON, form or text
+// payload where the values of secret fields ( e.g. password, token )
+// are replaced. Other payloads are only described.
+func RedactPayload(contentType string, data []byte) string {
+	// Masters send JSON also as text/plain.
+	trimmed := bytes.TrimSpace(data)
+	isJSON := strings.Contains(contentType, "json") || json.Valid(trimmed) ||
+		(len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '['))
+
+	switch {
+	case isJSON:
+		var obj interface{}
+		if err := json.Unmarshal(trimmed, &obj); err != nil {
+			// Truncated payload, secrets can't be found.
+			return fmt.Sprintf("<%d bytes of json>", len(data))
+		}
+		var b strings.Builder
+		enc := json.NewEncoder(&b)
+		enc.SetEscapeHTML(false)
+		enc.Encode(redactValue(obj))
+		return strings.TrimSuffix(b.String(), "\n")
+	case strings.HasPrefix(contentType, "application/x-www-form-urlencoded"):
+		values, err := url.ParseQuery(string(data))
+		if err != nil {
+			return fmt.Sprintf("<%d bytes of form>", len(data))
+		}
+		return redactValues(values).Encode()
+	case strings.HasPrefix(contentType, "text/") && !strings.HasPrefix(contentType, "text/html"):
+		return string(trimmed)
+	}
+	if contentType == "" {
+		contentType = "data"
+	}
+	return fmt.Sprintf("<%d bytes of %s>", len(data), contentType)
+}
+
+func isSecretField(name string) bool {
+	name = strings.ToLower(name)
+	for _, s := range []string{"password", "pass", "secret", "token", "apikey", "api_key", "key", "privkey"} {
+		if name == s || strings.HasSuffix(name, "_"+s) || strings.HasSuffix(name, "-"+s) {
+			return true
+		}
+	}
+	return false
+}
+
+func redactValue(v interface{}) interface{} {
+	switch obj := v.(type) {
+	case map[string]interface{}:
+		for k, val := range obj {
+			if isSecretField(k) {
+				obj[k] = debugRedacted
+			} else if env, ok := val.([]interface{}); ok && k == "environment" {
+				// Task variables, e.g. the --secret values.
+				for i, e := range env {
+					if s, ok := e.(string); ok {
+						env[i] = strings.SplitN(s, "=", 2)[0] + "=" + debugRedacted
+					}
+				}
+			} else {
+				obj[k] = redactValue(val)
+			}
+		}
+	case []interface{}:
+		for i, val := range obj {
+			obj[i] = redactValue(val)
+		}
+	}
+	return v
+}
+
+func redactValues(values url.Values) url.Values {
+	for k := range values {
+		if isSecretField(k) {
+			values[k] = []string{debugRedacted}
+		}
+	}
+	return values
+}
+
+func redactURL(u *url.URL) string {
+	c := *u
+	if c.RawQuery != "" {
+		if values, err := url.ParseQuery(c.RawQuery); err == nil {
+			c.RawQuery = redactValues(values).Encode()
+		}
+	}
+	return c.String()
+}
@@ -0,0 +1,64 @@
+// Copyright © 2019 Ettore Di Giacinto <mudler@gentoo.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package common_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/MottainaiCI/mottainai-cli/common"
+)
+
+var _ = Describe("Debug", func() {
+	Describe("RedactPayload", func() {
+		It("hides secret fields", func() {
+			Expect(RedactPayload("application/json", []byte(`[{"id":"1","key":"abc","user":{"password":"x"}}]`))).
+				To(Equal(`[{"id":"1","key":"REDACTED","user":{"password":"REDACTED"}}]`))
+			Expect(RedactPayload("application/x-www-form-urlencoded", []byte("name=u&api_key=abc"))).
+				To(Equal("api_key=REDACTED&name=u"))
+			Expect(RedactPayload("application/octet-stream", []byte("xyz"))).
+				To(Equal("<3 bytes of application/octet-stream>"))
+		})
+	})
+
+	Describe("DebugTransport", func() {
+		It("logs requests and keeps the response body", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"status":"ok","token":"t"}`))
+			}))
+			defer server.Close()
+
+			var log bytes.Buffer
+			client := &http.Client{Transport: &DebugTransport{Next: &http.Transport{}, Writer: &log, Body: true}}
+			resp, err := client.Get(server.URL + "/api?apikey=k")
+			Expect(err).ToNot(HaveOccurred())
+			data, err := ioutil.ReadAll(resp.Body)
+			Expect(err).ToNot(HaveOccurred())
+			resp.Body.Close()
+
+			Expect(string(data)).To(Equal(`{"status":"ok","token":"t"}`))
+			Expect(log.String()).To(ContainSubstring("> GET " + server.URL + "/api?apikey=REDACTED\n"))
+			Expect(log.String()).To(ContainSubstring("200 OK"))
+			Expect(log.String()).To(ContainSubstring(`< {"status":"ok","token":"REDACTED"}`))
+		})
+	})
+})
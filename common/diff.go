@@ -0,0 +1,124 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package common
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+type diffLine struct {
+	op   diffmatchpatch.Operation
+	text string
+}
+
+// UnifiedDiff returns the line based diff between a and b in unified
+// format with context lines around every change. It returns an empty
+// string when there are no differences. The output is colorized
+// unless color.NoColor is set.
+func UnifiedDiff(a, b, nameA, nameB string, context int) string {
+	dmp := diffmatchpatch.New()
+	ca, cb, lines := dmp.DiffLinesToChars(a, b)
+	diffs := dmp.DiffCharsToLines(dmp.DiffMain(ca, cb, false), lines)
+
+	var all []diffLine
+	changed := false
+	for _, d := range diffs {
+		text := strings.TrimSuffix(d.Text, "\n")
+		for _, l := range strings.Split(text, "\n") {
+			all = append(all, diffLine{op: d.Type, text: l})
+		}
+		if d.Type != diffmatchpatch.DiffEqual {
+			changed = true
+		}
+	}
+	if !changed {
+		return ""
+	}
+
+	red := color.New(color.FgRed).SprintFunc()
+	green := color.New(color.FgGreen).SprintFunc()
+	cyan := color.New(color.FgCyan).SprintFunc()
+	bold := color.New(color.Bold).SprintFunc()
+
+	var buf bytes.Buffer
+	fmt.Fprintln(&buf, bold("--- "+nameA))
+	fmt.Fprintln(&buf, bold("+++ "+nameB))
+
+	// Mark the lines near a change.
+	visible := make([]bool, len(all))
+	for i, l := range all {
+		if l.op == diffmatchpatch.DiffEqual {
+			continue
+		}
+		for j := i - context; j <= i+context; j++ {
+			if j >= 0 && j < len(all) {
+				visible[j] = true
+			}
+		}
+	}
+
+	// Line numbers of a and b.
+	na, nb := 1, 1
+	for i := 0; i < len(all); {
+		if !visible[i] {
+			if all[i].op != diffmatchpatch.DiffInsert {
+				na++
+			}
+			if all[i].op != diffmatchpatch.DiffDelete {
+				nb++
+			}
+			i++
+			continue
+		}
+
+		// Build the hunk.
+		end := i
+		for end < len(all) && visible[end] {
+			end++
+		}
+		var hunk bytes.Buffer
+		sa, sb := na, nb
+		for _, l := range all[i:end] {
+			switch l.op {
+			case diffmatchpatch.DiffDelete:
+				fmt.Fprintln(&hunk, red("-"+l.text))
+				na++
+			case diffmatchpatch.DiffInsert:
+				fmt.Fprintln(&hunk, green("+"+l.text))
+				nb++
+			default:
+				fmt.Fprintln(&hunk, " "+l.text)
+				na++
+				nb++
+			}
+		}
+		fmt.Fprintln(&buf, cyan(fmt.Sprintf("@@ -%d,%d +%d,%d @@", sa, na-sa, sb, nb-sb)))
+		buf.Write(hunk.Bytes())
+		i = end
+	}
+
+	return buf.String()
+}
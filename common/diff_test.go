@@ -0,0 +1,41 @@
+// Copyright © 2019 Ettore Di Giacinto <mudler@gentoo.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package common_test
+
+import (
+	"github.com/fatih/color"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/MottainaiCI/mottainai-cli/common"
+)
+
+var _ = Describe("UnifiedDiff", func() {
+	BeforeEach(func() {
+		color.NoColor = true
+	})
+
+	It("returns an empty diff for equal texts", func() {
+		Expect(UnifiedDiff("a\nb\n", "a\nb\n", "a", "b", 3)).To(Equal(""))
+	})
+
+	It("shows the changes with context", func() {
+		a := "1\n2\n3\n4\n5\n6\n"
+		b := "1\n2\n3\n4\nfive\n6\n"
+		Expect(UnifiedDiff(a, b, "a", "b", 1)).To(Equal(
+			"--- a\n+++ b\n@@ -4,3 +4,3 @@\n 4\n-5\n+five\n 6\n"))
+	})
+})
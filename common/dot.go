@@ -0,0 +1,100 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package common
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+
+	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
+	citasks "github.com/MottainaiCI/mottainai-server/pkg/tasks"
+)
+
+// DotGraph is a directed graph rendered with the Graphviz DOT language.
+type DotGraph struct {
+	Name  string
+	Nodes []*DotNode
+	Edges []*DotEdge
+}
+
+type DotNode struct {
+	ID    string
+	Label string
+	Color string
+}
+
+type DotEdge struct {
+	From string
+	To   string
+}
+
+func NewDotGraph(name string) *DotGraph {
+	return &DotGraph{Name: name, Nodes: []*DotNode{}, Edges: []*DotEdge{}}
+}
+
+func (g *DotGraph) AddNode(id, label, color string) {
+	g.Nodes = append(g.Nodes, &DotNode{ID: id, Label: label, Color: color})
+}
+
+func (g *DotGraph) AddEdge(from, to string) {
+	g.Edges = append(g.Edges, &DotEdge{From: from, To: to})
+}
+
+// String returns the graph in DOT format. Identifiers and labels
+// are always quoted, a \n inside a label is a line break.
+func (g *DotGraph) String() string {
+	var b bytes.Buffer
+
+	fmt.Fprintf(&b, "digraph %s {\n", strconv.Quote(g.Name))
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box, style=\"rounded,filled\", fillcolor=white];\n")
+	for _, n := range g.Nodes {
+		fmt.Fprintf(&b, "  %s [label=%s", strconv.Quote(n.ID), strconv.Quote(n.Label))
+		if n.Color != "" {
+			fmt.Fprintf(&b, ", fillcolor=%s", strconv.Quote(n.Color))
+		}
+		b.WriteString("];\n")
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(&b, "  %s -> %s;\n", strconv.Quote(e.From), strconv.Quote(e.To))
+	}
+	b.WriteString("}\n")
+
+	return b.String()
+}
+
+// TaskDotColor returns the color of the node of a task from its state.
+func TaskDotColor(t *citasks.Task) string {
+	switch {
+	case t.Result == setting.TASK_RESULT_SUCCESS:
+		return "palegreen"
+	case t.Result == setting.TASK_RESULT_FAILED, t.Result == setting.TASK_RESULT_ERROR:
+		return "salmon"
+	case t.IsStopped():
+		return "khaki"
+	case t.IsRunning(), t.IsSetup():
+		return "lightblue"
+	case t.IsWaiting():
+		return "lightgrey"
+	}
+	return ""
+}
@@ -0,0 +1,41 @@
+// Copyright © 2019 Ettore Di Giacinto <mudler@gentoo.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package common_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/MottainaiCI/mottainai-cli/common"
+)
+
+var _ = Describe("DotGraph", func() {
+	It("quotes identifiers and labels", func() {
+		g := NewDotGraph("my \"graph\"")
+		g.AddNode("build", "build\nfoo/bar", "palegreen")
+		g.AddNode("test", "test", "")
+		g.AddEdge("build", "test")
+
+		Expect(g.String()).To(Equal(`digraph "my \"graph\"" {
+  rankdir=LR;
+  node [shape=box, style="rounded,filled", fillcolor=white];
+  "build" [label="build\nfoo/bar", fillcolor="palegreen"];
+  "test" [label="test"];
+  "build" -> "test";
+}
+`))
+	})
+})
@@ -0,0 +1,133 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package common
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var dotEnvKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+// LoadDotEnv parses the dotenv file f.
+func LoadDotEnv(f string) ([]string, error) {
+	file, err := os.Open(f)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	ans, err := ParseDotEnv(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", f, err.Error())
+	}
+	return ans, nil
+}
+
+// ParseDotEnv parses variables in dotenv format and returns them as
+// KEY=VALUE items. Supported syntax:
+//
+//	# comment
+//	export KEY=value # inline comment
+//	KEY='literal value'
+//	KEY="value with \n escapes"
+func ParseDotEnv(r io.Reader) ([]string, error) {
+	var ans []string
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
+
+		item := strings.SplitN(line, "=", 2)
+		key := strings.TrimSpace(item[0])
+		if len(item) != 2 || !dotEnvKey.MatchString(key) {
+			return nil, fmt.Errorf("invalid line %d: %s", n, line)
+		}
+
+		value, err := dotEnvValue(strings.TrimSpace(item[1]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid value at line %d: %s", n, err.Error())
+		}
+		ans = MergeEnvironment(ans, []string{key + "=" + value})
+	}
+
+	return ans, scanner.Err()
+}
+
+func dotEnvValue(v string) (string, error) {
+	if v == "" {
+		return v, nil
+	}
+
+	switch quote := v[0]; quote {
+	case '\'', '"':
+		end := strings.LastIndexByte(v, quote)
+		if end == 0 {
+			return "", fmt.Errorf("unterminated quote in %s", v)
+		}
+		if rest := strings.TrimSpace(v[end+1:]); rest != "" && !strings.HasPrefix(rest, "#") {
+			return "", fmt.Errorf("unexpected characters after quoted value %s", v)
+		}
+		v = v[1:end]
+		if quote == '"' {
+			v = strings.NewReplacer(`\n`, "\n", `\t`, "\t", `\"`, `"`, `\\`, `\`).Replace(v)
+		}
+		return v, nil
+	}
+
+	if i := strings.Index(v, " #"); i >= 0 {
+		v = strings.TrimSpace(v[:i])
+	}
+	return v, nil
+}
+
+// MergeEnvironment returns the KEY=VALUE items of base with the
+// items of the overrides applied in order: existing keys are
+// replaced in place, the new ones appended.
+func MergeEnvironment(base []string, overrides ...[]string) []string {
+	ans := append([]string{}, base...)
+	index := make(map[string]int)
+	for i, e := range ans {
+		index[strings.SplitN(e, "=", 2)[0]] = i
+	}
+
+	for _, o := range overrides {
+		for _, e := range o {
+			key := strings.SplitN(e, "=", 2)[0]
+			if i, ok := index[key]; ok {
+				ans[i] = e
+			} else {
+				index[key] = len(ans)
+				ans = append(ans, e)
+			}
+		}
+	}
+
+	return ans
+}
@@ -0,0 +1,54 @@
+// Copyright © 2019 Ettore Di Giacinto <mudler@gentoo.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package common_test
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/MottainaiCI/mottainai-cli/common"
+)
+
+var _ = Describe("DotEnv", func() {
+	It("parses comments, exports and quotes", func() {
+		env, err := ParseDotEnv(strings.NewReader(`
+# build settings
+export ARCH=amd64 # default arch
+NAME='my # name'
+MSG="line1\nline2"
+EMPTY=
+ARCH=arm64
+`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(env).To(Equal([]string{
+			"ARCH=arm64", "NAME=my # name", "MSG=line1\nline2", "EMPTY=",
+		}))
+	})
+
+	It("rejects invalid lines", func() {
+		_, err := ParseDotEnv(strings.NewReader("NOVALUE\n"))
+		Expect(err).To(HaveOccurred())
+		_, err = ParseDotEnv(strings.NewReader("KEY=\"open\n"))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("merges the overrides", func() {
+		Expect(MergeEnvironment([]string{"A=1", "B=2"}, []string{"B=3", "C=4"})).To(
+			Equal([]string{"A=1", "B=3", "C=4"}))
+	})
+})
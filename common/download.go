@@ -0,0 +1,375 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package common
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	client "github.com/MottainaiCI/mottainai-server/pkg/client"
+	utils "github.com/MottainaiCI/mottainai-server/pkg/utils"
+)
+
+const (
+	ARTEFACT_TASK      = "artefact"
+	ARTEFACT_NAMESPACE = "namespace"
+	ARTEFACT_STORAGE   = "storage"
+
+	// Extension of the files with an incomplete download.
+	DOWNLOAD_PARTIAL_EXT = ".partial"
+
+	// Number of attempts for every file.
+	downloadTrials = 5
+)
+
+// ArtefactDownloader downloads the artefacts of a task or a
+// namespace with a pool of workers.
+type ArtefactDownloader struct {
+	Fetcher     client.HttpClient
+	Token       string
+	Concurrency int
+	Filters     []*regexp.Regexp
+	// Globs selects the artefacts with glob patterns, in addition
+	// to the regular expressions of Filters.
+	Globs ArtefactFilter
+	// Quiet disables the progress bar and the messages on stderr.
+	Quiet bool
+	// Verify checks the SHA-256 of the downloaded files against the
+	// checksums published with the artefacts and against Checksums.
+	Verify bool
+	// Checksums are additional expected checksums, indexed by the
+	// path of the artefact.
+	Checksums map[string]string
+}
+
+func NewArtefactDownloader(fetcher client.HttpClient, token string, concurrency int, filters []string) (*ArtefactDownloader, error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	ans := &ArtefactDownloader{
+		Fetcher:     fetcher,
+		Token:       token,
+		Concurrency: concurrency,
+		Verify:      true,
+	}
+
+	for _, f := range filters {
+		r, err := regexp.Compile(f)
+		if err != nil {
+			return nil, errors.New("Failed compiling regex (" + f + "): " + err.Error())
+		}
+		ans.Filters = append(ans.Filters, r)
+	}
+
+	return ans, nil
+}
+
+func (d *ArtefactDownloader) DownloadTask(id, target string) error {
+	list, err := d.Fetcher.TaskFileList(id)
+	if err != nil {
+		return errors.New("Failed getting task artefacts list: " + err.Error())
+	}
+	return d.download(ARTEFACT_TASK, id, list, target)
+}
+
+func (d *ArtefactDownloader) DownloadNamespace(name, target string) error {
+	list, err := d.Fetcher.NamespaceFileList(name)
+	if err != nil {
+		return errors.New("Failed getting namespace artefacts list: " + err.Error())
+	}
+	return d.download(ARTEFACT_NAMESPACE, name, list, target)
+}
+
+func (d *ArtefactDownloader) DownloadStorage(id, target string) error {
+	list, err := d.Fetcher.StorageFileList(id)
+	if err != nil {
+		return errors.New("Failed getting storage file list: " + err.Error())
+	}
+
+	path, err := StoragePath(d.Fetcher, id)
+	if err != nil {
+		return err
+	}
+	return d.download(ARTEFACT_STORAGE, path, list, target)
+}
+
+func (d *ArtefactDownloader) match(file string) bool {
+	if !d.Globs.Match(file) {
+		return false
+	}
+	if len(d.Filters) == 0 {
+		return true
+	}
+	for _, r := range d.Filters {
+		if r.MatchString(file) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *ArtefactDownloader) download(kind, id string, list []string, target string) error {
+	var files []string
+	for _, f := range list {
+		if d.match(f) {
+			files = append(files, f)
+		}
+	}
+
+	if err := os.MkdirAll(target, os.ModePerm); err != nil {
+		return err
+	}
+
+	progress := NewProgress("Downloading", len(files))
+	if d.Quiet {
+		progress.Enabled = false
+		progress.Writer = ioutil.Discard
+	}
+	// Partial files are kept on interrupt, the next run resumes them.
+	defer OnInterrupt(progress.Finish)()
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	var failed []string
+
+	for w := 0; w < d.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for file := range jobs {
+				url := d.Fetcher.GetBaseURL() + "/" + kind + "/" + id + utils.PathEscape(file)
+				dest := filepath.Join(target, file)
+
+				var err error
+				for i := 0; i < downloadTrials; i++ {
+					if err = d.downloadFile(url, dest, progress); err == nil {
+						break
+					}
+				}
+				if err != nil {
+					progress.Printf("Download of %s failed: %s\n", file, err.Error())
+					mutex.Lock()
+					failed = append(failed, file)
+					mutex.Unlock()
+				}
+				progress.Done()
+			}
+		}()
+	}
+	for _, f := range files {
+		jobs <- f
+	}
+	close(jobs)
+	wg.Wait()
+	progress.Finish()
+
+	if len(failed) > 0 {
+		return fmt.Errorf("Download failed for %d of %d files", len(failed), len(files))
+	}
+
+	if d.Verify {
+		return d.verify(d.Fetcher.GetBaseURL()+"/"+kind+"/"+id, list, files, target)
+	}
+	return nil
+}
+
+// verify compares the SHA-256 of the downloaded files with the
+// checksums of the SHA256SUMS manifests and of the .sha256 files
+// available in list. The corrupted files are removed, so they are
+// downloaded again by the next run.
+func (d *ArtefactDownloader) verify(baseURL string, list, files []string, target string) error {
+	expected := make(map[string]string)
+	for f, sum := range d.Checksums {
+		expected[artefactPath(f)] = sum
+	}
+
+	manifests := make(map[string]bool)
+	for _, f := range list {
+		isManifest := path.Base(f) == CHECKSUM_MANIFEST
+		if !isManifest && !strings.HasSuffix(f, CHECKSUM_EXT) {
+			continue
+		}
+		manifests[f] = true
+
+		data, err := d.fetch(baseURL + utils.PathEscape(f))
+		if err != nil {
+			return fmt.Errorf("Failed fetching checksums %s: %s", f, err.Error())
+		}
+		sums, err := ParseChecksums(data)
+		if err != nil {
+			return fmt.Errorf("Invalid checksums %s: %s", f, err.Error())
+		}
+		for name, sum := range sums {
+			if isManifest {
+				expected[artefactPath(path.Join(path.Dir(f), name))] = sum
+			} else {
+				expected[artefactPath(strings.TrimSuffix(f, CHECKSUM_EXT))] = sum
+			}
+		}
+	}
+
+	var corrupted []string
+	unverified := 0
+	for _, f := range files {
+		sum, ok := expected[artefactPath(f)]
+		if !ok {
+			if !manifests[f] {
+				unverified++
+			}
+			continue
+		}
+
+		dest := filepath.Join(target, f)
+		local, err := FileChecksum(dest)
+		if err != nil {
+			return err
+		}
+		if local != sum {
+			corrupted = append(corrupted, f)
+			os.Remove(dest)
+		}
+	}
+
+	if unverified > 0 && !d.Quiet {
+		fmt.Fprintf(os.Stderr, "%d of %d files not verified: no checksum available\n", unverified, len(files))
+	}
+	if len(corrupted) > 0 {
+		return fmt.Errorf("Checksum mismatch for %d files: %s", len(corrupted), strings.Join(corrupted, ", "))
+	}
+	return nil
+}
+
+// fetch returns the content of a small artefact.
+func (d *ArtefactDownloader) fetch(url string) ([]byte, error) {
+	request, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	setAuthHeader(request, d.Token)
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, errors.New("Error: " + response.Status)
+	}
+	return ioutil.ReadAll(response.Body)
+}
+
+// downloadFile downloads url on dest. The data are written on
+// dest.partial and when the file is already available the transfer
+// resumes from its end with a range request.
+func (d *ArtefactDownloader) downloadFile(url, dest string, progress *Progress) error {
+	if err := os.MkdirAll(filepath.Dir(dest), os.ModePerm); err != nil {
+		return err
+	}
+
+	// Skip the files already downloaded.
+	if info, err := os.Stat(dest); err == nil && info.Mode().IsRegular() {
+		if size, err := remoteSize(url, d.Token); err == nil && size == info.Size() {
+			return nil
+		}
+	}
+
+	partial := dest + DOWNLOAD_PARTIAL_EXT
+	var offset int64
+	if info, err := os.Stat(partial); err == nil {
+		offset = info.Size()
+	}
+
+	request, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+	setAuthHeader(request, d.Token)
+	if offset > 0 {
+		request.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	switch {
+	case response.StatusCode == http.StatusPartialContent && offset > 0:
+		flags |= os.O_APPEND
+		progress.Add(offset)
+	case response.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		// The partial file is already complete.
+		progress.Add(offset)
+		return os.Rename(partial, dest)
+	case response.StatusCode >= 200 && response.StatusCode <= 299:
+		// Range not supported or new download.
+		flags |= os.O_TRUNC
+	default:
+		return errors.New("Error: " + response.Status)
+	}
+
+	output, err := os.OpenFile(partial, flags, 0644)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(output, &progressReader{Reader: response.Body, Progress: progress})
+	if cerr := output.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(partial, dest)
+}
+
+// progressReader updates the progress with the bytes read.
+type progressReader struct {
+	io.Reader
+	Progress interface {
+		Add(n int64)
+	}
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.Progress.Add(int64(n))
+	return n, err
+}
+
+func setAuthHeader(r *http.Request, token string) {
+	if len(token) > 0 {
+		r.Header.Add("Authorization", "token "+token)
+	}
+}
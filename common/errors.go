@@ -0,0 +1,184 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package common
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Exit codes of the commands, so scripts can branch on the kind of
+// failure without parsing the messages.
+const (
+	EXIT_SUCCESS    = 0
+	EXIT_FAILURE    = 1
+	EXIT_VALIDATION = 2
+	EXIT_AUTH       = 3
+	EXIT_NOT_FOUND  = 4
+	EXIT_SERVER     = 5
+	EXIT_NETWORK    = 6
+	// Exit codes of the commands that wait a task, e.g. task monitor.
+	EXIT_TASK_STOPPED = 7
+	EXIT_TIMEOUT      = 8
+)
+
+// CLIError is an error with the exit code of the command.
+type CLIError struct {
+	Code int
+	Err  error
+}
+
+func (e *CLIError) Error() string { return e.Err.Error() }
+
+// NewCLIError returns err with the exit code, err is returned as is
+// when it already has an exit code.
+func NewCLIError(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*CLIError); ok {
+		return err
+	}
+	return &CLIError{Code: code, Err: err}
+}
+
+// ValidationError returns an error for invalid arguments or input.
+func ValidationError(format string, args ...interface{}) error {
+	return &CLIError{Code: EXIT_VALIDATION, Err: fmt.Errorf(format, args...)}
+}
+
+// NotFoundError returns the error of a missing object, e.g.
+// NotFoundError("Task", id).
+func NotFoundError(kind, id string) error {
+	return &CLIError{Code: EXIT_NOT_FOUND, Err: fmt.Errorf("%s %s not found", kind, id)}
+}
+
+// Max bytes of an error response read for its message.
+const maxErrorBodySize = 4 << 10
+
+// StatusError is the error of an API call answered with
+// a 4xx or 5xx status.
+type StatusError struct {
+	Code    int
+	Status  string
+	Message string
+}
+
+func (e *StatusError) Error() string {
+	if e.Message != "" {
+		return e.Status + ": " + e.Message
+	}
+	return e.Status
+}
+
+// StatusTransport turns the 4xx and 5xx responses of the API calls
+// to Master into a StatusError, the Fetcher doesn't check the status
+// of the responses. Other requests, e.g. artefact downloads, are
+// returned as they are.
+type StatusTransport struct {
+	Next   http.RoundTripper
+	Master string
+}
+
+func (t *StatusTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.Next.RoundTrip(req)
+	if err != nil || resp.StatusCode < 400 ||
+		!strings.HasPrefix(req.URL.String(), strings.TrimRight(t.Master, "/")+"/api/") {
+		return resp, err
+	}
+	defer resp.Body.Close()
+
+	ans := &StatusError{Code: resp.StatusCode, Status: resp.Status}
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	var msg struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(body, &msg) == nil && msg.Error != "" {
+		ans.Message = msg.Error
+	}
+	return nil, ans
+}
+
+// ExitCode returns the exit code of the command failed with err.
+func ExitCode(err error) int {
+	if err == nil {
+		return EXIT_SUCCESS
+	}
+	if e, ok := err.(*CLIError); ok {
+		return e.Code
+	}
+	if e, ok := err.(*ExitError); ok {
+		return e.Code
+	}
+	if e, ok := err.(*url.Error); ok {
+		if s, ok := e.Err.(*StatusError); ok {
+			return statusExitCode(s.Code)
+		}
+	}
+	if e, ok := err.(*StatusError); ok {
+		return statusExitCode(e.Code)
+	}
+	if isNetworkError(err) {
+		return EXIT_NETWORK
+	}
+
+	return EXIT_FAILURE
+}
+
+// IsTemporaryError returns true when the command failed with err
+// can be retried later, i.e. on network errors and 5xx responses.
+func IsTemporaryError(err error) bool {
+	code := ExitCode(err)
+	return code == EXIT_NETWORK || code == EXIT_SERVER
+}
+
+// statusExitCode returns the exit code of a failed call with
+// the HTTP status of its response.
+func statusExitCode(status int) int {
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return EXIT_AUTH
+	case status == http.StatusNotFound:
+		return EXIT_NOT_FOUND
+	case status >= 500:
+		return EXIT_SERVER
+	}
+	return EXIT_FAILURE
+}
+
+func isNetworkError(err error) bool {
+	if e, ok := err.(*url.Error); ok {
+		err = e.Err
+	}
+	switch err.(type) {
+	case net.Error, x509.UnknownAuthorityError, x509.HostnameError, x509.CertificateInvalidError:
+		return true
+	}
+	return err == context.DeadlineExceeded
+}
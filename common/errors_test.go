@@ -0,0 +1,83 @@
+// Copyright © 2019 Ettore Di Giacinto <mudler@gentoo.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package common_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/MottainaiCI/mottainai-cli/common"
+)
+
+var _ = Describe("ExitCode", func() {
+	It("returns the code of the CLI errors", func() {
+		Expect(ExitCode(nil)).To(Equal(EXIT_SUCCESS))
+		Expect(ExitCode(NotFoundError("Task", "1"))).To(Equal(EXIT_NOT_FOUND))
+		Expect(ExitCode(ValidationError("Invalid %s", "x"))).To(Equal(EXIT_VALIDATION))
+		Expect(ExitCode(NewCLIError(EXIT_AUTH, NotFoundError("Task", "1")))).To(Equal(EXIT_NOT_FOUND))
+	})
+
+	It("classifies the network errors", func() {
+		client := &http.Client{Transport: &StatusTransport{Next: &http.Transport{}}}
+		_, err := client.Get("http://127.0.0.1:1")
+		Expect(err).To(HaveOccurred())
+		Expect(ExitCode(err)).To(Equal(EXIT_NETWORK))
+		Expect(IsTemporaryError(err)).To(BeTrue())
+	})
+
+	It("classifies the errors with the status of the response", func() {
+		var status int
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+			w.Write([]byte(`{"error":"denied"}`))
+		}))
+		defer server.Close()
+
+		client := &http.Client{Transport: &StatusTransport{Next: &http.Transport{}, Master: server.URL}}
+		for code, exit := range map[int]int{
+			http.StatusForbidden:           EXIT_AUTH,
+			http.StatusUnauthorized:        EXIT_AUTH,
+			http.StatusNotFound:            EXIT_NOT_FOUND,
+			http.StatusInternalServerError: EXIT_SERVER,
+		} {
+			status = code
+			_, err := client.Get(server.URL + "/api/tasks")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("denied"))
+			Expect(ExitCode(err)).To(Equal(exit), strconv.Itoa(code))
+			Expect(IsTemporaryError(err)).To(Equal(exit == EXIT_SERVER), strconv.Itoa(code))
+		}
+		Expect(ExitCode(errors.New("invalid response"))).To(Equal(EXIT_FAILURE))
+	})
+
+	It("returns the responses of the other requests", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer server.Close()
+
+		client := &http.Client{Transport: &StatusTransport{Next: &http.Transport{}, Master: server.URL}}
+		resp, err := client.Get(server.URL + "/artefact/1/build_1.log")
+		Expect(err).ToNot(HaveOccurred())
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+	})
+})
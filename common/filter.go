@@ -0,0 +1,73 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package common
+
+import (
+	"path"
+	"strings"
+)
+
+// ArtefactFilter selects artefacts with glob patterns, matched on
+// the path, with or without the leading slash, and on the file name
+// of the artefacts.
+type ArtefactFilter struct {
+	Include []string
+	Exclude []string
+}
+
+// Validate checks the syntax of the patterns.
+func (f *ArtefactFilter) Validate() error {
+	for _, p := range append(append([]string{}, f.Include...), f.Exclude...) {
+		if _, err := path.Match(p, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Empty returns true when the filter selects all the artefacts.
+func (f *ArtefactFilter) Empty() bool {
+	return len(f.Include) == 0 && len(f.Exclude) == 0
+}
+
+// Match returns true when the artefact matches an include pattern,
+// or there are none, and doesn't match an exclude pattern.
+func (f *ArtefactFilter) Match(file string) bool {
+	if len(f.Include) > 0 && !matchGlobs(file, f.Include) {
+		return false
+	}
+	return !matchGlobs(file, f.Exclude)
+}
+
+func matchGlobs(file string, patterns []string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, file); ok {
+			return true
+		}
+		if ok, _ := path.Match(p, strings.TrimPrefix(file, "/")); ok {
+			return true
+		}
+		if ok, _ := path.Match(p, path.Base(file)); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+// Copyright © 2019 Ettore Di Giacinto <mudler@gentoo.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package common_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/MottainaiCI/mottainai-cli/common"
+)
+
+var _ = Describe("ArtefactFilter", func() {
+	It("matches the path and the file name", func() {
+		f := ArtefactFilter{Include: []string{"*.deb", "amd64/*"}, Exclude: []string{"*-dbg*"}}
+		Expect(f.Match("/pool/foo.deb")).To(BeTrue())
+		Expect(f.Match("/amd64/Packages")).To(BeTrue())
+		Expect(f.Match("/pool/foo-dbg.deb")).To(BeFalse())
+		Expect(f.Match("/build.log")).To(BeFalse())
+	})
+
+	It("selects everything without patterns", func() {
+		f := ArtefactFilter{}
+		Expect(f.Empty()).To(BeTrue())
+		Expect(f.Match("/any/file")).To(BeTrue())
+	})
+
+	It("rejects invalid patterns", func() {
+		f := ArtefactFilter{Exclude: []string{"[a-"}}
+		Expect(f.Validate()).To(HaveOccurred())
+	})
+})
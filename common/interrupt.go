@@ -0,0 +1,147 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package common
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// Exit code of the commands interrupted by a signal.
+const EXIT_INTERRUPTED = 130
+
+var (
+	interruptCtx, interruptCancel = context.WithCancel(context.Background())
+
+	interruptMutex sync.Mutex
+	interruptSeq   int
+	interruptFuncs = map[int]func(){}
+	// Signals received by the running command.
+	interruptCount int
+)
+
+// CommandContext returns the context of the running command. It is canceled
+// when the command is interrupted with SIGINT or SIGTERM.
+func CommandContext() context.Context {
+	interruptMutex.Lock()
+	defer interruptMutex.Unlock()
+	return interruptCtx
+}
+
+// ResetInterrupt prepares a new command context, used by the shell
+// before running the next command.
+func ResetInterrupt() {
+	interruptMutex.Lock()
+	defer interruptMutex.Unlock()
+	interruptCtx, interruptCancel = context.WithCancel(context.Background())
+	interruptCount = 0
+}
+
+// OnInterrupt registers fn to be called when the command is
+// interrupted, e.g. to remove temporary files or to restore the
+// terminal. The returned function unregisters fn and must be called
+// when the resources are released.
+func OnInterrupt(fn func()) func() {
+	interruptMutex.Lock()
+	defer interruptMutex.Unlock()
+	interruptSeq++
+	id := interruptSeq
+	interruptFuncs[id] = fn
+
+	return func() {
+		interruptMutex.Lock()
+		defer interruptMutex.Unlock()
+		delete(interruptFuncs, id)
+	}
+}
+
+// Interrupt calls the registered functions, the most recent first,
+// and cancels the command context.
+func Interrupt() {
+	interruptMutex.Lock()
+	var fns []func()
+	for id := interruptSeq; id > 0; id-- {
+		if fn, ok := interruptFuncs[id]; ok {
+			fns = append(fns, fn)
+			delete(interruptFuncs, id)
+		}
+	}
+	interruptMutex.Unlock()
+
+	for _, fn := range fns {
+		fn()
+	}
+	interruptMutex.Lock()
+	cancel := interruptCancel
+	interruptMutex.Unlock()
+	cancel()
+}
+
+// HandleInterrupt interrupts the command and exits on SIGINT and SIGTERM.
+// In shell mode only the running command is interrupted.
+func HandleInterrupt() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		for range signals {
+			interruptMutex.Lock()
+			interruptCount++
+			count := interruptCount
+			interruptMutex.Unlock()
+
+			// A second signal exits without waiting the cleanup.
+			if count > 1 {
+				os.Exit(EXIT_INTERRUPTED)
+			}
+			if ShellMode() {
+				go func() {
+					Interrupt()
+					fmt.Fprintln(os.Stderr, "Interrupted")
+				}()
+				continue
+			}
+			go func() {
+				Interrupt()
+				fmt.Fprintln(os.Stderr, "Interrupted")
+				os.Exit(EXIT_INTERRUPTED)
+			}()
+		}
+	}()
+}
+
+// InterruptTransport binds the requests without a context to the
+// command context, so they are canceled on interrupt.
+type InterruptTransport struct {
+	Next http.RoundTripper
+}
+
+func (t *InterruptTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Context() == context.Background() {
+		req = req.WithContext(CommandContext())
+	}
+	return t.Next.RoundTrip(req)
+}
@@ -0,0 +1,44 @@
+// Copyright © 2019 Ettore Di Giacinto <mudler@gentoo.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package common_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/MottainaiCI/mottainai-cli/common"
+)
+
+var _ = Describe("Interrupt", func() {
+	It("calls the registered functions and cancels the context", func() {
+		calls := []string{}
+		OnInterrupt(func() { calls = append(calls, "first") })
+		unregister := OnInterrupt(func() { calls = append(calls, "removed") })
+		OnInterrupt(func() {
+			Expect(CommandContext().Err()).ToNot(HaveOccurred())
+			calls = append(calls, "last")
+		})
+		unregister()
+
+		Interrupt()
+		Expect(calls).To(Equal([]string{"last", "first"}))
+		Expect(CommandContext().Err()).To(HaveOccurred())
+
+		// The functions are called only once.
+		Interrupt()
+		Expect(calls).To(HaveLen(2))
+	})
+})
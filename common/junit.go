@@ -0,0 +1,198 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package common
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
+	citasks "github.com/MottainaiCI/mottainai-server/pkg/tasks"
+)
+
+// Time format used by the server for the task timestamps.
+const junitTimeFormat = "20060102150405"
+
+// JUnitReport is the root element of a JUnit XML report. Every task
+// is a test case: successful tasks pass, failed tasks are failures,
+// errored tasks are errors and the tasks without a result are skipped.
+type JUnitReport struct {
+	XMLName  xml.Name      `xml:"testsuites"`
+	Name     string        `xml:"name,attr,omitempty"`
+	Tests    int           `xml:"tests,attr"`
+	Failures int           `xml:"failures,attr"`
+	Errors   int           `xml:"errors,attr"`
+	Skipped  int           `xml:"skipped,attr"`
+	Time     float64       `xml:"time,attr"`
+	Suites   []*JUnitSuite `xml:"testsuite"`
+}
+
+type JUnitSuite struct {
+	Name      string       `xml:"name,attr"`
+	Tests     int          `xml:"tests,attr"`
+	Failures  int          `xml:"failures,attr"`
+	Errors    int          `xml:"errors,attr"`
+	Skipped   int          `xml:"skipped,attr"`
+	Time      float64      `xml:"time,attr"`
+	Timestamp string       `xml:"timestamp,attr,omitempty"`
+	Cases     []*JUnitCase `xml:"testcase"`
+}
+
+type JUnitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *JUnitMessage `xml:"failure,omitempty"`
+	Error     *JUnitMessage `xml:"error,omitempty"`
+	Skipped   *JUnitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type JUnitMessage struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+}
+
+func NewJUnitReport(name string) *JUnitReport {
+	return &JUnitReport{Name: name, Suites: []*JUnitSuite{}}
+}
+
+// NewJUnitCase returns the test case of a task with its log as output.
+func NewJUnitCase(t *citasks.Task, log string) *JUnitCase {
+	name := t.Name
+	if name == "" {
+		name = t.ID
+	}
+	ans := &JUnitCase{
+		Name:      name,
+		ClassName: t.Image,
+		Time:      TaskDuration(t).Seconds(),
+		SystemOut: log,
+	}
+	if ans.ClassName == "" {
+		ans.ClassName = t.Type
+	}
+
+	switch {
+	case t.Result == setting.TASK_RESULT_SUCCESS:
+	case t.Result == setting.TASK_RESULT_FAILED:
+		ans.Failure = &JUnitMessage{
+			Message: fmt.Sprintf("Task %s failed with exit status %s", t.ID, t.ExitStatus),
+			Type:    t.Result,
+		}
+	case t.Result == setting.TASK_RESULT_ERROR:
+		ans.Error = &JUnitMessage{
+			Message: fmt.Sprintf("Task %s errored: %s", t.ID, t.Output),
+			Type:    t.Result,
+		}
+	default:
+		ans.Skipped = &JUnitMessage{Message: fmt.Sprintf("Task %s is %s", t.ID, t.Status)}
+	}
+
+	return ans
+}
+
+// AddSuite adds a test suite with a test case for every task.
+// The logs of the tasks are indexed by task id.
+func (r *JUnitReport) AddSuite(name string, tasks []citasks.Task, logs map[string]string) *JUnitSuite {
+	suite := &JUnitSuite{Name: name, Cases: []*JUnitCase{}}
+
+	var first time.Time
+	for i := range tasks {
+		c := NewJUnitCase(&tasks[i], logs[tasks[i].ID])
+		suite.Cases = append(suite.Cases, c)
+		suite.Tests++
+		suite.Time += c.Time
+		switch {
+		case c.Failure != nil:
+			suite.Failures++
+		case c.Error != nil:
+			suite.Errors++
+		case c.Skipped != nil:
+			suite.Skipped++
+		}
+
+		if start, err := time.Parse(junitTimeFormat, tasks[i].StartTime); err == nil {
+			if first.IsZero() || start.Before(first) {
+				first = start
+			}
+		}
+	}
+	if !first.IsZero() {
+		suite.Timestamp = first.Format("2006-01-02T15:04:05")
+	}
+
+	r.Suites = append(r.Suites, suite)
+	r.Tests += suite.Tests
+	r.Failures += suite.Failures
+	r.Errors += suite.Errors
+	r.Skipped += suite.Skipped
+	r.Time += suite.Time
+
+	return suite
+}
+
+// Write writes the report as indented XML.
+func (r *JUnitReport) Write(w io.Writer) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(r); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// WriteFile writes the report on the file f, or on stdout when f is -.
+func (r *JUnitReport) WriteFile(f string) error {
+	if f == "-" {
+		return r.Write(os.Stdout)
+	}
+
+	out, err := os.Create(f)
+	if err != nil {
+		return err
+	}
+	if err = r.Write(out); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// TaskDuration returns the execution time of a completed task.
+func TaskDuration(t *citasks.Task) time.Duration {
+	start, err := time.Parse(junitTimeFormat, t.StartTime)
+	if err != nil {
+		return 0
+	}
+	end, err := time.Parse(junitTimeFormat, t.EndTime)
+	if err != nil || end.Before(start) {
+		return 0
+	}
+	return end.Sub(start)
+}
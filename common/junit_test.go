@@ -0,0 +1,59 @@
+// Copyright © 2019 Ettore Di Giacinto <mudler@gentoo.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package common_test
+
+import (
+	"bytes"
+
+	citasks "github.com/MottainaiCI/mottainai-server/pkg/tasks"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/MottainaiCI/mottainai-cli/common"
+)
+
+var _ = Describe("JUnitReport", func() {
+	tasks := []citasks.Task{
+		{ID: "1", Name: "build", Image: "alpine", Result: "success",
+			StartTime: "20190101100000", EndTime: "20190101100130"},
+		{ID: "2", Name: "test", Image: "alpine", Result: "failed", ExitStatus: "2"},
+		{ID: "3", Image: "alpine", Status: "running"},
+	}
+
+	It("maps the task results to test cases", func() {
+		r := NewJUnitReport("ci")
+		s := r.AddSuite("pipeline", tasks, map[string]string{"2": "make: *** Error 2"})
+
+		Expect(s.Tests).To(Equal(3))
+		Expect(s.Failures).To(Equal(1))
+		Expect(s.Skipped).To(Equal(1))
+		Expect(s.Time).To(Equal(90.0))
+		Expect(s.Cases[2].Name).To(Equal("3"))
+		Expect(s.Cases[1].SystemOut).To(Equal("make: *** Error 2"))
+	})
+
+	It("writes the XML report", func() {
+		r := NewJUnitReport("ci")
+		r.AddSuite("pipeline", tasks[1:2], nil)
+
+		buf := &bytes.Buffer{}
+		Expect(r.Write(buf)).ToNot(HaveOccurred())
+		Expect(buf.String()).To(ContainSubstring(
+			`<testsuites name="ci" tests="1" failures="1" errors="0" skipped="0" time="0">`))
+		Expect(buf.String()).To(ContainSubstring(
+			`<failure message="Task 2 failed with exit status 2" type="failed"></failure>`))
+	})
+})
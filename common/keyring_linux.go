@@ -0,0 +1,72 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package common
+
+import (
+	"bytes"
+	"errors"
+	"os/exec"
+	"strings"
+)
+
+// KeyringStore saves API keys on the Secret Service
+// through the secret-tool utility of libsecret.
+type KeyringStore struct {
+	Service string
+}
+
+func NewKeyringStore(service string) *KeyringStore {
+	return &KeyringStore{Service: service}
+}
+
+func (k *KeyringStore) Get(profile string) (string, error) {
+	out, err := k.run("", "lookup", "service", k.Service, "profile", profile)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+func (k *KeyringStore) Set(profile, apikey string) error {
+	_, err := k.run(apikey, "store", "--label", k.Service+" "+profile,
+		"service", k.Service, "profile", profile)
+	return err
+}
+
+func (k *KeyringStore) Delete(profile string) error {
+	_, err := k.run("", "clear", "service", k.Service, "profile", profile)
+	return err
+}
+
+func (k *KeyringStore) run(stdin string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.Command("secret-tool", args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", errors.New("Keyring error: " + err.Error() + " " + strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.String(), nil
+}
@@ -0,0 +1,49 @@
+//go:build !linux && !darwin && !windows
+// +build !linux,!darwin,!windows
+
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package common
+
+import (
+	"errors"
+)
+
+// KeyringStore is not available on this platform.
+type KeyringStore struct {
+	Service string
+}
+
+func NewKeyringStore(service string) *KeyringStore {
+	return &KeyringStore{Service: service}
+}
+
+func (k *KeyringStore) Get(profile string) (string, error) {
+	return "", errors.New("Keyring is not supported on this platform")
+}
+
+func (k *KeyringStore) Set(profile, apikey string) error {
+	return errors.New("Keyring is not supported on this platform")
+}
+
+func (k *KeyringStore) Delete(profile string) error {
+	return errors.New("Keyring is not supported on this platform")
+}
@@ -0,0 +1,142 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package common
+
+import (
+	"errors"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+const (
+	credTypeGeneric         = 1
+	credPersistLocalMachine = 2
+)
+
+var (
+	advapi32       = windows.NewLazySystemDLL("advapi32.dll")
+	procCredReadW  = advapi32.NewProc("CredReadW")
+	procCredWriteW = advapi32.NewProc("CredWriteW")
+	procCredDelete = advapi32.NewProc("CredDeleteW")
+	procCredFree   = advapi32.NewProc("CredFree")
+)
+
+// credential is the CREDENTIALW structure of wincred.h.
+type credential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        windows.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+// KeyringStore saves API keys as generic credentials
+// of the Windows Credential Manager.
+type KeyringStore struct {
+	Service string
+}
+
+func NewKeyringStore(service string) *KeyringStore {
+	return &KeyringStore{Service: service}
+}
+
+func (k *KeyringStore) target(profile string) (*uint16, error) {
+	return windows.UTF16PtrFromString(k.Service + ":" + profile)
+}
+
+func (k *KeyringStore) Get(profile string) (string, error) {
+	target, err := k.target(profile)
+	if err != nil {
+		return "", err
+	}
+
+	var cred *credential
+	r, _, err := procCredReadW.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0,
+		uintptr(unsafe.Pointer(&cred)))
+	if r == 0 {
+		return "", keyringError(err)
+	}
+	defer procCredFree.Call(uintptr(unsafe.Pointer(cred)))
+
+	if cred.CredentialBlobSize == 0 {
+		return "", nil
+	}
+	blob := (*[1 << 20]byte)(unsafe.Pointer(cred.CredentialBlob))[:cred.CredentialBlobSize:cred.CredentialBlobSize]
+	return string(blob), nil
+}
+
+func (k *KeyringStore) Set(profile, apikey string) error {
+	target, err := k.target(profile)
+	if err != nil {
+		return err
+	}
+	user, err := windows.UTF16PtrFromString(profile)
+	if err != nil {
+		return err
+	}
+
+	cred := credential{
+		Type:       credTypeGeneric,
+		TargetName: target,
+		UserName:   user,
+		Persist:    credPersistLocalMachine,
+	}
+	blob := []byte(apikey)
+	if len(blob) > 0 {
+		cred.CredentialBlobSize = uint32(len(blob))
+		cred.CredentialBlob = &blob[0]
+	}
+
+	r, _, err := procCredWriteW.Call(uintptr(unsafe.Pointer(&cred)), 0)
+	if r == 0 {
+		return keyringError(err)
+	}
+	return nil
+}
+
+func (k *KeyringStore) Delete(profile string) error {
+	target, err := k.target(profile)
+	if err != nil {
+		return err
+	}
+
+	r, _, err := procCredDelete.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0)
+	if r == 0 {
+		return keyringError(err)
+	}
+	return nil
+}
+
+func keyringError(err error) error {
+	if errno, ok := err.(syscall.Errno); ok && errno == windows.ERROR_NOT_FOUND {
+		return errors.New("Keyring error: credential not found")
+	}
+	return errors.New("Keyring error: " + err.Error())
+}
@@ -0,0 +1,367 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package common
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	citasks "github.com/MottainaiCI/mottainai-server/pkg/tasks"
+	"github.com/ghodss/yaml"
+)
+
+const (
+	LINT_ERROR   = "error"
+	LINT_WARNING = "warning"
+)
+
+// LintIssue is a problem found on a pipeline manifest. Field is the
+// path of the field with the problem, e.g. tasks.build.script.
+type LintIssue struct {
+	Level   string `json:"level"`
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (i LintIssue) String() string {
+	if i.Field == "" {
+		return i.Level + ": " + i.Message
+	}
+	return i.Level + ": " + i.Field + ": " + i.Message
+}
+
+type pipelineLinter struct {
+	issues []LintIssue
+	tasks  map[string]bool
+	// Dependencies of the tasks defined by chain, chord and stages.
+	deps map[string][]string
+	used map[string]bool
+}
+
+// LintPipeline validates a pipeline manifest in YAML or JSON format
+// before its submission. It checks the types of the fields of the
+// pipeline and of its tasks, the references to undefined tasks and
+// the cycles between the tasks of chain, chord and stages.
+func LintPipeline(content []byte) []LintIssue {
+	l := &pipelineLinter{
+		tasks: make(map[string]bool),
+		deps:  make(map[string][]string),
+		used:  make(map[string]bool),
+	}
+
+	var dat map[string]interface{}
+	if err := yaml.Unmarshal(content, &dat); err != nil {
+		l.errorf("", "invalid manifest: %s", err.Error())
+		return l.issues
+	}
+	if dat == nil {
+		l.errorf("", "empty manifest")
+		return l.issues
+	}
+
+	fields := jsonFields(reflect.TypeOf(citasks.Pipeline{}))
+	delete(fields, "tasks")
+	l.checkFields("", dat, fields, map[string]bool{"tasks": true, "stages": true})
+
+	if tasks, ok := dat["tasks"]; !ok || tasks == nil {
+		l.errorf("tasks", "no tasks defined")
+	} else if m, ok := tasks.(map[string]interface{}); !ok {
+		l.errorf("tasks", "expected a mapping of task names to tasks")
+	} else {
+		taskFields := jsonFields(reflect.TypeOf(citasks.Task{}))
+		for _, name := range sortedKeys(m) {
+			l.tasks[name] = true
+			if t, ok := m[name].(map[string]interface{}); ok {
+				l.checkFields("tasks."+name, t, taskFields, nil)
+			} else {
+				l.errorf("tasks."+name, "expected a task definition")
+			}
+		}
+	}
+
+	flows := 0
+	for _, key := range []string{"chain", "group", "chord"} {
+		names, ok := stringList(dat[key])
+		if !ok || len(names) == 0 {
+			continue
+		}
+		flows++
+		l.checkRefs(key, names)
+		switch key {
+		case "chain":
+			for i := 1; i < len(names); i++ {
+				l.addDep(names[i], names[i-1])
+			}
+		case "chord":
+			callback := names[len(names)-1]
+			for _, n := range names[:len(names)-1] {
+				l.addDep(callback, n)
+			}
+		}
+	}
+	if stages, ok := dat["stages"]; ok {
+		if flows > 0 {
+			l.errorf("stages", "stages can't be used with chain, group or chord")
+		}
+		flows++
+		l.checkStages(stages)
+	}
+	if flows == 0 {
+		l.errorf("", "no stages, chain, group or chord defined")
+	}
+
+	l.checkCycles()
+
+	for _, name := range sortedKeys(l.tasks) {
+		if flows > 0 && !l.used[name] {
+			l.warnf("tasks."+name, "task not used on the pipeline")
+		}
+	}
+
+	return l.issues
+}
+
+// LintErrors returns the number of issues with the error level.
+func LintErrors(issues []LintIssue) int {
+	n := 0
+	for _, i := range issues {
+		if i.Level == LINT_ERROR {
+			n++
+		}
+	}
+	return n
+}
+
+func (l *pipelineLinter) errorf(field, format string, args ...interface{}) {
+	l.issues = append(l.issues, LintIssue{Level: LINT_ERROR, Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+func (l *pipelineLinter) warnf(field, format string, args ...interface{}) {
+	l.issues = append(l.issues, LintIssue{Level: LINT_WARNING, Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+func (l *pipelineLinter) addDep(task, dep string) {
+	l.deps[task] = append(l.deps[task], dep)
+}
+
+// checkFields checks the values of dat against the types of the
+// fields. The keys on extra are checked by the caller.
+func (l *pipelineLinter) checkFields(path string, dat map[string]interface{}, fields map[string]reflect.Type, extra map[string]bool) {
+	for _, key := range sortedKeys(dat) {
+		field := key
+		if path != "" {
+			field = path + "." + key
+		}
+		if extra[key] {
+			continue
+		}
+		typ, ok := fields[key]
+		if !ok {
+			l.warnf(field, "unknown field")
+			continue
+		}
+		value := dat[key]
+		if value == nil {
+			continue
+		}
+		switch typ.Kind() {
+		case reflect.String:
+			if _, ok := value.(string); !ok {
+				l.errorf(field, "expected a string, got %s (quote the value)", jsonType(value))
+			}
+		case reflect.Float64:
+			if _, ok := value.(float64); !ok {
+				l.errorf(field, "expected a number, got %s", jsonType(value))
+			}
+		case reflect.Slice:
+			if _, ok := stringList(value); !ok {
+				l.errorf(field, "expected a list of strings, got %s", jsonType(value))
+			}
+		}
+	}
+}
+
+func (l *pipelineLinter) checkRefs(field string, names []string) {
+	seen := make(map[string]bool)
+	for _, name := range names {
+		if !l.tasks[name] {
+			l.errorf(field, "task %s is not defined", name)
+		}
+		if seen[name] {
+			l.errorf(field, "task %s is used more than once", name)
+		}
+		seen[name] = true
+		l.used[name] = true
+	}
+}
+
+// checkStages checks the stages of the manifest, each stage is the
+// name of a task or a group of tasks and depends on the previous one.
+func (l *pipelineLinter) checkStages(stages interface{}) {
+	list, ok := stages.([]interface{})
+	if !ok {
+		l.errorf("stages", "expected a list of stages")
+		return
+	}
+
+	all := []string{}
+	previous := []string{}
+	for i, s := range list {
+		field := fmt.Sprintf("stages[%d]", i)
+		var names []string
+
+		switch stage := s.(type) {
+		case string:
+			names = []string{stage}
+		case map[string]interface{}:
+			for _, key := range sortedKeys(stage) {
+				if key != "group" && key != "task" {
+					l.warnf(field+"."+key, "unknown field")
+				}
+			}
+			if stage["task"] != nil && stage["group"] != nil {
+				l.errorf(field, "a stage can't have both a task and a group")
+			}
+			if task, ok := stage["task"].(string); ok {
+				names = append(names, task)
+			} else if stage["task"] != nil {
+				l.errorf(field+".task", "expected a task name")
+			}
+			if group, ok := stringList(stage["group"]); ok {
+				names = append(names, group...)
+			} else if stage["group"] != nil {
+				l.errorf(field+".group", "expected a list of task names")
+			}
+			if len(names) == 0 {
+				l.errorf(field, "a stage needs a task or a group")
+			}
+		default:
+			l.errorf(field, "expected a task name or a group")
+		}
+
+		for _, name := range names {
+			for _, dep := range previous {
+				l.addDep(name, dep)
+			}
+		}
+		all = append(all, names...)
+		previous = names
+	}
+
+	l.checkRefs("stages", all)
+}
+
+// checkCycles reports the cycles between the dependencies of the tasks.
+func (l *pipelineLinter) checkCycles() {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[string]int)
+	reported := make(map[string]bool)
+
+	var visit func(name string, path []string)
+	visit = func(name string, path []string) {
+		switch state[name] {
+		case visited:
+			return
+		case visiting:
+			for i, n := range path {
+				if n == name {
+					cycle := append(append([]string{}, path[i:]...), name)
+					key := strings.Join(cycle, "->")
+					if !reported[key] {
+						reported[key] = true
+						l.errorf("", "cycle between the tasks: %s", strings.Join(cycle, " -> "))
+					}
+					return
+				}
+			}
+			return
+		}
+		state[name] = visiting
+		for _, dep := range l.deps[name] {
+			visit(dep, append(path, name))
+		}
+		state[name] = visited
+	}
+
+	for _, name := range sortedKeys(l.deps) {
+		visit(name, nil)
+	}
+}
+
+// jsonFields returns the types of the fields of a struct by json name.
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	ans := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		ans[name] = f.Type
+	}
+	return ans
+}
+
+func stringList(value interface{}) ([]string, bool) {
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil, false
+	}
+	ans := []string{}
+	for _, v := range list {
+		s, ok := v.(string)
+		if !ok {
+			return nil, false
+		}
+		ans = append(ans, s)
+	}
+	return ans, true
+}
+
+func jsonType(value interface{}) string {
+	switch value.(type) {
+	case string:
+		return "a string"
+	case float64:
+		return "a number"
+	case bool:
+		return "a boolean"
+	case []interface{}:
+		return "a list"
+	case map[string]interface{}:
+		return "a mapping"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func sortedKeys(m interface{}) []string {
+	ans := []string{}
+	for _, k := range reflect.ValueOf(m).MapKeys() {
+		ans = append(ans, k.String())
+	}
+	sort.Strings(ans)
+	return ans
+}
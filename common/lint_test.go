@@ -0,0 +1,69 @@
+// Copyright © 2019 Ettore Di Giacinto <mudler@gentoo.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package common_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/MottainaiCI/mottainai-cli/common"
+)
+
+var _ = Describe("LintPipeline", func() {
+	It("accepts a valid manifest", func() {
+		issues := LintPipeline([]byte(`
+pipeline_name: release
+tasks:
+  build: { image: foo, script: ["make"], timeout: 30 }
+  test: { image: foo }
+chain: [build, test]
+`))
+		Expect(issues).To(BeEmpty())
+	})
+
+	It("reports types, undefined tasks and cycles", func() {
+		issues := LintPipeline([]byte(`
+concurrency: 2
+tasks:
+  build: { image: foo, script: make, imag: bar }
+  test: { image: foo }
+  docs: { image: foo }
+chain: [build, test, missing]
+chord: [test, build]
+`))
+		Expect(issues).To(Equal([]LintIssue{
+			{Level: LINT_ERROR, Field: "concurrency", Message: "expected a string, got a number (quote the value)"},
+			{Level: LINT_WARNING, Field: "tasks.build.imag", Message: "unknown field"},
+			{Level: LINT_ERROR, Field: "tasks.build.script", Message: "expected a list of strings, got a string"},
+			{Level: LINT_ERROR, Field: "chain", Message: "task missing is not defined"},
+			{Level: LINT_ERROR, Message: "cycle between the tasks: build -> test -> build"},
+			{Level: LINT_WARNING, Field: "tasks.docs", Message: "task not used on the pipeline"},
+		}))
+		Expect(LintErrors(issues)).To(Equal(4))
+	})
+
+	It("checks the stages", func() {
+		issues := LintPipeline([]byte(`
+tasks:
+  a: { image: foo }
+  b: { image: foo }
+stages:
+  - group: [a, b]
+  - a
+`))
+		Expect(issues).To(ContainElement(LintIssue{Level: LINT_ERROR, Field: "stages", Message: "task a is used more than once"}))
+	})
+})
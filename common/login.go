@@ -0,0 +1,82 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package common
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Page of the master web interface with the sign in form.
+const MCLI_LOGIN_PATH = "/user/login"
+
+var csrfInput = regexp.MustCompile(`name="_csrf"\s+value="([^"]*)"`)
+
+// SessionLogin signs in on the web interface of the master at baseURL
+// and returns the cookie jar with the session, to call the API routes
+// that require a user session, e.g. the token creation.
+func SessionLogin(baseURL, username, password string) (http.CookieJar, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{Jar: jar}
+	loginURL := strings.TrimRight(baseURL, "/") + MCLI_LOGIN_PATH
+
+	// The form contains the CSRF token when the master requires it.
+	resp, err := client.Get(loginURL)
+	if err != nil {
+		return nil, err
+	}
+	page, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &CLIError{Code: statusExitCode(resp.StatusCode),
+			Err: errors.New("Sign in page not available: " + resp.Status)}
+	}
+
+	form := url.Values{"user_name": {username}, "password": {password}}
+	if m := csrfInput.FindSubmatch(page); m != nil {
+		form.Set("_csrf", string(m[1]))
+	}
+	resp, err = client.PostForm(loginURL, form)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return nil, &CLIError{Code: statusExitCode(resp.StatusCode), Err: errors.New("Sign in failed: " + resp.Status)}
+	}
+	// On failure the master renders again the sign in form.
+	if strings.HasSuffix(resp.Request.URL.Path, MCLI_LOGIN_PATH) {
+		return nil, &CLIError{Code: EXIT_AUTH, Err: errors.New("Invalid username or password")}
+	}
+	return jar, nil
+}
@@ -0,0 +1,69 @@
+// Copyright © 2019 Ettore Di Giacinto <mudler@gentoo.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package common_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/MottainaiCI/mottainai-cli/common"
+)
+
+var _ = Describe("SessionLogin", func() {
+	var server *httptest.Server
+
+	BeforeEach(func() {
+		mux := http.NewServeMux()
+		mux.HandleFunc(MCLI_LOGIN_PATH, func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == "GET" {
+				w.Write([]byte(`<input type="hidden" name="_csrf" value="c1">`))
+				return
+			}
+			r.ParseForm()
+			if r.Form.Get("_csrf") != "c1" || r.Form.Get("password") != "pw" {
+				w.Write([]byte("sign in"))
+				return
+			}
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: r.Form.Get("user_name"), Path: "/"})
+			http.Redirect(w, r, "/", http.StatusFound)
+		})
+		mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {})
+		server = httptest.NewServer(mux)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("returns the session cookies", func() {
+		jar, err := SessionLogin(server.URL, "admin", "pw")
+		Expect(err).ToNot(HaveOccurred())
+
+		req, _ := http.NewRequest("GET", server.URL+"/api/token", nil)
+		cookies := jar.Cookies(req.URL)
+		Expect(cookies).To(HaveLen(1))
+		Expect(cookies[0].Value).To(Equal("admin"))
+	})
+
+	It("fails with invalid credentials", func() {
+		_, err := SessionLogin(server.URL, "admin", "wrong")
+		Expect(err).To(HaveOccurred())
+		Expect(ExitCode(err)).To(Equal(EXIT_AUTH))
+	})
+})
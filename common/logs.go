@@ -0,0 +1,125 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package common
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	client "github.com/MottainaiCI/mottainai-server/pkg/client"
+	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
+	schema "github.com/MottainaiCI/mottainai-server/routes/schema"
+	v1 "github.com/MottainaiCI/mottainai-server/routes/schema/v1"
+)
+
+// Initial size of the range requested for every wanted line.
+const logTailLineSize = 256
+
+// TaskLogTail returns the last n lines of the log of a task.
+// The log artefact is fetched with range requests that start from
+// the end of the file and grow until n lines are available. When
+// the artefact isn't available (for example the task is still
+// running) the whole log stream is fetched.
+func TaskLogTail(fetcher client.HttpClient, config *setting.Config, token, id string, n int) ([]byte, error) {
+	if n <= 0 {
+		return []byte{}, nil
+	}
+
+	size := int64(n * logTailLineSize)
+	for {
+		buff, complete, err := taskLogRange(fetcher, config, token, id, size)
+		if err != nil {
+			// Fallback to the log stream.
+			buff, err = fetcher.TaskLog(id)
+			if err != nil {
+				return nil, err
+			}
+			return LastLines(buff, n), nil
+		}
+
+		if complete || bytes.Count(buff, []byte("\n")) > n {
+			return LastLines(buff, n), nil
+		}
+		size *= 2
+	}
+}
+
+// taskLogRange fetches the last size bytes of the task log artefact.
+// complete is true when buff contains the whole file.
+func taskLogRange(fetcher client.HttpClient, config *setting.Config, token, id string, size int64) (buff []byte, complete bool, err error) {
+	req := schema.Request{
+		Route: v1.Schema.GetTaskRoute("task_log"),
+		Options: map[string]interface{}{
+			":id": id,
+		},
+	}
+
+	request, err := req.NewAPIHTTPRequest(fetcher.GetBaseURL() + config.GetWeb().BuildURI(""))
+	if err != nil {
+		return nil, false, err
+	}
+	setAuthHeader(request, token)
+	request.Header.Set("Range", fmt.Sprintf("bytes=-%d", size))
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return nil, false, err
+	}
+	defer response.Body.Close()
+
+	switch response.StatusCode {
+	case http.StatusPartialContent:
+		var total int64
+		var start, end int64
+		_, err = fmt.Sscanf(response.Header.Get("Content-Range"), "bytes %d-%d/%d", &start, &end, &total)
+		if err != nil {
+			return nil, false, errors.New("Invalid Content-Range header: " + err.Error())
+		}
+		complete = start == 0
+	case http.StatusRequestedRangeNotSatisfiable:
+		// Empty file.
+		return []byte{}, true, nil
+	case http.StatusOK:
+		// Range not supported, the body is the whole file.
+		complete = true
+	default:
+		return nil, false, errors.New("Log not available: " + response.Status)
+	}
+
+	buff, err = ioutil.ReadAll(response.Body)
+	return buff, complete, err
+}
+
+// LastLines returns the last n lines of buff.
+func LastLines(buff []byte, n int) []byte {
+	buff = bytes.TrimRight(buff, "\n")
+	pos := len(buff)
+	for ; n > 0 && pos >= 0; n-- {
+		pos = bytes.LastIndexByte(buff[:pos], '\n')
+	}
+	if pos < 0 {
+		return buff
+	}
+	return buff[pos+1:]
+}
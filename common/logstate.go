@@ -0,0 +1,118 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// File under MCLI_HOME_PATH with the offsets of the fetched logs.
+	MCLI_LOG_STATE = "log-state.json"
+
+	// Max number of checkpoints stored for every task.
+	logStateCheckpoints = 50
+)
+
+// LogCheckpoint is the size of a task log at a point in time.
+type LogCheckpoint struct {
+	Time   time.Time `json:"time"`
+	Offset int       `json:"offset"`
+}
+
+// LogState tracks the size of the task logs every time they are
+// fetched, so the log appended after a point in time can be
+// retrieved starting from the matching offset.
+type LogState struct {
+	Logs map[string][]LogCheckpoint `json:"logs"`
+
+	file string
+}
+
+func DefaultLogStateFile() string {
+	return filepath.Join(GetHomeDir(), MCLI_HOME_PATH, MCLI_LOG_STATE)
+}
+
+// LoadLogState reads the state file f. A missing file is an empty state.
+func LoadLogState(f string) (*LogState, error) {
+	ans := &LogState{Logs: make(map[string][]LogCheckpoint), file: f}
+
+	data, err := ioutil.ReadFile(f)
+	if os.IsNotExist(err) {
+		return ans, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(data, ans); err != nil {
+		return nil, fmt.Errorf("Invalid log state file %s: %s", f, err.Error())
+	}
+	if ans.Logs == nil {
+		ans.Logs = make(map[string][]LogCheckpoint)
+	}
+
+	return ans, nil
+}
+
+// Offset returns the offset of the log reached at the time since.
+// ok is false when no checkpoint is old enough.
+func (s *LogState) Offset(key string, since time.Time) (offset int, ok bool) {
+	for _, c := range s.Logs[key] {
+		if c.Time.After(since) {
+			break
+		}
+		offset, ok = c.Offset, true
+	}
+	return offset, ok
+}
+
+// Last returns the offset of the last checkpoint.
+func (s *LogState) Last(key string) (offset int, ok bool) {
+	list := s.Logs[key]
+	if len(list) == 0 {
+		return 0, false
+	}
+	return list[len(list)-1].Offset, true
+}
+
+// Add stores a checkpoint, dropping the oldest ones over the limit.
+func (s *LogState) Add(key string, t time.Time, offset int) {
+	list := append(s.Logs[key], LogCheckpoint{Time: t, Offset: offset})
+	if len(list) > logStateCheckpoints {
+		list = list[len(list)-logStateCheckpoints:]
+	}
+	s.Logs[key] = list
+}
+
+func (s *LogState) Save() error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(s.file), os.ModePerm); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(s.file, data, 0600)
+}
@@ -0,0 +1,64 @@
+// Copyright © 2019 Ettore Di Giacinto <mudler@gentoo.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package common_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/MottainaiCI/mottainai-cli/common"
+)
+
+var _ = Describe("LogState", func() {
+	var dir string
+	now := time.Now()
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "mcli-logstate")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("returns the offset reached at a point in time", func() {
+		f := filepath.Join(dir, "state", "log-state.json")
+		s, err := LoadLogState(f)
+		Expect(err).ToNot(HaveOccurred())
+
+		s.Add("1", now.Add(-2*time.Hour), 100)
+		s.Add("1", now.Add(-time.Hour), 250)
+		Expect(s.Save()).ToNot(HaveOccurred())
+
+		s, err = LoadLogState(f)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, ok := s.Offset("1", now.Add(-3*time.Hour))
+		Expect(ok).To(BeFalse())
+		offset, ok := s.Offset("1", now.Add(-90*time.Minute))
+		Expect(ok).To(BeTrue())
+		Expect(offset).To(Equal(100))
+		offset, _ = s.Last("1")
+		Expect(offset).To(Equal(250))
+	})
+})
@@ -0,0 +1,150 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package common
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	cookiejar "github.com/juju/persistent-cookiejar"
+	"gopkg.in/macaroon-bakery.v2/bakery"
+	"gopkg.in/macaroon-bakery.v2/httpbakery"
+)
+
+const (
+	// Authentication modes of the --auth option.
+	AUTH_TOKEN    = "token"
+	AUTH_MACAROON = "macaroon"
+
+	// File under CacheDir with the discharged macaroons.
+	MCLI_MACAROON_FILE = "macaroons"
+
+	// Discharges attempted for a single request, every layer of
+	// security can require a new one.
+	maxDischarges = 3
+	// Size of the error responses read looking for a discharge request.
+	maxDischargeBody = 1 << 20
+)
+
+// MacaroonTransport authenticates the requests with macaroons. When
+// the master replies that a discharge is required the third party
+// caveats are discharged, with the user interaction on the web browser
+// when needed, and the request is sent again with the macaroons.
+// The discharged macaroons are stored on Jar and reused by the next
+// commands until they expire.
+type MacaroonTransport struct {
+	Next   http.RoundTripper
+	Client *httpbakery.Client
+	Jar    *cookiejar.Jar
+}
+
+// NewMacaroonTransport returns a MacaroonTransport that stores the
+// macaroons on file. The discharge requests are sent with next.
+func NewMacaroonTransport(next http.RoundTripper, file string) (*MacaroonTransport, error) {
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return nil, err
+	}
+	jar, err := cookiejar.New(&cookiejar.Options{Filename: file})
+	if err != nil {
+		return nil, err
+	}
+
+	c := httpbakery.NewClient()
+	c.Client.Transport = next
+	c.Client.Jar = jar
+	c.AddInteractor(httpbakery.WebBrowserInteractor{})
+
+	return &MacaroonTransport{Next: next, Client: c, Jar: jar}, nil
+}
+
+func (t *MacaroonTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for i := 0; ; i++ {
+		resp, err := t.Next.RoundTrip(t.withMacaroons(req))
+		if err != nil {
+			return nil, err
+		}
+
+		derr := dischargeRequired(resp)
+		// Requests with a body not available again can't be repeated.
+		if derr == nil || i >= maxDischarges || (req.Body != nil && req.GetBody == nil) {
+			return resp, nil
+		}
+		resp.Body.Close()
+
+		if err := t.Client.HandleError(req.Context(), req.URL, derr); err != nil {
+			return nil, err
+		}
+		if err := t.Jar.Save(); err != nil {
+			return nil, err
+		}
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			r := new(http.Request)
+			*r = *req
+			r.Body = body
+			req = r
+		}
+	}
+}
+
+// withMacaroons returns a copy of req with the stored macaroons.
+func (t *MacaroonTransport) withMacaroons(req *http.Request) *http.Request {
+	r := new(http.Request)
+	*r = *req
+	r.Header = cloneHeader(req.Header)
+	r.Header.Set(httpbakery.BakeryProtocolHeader, fmt.Sprint(bakery.LatestVersion))
+	for _, c := range t.Jar.Cookies(req.URL) {
+		r.AddCookie(c)
+	}
+	return r
+}
+
+// dischargeRequired returns the discharge request of the response,
+// the body is left available to the caller.
+func dischargeRequired(resp *http.Response) *httpbakery.Error {
+	if resp.StatusCode != http.StatusUnauthorized && resp.StatusCode != http.StatusProxyAuthRequired {
+		return nil
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxDischargeBody))
+	body := resp.Body
+	resp.Body = &multiReadCloser{Reader: io.MultiReader(bytes.NewReader(data), body), Closer: body}
+	if err != nil {
+		return nil
+	}
+
+	r := new(http.Response)
+	*r = *resp
+	r.Body = ioutil.NopCloser(bytes.NewReader(data))
+	if e, ok := httpbakery.DefaultGetError(r).(*httpbakery.Error); ok && e.Code == httpbakery.ErrDischargeRequired {
+		return e
+	}
+	return nil
+}
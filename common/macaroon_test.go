@@ -0,0 +1,108 @@
+// Copyright © 2019 Ettore Di Giacinto <mudler@gentoo.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package common_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"gopkg.in/macaroon-bakery.v2/bakery"
+	"gopkg.in/macaroon-bakery.v2/httpbakery"
+
+	. "github.com/MottainaiCI/mottainai-cli/common"
+)
+
+var _ = Describe("MacaroonTransport", func() {
+	var dir string
+	var server *httptest.Server
+	var denied, served int
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "mcli-macaroon")
+		Expect(err).ToNot(HaveOccurred())
+
+		denied, served = 0, 0
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/open" {
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte("unauthorized"))
+				return
+			}
+			if _, err := r.Cookie("macaroon-auth"); err != nil {
+				denied++
+				m, err := bakery.NewMacaroon([]byte("root-key"), []byte("id"), "", bakery.LatestVersion, nil)
+				Expect(err).ToNot(HaveOccurred())
+				httpbakery.WriteError(context.Background(), w, httpbakery.NewDischargeRequiredError(httpbakery.DischargeRequiredErrorParams{
+					Macaroon: m,
+					Request:  r,
+				}))
+				return
+			}
+			served++
+			w.Write([]byte("ok"))
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+		os.RemoveAll(dir)
+	})
+
+	get := func(t http.RoundTripper, path string) (int, string) {
+		req, err := http.NewRequest("GET", server.URL+path, nil)
+		Expect(err).ToNot(HaveOccurred())
+		resp, err := t.RoundTrip(req)
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+		data, err := ioutil.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		return resp.StatusCode, string(data)
+	}
+
+	It("repeats the request with the discharged macaroons", func() {
+		file := filepath.Join(dir, "macaroons")
+		t, err := NewMacaroonTransport(http.DefaultTransport, file)
+		Expect(err).ToNot(HaveOccurred())
+
+		code, body := get(t, "/api/tasks")
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(body).To(Equal("ok"))
+		Expect(denied).To(Equal(1))
+
+		// The macaroons are reused by the next commands.
+		t, err = NewMacaroonTransport(http.DefaultTransport, file)
+		Expect(err).ToNot(HaveOccurred())
+		get(t, "/api/tasks")
+		Expect(denied).To(Equal(1))
+		Expect(served).To(Equal(2))
+	})
+
+	It("leaves the other unauthorized responses untouched", func() {
+		t, err := NewMacaroonTransport(http.DefaultTransport, filepath.Join(dir, "macaroons"))
+		Expect(err).ToNot(HaveOccurred())
+
+		code, body := get(t, "/open")
+		Expect(code).To(Equal(http.StatusUnauthorized))
+		Expect(body).To(Equal("unauthorized"))
+	})
+})
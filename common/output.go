@@ -0,0 +1,249 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package common
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"text/template"
+
+	"github.com/ghodss/yaml"
+	jmespath "github.com/jmespath/go-jmespath"
+	tablewriter "github.com/olekukonko/tablewriter"
+	v "github.com/spf13/viper"
+)
+
+const (
+	OUTPUT_TABLE = "table"
+	OUTPUT_JSON  = "json"
+	OUTPUT_YAML  = "yaml"
+	OUTPUT_CSV   = "csv"
+)
+
+// Output renders command results following the --output,
+// --format, --columns, --sort-by and --query global flags.
+type Output struct {
+	Format   string
+	Template string
+	Columns  []string
+	SortBy   string
+	Query    string
+	Writer   io.Writer
+}
+
+func NewOutput(viper *v.Viper) *Output {
+	return &Output{
+		Format:   viper.GetString("output"),
+		Template: viper.GetString("format"),
+		Columns:  viper.GetStringSlice("columns"),
+		SortBy:   viper.GetString("sort-by"),
+		Query:    viper.GetString("query"),
+		Writer:   os.Stdout,
+	}
+}
+
+// PrintList renders a list of objects. When no output format
+// is selected the table is used.
+func (o *Output) PrintList(obj interface{}, table *Table) error {
+	return o.print(obj, table, OUTPUT_TABLE)
+}
+
+// PrintObject renders a single object. When no output format
+// is selected the object is printed as JSON.
+func (o *Output) PrintObject(obj interface{}) error {
+	return o.print(obj, nil, OUTPUT_JSON)
+}
+
+func (o *Output) print(obj interface{}, table *Table, defaultFormat string) error {
+	if o.Query != "" {
+		var err error
+		obj, err = o.search(obj)
+		if err != nil {
+			return err
+		}
+		// The table of the command doesn't describe the query result.
+		table = nil
+		defaultFormat = OUTPUT_JSON
+	}
+
+	if o.Template != "" {
+		return o.printTemplate(obj)
+	}
+
+	format := o.Format
+	if format == "" {
+		format = defaultFormat
+	}
+
+	switch format {
+	case OUTPUT_JSON:
+		b, err := json.MarshalIndent(obj, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(o.Writer, string(b))
+	case OUTPUT_YAML:
+		b, err := yaml.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(o.Writer, string(b))
+	case OUTPUT_TABLE, OUTPUT_CSV:
+		var err error
+		if table == nil {
+			table, err = objectTable(obj)
+			if err != nil {
+				return err
+			}
+		}
+		if len(o.Columns) > 0 || o.SortBy != "" {
+			table, err = table.Customize(obj, o.Columns, o.SortBy)
+			if err != nil {
+				return err
+			}
+		}
+		if format == OUTPUT_CSV {
+			return o.renderCSV(table)
+		}
+		o.renderTable(table)
+	default:
+		return errors.New("Invalid output format " + format)
+	}
+
+	return nil
+}
+
+func (o *Output) renderTable(t *Table) {
+	table := tablewriter.NewWriter(o.Writer)
+	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	table.SetCenterSeparator("|")
+	table.SetHeader(t.Header)
+	for _, row := range t.Rows {
+		table.Append(row)
+	}
+	table.Render()
+}
+
+func (o *Output) renderCSV(t *Table) error {
+	w := csv.NewWriter(o.Writer)
+	if err := w.Write(t.Header); err != nil {
+		return err
+	}
+	if err := w.WriteAll(t.Rows); err != nil {
+		return err
+	}
+	return w.Error()
+}
+
+// search evaluates the JMESPath query against the JSON
+// representation of obj.
+func (o *Output) search(obj interface{}) (interface{}, error) {
+	var data interface{}
+
+	b, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(b, &data); err != nil {
+		return nil, err
+	}
+
+	ans, err := jmespath.Search(o.Query, data)
+	if err != nil {
+		return nil, errors.New("Invalid query " + o.Query + ": " + err.Error())
+	}
+
+	return ans, nil
+}
+
+// printTemplate executes the go template against every element
+// of obj when it is a slice or a map, or against obj itself otherwise.
+func (o *Output) printTemplate(obj interface{}) error {
+	tmpl, err := template.New("format").Parse(o.Template)
+	if err != nil {
+		return err
+	}
+
+	val := reflect.ValueOf(obj)
+	for val.Kind() == reflect.Ptr && !val.IsNil() {
+		val = val.Elem()
+	}
+
+	var items []interface{}
+	switch val.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < val.Len(); i++ {
+			items = append(items, val.Index(i).Interface())
+		}
+	case reflect.Map:
+		keys := val.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprintf("%v", keys[i]) < fmt.Sprintf("%v", keys[j])
+		})
+		for _, k := range keys {
+			items = append(items, val.MapIndex(k).Interface())
+		}
+	default:
+		items = []interface{}{obj}
+	}
+
+	for _, item := range items {
+		if err = tmpl.Execute(o.Writer, item); err != nil {
+			return err
+		}
+		fmt.Fprintln(o.Writer)
+	}
+
+	return nil
+}
+
+// objectTable converts a single object to a Field/Value table
+// using its JSON representation.
+func objectTable(obj interface{}) (*Table, error) {
+	var m map[string]interface{}
+
+	b, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(b, &m); err != nil {
+		return nil, errors.New("Object can't be rendered as table")
+	}
+
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	t := NewTable([]string{"Field", "Value"})
+	for _, k := range keys {
+		t.Append([]string{k, fmt.Sprintf("%v", m[k])})
+	}
+
+	return t, nil
+}
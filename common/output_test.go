@@ -0,0 +1,108 @@
+// Copyright © 2019 Ettore Di Giacinto <mudler@gentoo.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package common_test
+
+import (
+	"bytes"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/MottainaiCI/mottainai-cli/common"
+)
+
+func TestCommon(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Common tests")
+}
+
+type item struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+}
+
+var _ = Describe("Output", func() {
+	var buf *bytes.Buffer
+	var items []item
+	var table *Table
+
+	BeforeEach(func() {
+		buf = &bytes.Buffer{}
+		items = []item{{ID: "1", Status: "done"}, {ID: "2", Status: "running, \"slow\""}}
+		table = NewTable([]string{"ID", "Status"})
+		for _, i := range items {
+			table.Append([]string{i.ID, i.Status})
+		}
+	})
+
+	Describe("PrintList", func() {
+		Context("Using csv format", func() {
+			It("writes header and quoted rows", func() {
+				o := &Output{Format: OUTPUT_CSV, Writer: buf}
+				Expect(o.PrintList(items, table)).ToNot(HaveOccurred())
+				Expect(buf.String()).To(Equal("ID,Status\n1,done\n2,\"running, \"\"slow\"\"\"\n"))
+			})
+		})
+
+		Context("Using a go template", func() {
+			It("renders every element", func() {
+				o := &Output{Template: "{{.ID}}={{.Status}}", Writer: buf}
+				Expect(o.PrintList(items[:1], table)).ToNot(HaveOccurred())
+				Expect(buf.String()).To(Equal("1=done\n"))
+			})
+		})
+
+		Context("Selecting and sorting columns", func() {
+			It("reads header and struct fields", func() {
+				o := &Output{Format: OUTPUT_CSV, Columns: []string{"status", "id"}, SortBy: "-ID", Writer: buf}
+				Expect(o.PrintList(items, NewTable([]string{"Identifier"}))).To(HaveOccurred())
+
+				t := NewTable([]string{"Status"})
+				for _, i := range items {
+					t.Append([]string{i.Status})
+				}
+				Expect(o.PrintList(items, t)).ToNot(HaveOccurred())
+				Expect(buf.String()).To(Equal("status,id\n\"running, \"\"slow\"\"\",2\ndone,1\n"))
+			})
+		})
+
+		Context("Using a query", func() {
+			It("filters the json representation", func() {
+				o := &Output{Query: "[?status=='done'].id", Writer: buf}
+				Expect(o.PrintList(items, table)).ToNot(HaveOccurred())
+				Expect(buf.String()).To(Equal("[\n  \"1\"\n]\n"))
+			})
+		})
+
+		Context("Using an invalid format", func() {
+			It("returns an error", func() {
+				o := &Output{Format: "xml", Writer: buf}
+				Expect(o.PrintList(items, table)).To(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("PrintObject", func() {
+		Context("Using yaml format", func() {
+			It("uses json field names", func() {
+				o := &Output{Format: OUTPUT_YAML, Writer: buf}
+				Expect(o.PrintObject(items[0])).ToNot(HaveOccurred())
+				Expect(buf.String()).To(Equal("id: \"1\"\nstatus: done\n"))
+			})
+		})
+	})
+})
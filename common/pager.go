@@ -0,0 +1,297 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package common
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+
+	client "github.com/MottainaiCI/mottainai-server/pkg/client"
+	schema "github.com/MottainaiCI/mottainai-server/routes/schema"
+)
+
+// Pager iterates over the pages of a list route. The page and
+// limit query parameters are sent to the master; when the master
+// returns the whole list the pages are sliced on the client.
+type Pager struct {
+	Fetcher client.HttpClient
+	Route   schema.Route
+	Options map[string]interface{}
+	// Number of elements for page.
+	Limit int
+	// Sort is applied to the list when the master doesn't support
+	// the pagination, so pages are consistent.
+	Sort func(interface{})
+	// Match selects the elements of the list. When it's set the whole
+	// list is retrieved and filtered before slicing the pages, so the
+	// filter works with masters that ignore the query parameters.
+	Match func(item interface{}) bool
+
+	page int
+	done bool
+	// Set when the master is known to handle the pagination.
+	paginated bool
+	// Full list returned by a master without pagination support.
+	full *reflect.Value
+}
+
+// ErrStopPaging can be returned by the callback of Each to stop
+// the iteration without errors.
+var ErrStopPaging = errors.New("stop paging")
+
+// PagerItem is an element returned by the channel of Items.
+// Err is set when a page can't be retrieved.
+type PagerItem struct {
+	Item interface{}
+	Err  error
+}
+
+// NewPager returns a Pager that starts from the page
+// (1 based) and returns limit elements for every page.
+func NewPager(fetcher client.HttpClient, route schema.Route, page, limit int) *Pager {
+	if page < 1 {
+		page = 1
+	}
+	return &Pager{
+		Fetcher: fetcher,
+		Route:   route,
+		Options: map[string]interface{}{},
+		Limit:   limit,
+		page:    page,
+	}
+}
+
+// Page returns the number of the page returned by the next call of Next.
+func (p *Pager) Page() int { return p.page }
+
+// HasNext returns false when the last page has been reached.
+func (p *Pager) HasNext() bool { return !p.done }
+
+// fetch retrieves a list of type typ. page and limit are sent to
+// the master when limit is greater than zero.
+func (p *Pager) fetch(typ reflect.Type, page, limit int) (reflect.Value, error) {
+	options := map[string]interface{}{}
+	for k, v := range p.Options {
+		options[k] = v
+	}
+	if limit > 0 {
+		options["page"] = strconv.Itoa(page)
+		options["limit"] = strconv.Itoa(limit)
+	}
+
+	items := reflect.New(typ)
+	req := schema.Request{
+		Route:   p.Route,
+		Options: options,
+		Target:  items.Interface(),
+	}
+	if err := p.Fetcher.Handle(req); err != nil {
+		return reflect.Value{}, err
+	}
+
+	return items.Elem(), nil
+}
+
+// setFull stores the list to slice on the client, after
+// applying Match and Sort.
+func (p *Pager) setFull(items reflect.Value) {
+	full := reflect.MakeSlice(items.Type(), 0, items.Len())
+	for i := 0; i < items.Len(); i++ {
+		if p.Match == nil || p.Match(items.Index(i).Interface()) {
+			full = reflect.Append(full, items.Index(i))
+		}
+	}
+
+	ptr := reflect.New(items.Type())
+	ptr.Elem().Set(full)
+	if p.Sort != nil {
+		p.Sort(ptr.Interface())
+	}
+	full = ptr.Elem()
+	p.full = &full
+}
+
+// probe checks if the master handles the pagination, asking for two
+// pages of one element: a master that ignores the parameters returns
+// the whole list, or the same element twice. In that case the list
+// is stored to be sliced on the client.
+func (p *Pager) probe(typ reflect.Type) error {
+	first, err := p.fetch(typ, 1, 1)
+	if err != nil {
+		return err
+	}
+	if first.Len() != 1 {
+		p.setFull(first)
+		return nil
+	}
+
+	second, err := p.fetch(typ, 2, 1)
+	if err != nil {
+		return err
+	}
+	if second.Len() == 1 &&
+		reflect.DeepEqual(first.Index(0).Interface(), second.Index(0).Interface()) {
+		p.setFull(first)
+		return nil
+	}
+	p.paginated = true
+
+	return nil
+}
+
+// Next stores the next page on target that must be a pointer to
+// a slice. It returns false when there aren't more elements.
+func (p *Pager) Next(target interface{}) (bool, error) {
+	ptr := reflect.ValueOf(target)
+	if ptr.Kind() != reflect.Ptr || ptr.Elem().Kind() != reflect.Slice {
+		return false, errors.New("Pager target must be a pointer to a slice")
+	}
+	if p.done {
+		return false, nil
+	}
+
+	typ := ptr.Elem().Type()
+	if p.full == nil && !p.paginated && p.Limit > 0 && p.Match == nil {
+		if err := p.probe(typ); err != nil {
+			return false, err
+		}
+	}
+
+	if p.paginated {
+		items, err := p.fetch(typ, p.page, p.Limit)
+		if err != nil {
+			return false, err
+		}
+		if items.Len() > p.Limit {
+			// The master stopped paginating, slice what it returned.
+			p.paginated = false
+			p.setFull(items)
+		} else {
+			ptr.Elem().Set(items)
+			p.page++
+			p.done = items.Len() < p.Limit
+			return items.Len() > 0, nil
+		}
+	}
+
+	if p.full == nil {
+		items, err := p.fetch(typ, 0, 0)
+		if err != nil {
+			return false, err
+		}
+		p.setFull(items)
+	}
+
+	limit := p.Limit
+	if limit <= 0 {
+		limit = p.full.Len()
+	}
+	start := (p.page - 1) * limit
+	if start >= p.full.Len() {
+		p.done = true
+		ptr.Elem().Set(reflect.MakeSlice(typ, 0, 0))
+		return false, nil
+	}
+	end := start + limit
+	if end >= p.full.Len() {
+		end = p.full.Len()
+		p.done = true
+	}
+	ptr.Elem().Set(p.full.Slice(start, end))
+	p.page++
+
+	return true, nil
+}
+
+// Each walks the pages from the current one and calls fn with every
+// element. target must be a pointer to a slice of the elements type
+// and contains the last page retrieved.
+func (p *Pager) Each(target interface{}, fn func(item interface{}) error) error {
+	for {
+		found, err := p.Next(target)
+		if err != nil {
+			return err
+		}
+
+		page := reflect.ValueOf(target).Elem()
+		for i := 0; i < page.Len(); i++ {
+			if err := fn(page.Index(i).Interface()); err != nil {
+				if err == ErrStopPaging {
+					return nil
+				}
+				return err
+			}
+		}
+		if !found || p.done {
+			return nil
+		}
+	}
+}
+
+// All stores on target, a pointer to a slice, the elements of all
+// the pages from the current one.
+func (p *Pager) All(target interface{}) error {
+	ptr := reflect.ValueOf(target)
+	if ptr.Kind() != reflect.Ptr || ptr.Elem().Kind() != reflect.Slice {
+		return errors.New("Pager target must be a pointer to a slice")
+	}
+
+	ans := reflect.MakeSlice(ptr.Elem().Type(), 0, 0)
+	page := reflect.New(ptr.Elem().Type())
+	err := p.Each(page.Interface(), func(item interface{}) error {
+		ans = reflect.Append(ans, reflect.ValueOf(item))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	ptr.Elem().Set(ans)
+
+	return nil
+}
+
+// Items returns a channel that yields the elements of all the
+// pages from the current one, target describes the slice type
+// as in Each. The pages are retrieved until stop is closed.
+func (p *Pager) Items(target interface{}, stop <-chan struct{}) <-chan PagerItem {
+	ch := make(chan PagerItem)
+
+	go func() {
+		defer close(ch)
+		err := p.Each(target, func(item interface{}) error {
+			select {
+			case ch <- PagerItem{Item: item}:
+				return nil
+			case <-stop:
+				return ErrStopPaging
+			}
+		})
+		if err != nil {
+			select {
+			case ch <- PagerItem{Err: err}:
+			case <-stop:
+			}
+		}
+	}()
+
+	return ch
+}
@@ -0,0 +1,162 @@
+// Copyright © 2019 Ettore Di Giacinto <mudler@gentoo.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package common_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/MottainaiCI/mottainai-cli/common"
+	client "github.com/MottainaiCI/mottainai-server/pkg/client"
+	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
+	v1 "github.com/MottainaiCI/mottainai-server/routes/schema/v1"
+)
+
+type pagerItem struct {
+	ID string `json:"ID"`
+}
+
+var _ = Describe("Pager", func() {
+	var server *httptest.Server
+	var paginate bool
+	var served []pagerItem
+
+	items := []pagerItem{}
+	for i := 1; i <= 7; i++ {
+		items = append(items, pagerItem{ID: strconv.Itoa(i)})
+	}
+
+	BeforeEach(func() {
+		paginate = true
+		served = items
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ans := served
+			page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+			limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+			if paginate && limit > 0 {
+				start := (page - 1) * limit
+				if start > len(served) {
+					start = len(served)
+				}
+				end := start + limit
+				if end > len(served) {
+					end = len(served)
+				}
+				ans = served[start:end]
+			}
+			json.NewEncoder(w).Encode(ans)
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	newPager := func(page, limit int) *Pager {
+		config := setting.NewConfig(nil)
+		Expect(config.Unmarshal()).ToNot(HaveOccurred())
+		fetcher := client.NewTokenClient(server.URL, "", config)
+		return NewPager(fetcher, v1.Schema.GetTaskRoute("show_all"), page, limit)
+	}
+
+	Context("All", func() {
+		It("walks the pages of the master", func() {
+			var ans []pagerItem
+			Expect(newPager(1, 3).All(&ans)).ToNot(HaveOccurred())
+			Expect(ans).To(Equal(items))
+		})
+
+		It("walks the pages sliced on the client", func() {
+			paginate = false
+			var ans []pagerItem
+			Expect(newPager(2, 3).All(&ans)).ToNot(HaveOccurred())
+			Expect(ans).To(Equal(items[3:]))
+		})
+
+		It("ends when the master ignores the limit and returns exactly limit items", func() {
+			paginate = false
+			var ans []pagerItem
+			Expect(newPager(1, 7).All(&ans)).ToNot(HaveOccurred())
+			Expect(ans).To(Equal(items))
+
+			pager := newPager(2, 7)
+			found, err := pager.Next(&ans)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeFalse())
+			Expect(ans).To(BeEmpty())
+		})
+
+		It("handles a master without pagination returning one element", func() {
+			paginate = false
+			served = items[:1]
+			var ans []pagerItem
+			Expect(newPager(1, 1).All(&ans)).ToNot(HaveOccurred())
+			Expect(ans).To(Equal(items[:1]))
+		})
+
+		It("filters the whole list before slicing the pages", func() {
+			for _, p := range []bool{true, false} {
+				paginate = p
+				pager := newPager(1, 2)
+				pager.Match = func(item interface{}) bool {
+					id, _ := strconv.Atoi(item.(pagerItem).ID)
+					return id%2 == 1
+				}
+				var ans []pagerItem
+				_, err := pager.Next(&ans)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(ans).To(Equal([]pagerItem{items[0], items[2]}))
+				Expect(pager.HasNext()).To(BeTrue())
+				Expect(pager.All(&ans)).ToNot(HaveOccurred())
+				Expect(ans).To(Equal([]pagerItem{items[4], items[6]}))
+			}
+		})
+	})
+
+	Context("Each", func() {
+		It("stops on ErrStopPaging", func() {
+			ids := []string{}
+			err := newPager(1, 2).Each(&[]pagerItem{}, func(item interface{}) error {
+				ids = append(ids, item.(pagerItem).ID)
+				if len(ids) == 3 {
+					return ErrStopPaging
+				}
+				return nil
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ids).To(Equal([]string{"1", "2", "3"}))
+		})
+	})
+
+	Context("Items", func() {
+		It("yields the elements of all the pages", func() {
+			stop := make(chan struct{})
+			defer close(stop)
+
+			ids := []string{}
+			for i := range newPager(1, 4).Items(&[]pagerItem{}, stop) {
+				Expect(i.Err).ToNot(HaveOccurred())
+				ids = append(ids, i.Item.(pagerItem).ID)
+			}
+			Expect(ids).To(Equal([]string{"1", "2", "3", "4", "5", "6", "7"}))
+		})
+	})
+})
@@ -0,0 +1,126 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package common
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	v "github.com/spf13/viper"
+)
+
+// Prefix of the executables on PATH run as subcommands of the cli:
+// "mottainai-cli foo" runs "mottainai-foo".
+const MCLI_PLUGIN_PREFIX = "mottainai-"
+
+// Name of the cli itself, never run as a plugin.
+const pluginSelf = "cli"
+
+// Settings supplied to the plugins as MOTTAINAI_CLI_<NAME> variables,
+// the same read by the cli, so plugins can call it back.
+var pluginSettings = []string{
+	"master", "apikey", "profile", "cacert", "cert", "key", "insecure",
+	"proxy", "timeout", "auth",
+}
+
+// Plugin is an executable found on PATH.
+type Plugin struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	// Paths of the executables with the same name hidden by Path.
+	Shadowed []string `json:"shadowed,omitempty"`
+}
+
+// FindPlugin returns the path of the plugin name.
+func FindPlugin(name string) (string, error) {
+	if name == pluginSelf {
+		return "", errors.New("Invalid plugin name " + name)
+	}
+	return exec.LookPath(MCLI_PLUGIN_PREFIX + name)
+}
+
+// ListPlugins returns the plugins found on PATH sorted by name. When
+// more executables have the same name the first one is used.
+func ListPlugins() []Plugin {
+	var ans []Plugin
+	index := map[string]int{}
+
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, f := range files {
+			if !strings.HasPrefix(f.Name(), MCLI_PLUGIN_PREFIX) || f.IsDir() || !isExecutable(f) {
+				continue
+			}
+			name := strings.TrimPrefix(f.Name(), MCLI_PLUGIN_PREFIX)
+			if runtime.GOOS == "windows" {
+				name = strings.TrimSuffix(name, filepath.Ext(name))
+			}
+			if name == pluginSelf {
+				continue
+			}
+			path := filepath.Join(dir, f.Name())
+
+			if i, ok := index[name]; ok {
+				ans[i].Shadowed = append(ans[i].Shadowed, path)
+				continue
+			}
+			index[name] = len(ans)
+			ans = append(ans, Plugin{Name: name, Path: path})
+		}
+	}
+
+	sort.Slice(ans, func(i, j int) bool { return ans[i].Name < ans[j].Name })
+	return ans
+}
+
+func isExecutable(f os.FileInfo) bool {
+	if runtime.GOOS == "windows" {
+		return strings.EqualFold(filepath.Ext(f.Name()), ".exe")
+	}
+	return f.Mode()&0111 != 0
+}
+
+// PluginEnv returns the environment of the plugins: the one of the
+// cli with the active profile, master and token.
+func PluginEnv(viper *v.Viper) []string {
+	env := os.Environ()
+	for _, s := range pluginSettings {
+		if value := viper.GetString(s); value != "" {
+			env = append(env, MCLI_ENV_PREFIX+"_"+strings.ToUpper(s)+"="+value)
+		}
+	}
+	if ns := viper.GetString("profile-namespace"); ns != "" {
+		env = append(env, MCLI_ENV_PREFIX+"_NAMESPACE="+ns)
+	}
+	if self, err := os.Executable(); err == nil {
+		env = append(env, MCLI_ENV_PREFIX+"_BIN="+self)
+	}
+	return env
+}
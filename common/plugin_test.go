@@ -0,0 +1,91 @@
+// Copyright © 2019 Ettore Di Giacinto <mudler@gentoo.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package common_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	v "github.com/spf13/viper"
+
+	. "github.com/MottainaiCI/mottainai-cli/common"
+)
+
+var _ = Describe("Plugins", func() {
+	var dirs []string
+	var path string
+
+	BeforeEach(func() {
+		dirs = nil
+		for i := 0; i < 2; i++ {
+			dir, err := ioutil.TempDir("", "mcli-plugins")
+			Expect(err).ToNot(HaveOccurred())
+			dirs = append(dirs, dir)
+		}
+		plugins := map[string]os.FileMode{
+			filepath.Join(dirs[0], "mottainai-report"): 0755,
+			filepath.Join(dirs[0], "mottainai-cli"):    0755,
+			filepath.Join(dirs[0], "mottainai-notes"):  0644,
+			filepath.Join(dirs[1], "mottainai-report"): 0755,
+			filepath.Join(dirs[1], "mottainai-audit"):  0755,
+		}
+		for f, mode := range plugins {
+			Expect(ioutil.WriteFile(f, []byte("#!/bin/sh\n"), mode)).To(Succeed())
+		}
+
+		path = os.Getenv("PATH")
+		os.Setenv("PATH", strings.Join(dirs, string(os.PathListSeparator)))
+	})
+
+	AfterEach(func() {
+		os.Setenv("PATH", path)
+		for _, dir := range dirs {
+			os.RemoveAll(dir)
+		}
+	})
+
+	It("lists the executables with the plugin prefix", func() {
+		Expect(ListPlugins()).To(Equal([]Plugin{
+			{Name: "audit", Path: filepath.Join(dirs[1], "mottainai-audit")},
+			{Name: "report", Path: filepath.Join(dirs[0], "mottainai-report"),
+				Shadowed: []string{filepath.Join(dirs[1], "mottainai-report")}},
+		}))
+	})
+
+	It("finds the plugins on PATH", func() {
+		Expect(FindPlugin("report")).To(Equal(filepath.Join(dirs[0], "mottainai-report")))
+		_, err := FindPlugin("notes")
+		Expect(err).To(HaveOccurred())
+		_, err = FindPlugin("cli")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("supplies the settings of the profile to the plugins", func() {
+		viper := v.New()
+		viper.Set("master", "https://mottainai.example.com")
+		viper.Set("apikey", "secret")
+		viper.Set("profile-namespace", "team")
+
+		env := PluginEnv(viper)
+		Expect(env).To(ContainElement("MOTTAINAI_CLI_MASTER=https://mottainai.example.com"))
+		Expect(env).To(ContainElement("MOTTAINAI_CLI_APIKEY=secret"))
+		Expect(env).To(ContainElement("MOTTAINAI_CLI_NAMESPACE=team"))
+	})
+})
@@ -21,6 +21,14 @@ package common
 
 import (
 	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	path "path/filepath"
+	"strings"
+
+	v "github.com/spf13/viper"
+	yaml "gopkg.in/yaml.v2"
 )
 
 const (
@@ -29,7 +37,9 @@ const (
 	// NOTE: doesn't use $HOME because os.Mkdir doesn't resolve it.
 	MCLI_HOME_PATH  = ".config/mottainai"
 	MCLI_LOCAL_PATH = ".mottainai"
-	MCLI_HEADER     = `Mottainai CLI
+	// Project local profile available under MCLI_LOCAL_PATH.
+	MCLI_LOCAL_PROFILE = "profile.yaml"
+	MCLI_HEADER        = `Mottainai CLI
 Copyright (c) 2017-2019 Mottainai
 
 Command line interface for Mottainai clusters`
@@ -39,12 +49,45 @@ Command line interface for Mottainai clusters`
 //       object have public attribute
 
 type Profile struct {
-	Master string `mapstructure:"master"`
-	ApiKey string `mapstructure:"apikey"`
+	Master    string `mapstructure:"master" yaml:"master" json:"master"`
+	ApiKey    string `mapstructure:"apikey" yaml:"apikey,omitempty" json:"apikey,omitempty"`
+	Namespace string `mapstructure:"namespace" yaml:"namespace,omitempty" json:"namespace,omitempty"`
+
+	// Where the API key is stored when it isn't saved on the profiles file.
+	CredentialStore string `mapstructure:"credential_store" yaml:"credential_store,omitempty" json:"credential_store,omitempty"`
+
+	// TLS options used to connect to the master.
+	CACert             string `mapstructure:"cacert" yaml:"cacert,omitempty" json:"cacert,omitempty"`
+	ClientCert         string `mapstructure:"cert" yaml:"cert,omitempty" json:"cert,omitempty"`
+	ClientKey          string `mapstructure:"key" yaml:"key,omitempty" json:"key,omitempty"`
+	InsecureSkipVerify bool   `mapstructure:"insecure" yaml:"insecure,omitempty" json:"insecure,omitempty"`
+
+	// HTTP or SOCKS5 proxy used to reach the master.
+	Proxy string `mapstructure:"proxy" yaml:"proxy,omitempty" json:"proxy,omitempty"`
+
+	// Timeout of the API calls ( e.g. 30s ).
+	RequestTimeout string `mapstructure:"request_timeout" yaml:"request_timeout,omitempty" json:"request_timeout,omitempty"`
+
+	// Authentication mode, "token" ( default ), "macaroon" or "sso".
+	Auth string `mapstructure:"auth" yaml:"auth,omitempty" json:"auth,omitempty"`
+
+	// Identity provider used by "login --sso".
+	SSOIssuer   string `mapstructure:"sso_issuer" yaml:"sso_issuer,omitempty" json:"sso_issuer,omitempty"`
+	SSOClientID string `mapstructure:"sso_client_id" yaml:"sso_client_id,omitempty" json:"sso_client_id,omitempty"`
+
+	// Retry policy of the failed API calls, see RetryPolicy.
+	Retries    int    `mapstructure:"retries" yaml:"retries,omitempty" json:"retries,omitempty"`
+	RetryDelay string `mapstructure:"retry_delay" yaml:"retry_delay,omitempty" json:"retry_delay,omitempty"`
+
+	// Key used by profiles written by hand following the
+	// agent configuration syntax. See ProfileConf.Migrate().
+	LegacyApiKey string `mapstructure:"api_key" yaml:"-" json:"-"`
 }
 
 type ProfileConf struct {
 	Profiles map[string](Profile) `mapstructure:"profiles"`
+	// Name of the profile used when --profile is not supplied.
+	Current string `mapstructure:"current"`
 }
 
 func NewProfileConf() *ProfileConf {
@@ -67,7 +110,7 @@ func (p *ProfileConf) GetProfile(name string) (*Profile, error) {
 	return ans, nil
 }
 
-func (p *ProfileConf) AddProfile(name string, master string, apikey string) error {
+func (p *ProfileConf) AddProfile(name, master, apikey, namespace string) error {
 
 	if name == "" {
 		return errors.New("Invalid name")
@@ -81,12 +124,152 @@ func (p *ProfileConf) AddProfile(name string, master string, apikey string) erro
 	if p.Profiles == nil {
 		p.Profiles = make(map[string]Profile)
 	}
-	if apikey == "" {
-		p.Profiles[name] = Profile{Master: master}
-	} else {
-		p.Profiles[name] = Profile{Master: master, ApiKey: apikey}
+	p.Profiles[name] = Profile{Master: master, ApiKey: apikey, Namespace: namespace}
+
+	return nil
+}
+
+// Migrate converts profiles written with an old syntax to
+// the current one. It returns the list of migrated profiles.
+func (p *ProfileConf) Migrate() []string {
+	var ans []string
+
+	for name, profile := range p.Profiles {
+		if profile.LegacyApiKey == "" {
+			continue
+		}
+		if profile.ApiKey == "" {
+			profile.ApiKey = profile.LegacyApiKey
+		}
+		profile.LegacyApiKey = ""
+		p.Profiles[name] = profile
+		ans = append(ans, name)
+	}
+
+	return ans
+}
+
+// Write stores profiles on file f. If f is empty the file under
+// the user home directory is used.
+func (p *ProfileConf) Write(f string) (string, error) {
+	if f == "" {
+		f = fmt.Sprintf("%s/%s/%s.yml",
+			GetHomeDir(), MCLI_HOME_PATH, MCLI_CONFIG_NAME)
+	}
+
+	// Create directory where save file if doesn't exists
+	if _, err := os.Stat(path.Dir(f)); os.IsNotExist(err) {
+		if err = os.MkdirAll(path.Dir(f), 0760); err != nil {
+			return f, err
+		}
+	}
+
+	if IsEncryptedFile(f) {
+		return f, p.writeEncrypted(f)
+	}
+
+	// Create new viper configuration to avoid
+	// write of command line arguments/settings
+	viper := v.New()
+	viper.SetConfigType("yaml")
+	for k, val := range p.toMap() {
+		viper.Set(k, val)
+	}
+
+	return f, viper.WriteConfigAs(f)
+}
+
+func (p *ProfileConf) toMap() map[string]interface{} {
+	ans := map[string]interface{}{"profiles": p.Profiles}
+	if p.Current != "" {
+		ans["current"] = p.Current
+	}
+	return ans
+}
+
+func (p *ProfileConf) ToYaml() ([]byte, error) {
+	return yaml.Marshal(p.toMap())
+}
+
+func (p *ProfileConf) writeEncrypted(f string) error {
+	data, err := p.ToYaml()
+	if err != nil {
+		return err
+	}
+
+	passphrase, err := GetPassphrase()
+	if err != nil {
+		return err
+	}
+
+	data, err = Encrypt(data, passphrase)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(f, data, 0600)
+}
+
+// Export returns a copy of the profiles that doesn't depend on
+// local credential stores. API keys are removed when withSecrets
+// is false.
+func (p *ProfileConf) Export(withSecrets bool) (*ProfileConf, error) {
+	ans := NewProfileConf()
+	ans.Current = p.Current
+
+	for name, profile := range p.Profiles {
+		if withSecrets {
+			apikey, err := profile.ResolveApiKey(name)
+			if err != nil {
+				return nil, err
+			}
+			profile.ApiKey = apikey
+		} else {
+			profile.ApiKey = ""
+		}
+		profile.LegacyApiKey = ""
+		profile.CredentialStore = CREDENTIAL_STORE_PROFILE
+		ans.Profiles[name] = profile
+	}
+
+	return ans, nil
+}
+
+// Import adds the profiles of conf. Existing profiles are replaced
+// only if overwrite is true. It returns the list of imported profiles.
+func (p *ProfileConf) Import(conf *ProfileConf, overwrite bool) []string {
+	var ans []string
+
+	if p.Profiles == nil {
+		p.Profiles = make(map[string]Profile)
+	}
+
+	for name, profile := range conf.Profiles {
+		if _, ok := p.Profiles[name]; ok && !overwrite {
+			continue
+		}
+		p.Profiles[name] = profile
+		ans = append(ans, name)
+	}
+
+	if p.Current == "" && conf.Current != "" {
+		if _, ok := p.Profiles[conf.Current]; ok {
+			p.Current = conf.Current
+		}
 	}
 
+	return ans
+}
+
+func (p *ProfileConf) GetCurrent() string {
+	return p.Current
+}
+
+func (p *ProfileConf) SetCurrent(name string) error {
+	if _, ok := p.Profiles[name]; !ok {
+		return errors.New("No profile with name " + name)
+	}
+	p.Current = name
 	return nil
 }
 
@@ -97,6 +280,9 @@ func (p *ProfileConf) RemoveProfile(name string) *Profile {
 	if ok {
 		ans = &profile
 		delete(p.Profiles, name)
+		if p.Current == name {
+			p.Current = ""
+		}
 	}
 
 	return ans
@@ -107,5 +293,151 @@ func (p *Profile) GetMaster() string {
 }
 
 func (p *Profile) GetApiKey() string {
+	if p.ApiKey == "" {
+		return p.LegacyApiKey
+	}
 	return p.ApiKey
 }
+
+func (p *Profile) GetNamespace() string {
+	return p.Namespace
+}
+
+// SetTLSOptions stores the TLS options of the profile on viper
+// when they aren't already defined.
+func (p *Profile) SetTLSOptions(viper *v.Viper) {
+	options := map[string]string{
+		"cacert": p.CACert,
+		"cert":   p.ClientCert,
+		"key":    p.ClientKey,
+	}
+	for k, val := range options {
+		if val != "" && viper.GetString(k) == "" {
+			viper.Set(k, val)
+		}
+	}
+	if p.InsecureSkipVerify {
+		viper.Set("insecure", true)
+	}
+}
+
+// LocalProfile is the profile defined inside a project
+// on the file .mottainai/profile.yaml.
+type LocalProfile struct {
+	Profile `mapstructure:",squash"`
+	// Name of the global profile to extend.
+	Name string `mapstructure:"profile"`
+}
+
+// FindLocalProfile searches the project local profile from the
+// current directory up to the root. It returns nil if there
+// isn't a local profile.
+func FindLocalProfile() (*LocalProfile, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		f := path.Join(dir, MCLI_LOCAL_PATH, MCLI_LOCAL_PROFILE)
+		if _, err := os.Stat(f); err == nil {
+			return LoadLocalProfile(f)
+		}
+
+		parent := path.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+func LoadLocalProfile(f string) (*LocalProfile, error) {
+	var ans LocalProfile
+
+	viper := v.New()
+	viper.SetConfigFile(f)
+	if err := viper.ReadInConfig(); err != nil {
+		return nil, err
+	}
+	if err := viper.Unmarshal(&ans); err != nil {
+		return nil, err
+	}
+
+	return &ans, nil
+}
+
+// ChangesEndpoint returns true when p sends the requests to a
+// different master than the global profile, or through a different
+// proxy or TLS configuration.
+func (p *LocalProfile) ChangesEndpoint(global *Profile) bool {
+	var g Profile
+	if global != nil {
+		g = *global
+	}
+
+	return (p.Master != "" && strings.TrimRight(p.Master, "/") != strings.TrimRight(g.Master, "/")) ||
+		(p.Proxy != "" && p.Proxy != g.Proxy) ||
+		(p.CACert != "" && p.CACert != g.CACert) ||
+		(p.InsecureSkipVerify && !g.InsecureSkipVerify)
+}
+
+// MergeOver returns a new profile with the values of p that
+// override the values of the global profile. The credentials of
+// the global profile are kept only when p doesn't change the
+// endpoint: a project could otherwise send the API key of the
+// user to another master. In that case p must supply its own key.
+func (p *LocalProfile) MergeOver(global *Profile) *Profile {
+	var ans Profile
+
+	if global != nil {
+		ans = *global
+	}
+	if p.ChangesEndpoint(global) {
+		ans.ApiKey = ""
+		ans.LegacyApiKey = ""
+		ans.CredentialStore = CREDENTIAL_STORE_PROFILE
+		ans.ClientCert = ""
+		ans.ClientKey = ""
+	}
+
+	if p.Master != "" {
+		ans.Master = p.Master
+	}
+	if p.GetApiKey() != "" {
+		ans.ApiKey = p.GetApiKey()
+		ans.LegacyApiKey = ""
+		ans.CredentialStore = CREDENTIAL_STORE_PROFILE
+	} else if p.CredentialStore != "" && !p.ChangesEndpoint(global) {
+		ans.CredentialStore = p.CredentialStore
+	}
+	if p.Namespace != "" {
+		ans.Namespace = p.Namespace
+	}
+	if p.CACert != "" {
+		ans.CACert = p.CACert
+	}
+	if p.ClientCert != "" {
+		ans.ClientCert = p.ClientCert
+	}
+	if p.ClientKey != "" {
+		ans.ClientKey = p.ClientKey
+	}
+	if p.InsecureSkipVerify {
+		ans.InsecureSkipVerify = true
+	}
+	if p.Proxy != "" {
+		ans.Proxy = p.Proxy
+	}
+	if p.RequestTimeout != "" {
+		ans.RequestTimeout = p.RequestTimeout
+	}
+	if p.Retries > 0 {
+		ans.Retries = p.Retries
+	}
+	if p.RetryDelay != "" {
+		ans.RetryDelay = p.RetryDelay
+	}
+
+	return &ans
+}
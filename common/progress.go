@@ -0,0 +1,202 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package common
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+const progressBarWidth = 30
+
+// Progress renders a progress bar of a set of operations
+// on stderr when it is a terminal.
+type Progress struct {
+	Label   string
+	Total   int
+	Writer  io.Writer
+	Enabled bool
+
+	mutex sync.Mutex
+	done  int
+	bytes int64
+}
+
+func NewProgress(label string, total int) *Progress {
+	return &Progress{
+		Label:   label,
+		Total:   total,
+		Writer:  os.Stderr,
+		Enabled: terminal.IsTerminal(int(os.Stderr.Fd())),
+	}
+}
+
+// Add updates the transferred bytes.
+func (p *Progress) Add(n int64) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.bytes += n
+	p.render()
+}
+
+// Done marks an operation as completed.
+func (p *Progress) Done() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.done++
+	p.render()
+}
+
+// Printf prints a message without breaking the progress bar.
+func (p *Progress) Printf(format string, args ...interface{}) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.Enabled {
+		fmt.Fprint(p.Writer, "\r\033[K")
+	}
+	fmt.Fprintf(p.Writer, format, args...)
+	p.render()
+}
+
+// Finish terminates the progress bar line.
+func (p *Progress) Finish() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.Enabled {
+		p.render()
+		fmt.Fprintln(p.Writer)
+	}
+}
+
+func (p *Progress) render() {
+	if !p.Enabled {
+		return
+	}
+
+	filled := progressBarWidth
+	if p.Total > 0 {
+		filled = p.done * progressBarWidth / p.Total
+	}
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", progressBarWidth-filled)
+	fmt.Fprintf(p.Writer, "\r\033[K%s [%s] %d/%d %s",
+		p.Label, bar, p.done, p.Total, HumanSize(p.bytes))
+}
+
+// HumanSize returns the size in a human readable format.
+func HumanSize(size int64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	s := float64(size)
+	i := 0
+	for ; s >= 1024 && i < len(units)-1; i++ {
+		s /= 1024
+	}
+	if i == 0 {
+		return fmt.Sprintf("%d %s", size, units[i])
+	}
+	return fmt.Sprintf("%.1f %s", s, units[i])
+}
+
+// TransferProgress renders a progress bar of a single transfer
+// with its rate and the estimated time to completion.
+type TransferProgress struct {
+	Label   string
+	Total   int64
+	Writer  io.Writer
+	Enabled bool
+
+	mutex    sync.Mutex
+	bytes    int64
+	start    time.Time
+	rendered time.Time
+}
+
+// Interval between two updates of the transfer progress bar.
+const transferRefresh = 200 * time.Millisecond
+
+func NewTransferProgress(label string, total int64) *TransferProgress {
+	return &TransferProgress{
+		Label:   label,
+		Total:   total,
+		Writer:  os.Stderr,
+		Enabled: terminal.IsTerminal(int(os.Stderr.Fd())),
+		start:   time.Now(),
+	}
+}
+
+// Add updates the transferred bytes.
+func (p *TransferProgress) Add(n int64) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.bytes += n
+	if time.Since(p.rendered) >= transferRefresh {
+		p.render()
+	}
+}
+
+// Finish renders the final state and terminates the progress bar line.
+func (p *TransferProgress) Finish() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.Enabled {
+		p.render()
+		fmt.Fprintln(p.Writer)
+	}
+}
+
+func (p *TransferProgress) render() {
+	if !p.Enabled {
+		return
+	}
+	p.rendered = time.Now()
+
+	elapsed := time.Since(p.start)
+	var rate float64
+	if elapsed > 0 {
+		rate = float64(p.bytes) / elapsed.Seconds()
+	}
+
+	// Without the total size only the transferred bytes are known.
+	if p.Total <= 0 {
+		fmt.Fprintf(p.Writer, "\r\033[K%s %s %s/s", p.Label, HumanSize(p.bytes), HumanSize(int64(rate)))
+		return
+	}
+
+	filled := int(p.bytes * progressBarWidth / p.Total)
+	percent := int(p.bytes * 100 / p.Total)
+	if filled > progressBarWidth {
+		filled = progressBarWidth
+	}
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", progressBarWidth-filled)
+
+	eta := "--"
+	if rate > 0 && p.Total >= p.bytes {
+		eta = time.Duration(float64(p.Total-p.bytes) / rate * float64(time.Second)).Round(time.Second).String()
+	}
+
+	fmt.Fprintf(p.Writer, "\r\033[K%s [%s] %3d%% %s/%s %s/s ETA %s",
+		p.Label, bar, percent, HumanSize(p.bytes), HumanSize(p.Total), HumanSize(int64(rate)), eta)
+}
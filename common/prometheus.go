@@ -0,0 +1,96 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package common
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PromMetric is a metric rendered in the Prometheus text
+// exposition format.
+type PromMetric struct {
+	Name    string
+	Help    string
+	Type    string
+	Samples []PromSample
+}
+
+type PromSample struct {
+	Labels map[string]string
+	Value  float64
+}
+
+func NewPromMetric(name, typ, help string) *PromMetric {
+	return &PromMetric{Name: name, Type: typ, Help: help}
+}
+
+// Add appends a sample with the labels, as name and value pairs.
+func (m *PromMetric) Add(value float64, labels ...string) {
+	l := make(map[string]string)
+	for i := 0; i+1 < len(labels); i += 2 {
+		l[labels[i]] = labels[i+1]
+	}
+	m.Samples = append(m.Samples, PromSample{Labels: l, Value: value})
+}
+
+// WritePrometheus writes the metrics in the Prometheus text
+// exposition format.
+func WritePrometheus(w io.Writer, metrics []*PromMetric) error {
+	var buf bytes.Buffer
+
+	for _, m := range metrics {
+		if m.Help != "" {
+			fmt.Fprintf(&buf, "# HELP %s %s\n", m.Name, strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(m.Help))
+		}
+		if m.Type != "" {
+			fmt.Fprintf(&buf, "# TYPE %s %s\n", m.Name, m.Type)
+		}
+		for _, s := range m.Samples {
+			buf.WriteString(m.Name)
+			if len(s.Labels) > 0 {
+				names := []string{}
+				for k := range s.Labels {
+					names = append(names, k)
+				}
+				sort.Strings(names)
+
+				labels := []string{}
+				for _, k := range names {
+					labels = append(labels, k+"="+promLabelValue(s.Labels[k]))
+				}
+				buf.WriteString("{" + strings.Join(labels, ",") + "}")
+			}
+			buf.WriteString(" " + strconv.FormatFloat(s.Value, 'g', -1, 64) + "\n")
+		}
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func promLabelValue(v string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v) + `"`
+}
@@ -0,0 +1,41 @@
+// Copyright © 2019 Ettore Di Giacinto <mudler@gentoo.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package common_test
+
+import (
+	"bytes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/MottainaiCI/mottainai-cli/common"
+)
+
+var _ = Describe("WritePrometheus", func() {
+	It("renders the samples with sorted and escaped labels", func() {
+		m := NewPromMetric("mottainai_tasks", "gauge", "Number of tasks")
+		m.Add(3, "status", "done", "queue", "a\"b")
+		m.Add(0.5)
+
+		var buf bytes.Buffer
+		Expect(WritePrometheus(&buf, []*PromMetric{m})).ToNot(HaveOccurred())
+		Expect(buf.String()).To(Equal(`# HELP mottainai_tasks Number of tasks
+# TYPE mottainai_tasks gauge
+mottainai_tasks{queue="a\"b",status="done"} 3
+mottainai_tasks 0.5
+`))
+	})
+})
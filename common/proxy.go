@@ -0,0 +1,95 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package common
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// NewProxyFunc returns the function that selects the proxy of the
+// requests. Without an explicit proxy the HTTP_PROXY, HTTPS_PROXY and
+// NO_PROXY environment variables are used. An explicit proxy could
+// be an HTTP or a SOCKS5 proxy ( e.g. socks5://127.0.0.1:1080 ) and
+// is used for all hosts but the ones listed on NO_PROXY.
+func NewProxyFunc(proxy string) (func(*http.Request) (*url.URL, error), error) {
+	if proxy == "" {
+		return http.ProxyFromEnvironment, nil
+	}
+
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, errors.New("Unsupported proxy scheme " + u.Scheme +
+			", supported schemes are: http, https, socks5, socks5h")
+	}
+
+	noProxy := os.Getenv("NO_PROXY")
+	if noProxy == "" {
+		noProxy = os.Getenv("no_proxy")
+	}
+
+	return func(r *http.Request) (*url.URL, error) {
+		if MatchNoProxy(noProxy, r.URL.Host) {
+			return nil, nil
+		}
+		return u, nil
+	}, nil
+}
+
+// MatchNoProxy returns true if host is excluded from the proxy by
+// the noProxy list. The list contains host names, domains
+// ( e.g. .example.com ), IP addresses or "*" for all hosts.
+func MatchNoProxy(noProxy, host string) bool {
+	hostname := host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		hostname = h
+	}
+	hostname = strings.ToLower(hostname)
+
+	for _, p := range strings.Split(noProxy, ",") {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if p == "" {
+			continue
+		}
+		if p == "*" {
+			return true
+		}
+		if strings.Contains(p, ":") && p == strings.ToLower(host) {
+			return true
+		}
+		if h, _, err := net.SplitHostPort(p); err == nil {
+			p = h
+		}
+		if hostname == strings.TrimPrefix(p, ".") || strings.HasSuffix(hostname, "."+strings.TrimPrefix(p, ".")) {
+			return true
+		}
+	}
+	return false
+}
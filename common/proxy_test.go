@@ -0,0 +1,56 @@
+// Copyright © 2019 Ettore Di Giacinto <mudler@gentoo.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package common_test
+
+import (
+	"net/http"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/MottainaiCI/mottainai-cli/common"
+)
+
+var _ = Describe("Proxy", func() {
+	Describe("MatchNoProxy", func() {
+		It("matches hosts, domains and ports", func() {
+			list := "localhost, .example.com,10.0.0.1,master:8443"
+			Expect(MatchNoProxy(list, "localhost:8080")).To(BeTrue())
+			Expect(MatchNoProxy(list, "ci.example.com")).To(BeTrue())
+			Expect(MatchNoProxy(list, "example.com")).To(BeTrue())
+			Expect(MatchNoProxy(list, "10.0.0.1:80")).To(BeTrue())
+			Expect(MatchNoProxy(list, "master:8443")).To(BeTrue())
+			Expect(MatchNoProxy(list, "notexample.com")).To(BeFalse())
+			Expect(MatchNoProxy("*", "any")).To(BeTrue())
+		})
+	})
+
+	Describe("NewProxyFunc", func() {
+		It("uses the explicit proxy", func() {
+			f, err := NewProxyFunc("socks5://127.0.0.1:1080")
+			Expect(err).ToNot(HaveOccurred())
+			r, _ := http.NewRequest("GET", "http://master.test/api", nil)
+			u, err := f(r)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(u.String()).To(Equal("socks5://127.0.0.1:1080"))
+		})
+
+		It("rejects unsupported schemes", func() {
+			_, err := NewProxyFunc("ftp://proxy")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
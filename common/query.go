@@ -0,0 +1,292 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Query is a filter expression in the form:
+//
+//	status=error AND image~alpine AND created>2024-01-01
+//
+// Conditions are joined with AND and OR (AND has precedence)
+// and can be negated with NOT. Supported operators are
+// =, !=, ~ (regex match), !~, >, >=, < and <=.
+type Query struct {
+	// Any of the groups must match, every condition of a group must match.
+	groups [][]queryCondition
+	// Aliases maps the names used on the query to the fields
+	// of the JSON representation of the objects.
+	Aliases map[string]string
+}
+
+type queryCondition struct {
+	Field    string
+	Operator string
+	Value    string
+	Negate   bool
+
+	re *regexp.Regexp
+}
+
+type queryToken struct {
+	value  string
+	quoted bool
+}
+
+// Layouts of the dates accepted by the queries and the
+// layout used by the master for the timestamps.
+var queryTimeLayouts = []string{"20060102150405", time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"}
+
+func isQueryOperator(r rune) bool {
+	return r == '=' || r == '!' || r == '~' || r == '<' || r == '>'
+}
+
+func tokenizeQuery(q string) ([]queryToken, error) {
+	var ans []queryToken
+	runes := []rune(q)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '"' || r == '\'':
+			end := i + 1
+			for end < len(runes) && runes[end] != r {
+				end++
+			}
+			if end == len(runes) {
+				return nil, errors.New("Unterminated string on query")
+			}
+			ans = append(ans, queryToken{value: string(runes[i+1 : end]), quoted: true})
+			i = end + 1
+		case isQueryOperator(r):
+			end := i
+			for end < len(runes) && isQueryOperator(runes[end]) {
+				end++
+			}
+			ans = append(ans, queryToken{value: string(runes[i:end])})
+			i = end
+		default:
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) &&
+				!isQueryOperator(runes[end]) && runes[end] != '"' && runes[end] != '\'' {
+				end++
+			}
+			ans = append(ans, queryToken{value: string(runes[i:end])})
+			i = end
+		}
+	}
+
+	return ans, nil
+}
+
+// ParseQuery parses a query expression.
+func ParseQuery(q string) (*Query, error) {
+	tokens, err := tokenizeQuery(q)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, errors.New("Empty query")
+	}
+
+	ans := &Query{Aliases: map[string]string{}}
+	group := []queryCondition{}
+	keyword := func(t queryToken, k string) bool {
+		return !t.quoted && strings.EqualFold(t.value, k)
+	}
+
+	for i := 0; i < len(tokens); {
+		cond := queryCondition{}
+		if keyword(tokens[i], "NOT") {
+			cond.Negate = true
+			i++
+		}
+		if i+2 >= len(tokens) {
+			return nil, fmt.Errorf("Incomplete condition at position %d", i+1)
+		}
+		cond.Field, cond.Operator, cond.Value = tokens[i].value, tokens[i+1].value, tokens[i+2].value
+		switch cond.Operator {
+		case "=", "!=", ">", ">=", "<", "<=":
+		case "~", "!~":
+			if cond.re, err = regexp.Compile(cond.Value); err != nil {
+				return nil, errors.New("Invalid regex " + cond.Value + ": " + err.Error())
+			}
+		default:
+			return nil, errors.New("Invalid operator " + cond.Operator)
+		}
+		group = append(group, cond)
+		i += 3
+
+		if i == len(tokens) {
+			break
+		}
+		switch {
+		case keyword(tokens[i], "AND"):
+		case keyword(tokens[i], "OR"):
+			ans.groups = append(ans.groups, group)
+			group = []queryCondition{}
+		default:
+			return nil, errors.New("Expected AND or OR instead of " + tokens[i].value)
+		}
+		i++
+		if i == len(tokens) {
+			return nil, errors.New("Missing condition after " + tokens[i-1].value)
+		}
+	}
+	ans.groups = append(ans.groups, group)
+
+	return ans, nil
+}
+
+// Match returns true if the JSON representation of obj
+// satisfies the query.
+func (q *Query) Match(obj interface{}) (bool, error) {
+	var data map[string]interface{}
+
+	b, err := json.Marshal(obj)
+	if err != nil {
+		return false, err
+	}
+	if err = json.Unmarshal(b, &data); err != nil {
+		return false, errors.New("Query supports only objects")
+	}
+
+	for _, group := range q.groups {
+		match := true
+		for _, c := range group {
+			field := c.Field
+			if alias, ok := q.Aliases[field]; ok {
+				field = alias
+			}
+			value, ok := data[field]
+			if !ok {
+				return false, errors.New("Invalid query field " + c.Field)
+			}
+			if c.match(value) == c.Negate {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (c *queryCondition) match(value interface{}) bool {
+	var s string
+	switch v := value.(type) {
+	case nil:
+		s = ""
+	case string:
+		s = v
+	case []interface{}:
+		parts := make([]string, 0, len(v))
+		for _, p := range v {
+			parts = append(parts, fmt.Sprintf("%v", p))
+		}
+		s = strings.Join(parts, ",")
+	default:
+		s = fmt.Sprintf("%v", v)
+	}
+
+	switch c.Operator {
+	case "=":
+		return s == c.Value
+	case "!=":
+		return s != c.Value
+	case "~":
+		return c.re.MatchString(s)
+	case "!~":
+		return !c.re.MatchString(s)
+	}
+
+	cmp, ok := compareQueryValues(s, c.Value)
+	if !ok {
+		return false
+	}
+	switch c.Operator {
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	default:
+		return cmp <= 0
+	}
+}
+
+// compareQueryValues compares a and b as numbers, as dates or
+// as strings. ok is false when a is empty.
+func compareQueryValues(a, b string) (cmp int, ok bool) {
+	if a == "" {
+		return 0, false
+	}
+
+	if x, err := strconv.ParseFloat(a, 64); err == nil {
+		if y, err := strconv.ParseFloat(b, 64); err == nil {
+			switch {
+			case x < y:
+				return -1, true
+			case x > y:
+				return 1, true
+			}
+			return 0, true
+		}
+	}
+
+	if x, err := parseQueryTime(a); err == nil {
+		if y, err := parseQueryTime(b); err == nil {
+			switch {
+			case x.Before(y):
+				return -1, true
+			case x.After(y):
+				return 1, true
+			}
+			return 0, true
+		}
+	}
+
+	return strings.Compare(a, b), true
+}
+
+func parseQueryTime(s string) (time.Time, error) {
+	for _, layout := range queryTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.New("Invalid date " + s)
+}
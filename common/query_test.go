@@ -0,0 +1,55 @@
+// Copyright © 2019 Ettore Di Giacinto <mudler@gentoo.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package common_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/MottainaiCI/mottainai-cli/common"
+)
+
+type queryItem struct {
+	Status  string `json:"status"`
+	Image   string `json:"image"`
+	Created string `json:"created_time"`
+}
+
+var _ = Describe("Query", func() {
+	item := queryItem{Status: "error", Image: "alpine:3.9", Created: "20240105103000"}
+
+	match := func(q string) bool {
+		query, err := ParseQuery(q)
+		Expect(err).ToNot(HaveOccurred())
+		query.Aliases = map[string]string{"created": "created_time"}
+		ans, err := query.Match(item)
+		Expect(err).ToNot(HaveOccurred())
+		return ans
+	}
+
+	It("matches conditions joined by AND and OR", func() {
+		Expect(match("status=error AND image~alpine AND created>2024-01-01")).To(BeTrue())
+		Expect(match("status = error AND created < '2024-01-01'")).To(BeFalse())
+		Expect(match("status=done OR NOT image!~^alpine")).To(BeTrue())
+	})
+
+	It("rejects invalid queries", func() {
+		for _, q := range []string{"", "status", "status=error AND", "status=error image=x", "status=>x"} {
+			_, err := ParseQuery(q)
+			Expect(err).To(HaveOccurred(), q)
+		}
+	})
+})
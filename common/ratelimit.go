@@ -0,0 +1,117 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package common
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ParseRate parses a rate as <requests>/<unit>, where unit is s, m
+// or h ( e.g. 10/s or 100/m ). A rate without unit is per second.
+// It returns the number of requests per second.
+func ParseRate(s string) (float64, error) {
+	unit := time.Second
+	n := s
+	if i := strings.Index(s, "/"); i >= 0 {
+		n = s[:i]
+		switch s[i+1:] {
+		case "s":
+		case "m":
+			unit = time.Minute
+		case "h":
+			unit = time.Hour
+		default:
+			return 0, errors.New("Invalid rate unit " + s[i+1:] + ", valid units are: s, m, h")
+		}
+	}
+
+	v, err := strconv.ParseFloat(n, 64)
+	if err != nil || v <= 0 {
+		return 0, errors.New("Invalid rate " + s)
+	}
+	return v / unit.Seconds(), nil
+}
+
+// RateLimiter is a token bucket that permits Rate requests per
+// second with bursts of Burst requests.
+type RateLimiter struct {
+	Rate  float64
+	Burst float64
+
+	mutex  sync.Mutex
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{Rate: rate, Burst: float64(burst), tokens: float64(burst), now: time.Now}
+}
+
+// Reserve takes a token from the bucket and returns the time
+// to wait before it's available.
+func (l *RateLimiter) Reserve() time.Duration {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	if !l.last.IsZero() {
+		l.tokens += now.Sub(l.last).Seconds() * l.Rate
+		if l.tokens > l.Burst {
+			l.tokens = l.Burst
+		}
+	}
+	l.last = now
+
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.Rate * float64(time.Second))
+}
+
+// RateLimitTransport delays the requests that exceed the
+// rate of the limiter.
+type RateLimitTransport struct {
+	Next    http.RoundTripper
+	Limiter *RateLimiter
+}
+
+func (t *RateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if wait := t.Limiter.Reserve(); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+	return t.Next.RoundTrip(req)
+}
@@ -0,0 +1,50 @@
+// Copyright © 2019 Ettore Di Giacinto <mudler@gentoo.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package common_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/MottainaiCI/mottainai-cli/common"
+)
+
+var _ = Describe("RateLimit", func() {
+	Describe("ParseRate", func() {
+		It("converts rates to requests per second", func() {
+			Expect(ParseRate("10/s")).To(Equal(10.0))
+			Expect(ParseRate("120/m")).To(Equal(2.0))
+			Expect(ParseRate("5")).To(Equal(5.0))
+
+			_, err := ParseRate("10/d")
+			Expect(err).To(HaveOccurred())
+			_, err = ParseRate("0/s")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("RateLimiter", func() {
+		It("delays requests over the burst", func() {
+			l := NewRateLimiter(1, 2)
+			Expect(l.Reserve()).To(BeZero())
+			Expect(l.Reserve()).To(BeZero())
+			Expect(l.Reserve()).To(BeNumerically("~", time.Second, 50*time.Millisecond))
+			Expect(l.Reserve()).To(BeNumerically("~", 2*time.Second, 50*time.Millisecond))
+		})
+	})
+})
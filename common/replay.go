@@ -0,0 +1,233 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package common
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Responses of the API bigger than this size aren't recorded.
+const recordMaxBody = 1 << 20
+
+// Interaction is a request to the master and its response, as stored
+// on the session files of --record and --replay.
+type Interaction struct {
+	Method string `json:"method"`
+	// Path and query of the request, the master isn't stored so a
+	// session can be replayed with any --master.
+	URL string `json:"url"`
+	// Request payload, without secrets and only for reference.
+	Request string `json:"request,omitempty"`
+
+	Status   int         `json:"status,omitempty"`
+	Header   http.Header `json:"header,omitempty"`
+	Body     string      `json:"body,omitempty"`
+	Encoding string      `json:"encoding,omitempty"`
+	Error    string      `json:"error,omitempty"`
+	// Set when the body isn't recorded, e.g. for artefacts.
+	Omitted bool `json:"omitted,omitempty"`
+}
+
+// Session is the list of the interactions of a command.
+type Session struct {
+	Interactions []*Interaction `json:"interactions"`
+}
+
+func interactionURL(req *http.Request) string {
+	u := *req.URL
+	u.Scheme = ""
+	u.Host = ""
+	u.User = nil
+	return redactURL(&u)
+}
+
+// ReadSession reads a session file written by RecordTransport.
+func ReadSession(file string) (*Session, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	s := &Session{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("Invalid session file %s: %s", file, err.Error())
+	}
+	return s, nil
+}
+
+// RecordTransport stores the interactions on File. The file is
+// written after every response because commands can exit at any
+// point. Only the JSON responses of the API are stored, without the
+// values of secret fields as in RedactPayload. Other bodies, e.g.
+// artefacts and logs, are passed to the caller without recording
+// them and can't be replayed.
+type RecordTransport struct {
+	Next http.RoundTripper
+	File string
+
+	mutex   sync.Mutex
+	session Session
+}
+
+func (t *RecordTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	i := &Interaction{Method: req.Method, URL: interactionURL(req)}
+	if req.Body != nil && req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			data, _ := ioutil.ReadAll(io.LimitReader(body, debugBodySize))
+			body.Close()
+			i.Request = RedactPayload(req.Header.Get("Content-Type"), data)
+		}
+	}
+
+	resp, err := t.Next.RoundTrip(req)
+	if err != nil {
+		i.Error = err.Error()
+		if werr := t.add(i); werr != nil {
+			return nil, werr
+		}
+		return nil, err
+	}
+
+	i.Status = resp.StatusCode
+	i.Header = cloneHeader(resp.Header)
+	i.Header.Del("Set-Cookie")
+
+	if !strings.Contains(req.URL.Path, cacheRoutes) {
+		i.Omitted = true
+	} else {
+		data, err := ioutil.ReadAll(io.LimitReader(resp.Body, recordMaxBody+1))
+		if err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+		if len(data) > recordMaxBody || (len(data) > 0 && !json.Valid(bytes.TrimSpace(data))) {
+			i.Omitted = true
+			resp.Body = &multiReadCloser{Reader: io.MultiReader(bytes.NewReader(data), resp.Body), Closer: resp.Body}
+		} else {
+			resp.Body.Close()
+			resp.Body = ioutil.NopCloser(bytes.NewReader(data))
+			if len(data) > 0 {
+				i.Body = RedactPayload("application/json", data)
+			}
+		}
+	}
+	if err := t.add(i); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (t *RecordTransport) add(i *Interaction) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.session.Interactions = append(t.session.Interactions, i)
+
+	data, err := json.MarshalIndent(&t.session, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(t.File, data, 0600)
+}
+
+// ReplayTransport returns the responses of a recorded session without
+// contacting the master. The requests are matched by method and URL
+// in the recorded order. When a request is repeated more times than
+// recorded, e.g. while polling, the last response is returned again.
+type ReplayTransport struct {
+	Session *Session
+
+	mutex sync.Mutex
+	used  map[*Interaction]bool
+}
+
+func NewReplayTransport(file string) (*ReplayTransport, error) {
+	s, err := ReadSession(file)
+	if err != nil {
+		return nil, err
+	}
+	return &ReplayTransport{Session: s, used: map[*Interaction]bool{}}, nil
+}
+
+func (t *ReplayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	i := t.match(req.Method, interactionURL(req))
+	if i == nil {
+		return nil, fmt.Errorf("No recorded response for %s %s", req.Method, interactionURL(req))
+	}
+	if i.Error != "" {
+		return nil, errors.New(i.Error)
+	}
+	if i.Omitted {
+		return nil, fmt.Errorf("The response of %s %s isn't recorded", req.Method, interactionURL(req))
+	}
+
+	data := []byte(i.Body)
+	if i.Encoding == "base64" {
+		var err error
+		if data, err = base64.StdEncoding.DecodeString(i.Body); err != nil {
+			return nil, err
+		}
+	}
+
+	header := http.Header{}
+	for k, v := range i.Header {
+		header[k] = append([]string(nil), v...)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", i.Status, http.StatusText(i.Status)),
+		StatusCode:    i.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(data)),
+		ContentLength: int64(len(data)),
+		Request:       req,
+	}, nil
+}
+
+func (t *ReplayTransport) match(method, url string) *Interaction {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	var last *Interaction
+	for _, i := range t.Session.Interactions {
+		if i.Method != method || i.URL != url {
+			continue
+		}
+		if !t.used[i] {
+			t.used[i] = true
+			return i
+		}
+		last = i
+	}
+	return last
+}
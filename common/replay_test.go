@@ -0,0 +1,114 @@
+// Copyright © 2019 Ettore Di Giacinto <mudler@gentoo.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package common_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/MottainaiCI/mottainai-cli/common"
+)
+
+var _ = Describe("Record and replay", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "mcli-session")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	get := func(client *http.Client, url string) string {
+		resp, err := client.Get(url)
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+		data, err := ioutil.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		return resp.Status + " " + string(data)
+	}
+
+	It("replays the recorded responses in order", func() {
+		calls := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			if r.URL.Path == "/api/missing" {
+				w.WriteHeader(http.StatusNotFound)
+			}
+			w.Write([]byte(`{"id":"` + r.URL.Path + " " + strconv.Itoa(calls) + `"}`))
+		}))
+		session := filepath.Join(dir, "session.json")
+
+		client := &http.Client{Transport: &RecordTransport{Next: &http.Transport{}, File: session}}
+		Expect(get(client, server.URL+"/api/tasks")).To(Equal(`200 OK {"id":"/api/tasks 1"}`))
+		Expect(get(client, server.URL+"/api/tasks")).To(Equal(`200 OK {"id":"/api/tasks 2"}`))
+		Expect(get(client, server.URL+"/api/missing")).To(Equal(`404 Not Found {"id":"/api/missing 3"}`))
+		server.Close()
+
+		replay, err := NewReplayTransport(session)
+		Expect(err).ToNot(HaveOccurred())
+		client = &http.Client{Transport: replay}
+		// The master isn't part of the recorded requests.
+		Expect(get(client, "http://other/api/tasks")).To(Equal(`200 OK {"id":"/api/tasks 1"}`))
+		Expect(get(client, "http://other/api/missing")).To(Equal(`404 Not Found {"id":"/api/missing 3"}`))
+		Expect(get(client, "http://other/api/tasks")).To(Equal(`200 OK {"id":"/api/tasks 2"}`))
+		Expect(get(client, "http://other/api/tasks")).To(Equal(`200 OK {"id":"/api/tasks 2"}`))
+
+		_, err = client.Get("http://other/api/nodes")
+		Expect(err).To(HaveOccurred())
+		Expect(strings.Contains(err.Error(), "No recorded response")).To(BeTrue())
+	})
+
+	It("records only the API responses without secrets", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/api/tasks/1" {
+				w.Write([]byte(`{"id":"1","privkey":"pk","environment":["FOO=bar"]}`))
+				return
+			}
+			w.Write([]byte("artefact content"))
+		}))
+		defer server.Close()
+		session := filepath.Join(dir, "session.json")
+
+		client := &http.Client{Transport: &RecordTransport{Next: &http.Transport{}, File: session}}
+		Expect(get(client, server.URL+"/api/tasks/1")).To(ContainSubstring(`"privkey":"pk"`))
+		Expect(get(client, server.URL+"/artefact/1/file")).To(Equal("200 OK artefact content"))
+
+		data, err := ioutil.ReadFile(session)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).ToNot(ContainSubstring("pk"))
+		Expect(string(data)).ToNot(ContainSubstring("bar"))
+		Expect(string(data)).ToNot(ContainSubstring("artefact content"))
+
+		replay, err := NewReplayTransport(session)
+		Expect(err).ToNot(HaveOccurred())
+		client = &http.Client{Transport: replay}
+		Expect(get(client, "http://other/api/tasks/1")).To(ContainSubstring(`"environment":["FOO=REDACTED"]`))
+		_, err = client.Get("http://other/artefact/1/file")
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,132 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package common
+
+import (
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	RETENTION_KEEP   = "keep"
+	RETENTION_REMOVE = "remove"
+)
+
+// ArtefactGroup is a set of artefacts produced by the same build.
+type ArtefactGroup struct {
+	Name     string         `json:"name"`
+	Files    []ArtefactFile `json:"files"`
+	Size     int64          `json:"size"`
+	Modified time.Time      `json:"modified"`
+	Action   string         `json:"action"`
+}
+
+// GroupArtefacts groups the artefacts by build. The build of an
+// artefact is the first submatch of re on its path, or the whole
+// match when re has no groups. Without re, the build is the top
+// level directory of the artefact. The artefacts not matching re
+// and the files in the root directory aren't grouped.
+// The modification time of a group is zero when it isn't available
+// for any of its files. The groups are sorted from the most recent.
+func GroupArtefacts(files []ArtefactFile, re *regexp.Regexp) []*ArtefactGroup {
+	groups := make(map[string]*ArtefactGroup)
+	unknown := make(map[string]bool)
+	for _, f := range files {
+		name := artefactBuild(f.Path, re)
+		if name == "" {
+			continue
+		}
+
+		g, ok := groups[name]
+		if !ok {
+			g = &ArtefactGroup{Name: name}
+			groups[name] = g
+		}
+		g.Files = append(g.Files, f)
+		if f.Size > 0 {
+			g.Size += f.Size
+		}
+		if f.Modified.IsZero() {
+			unknown[name] = true
+		}
+		if f.Modified.After(g.Modified) {
+			g.Modified = f.Modified
+		}
+	}
+
+	ans := make([]*ArtefactGroup, 0, len(groups))
+	for _, g := range groups {
+		if unknown[g.Name] {
+			g.Modified = time.Time{}
+		}
+		ans = append(ans, g)
+	}
+	sort.Slice(ans, func(i, j int) bool {
+		if ans[i].Modified.Equal(ans[j].Modified) {
+			return ans[i].Name > ans[j].Name
+		}
+		return ans[i].Modified.After(ans[j].Modified)
+	})
+	return ans
+}
+
+func artefactBuild(file string, re *regexp.Regexp) string {
+	if re == nil {
+		parts := strings.SplitN(strings.TrimPrefix(path.Clean("/"+file), "/"), "/", 2)
+		if len(parts) < 2 {
+			return ""
+		}
+		return parts[0]
+	}
+
+	m := re.FindStringSubmatch(file)
+	switch {
+	case m == nil:
+		return ""
+	case len(m) > 1:
+		return m[1]
+	default:
+		return m[0]
+	}
+}
+
+// ApplyRetention sets the action of the groups, sorted from the most
+// recent. A group is removed when it isn't one of the last keepLast
+// groups and it's older than olderThan. A zero value disables the
+// condition. The groups without a modification time are always kept,
+// their age is unknown.
+func ApplyRetention(groups []*ArtefactGroup, keepLast int, olderThan time.Duration, now time.Time) {
+	for i, g := range groups {
+		g.Action = RETENTION_REMOVE
+		if g.Modified.IsZero() {
+			g.Action = RETENTION_KEEP
+		}
+		if keepLast > 0 && i < keepLast {
+			g.Action = RETENTION_KEEP
+		}
+		if olderThan > 0 && now.Sub(g.Modified) <= olderThan {
+			g.Action = RETENTION_KEEP
+		}
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright © 2019 Ettore Di Giacinto <mudler@gentoo.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package common_test
+
+import (
+	"regexp"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/MottainaiCI/mottainai-cli/common"
+)
+
+var _ = Describe("Retention", func() {
+	now := time.Date(2019, 10, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	files := []ArtefactFile{
+		{Path: "/README", Size: 1, Modified: now.Add(-100 * day)},
+		{Path: "/build-1/a.deb", Size: 10, Modified: now.Add(-30 * day)},
+		{Path: "/build-1/b.deb", Size: 5, Modified: now.Add(-29 * day)},
+		{Path: "/build-2/a.deb", Size: 10, Modified: now.Add(-10 * day)},
+		{Path: "/build-3/a.deb", Size: 10, Modified: now.Add(-1 * day)},
+	}
+
+	actions := func(groups []*ArtefactGroup) map[string]string {
+		ans := make(map[string]string)
+		for _, g := range groups {
+			ans[g.Name] = g.Action
+		}
+		return ans
+	}
+
+	It("groups by top level directory from the most recent", func() {
+		groups := GroupArtefacts(files, nil)
+		Expect(groups).To(HaveLen(3))
+		Expect(groups[0].Name).To(Equal("build-3"))
+		Expect(groups[2].Name).To(Equal("build-1"))
+		Expect(groups[2].Size).To(Equal(int64(15)))
+		Expect(groups[2].Modified).To(Equal(now.Add(-29 * day)))
+	})
+
+	It("groups by the submatch of a regular expression", func() {
+		groups := GroupArtefacts(files, regexp.MustCompile(`/([ab])\.deb$`))
+		Expect(groups).To(HaveLen(2))
+		Expect(groups[0].Name).To(Equal("a"))
+		Expect(groups[0].Files).To(HaveLen(3))
+	})
+
+	It("keeps the last builds", func() {
+		groups := GroupArtefacts(files, nil)
+		ApplyRetention(groups, 2, 0, now)
+		Expect(actions(groups)).To(Equal(map[string]string{
+			"build-3": RETENTION_KEEP, "build-2": RETENTION_KEEP, "build-1": RETENTION_REMOVE}))
+	})
+
+	It("removes only the builds older than the duration", func() {
+		groups := GroupArtefacts(files, nil)
+		ApplyRetention(groups, 1, 5*day, now)
+		Expect(actions(groups)).To(Equal(map[string]string{
+			"build-3": RETENTION_KEEP, "build-2": RETENTION_REMOVE, "build-1": RETENTION_REMOVE}))
+		ApplyRetention(groups, 0, 20*day, now)
+		Expect(actions(groups)).To(Equal(map[string]string{
+			"build-3": RETENTION_KEEP, "build-2": RETENTION_KEEP, "build-1": RETENTION_REMOVE}))
+	})
+
+	It("keeps the builds without a modification time", func() {
+		groups := GroupArtefacts([]ArtefactFile{
+			{Path: "/build-9/a.deb", Size: 10, Modified: now.Add(-10 * day)},
+			{Path: "/build-10/a.deb", Size: -1},
+			{Path: "/build-10/b.deb", Size: 10, Modified: now.Add(-20 * day)},
+			{Path: "/build-11/a.deb", Size: 10},
+		}, nil)
+		Expect(groups[0].Name).To(Equal("build-9"))
+		Expect(groups[1].Modified.IsZero()).To(BeTrue())
+		ApplyRetention(groups, 1, 0, now)
+		Expect(actions(groups)).To(Equal(map[string]string{
+			"build-9": RETENTION_KEEP, "build-10": RETENTION_KEEP, "build-11": RETENTION_KEEP}))
+		ApplyRetention(groups, 0, 5*day, now)
+		Expect(actions(groups)).To(Equal(map[string]string{
+			"build-9": RETENTION_REMOVE, "build-10": RETENTION_KEEP, "build-11": RETENTION_KEEP}))
+	})
+})
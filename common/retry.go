@@ -0,0 +1,166 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package common
+
+import (
+	"math/rand"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	v "github.com/spf13/viper"
+)
+
+// RetryPolicy defines how failed API calls are retried. The delay
+// doubles after every attempt up to MaxDelay, and a random part
+// of it (Jitter, between 0 and 1) is added to spread the calls.
+type RetryPolicy struct {
+	Retries  int
+	Delay    time.Duration
+	MaxDelay time.Duration
+	Jitter   float64
+}
+
+// NewRetryPolicy returns the policy defined by the --retries and
+// --retry-delay settings available on viper.
+func NewRetryPolicy(viper *v.Viper) RetryPolicy {
+	return RetryPolicy{
+		Retries:  viper.GetInt("retries"),
+		Delay:    viper.GetDuration("retry-delay"),
+		MaxDelay: 30 * time.Second,
+		Jitter:   0.2,
+	}
+}
+
+// Backoff returns the time to wait before the retry number
+// attempt (1 based).
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	d := p.Delay
+	for i := 1; i < attempt && (p.MaxDelay <= 0 || d < p.MaxDelay); i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if p.Jitter > 0 {
+		d += time.Duration(rand.Float64() * p.Jitter * float64(d))
+	}
+	return d
+}
+
+// Routes of the master that only read data. The v1 API changes
+// data with GET requests too (e.g. /api/tasks/clone/:id), so the
+// method alone doesn't tell if a request can be sent twice.
+var readOnlyRoutes = regexp.MustCompile(`/api/(` + strings.Join([]string{
+	`settings`, `stats`, `token`,
+	`storage/list`, `storage/[^/]+/(list|show)`,
+	`user/list`, `user/show/[^/]+`,
+	`namespace/list`, `namespace/[^/]+/list`,
+	`webhook`, `webhook/show/[^/]+`,
+	`secret`, `secret/show/[^/]+`, `secret/search/name/[^/]+`,
+	`nodes`, `nodes/show/[^/]+`, `nodes/tasks/[^/]+`,
+	`tasks`, `tasks/status/[^/]+`, `tasks/[^/]+/artefacts`, `artefacts`,
+	`tasks/(stream|tail)_output/[^/]+/[^/]+`,
+	`tasks/planned`, `tasks/plan/[^/]+`,
+	`tasks/pipelines`, `tasks/pipeline/[^/]+`,
+	`tasks/[^/]+`,
+}, "|") + `)$`)
+
+// Task routes that change data and match the tasks/:id pattern.
+var taskActionRoutes = regexp.MustCompile(
+	`/api/tasks/(update|updatefield|append|plan|pipeline|pipelines/delete/[^/]+|plan/delete/[^/]+)$`)
+
+// RetryTransport retries the requests that fail for a connection
+// error or a 5xx response of the master. Only the GET requests of
+// the read only routes are retried after the request reached the
+// master, every other request is retried only when the connection
+// to the master failed, so it's never executed twice. Streamed
+// bodies can't be sent again and are never retried.
+type RetryTransport struct {
+	Next   http.RoundTripper
+	Policy RetryPolicy
+	Sleep  func(time.Duration)
+}
+
+func NewRetryTransport(next http.RoundTripper, policy RetryPolicy) *RetryTransport {
+	return &RetryTransport{Next: next, Policy: policy, Sleep: time.Sleep}
+}
+
+func (t *RetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		r := req
+		if attempt > 1 && req.Body != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			r = req.WithContext(req.Context())
+			r.Body = body
+		}
+
+		resp, err := t.Next.RoundTrip(r)
+		if attempt > t.Policy.Retries || !t.retriable(req, resp, err) {
+			return resp, err
+		}
+
+		wait := t.Policy.Backoff(attempt)
+		if resp != nil {
+			if after, aerr := strconv.Atoi(resp.Header.Get("Retry-After")); aerr == nil && after >= 0 {
+				wait = time.Duration(after) * time.Second
+			}
+			resp.Body.Close()
+		}
+		if req.Context().Err() != nil {
+			return nil, req.Context().Err()
+		}
+		t.Sleep(wait)
+	}
+}
+
+func (t *RetryTransport) retriable(req *http.Request, resp *http.Response, err error) bool {
+	if req.Body != nil && req.GetBody == nil {
+		return false
+	}
+	if req.Context().Err() != nil {
+		return false
+	}
+
+	if err != nil {
+		if op, ok := err.(*net.OpError); ok && op.Op == "dial" {
+			return true
+		}
+		return isReadOnly(req)
+	}
+
+	return isReadOnly(req) &&
+		resp.StatusCode >= 500 && resp.StatusCode != http.StatusNotImplemented
+}
+
+// isReadOnly returns true when req doesn't change data on the master.
+func isReadOnly(req *http.Request) bool {
+	if req.Method != "" && req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+	return readOnlyRoutes.MatchString(req.URL.Path) && !taskActionRoutes.MatchString(req.URL.Path)
+}
@@ -0,0 +1,92 @@
+// Copyright © 2019 Ettore Di Giacinto <mudler@gentoo.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package common_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/MottainaiCI/mottainai-cli/common"
+)
+
+var _ = Describe("Retry", func() {
+	var calls int
+	var server *httptest.Server
+	var transport *RetryTransport
+	var waits []time.Duration
+
+	BeforeEach(func() {
+		calls = 0
+		waits = nil
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			if calls < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Write([]byte("ok"))
+		}))
+		transport = NewRetryTransport(http.DefaultTransport,
+			RetryPolicy{Retries: 3, Delay: time.Second, MaxDelay: 3 * time.Second})
+		transport.Sleep = func(d time.Duration) { waits = append(waits, d) }
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("RetryPolicy", func() {
+		It("doubles the delay up to the maximum", func() {
+			p := RetryPolicy{Delay: time.Second, MaxDelay: 3 * time.Second}
+			Expect(p.Backoff(1)).To(Equal(time.Second))
+			Expect(p.Backoff(2)).To(Equal(2 * time.Second))
+			Expect(p.Backoff(3)).To(Equal(3 * time.Second))
+		})
+	})
+
+	Describe("RetryTransport", func() {
+		It("retries 5xx responses", func() {
+			resp, err := (&http.Client{Transport: transport}).Get(server.URL + "/api/tasks/1")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			Expect(calls).To(Equal(3))
+			Expect(waits).To(Equal([]time.Duration{time.Second, 2 * time.Second}))
+		})
+
+		It("doesn't retry data changes on 5xx responses", func() {
+			resp, err := (&http.Client{Transport: transport}).Post(server.URL, "text/plain", strings.NewReader("x"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(http.StatusServiceUnavailable))
+			Expect(calls).To(Equal(1))
+		})
+
+		It("doesn't retry GET routes that change data on 5xx responses", func() {
+			for _, path := range []string{"/api/tasks/clone/1", "/api/token/create", "/api/nodes/add",
+				"/api/namespace/ns/tag/1", "/api/tasks/update"} {
+				calls = 0
+				resp, err := (&http.Client{Transport: transport}).Get(server.URL + path)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(resp.StatusCode).To(Equal(http.StatusServiceUnavailable))
+				Expect(calls).To(Equal(1))
+			}
+		})
+	})
+})
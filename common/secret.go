@@ -0,0 +1,103 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package common
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+const (
+	SECRET_BACKEND_KEYRING = "keyring"
+	SECRET_BACKEND_ENV     = "env"
+	SECRET_BACKEND_FILE    = "file"
+	SECRET_BACKEND_CMD     = "cmd"
+
+	// Service name used to store secrets on OS keyring.
+	MCLI_SECRET_KEYRING_SERVICE = "mottainai-cli-secret"
+)
+
+// ResolveSecret returns the value of a secret reference in the
+// format <backend>:<name>. Supported backends are:
+//
+//	keyring:<name>    the secret <name> of the OS keyring
+//	env:<variable>    a local environment variable
+//	file:<path>       the content of a file
+//	cmd:<command>     the output of a command ( e.g. a vault client )
+func ResolveSecret(ref string) (string, error) {
+	item := strings.SplitN(ref, ":", 2)
+	if len(item) != 2 || item[1] == "" {
+		return "", errors.New("Invalid secret reference " + ref + ", use <backend>:<name>")
+	}
+	backend, name := item[0], item[1]
+
+	switch backend {
+	case SECRET_BACKEND_KEYRING:
+		return NewKeyringStore(MCLI_SECRET_KEYRING_SERVICE).Get(name)
+	case SECRET_BACKEND_ENV:
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return "", errors.New("Environment variable " + name + " not defined")
+		}
+		return value, nil
+	case SECRET_BACKEND_FILE:
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	case SECRET_BACKEND_CMD:
+		var stdout, stderr bytes.Buffer
+		cmd := exec.Command("sh", "-c", name)
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			return "", errors.New("Secret command failed: " + err.Error() + " " + strings.TrimSpace(stderr.String()))
+		}
+		return strings.TrimRight(stdout.String(), "\r\n"), nil
+	}
+
+	return "", errors.New("Invalid secret backend " + backend)
+}
+
+// ResolveSecrets resolves a list of KEY=<backend>:<name> items and
+// returns them as KEY=VALUE environment variables.
+func ResolveSecrets(secrets []string) ([]string, error) {
+	var ans []string
+
+	for _, s := range secrets {
+		item := strings.SplitN(s, "=", 2)
+		if len(item) != 2 || item[0] == "" {
+			return nil, errors.New("Invalid secret " + s + ", use KEY=<backend>:<name>")
+		}
+		value, err := ResolveSecret(item[1])
+		if err != nil {
+			return nil, errors.New("Failed resolving secret " + item[0] + ": " + err.Error())
+		}
+		ans = append(ans, item[0]+"="+value)
+	}
+
+	return ans, nil
+}
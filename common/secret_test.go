@@ -0,0 +1,52 @@
+// Copyright © 2019 Ettore Di Giacinto <mudler@gentoo.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package common_test
+
+import (
+	"io/ioutil"
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/MottainaiCI/mottainai-cli/common"
+)
+
+var _ = Describe("ResolveSecrets", func() {
+	It("resolves env, file and cmd references", func() {
+		os.Setenv("MCLI_TEST_SECRET", "s3cr3t")
+		defer os.Unsetenv("MCLI_TEST_SECRET")
+
+		f, err := ioutil.TempFile("", "mcli-secret")
+		Expect(err).ToNot(HaveOccurred())
+		defer os.Remove(f.Name())
+		f.WriteString("from-file\n")
+		f.Close()
+
+		env, err := ResolveSecrets([]string{
+			"A=env:MCLI_TEST_SECRET", "B=file:" + f.Name(), "C=cmd:echo from-cmd",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(env).To(Equal([]string{"A=s3cr3t", "B=from-file", "C=from-cmd"}))
+	})
+
+	It("rejects invalid references", func() {
+		_, err := ResolveSecrets([]string{"A=plain"})
+		Expect(err).To(HaveOccurred())
+		_, err = ResolveSecrets([]string{"A=vault:x"})
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,112 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package common
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// Set while the commands are run by the interactive shell.
+var shellMode int32
+
+// SetShellMode enables the behavior of the commands run by the
+// interactive shell: Exit returns to the prompt and an interrupt
+// cancels only the running command.
+func SetShellMode(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&shellMode, v)
+}
+
+// ShellMode returns true when the commands are run by the shell.
+func ShellMode() bool {
+	return atomic.LoadInt32(&shellMode) == 1
+}
+
+// ExitError is raised by Exit in shell mode with the exit code
+// of the command, the shell recovers it and shows the prompt.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// Exit terminates the command with code. In shell mode the process
+// keeps running and the command returns to the prompt.
+func Exit(code int) {
+	if ShellMode() {
+		panic(&ExitError{Code: code})
+	}
+	os.Exit(code)
+}
+
+// SplitCommandLine splits a line of the shell in arguments. Single
+// and double quotes group words, a backslash escapes the next char
+// outside of single quotes.
+func SplitCommandLine(line string) ([]string, error) {
+	var args []string
+	var arg strings.Builder
+	var quote rune
+	var escaped, inArg bool
+
+	for _, c := range line {
+		switch {
+		case escaped:
+			arg.WriteRune(c)
+			escaped = false
+		case c == '\\' && quote != '\'':
+			escaped, inArg = true, true
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else {
+				arg.WriteRune(c)
+			}
+		case c == '\'' || c == '"':
+			quote, inArg = c, true
+		case c == ' ' || c == '\t':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(c)
+			inArg = true
+		}
+	}
+
+	if escaped || quote != 0 {
+		return nil, errors.New("Unterminated quote or escape")
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
@@ -0,0 +1,55 @@
+// Copyright © 2019 Ettore Di Giacinto <mudler@gentoo.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package common_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/MottainaiCI/mottainai-cli/common"
+)
+
+var _ = Describe("Shell", func() {
+	Context("SplitCommandLine", func() {
+		It("splits the words with quotes and escapes", func() {
+			Expect(SplitCommandLine(`  task list --status running `)).To(Equal([]string{"task", "list", "--status", "running"}))
+			Expect(SplitCommandLine(`task list --query "[?a=='b']" --format '{{.ID}} \n'`)).To(Equal(
+				[]string{"task", "list", "--query", "[?a=='b']", "--format", `{{.ID}} \n`}))
+			Expect(SplitCommandLine(`namespace show a\ b ""`)).To(Equal([]string{"namespace", "show", "a b", ""}))
+		})
+
+		It("fails on unterminated quotes", func() {
+			_, err := SplitCommandLine(`task show "abc`)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("Exit", func() {
+		AfterEach(func() {
+			SetShellMode(false)
+		})
+
+		It("returns to the shell with the exit code", func() {
+			SetShellMode(true)
+			defer func() {
+				err, _ := recover().(error)
+				Expect(err).To(HaveOccurred())
+				Expect(ExitCode(err)).To(Equal(EXIT_NOT_FOUND))
+			}()
+			Exit(EXIT_NOT_FOUND)
+		})
+	})
+})
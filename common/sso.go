@@ -0,0 +1,253 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// Authentication mode of the --auth option with the tokens of an
+	// external identity provider, see "login --sso".
+	AUTH_SSO = "sso"
+
+	// OpenID Connect discovery document of the identity provider.
+	MCLI_SSO_DISCOVERY_PATH = "/.well-known/openid-configuration"
+
+	// Grant type of the token requests of the device flow (RFC 8628).
+	deviceCodeGrant = "urn:ietf:params:oauth:grant-type:device_code"
+	// Poll interval used when the provider doesn't define one.
+	defaultDeviceInterval = 5 * time.Second
+	// Size of the responses read from the identity provider.
+	maxSSOBody = 1 << 20
+)
+
+// DeviceAuthorization is the answer of the identity provider to the
+// device authorization request: the user completes the sign in on
+// VerificationURI with UserCode while the client polls for the token.
+type DeviceAuthorization struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete,omitempty"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval,omitempty"`
+}
+
+// SSOToken is the token issued by the identity provider.
+type SSOToken struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	ExpiresIn    int    `json:"expires_in,omitempty"`
+}
+
+// ssoError is the error response of the OAuth2 endpoints.
+type ssoError struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+func (e *ssoError) Error() string {
+	if e.Description != "" {
+		return e.Code + ": " + e.Description
+	}
+	return e.Code
+}
+
+// DeviceFlow implements the OAuth2 device authorization grant against
+// the identity provider Issuer, with the endpoints published on its
+// discovery document.
+type DeviceFlow struct {
+	Issuer   string
+	ClientID string
+	Scopes   []string
+	Client   *http.Client
+
+	DeviceAuthorizationEndpoint string
+	TokenEndpoint               string
+}
+
+func NewDeviceFlow(issuer, clientID string, scopes []string) *DeviceFlow {
+	return &DeviceFlow{
+		Issuer:   strings.TrimRight(issuer, "/"),
+		ClientID: clientID,
+		Scopes:   scopes,
+		Client:   http.DefaultClient,
+	}
+}
+
+// Discover reads the endpoints of the device flow from the discovery
+// document of the issuer.
+func (d *DeviceFlow) Discover() error {
+	resp, err := d.Client.Get(d.Issuer + MCLI_SSO_DISCOVERY_PATH)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return &CLIError{Code: statusExitCode(resp.StatusCode),
+			Err: errors.New("Discovery of the identity provider failed: " + resp.Status)}
+	}
+	var doc struct {
+		DeviceAuthorizationEndpoint string `json:"device_authorization_endpoint"`
+		TokenEndpoint               string `json:"token_endpoint"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxSSOBody)).Decode(&doc); err != nil {
+		return fmt.Errorf("Invalid discovery document of %s: %s", d.Issuer, err)
+	}
+	d.DeviceAuthorizationEndpoint, d.TokenEndpoint = doc.DeviceAuthorizationEndpoint, doc.TokenEndpoint
+	if d.DeviceAuthorizationEndpoint == "" || d.TokenEndpoint == "" {
+		return errors.New("The identity provider " + d.Issuer + " doesn't support the device authorization flow")
+	}
+	return nil
+}
+
+// Authorize starts the device flow and returns the code to show to
+// the user.
+func (d *DeviceFlow) Authorize() (*DeviceAuthorization, error) {
+	form := url.Values{"client_id": {d.ClientID}}
+	if len(d.Scopes) > 0 {
+		form.Set("scope", strings.Join(d.Scopes, " "))
+	}
+
+	var auth DeviceAuthorization
+	if err := d.post(d.DeviceAuthorizationEndpoint, form, &auth); err != nil {
+		return nil, err
+	}
+	if auth.DeviceCode == "" || auth.UserCode == "" || auth.VerificationURI == "" {
+		return nil, errors.New("Invalid device authorization response")
+	}
+	return &auth, nil
+}
+
+// Poll waits for the user to complete the sign in and returns the
+// token. It stops when the code expires, the user denies the access
+// or ctx is done.
+func (d *DeviceFlow) Poll(ctx context.Context, auth *DeviceAuthorization) (*SSOToken, error) {
+	interval := defaultDeviceInterval
+	if auth.Interval > 0 {
+		interval = time.Duration(auth.Interval) * time.Second
+	}
+	var expire <-chan time.Time
+	if auth.ExpiresIn > 0 {
+		timer := time.NewTimer(time.Duration(auth.ExpiresIn) * time.Second)
+		defer timer.Stop()
+		expire = timer.C
+	}
+
+	form := url.Values{
+		"grant_type":  {deviceCodeGrant},
+		"device_code": {auth.DeviceCode},
+		"client_id":   {d.ClientID},
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-expire:
+			return nil, &CLIError{Code: EXIT_AUTH, Err: errors.New("The device code is expired")}
+		case <-time.After(interval):
+		}
+
+		var t SSOToken
+		err := d.post(d.TokenEndpoint, form, &t)
+		if e, ok := err.(*ssoError); ok {
+			switch e.Code {
+			case "authorization_pending":
+				continue
+			case "slow_down":
+				interval += 5 * time.Second
+				continue
+			case "expired_token":
+				return nil, &CLIError{Code: EXIT_AUTH, Err: errors.New("The device code is expired")}
+			case "access_denied":
+				return nil, &CLIError{Code: EXIT_AUTH, Err: errors.New("The sign in was denied")}
+			}
+			return nil, &CLIError{Code: EXIT_AUTH, Err: e}
+		}
+		if err != nil {
+			return nil, err
+		}
+		if t.AccessToken == "" {
+			return nil, errors.New("Invalid token response")
+		}
+		return &t, nil
+	}
+}
+
+// post sends form to the endpoint and decodes the answer on target,
+// or returns the ssoError of the provider.
+func (d *DeviceFlow) post(endpoint string, form url.Values, target interface{}) error {
+	req, err := http.NewRequest("POST", endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxSSOBody))
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var e ssoError
+		if json.Unmarshal(data, &e) == nil && e.Code != "" {
+			return &e
+		}
+		return &CLIError{Code: statusExitCode(resp.StatusCode),
+			Err: errors.New("Request to the identity provider failed: " + resp.Status)}
+	}
+	return json.Unmarshal(data, target)
+}
+
+// BearerTransport sends the API key as a bearer token, as expected by
+// the masters that accept the tokens of an external identity provider.
+type BearerTransport struct {
+	Next http.RoundTripper
+}
+
+func (t *BearerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	auth := req.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "token ") {
+		return t.Next.RoundTrip(req)
+	}
+	r := new(http.Request)
+	*r = *req
+	r.Header = cloneHeader(req.Header)
+	r.Header.Set("Authorization", "Bearer "+strings.TrimPrefix(auth, "token "))
+	return t.Next.RoundTrip(r)
+}
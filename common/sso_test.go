@@ -0,0 +1,125 @@
+// Copyright © 2019 Ettore Di Giacinto <mudler@gentoo.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package common_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/MottainaiCI/mottainai-cli/common"
+)
+
+var _ = Describe("DeviceFlow", func() {
+	var server *httptest.Server
+	var polls int
+	var result string
+
+	BeforeEach(func() {
+		polls, result = 0, "granted"
+		mux := http.NewServeMux()
+		mux.HandleFunc(MCLI_SSO_DISCOVERY_PATH, func(w http.ResponseWriter, r *http.Request) {
+			json.NewEncoder(w).Encode(map[string]string{
+				"issuer":                        server.URL,
+				"device_authorization_endpoint": server.URL + "/device",
+				"token_endpoint":                server.URL + "/token",
+			})
+		})
+		mux.HandleFunc("/device", func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.FormValue("client_id")).To(Equal("mcli"))
+			Expect(r.FormValue("scope")).To(Equal("openid profile"))
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"device_code":      "dev",
+				"user_code":        "ABCD-EFGH",
+				"verification_uri": server.URL + "/activate",
+				"expires_in":       60,
+				"interval":         1,
+			})
+		})
+		mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.FormValue("device_code")).To(Equal("dev"))
+			polls++
+			if polls == 1 || result != "granted" {
+				code := "authorization_pending"
+				if polls > 1 {
+					code = result
+				}
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": code})
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]string{"access_token": "secret", "token_type": "Bearer"})
+		})
+		server = httptest.NewServer(mux)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	authorize := func() (*DeviceFlow, *DeviceAuthorization) {
+		flow := NewDeviceFlow(server.URL+"/", "mcli", []string{"openid", "profile"})
+		Expect(flow.Discover()).To(Succeed())
+		Expect(flow.TokenEndpoint).To(Equal(server.URL + "/token"))
+		auth, err := flow.Authorize()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(auth.UserCode).To(Equal("ABCD-EFGH"))
+		return flow, auth
+	}
+
+	It("polls for the token until the sign in is completed", func() {
+		flow, auth := authorize()
+		t, err := flow.Poll(context.Background(), auth)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(t.AccessToken).To(Equal("secret"))
+		Expect(polls).To(Equal(2))
+	})
+
+	It("fails when the user denies the access", func() {
+		result = "access_denied"
+		flow, auth := authorize()
+		_, err := flow.Poll(context.Background(), auth)
+		Expect(err).To(HaveOccurred())
+		Expect(ExitCode(err)).To(Equal(EXIT_AUTH))
+	})
+
+	It("fails when the provider doesn't support the device flow", func() {
+		flow := NewDeviceFlow(server.URL+"/missing", "mcli", nil)
+		Expect(flow.Discover()).ToNot(Succeed())
+	})
+})
+
+var _ = Describe("BearerTransport", func() {
+	It("sends the API key as a bearer token", func() {
+		var auth string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			auth = r.Header.Get("Authorization")
+		}))
+		defer server.Close()
+
+		req, _ := http.NewRequest("GET", server.URL, nil)
+		req.Header.Set("Authorization", "token secret")
+		resp, err := (&BearerTransport{Next: http.DefaultTransport}).RoundTrip(req)
+		Expect(err).ToNot(HaveOccurred())
+		resp.Body.Close()
+		Expect(auth).To(Equal("Bearer secret"))
+		Expect(req.Header.Get("Authorization")).To(Equal("token secret"))
+	})
+})
@@ -0,0 +1,122 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package common
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	client "github.com/MottainaiCI/mottainai-server/pkg/client"
+	storageci "github.com/MottainaiCI/mottainai-server/pkg/storage"
+	utils "github.com/MottainaiCI/mottainai-server/pkg/utils"
+	schema "github.com/MottainaiCI/mottainai-server/routes/schema"
+	v1 "github.com/MottainaiCI/mottainai-server/routes/schema/v1"
+)
+
+// ArtefactFile is an artefact with its size and its modification
+// time. The size is -1 when it isn't available.
+type ArtefactFile struct {
+	Path     string    `json:"path"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+}
+
+// StoragePath returns the path of a storage, used in the URLs of
+// its files.
+func StoragePath(fetcher client.HttpClient, id string) (string, error) {
+	var data storageci.Storage
+	err := fetcher.Handle(schema.Request{
+		Route:   v1.Schema.GetStorageRoute("show"),
+		Options: map[string]interface{}{":id": id},
+		Target:  &data,
+	})
+	if err != nil {
+		return "", errors.New("Failed getting storage data: " + err.Error())
+	}
+	return data.Path, nil
+}
+
+// ArtefactSizes returns the size and the modification time of the
+// files available on baseURL. The listings of the master don't include
+// them, so they are fetched with a HEAD request for every file.
+func ArtefactSizes(baseURL, token string, files []string, concurrency int) []ArtefactFile {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	ans := make([]ArtefactFile, len(files))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				size, modified, err := remoteStat(baseURL+utils.PathEscape(files[i]), token)
+				if err != nil {
+					size = -1
+				}
+				ans[i] = ArtefactFile{Path: files[i], Size: size, Modified: modified}
+			}
+		}()
+	}
+	for i := range files {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return ans
+}
+
+// remoteSize returns the size of the file available on url.
+func remoteSize(url, token string) (int64, error) {
+	size, _, err := remoteStat(url, token)
+	return size, err
+}
+
+// remoteStat returns the size and the modification time of the file
+// available on url. The time is zero when the server doesn't send it.
+func remoteStat(url, token string) (int64, time.Time, error) {
+	var modified time.Time
+
+	request, err := http.NewRequest("HEAD", url, nil)
+	if err != nil {
+		return 0, modified, err
+	}
+	setAuthHeader(request, token)
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return 0, modified, err
+	}
+	response.Body.Close()
+
+	if response.StatusCode != http.StatusOK || response.ContentLength < 0 {
+		return 0, modified, errors.New("Size not available")
+	}
+	if t, err := http.ParseTime(response.Header.Get("Last-Modified")); err == nil {
+		modified = t
+	}
+	return response.ContentLength, modified, nil
+}
@@ -0,0 +1,227 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package common
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+
+	client "github.com/MottainaiCI/mottainai-server/pkg/client"
+	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
+	utils "github.com/MottainaiCI/mottainai-server/pkg/utils"
+)
+
+const (
+	SYNC_UPLOAD = "upload"
+	SYNC_DELETE = "delete"
+)
+
+// SyncChange is a change needed to align a storage to a local directory.
+type SyncChange struct {
+	Action string `json:"action"`
+	// Path of the file relative to the synchronized directory,
+	// with a leading slash as in the storage listing.
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+// StorageSync synchronizes a local directory on a storage, uploading
+// only the files that are missing or changed. The master doesn't
+// expose the checksums of the files, so they are compared by size and,
+// with Checksum, by the SHA-256 of the remote content.
+type StorageSync struct {
+	Fetcher  client.HttpClient
+	Config   *setting.Config
+	Token    string
+	Checksum bool
+	// Delete removes the remote files missing in the local directory.
+	Delete bool
+	Quiet  bool
+}
+
+func NewStorageSync(fetcher client.HttpClient, config *setting.Config, token string) *StorageSync {
+	return &StorageSync{
+		Fetcher: fetcher,
+		Config:  config,
+		Token:   token,
+	}
+}
+
+// Changes returns the changes needed to align the storage to dir.
+func (s *StorageSync) Changes(storage, dir string) ([]SyncChange, error) {
+	local, err := LocalFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := s.Fetcher.StorageFileList(storage)
+	if err != nil {
+		return nil, errors.New("Failed getting storage file list: " + err.Error())
+	}
+	remote := make(map[string]bool)
+	for _, f := range list {
+		remote[artefactPath(f)] = true
+	}
+
+	storagePath, err := StoragePath(s.Fetcher, storage)
+	if err != nil {
+		return nil, err
+	}
+	baseURL := s.Fetcher.GetBaseURL() + "/" + ARTEFACT_STORAGE + "/" + storagePath
+
+	var paths []string
+	for f := range local {
+		paths = append(paths, f)
+	}
+	sort.Strings(paths)
+
+	var changes []SyncChange
+	for _, f := range paths {
+		if !remote[f] {
+			changes = append(changes, SyncChange{Action: SYNC_UPLOAD, Path: f, Reason: "new"})
+			continue
+		}
+
+		url := baseURL + utils.PathEscape(f)
+		size, err := remoteSize(url, s.Token)
+		if err != nil {
+			return nil, fmt.Errorf("Failed getting size of %s: %s", f, err.Error())
+		}
+		if size != local[f] {
+			changes = append(changes, SyncChange{Action: SYNC_UPLOAD, Path: f, Reason: "size"})
+			continue
+		}
+
+		if s.Checksum {
+			same, err := s.sameContent(url, filepath.Join(dir, filepath.FromSlash(f)))
+			if err != nil {
+				return nil, fmt.Errorf("Failed comparing %s: %s", f, err.Error())
+			}
+			if !same {
+				changes = append(changes, SyncChange{Action: SYNC_UPLOAD, Path: f, Reason: "checksum"})
+			}
+		}
+	}
+
+	if s.Delete {
+		var extra []string
+		for f := range remote {
+			if _, ok := local[f]; !ok {
+				extra = append(extra, f)
+			}
+		}
+		sort.Strings(extra)
+		for _, f := range extra {
+			changes = append(changes, SyncChange{Action: SYNC_DELETE, Path: f, Reason: "missing locally"})
+		}
+	}
+
+	return changes, nil
+}
+
+// Apply uploads and deletes the files of changes. The errors don't
+// stop the synchronization and the failed paths are returned.
+func (s *StorageSync) Apply(storage, dir string, changes []SyncChange) (failed []string) {
+	uploader := NewFileUploader(s.Fetcher, s.Config, s.Token)
+	uploader.Quiet = s.Quiet
+
+	for _, c := range changes {
+		var err error
+		switch c.Action {
+		case SYNC_UPLOAD:
+			err = uploader.UploadStorage(storage, filepath.Join(dir, filepath.FromSlash(c.Path)), path.Dir(c.Path))
+		case SYNC_DELETE:
+			_, err = s.Fetcher.StorageRemovePath(storage, c.Path)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to %s %s: %s\n", c.Action, c.Path, err.Error())
+			failed = append(failed, c.Path)
+		}
+	}
+
+	return failed
+}
+
+// sameContent compares the SHA-256 of the remote file with the
+// one of the local file.
+func (s *StorageSync) sameContent(url, file string) (bool, error) {
+	localSum, err := FileChecksum(file)
+	if err != nil {
+		return false, err
+	}
+
+	request, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return false, err
+	}
+	setAuthHeader(request, s.Token)
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return false, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return false, errors.New("Error: " + response.Status)
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(h, response.Body); err != nil {
+		return false, err
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)) == localSum, nil
+}
+
+// LocalFiles returns the size of the regular files under dir, indexed
+// by their slash separated path relative to dir with a leading slash.
+func LocalFiles(dir string) (map[string]int64, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, errors.New(dir + " is not a directory")
+	}
+
+	files := make(map[string]int64)
+	err = filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		files["/"+filepath.ToSlash(rel)] = info.Size()
+		return nil
+	})
+	return files, err
+}
@@ -0,0 +1,55 @@
+// Copyright © 2019 Ettore Di Giacinto <mudler@gentoo.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package common_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/MottainaiCI/mottainai-cli/common"
+)
+
+var _ = Describe("LocalFiles", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "mcli-sync")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(os.MkdirAll(filepath.Join(dir, "sub", "empty"), os.ModePerm)).ToNot(HaveOccurred())
+		Expect(ioutil.WriteFile(filepath.Join(dir, "a.txt"), []byte("aaa"), 0644)).ToNot(HaveOccurred())
+		Expect(ioutil.WriteFile(filepath.Join(dir, "sub", "b.txt"), []byte("b"), 0644)).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("returns the size of the regular files by relative path", func() {
+		files, err := LocalFiles(dir)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(files).To(Equal(map[string]int64{"/a.txt": 3, "/sub/b.txt": 1}))
+	})
+
+	It("fails when the path is not a directory", func() {
+		_, err := LocalFiles(filepath.Join(dir, "a.txt"))
+		Expect(err).To(HaveOccurred())
+	})
+})
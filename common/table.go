@@ -0,0 +1,175 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package common
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Table contains the tabular representation of a result,
+// used when the output format is table or csv.
+type Table struct {
+	Header []string
+	Rows   [][]string
+}
+
+func NewTable(header []string) *Table {
+	return &Table{Header: header, Rows: [][]string{}}
+}
+
+func (t *Table) Append(row []string) {
+	t.Rows = append(t.Rows, row)
+}
+
+// Customize returns a new table with only the selected columns,
+// sorted by the sortBy column (a leading '-' reverses the order).
+// Columns are first looked up on the table header and then on
+// the fields of the elements of obj, when obj is a slice whose
+// elements match the table rows.
+func (t *Table) Customize(obj interface{}, columns []string, sortBy string) (*Table, error) {
+	var err error
+	var header []string
+	var cols [][]string
+
+	if len(columns) == 0 {
+		columns = t.Header
+	}
+
+	for _, c := range columns {
+		var col []string
+		col, err = t.column(obj, c)
+		if err != nil {
+			return nil, err
+		}
+		header = append(header, c)
+		cols = append(cols, col)
+	}
+
+	ans := NewTable(header)
+	for i := range t.Rows {
+		row := make([]string, len(cols))
+		for j, col := range cols {
+			row[j] = col[i]
+		}
+		ans.Append(row)
+	}
+
+	if sortBy != "" {
+		desc := strings.HasPrefix(sortBy, "-")
+		keys, err := t.column(obj, strings.TrimPrefix(sortBy, "-"))
+		if err != nil {
+			return nil, err
+		}
+
+		idx := make([]int, len(ans.Rows))
+		for i := range idx {
+			idx[i] = i
+		}
+		sort.SliceStable(idx, func(i, j int) bool {
+			if desc {
+				return keys[idx[i]] > keys[idx[j]]
+			}
+			return keys[idx[i]] < keys[idx[j]]
+		})
+
+		rows := make([][]string, len(idx))
+		for i, n := range idx {
+			rows[i] = ans.Rows[n]
+		}
+		ans.Rows = rows
+	}
+
+	return ans, nil
+}
+
+func (t *Table) column(obj interface{}, name string) ([]string, error) {
+	ans := make([]string, len(t.Rows))
+
+	for i, h := range t.Header {
+		if normalizeColumn(h) == normalizeColumn(name) {
+			for n, row := range t.Rows {
+				ans[n] = row[i]
+			}
+			return ans, nil
+		}
+	}
+
+	val := reflect.ValueOf(obj)
+	for val.Kind() == reflect.Ptr && !val.IsNil() {
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Slice || val.Len() != len(t.Rows) {
+		return nil, errors.New("Unknown column " + name)
+	}
+
+	for n := 0; n < val.Len(); n++ {
+		field, ok := structField(val.Index(n), normalizeColumn(name))
+		if !ok {
+			return nil, errors.New("Unknown column " + name)
+		}
+		ans[n] = fmt.Sprintf("%v", field.Interface())
+	}
+
+	return ans, nil
+}
+
+// structField searches a field by json tag or by name,
+// descending into embedded structs.
+func structField(v reflect.Value, name string) (reflect.Value, bool) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return v, false
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return v, false
+	}
+
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Type().Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+		tag := strings.Split(f.Tag.Get("json"), ",")[0]
+		if (tag != "" && normalizeColumn(tag) == name) || normalizeColumn(f.Name) == name {
+			return v.Field(i), true
+		}
+	}
+
+	for i := 0; i < v.NumField(); i++ {
+		if v.Type().Field(i).Anonymous {
+			if field, ok := structField(v.Field(i), name); ok {
+				return field, true
+			}
+		}
+	}
+
+	return v, false
+}
+
+func normalizeColumn(name string) string {
+	return strings.ToLower(strings.NewReplacer(" ", "", "_", "", "-", "").Replace(name))
+}
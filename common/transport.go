@@ -0,0 +1,259 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package common
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	v "github.com/spf13/viper"
+	http2 "golang.org/x/net/http2"
+)
+
+// Settings used by SetupTransport.
+var transportSettings = []string{
+	"master", "cacert", "cert", "key", "insecure", "proxy", "timeout", "no-compress",
+	"record", "replay", "debug", "debug-body", "rate-limit", "retries",
+	"retry-delay", "auth", "cache",
+}
+
+// Settings of the transport used by the shell commands.
+var shellTransport string
+
+// NewTLSConfig returns the TLS configuration for the input options
+// or nil if the default configuration could be used.
+func NewTLSConfig(cacert, cert, key string, insecure bool) (*tls.Config, error) {
+	if cacert == "" && cert == "" && key == "" && !insecure {
+		return nil, nil
+	}
+
+	ans := &tls.Config{InsecureSkipVerify: insecure}
+
+	if cacert != "" {
+		rootCAs, _ := x509.SystemCertPool()
+		if rootCAs == nil {
+			rootCAs = x509.NewCertPool()
+		}
+
+		certs, err := ioutil.ReadFile(cacert)
+		if err != nil {
+			return nil, err
+		}
+		if ok := rootCAs.AppendCertsFromPEM(certs); !ok {
+			return nil, errors.New("No certificates found on " + cacert)
+		}
+		ans.RootCAs = rootCAs
+	}
+
+	if cert != "" || key != "" {
+		if cert == "" || key == "" {
+			return nil, errors.New("Both client certificate and key are needed")
+		}
+		pair, err := tls.LoadX509KeyPair(cert, key)
+		if err != nil {
+			return nil, err
+		}
+		ans.Certificates = []tls.Certificate{pair}
+	}
+
+	return ans, nil
+}
+
+// NewTransport returns the transport used by all API calls,
+// configured with the connection settings available on viper.
+func NewTransport(viper *v.Viper) (*http.Transport, error) {
+	tlsConfig, err := NewTLSConfig(
+		viper.GetString("cacert"),
+		viper.GetString("cert"),
+		viper.GetString("key"),
+		viper.GetBool("insecure"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	proxy, err := NewProxyFunc(viper.GetString("proxy"))
+	if err != nil {
+		return nil, err
+	}
+
+	// The timeout bounds the wait of the master response, so a hung
+	// master doesn't block the CLI while long transfers still work.
+	timeout := viper.GetDuration("timeout")
+	dialTimeout := 30 * time.Second
+	if timeout > 0 && timeout < dialTimeout {
+		dialTimeout = timeout
+	}
+
+	ans := &http.Transport{
+		Proxy: proxy,
+		DialContext: (&net.Dialer{
+			Timeout:   dialTimeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		// Commands use up to 8 concurrent requests to the master,
+		// the idle connections are kept to be reused by the next ones.
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   16,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		ResponseHeaderTimeout: timeout,
+		TLSClientConfig:       tlsConfig,
+		// Responses are requested and decoded with gzip unless
+		// disabled, e.g. for masters behind broken proxies.
+		DisableCompression: viper.GetBool("no-compress"),
+	}
+
+	// A transport with a custom TLS configuration doesn't enable
+	// HTTP/2 by itself.
+	if err = http2.ConfigureTransport(ans); err != nil {
+		return nil, err
+	}
+
+	return ans, nil
+}
+
+// SetupTransport replaces the default HTTP transport used by
+// the client.Fetcher with the one defined by the settings. Requests
+// are logged with --debug, limited by the --rate-limit setting and
+// failed calls are retried following the retry policy. GET responses
+// are cached with --cache and requests are canceled when
+// the command is interrupted. With --replay the responses are read
+// from a session recorded with --record. With --auth macaroon the
+// requests are authenticated with discharged macaroons, with --auth sso
+// the API key is sent as a bearer token. In shell mode the transport,
+// with its open connections, is kept while the settings don't change.
+func SetupTransport(viper *v.Viper) error {
+	var key []string
+	for _, s := range transportSettings {
+		key = append(key, fmt.Sprint(viper.Get(s)))
+	}
+	if ShellMode() && strings.Join(key, "\n") == shellTransport {
+		return nil
+	}
+	if err := setupTransport(viper); err != nil {
+		return err
+	}
+	shellTransport = strings.Join(key, "\n")
+	return nil
+}
+
+func setupTransport(viper *v.Viper) error {
+	record, replay := viper.GetString("record"), viper.GetString("replay")
+	if record != "" && replay != "" {
+		return ValidationError("--record and --replay can't be used together")
+	}
+
+	var rt http.RoundTripper
+	if replay != "" {
+		r, err := NewReplayTransport(replay)
+		if err != nil {
+			return err
+		}
+		rt = r
+	} else {
+		t, err := NewTransport(viper)
+		if err != nil {
+			return err
+		}
+		rt = &DrainTransport{Next: t}
+	}
+	if viper.GetBool("debug") || viper.GetBool("debug-body") {
+		rt = &DebugTransport{Next: rt, Writer: os.Stderr, Body: viper.GetBool("debug-body")}
+	}
+	if replay != "" {
+		http.DefaultTransport = &InterruptTransport{Next: &StatusTransport{Next: rt, Master: viper.GetString("master")}}
+		return nil
+	}
+
+	if rate := viper.GetString("rate-limit"); rate != "" {
+		r, err := ParseRate(rate)
+		if err != nil {
+			return err
+		}
+		rt = &RateLimitTransport{Next: rt, Limiter: NewRateLimiter(r, 1)}
+	}
+	policy := NewRetryPolicy(viper)
+	if policy.Retries > 0 {
+		rt = NewRetryTransport(rt, policy)
+	}
+	switch auth := viper.GetString("auth"); auth {
+	case "", AUTH_TOKEN:
+	case AUTH_MACAROON:
+		m, err := NewMacaroonTransport(rt, filepath.Join(CacheDir(), MCLI_MACAROON_FILE))
+		if err != nil {
+			return err
+		}
+		rt = m
+	case AUTH_SSO:
+		rt = &BearerTransport{Next: rt}
+	default:
+		return ValidationError("Invalid auth mode %s, valid modes are: %s, %s, %s", auth, AUTH_TOKEN, AUTH_MACAROON, AUTH_SSO)
+	}
+	if viper.GetBool("cache") {
+		rt = &CacheTransport{Next: rt, Dir: CacheDir()}
+	}
+	if record != "" {
+		rt = &RecordTransport{Next: rt, File: record}
+	}
+	http.DefaultTransport = &InterruptTransport{Next: &StatusTransport{Next: rt, Master: viper.GetString("master")}}
+	return nil
+}
+
+// Bytes read from a response body on close to reuse its connection.
+const maxDrainSize = 256 << 10
+
+// DrainTransport reads the rest of a response body when it's closed.
+// The client.Fetcher closes the bodies decoded by json.Decoder
+// without reading them up to EOF and the connection of a body not
+// fully read isn't reused, so every call would open a new one.
+type DrainTransport struct {
+	Next http.RoundTripper
+}
+
+func (t *DrainTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.Next.RoundTrip(req)
+	if err != nil || resp.Body == nil || resp.Body == http.NoBody {
+		return resp, err
+	}
+	resp.Body = &drainBody{ReadCloser: resp.Body}
+	return resp, nil
+}
+
+type drainBody struct {
+	io.ReadCloser
+}
+
+func (b *drainBody) Close() error {
+	io.CopyN(ioutil.Discard, b.ReadCloser, maxDrainSize)
+	return b.ReadCloser.Close()
+}
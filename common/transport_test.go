@@ -0,0 +1,58 @@
+// Copyright © 2019 Ettore Di Giacinto <mudler@gentoo.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package common_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/MottainaiCI/mottainai-cli/common"
+)
+
+var _ = Describe("Transport", func() {
+	Describe("DrainTransport", func() {
+		It("reuses the connection of bodies not read up to EOF", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				// json.Decoder stops reading after the object.
+				w.Write([]byte("{\"id\":\"1\"}" + strings.Repeat(" ", 64<<10)))
+			}))
+			defer server.Close()
+
+			client := &http.Client{Transport: &DrainTransport{Next: &http.Transport{}}}
+			reused := []bool{}
+			for i := 0; i < 3; i++ {
+				trace := &httptrace.ClientTrace{
+					GotConn: func(info httptrace.GotConnInfo) { reused = append(reused, info.Reused) },
+				}
+				req, _ := http.NewRequest("GET", server.URL, nil)
+				req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+				resp, err := client.Do(req)
+				Expect(err).ToNot(HaveOccurred())
+
+				var target map[string]string
+				Expect(json.NewDecoder(resp.Body).Decode(&target)).ToNot(HaveOccurred())
+				resp.Body.Close()
+			}
+			Expect(reused).To(Equal([]bool{false, true, true}))
+		})
+	})
+})
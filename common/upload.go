@@ -0,0 +1,151 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package common
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	client "github.com/MottainaiCI/mottainai-server/pkg/client"
+	setting "github.com/MottainaiCI/mottainai-server/pkg/settings"
+	schema "github.com/MottainaiCI/mottainai-server/routes/schema"
+	v1 "github.com/MottainaiCI/mottainai-server/routes/schema/v1"
+)
+
+// FileUploader uploads files on a storage or a namespace. The file is
+// streamed in the multipart request through a reader that updates
+// the transfer progress.
+type FileUploader struct {
+	Fetcher client.HttpClient
+	Config  *setting.Config
+	Token   string
+	// Quiet disables the progress bar.
+	Quiet bool
+}
+
+func NewFileUploader(fetcher client.HttpClient, config *setting.Config, token string) *FileUploader {
+	return &FileUploader{
+		Fetcher: fetcher,
+		Config:  config,
+		Token:   token,
+	}
+}
+
+func (u *FileUploader) UploadStorage(storage, file, path string) error {
+	return u.uploadFile(ARTEFACT_STORAGE, storage, file, path)
+}
+
+func (u *FileUploader) UploadNamespace(namespace, file, path string) error {
+	return u.uploadFile(ARTEFACT_NAMESPACE, namespace, file, path)
+}
+
+func (u *FileUploader) uploadFile(kind, id, file, path string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return errors.New(file + " is not a regular file")
+	}
+
+	return u.UploadReader(kind, id, filepath.Base(file), path, f, info.Size())
+}
+
+// UploadReader uploads the content of r as the file name in the path
+// of a storage or a namespace. The size is used for the progress.
+func (u *FileUploader) UploadReader(kind, id, name, path string, r io.Reader, size int64) error {
+	var route schema.Route
+	fields := map[string]string{"name": name, "path": path}
+	switch kind {
+	case ARTEFACT_STORAGE:
+		route = v1.Schema.GetStorageRoute("upload")
+		fields["storageid"] = id
+	case ARTEFACT_NAMESPACE:
+		route = v1.Schema.GetNamespaceRoute("upload")
+		fields["namespace"] = id
+	default:
+		return errors.New("Upload not supported on " + kind)
+	}
+
+	progress := NewTransferProgress("Uploading "+name, size)
+	if u.Quiet {
+		progress.Enabled = false
+		progress.Writer = ioutil.Discard
+	}
+	defer OnInterrupt(progress.Finish)()
+
+	// The multipart body is written on a pipe while the request
+	// is sent, so the file is never loaded in memory.
+	rd, wr := io.Pipe()
+	defer rd.Close()
+	mpWriter := multipart.NewWriter(wr)
+
+	go func() {
+		for k, v := range fields {
+			if err := mpWriter.WriteField(k, v); err != nil {
+				wr.CloseWithError(err)
+				return
+			}
+		}
+		part, err := mpWriter.CreateFormFile("file", name)
+		if err != nil {
+			wr.CloseWithError(err)
+			return
+		}
+		_, err = io.Copy(part, &progressReader{Reader: r, Progress: progress})
+		progress.Finish()
+		if err != nil {
+			wr.CloseWithError(err)
+			return
+		}
+		wr.CloseWithError(mpWriter.Close())
+	}()
+
+	req := schema.Request{Route: route, Body: rd}
+	request, err := req.NewAPIHTTPRequest(u.Fetcher.GetBaseURL() + u.Config.GetWeb().BuildURI(""))
+	if err != nil {
+		return err
+	}
+	setAuthHeader(request, u.Token)
+	request.Header.Set("Content-Type", mpWriter.FormDataContentType())
+	request.ContentLength = -1
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return errors.New("Upload of " + name + " failed: " + response.Status)
+	}
+	return nil
+}
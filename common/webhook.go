@@ -0,0 +1,133 @@
+/*
+
+Copyright (C) 2017-2019  Ettore Di Giacinto <mudler@gentoo.org>
+                         Daniele Rondina <geaaru@sabayonlinux.org>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+*/
+
+package common
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	WEBHOOK_GITHUB = "github"
+	WEBHOOK_GITLAB = "gitlab"
+)
+
+// WebHookPayload returns a minimal push event of the webhook kind
+// for the repository repo (e.g. owner/project), the ref and the commit.
+func WebHookPayload(kind, repo, ref, commit string) ([]byte, error) {
+	var payload map[string]interface{}
+
+	if repo == "" {
+		return nil, errors.New("A repository is needed to craft a payload")
+	}
+	if !strings.HasPrefix(ref, "refs/") {
+		ref = "refs/heads/" + ref
+	}
+
+	switch kind {
+	case WEBHOOK_GITHUB:
+		payload = map[string]interface{}{
+			"ref":         ref,
+			"after":       commit,
+			"head_commit": map[string]string{"id": commit},
+			"repository": map[string]string{
+				"full_name": repo,
+				"clone_url": "https://github.com/" + repo + ".git",
+			},
+		}
+	case WEBHOOK_GITLAB:
+		payload = map[string]interface{}{
+			"object_kind":  "push",
+			"ref":          ref,
+			"after":        commit,
+			"checkout_sha": commit,
+			"project": map[string]string{
+				"path_with_namespace": repo,
+				"git_http_url":        "https://gitlab.com/" + repo + ".git",
+			},
+		}
+	default:
+		return nil, errors.New("Unsupported webhook type " + kind)
+	}
+
+	return json.MarshalIndent(payload, "", "  ")
+}
+
+// NewWebHookRequest returns the request that delivers the payload
+// of the event to the webhook with the input key, as sent by the
+// git hosting service. The secret is used to sign the payload of
+// GitHub events and as token of GitLab events.
+func NewWebHookRequest(url, kind, key, event, secret string, payload []byte) (*http.Request, error) {
+	request, err := http.NewRequest("POST",
+		strings.TrimSuffix(url, "/")+"/webhook/"+key+"/"+kind, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	switch kind {
+	case WEBHOOK_GITHUB:
+		request.Header.Set("X-GitHub-Event", event)
+		request.Header.Set("X-GitHub-Delivery", fmt.Sprintf("mcli-%d", time.Now().UnixNano()))
+		if secret != "" {
+			request.Header.Set("X-Hub-Signature", "sha1="+webHookSignature(sha1.New, secret, payload))
+			request.Header.Set("X-Hub-Signature-256", "sha256="+webHookSignature(sha256.New, secret, payload))
+		}
+	case WEBHOOK_GITLAB:
+		request.Header.Set("X-Gitlab-Event", gitlabEvent(event))
+		if secret != "" {
+			request.Header.Set("X-Gitlab-Token", secret)
+		}
+	default:
+		return nil, errors.New("Unsupported webhook type " + kind)
+	}
+
+	return request, nil
+}
+
+func webHookSignature(h func() hash.Hash, secret string, payload []byte) string {
+	mac := hmac.New(h, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// gitlabEvent maps the GitHub event names to the GitLab ones.
+func gitlabEvent(event string) string {
+	switch event {
+	case "push":
+		return "Push Hook"
+	case "pull_request", "merge_request":
+		return "Merge Request Hook"
+	case "tag", "tag_push":
+		return "Tag Push Hook"
+	}
+	return event
+}
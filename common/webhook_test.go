@@ -0,0 +1,61 @@
+// Copyright © 2019 Ettore Di Giacinto <mudler@gentoo.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package common_test
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/MottainaiCI/mottainai-cli/common"
+)
+
+var _ = Describe("WebHook", func() {
+	Describe("WebHookPayload", func() {
+		It("crafts a push event", func() {
+			data, err := WebHookPayload(WEBHOOK_GITLAB, "org/repo", "master", "abc")
+			Expect(err).ToNot(HaveOccurred())
+
+			var payload map[string]interface{}
+			Expect(json.Unmarshal(data, &payload)).ToNot(HaveOccurred())
+			Expect(payload["ref"]).To(Equal("refs/heads/master"))
+			Expect(payload["checkout_sha"]).To(Equal("abc"))
+		})
+
+		It("rejects unknown webhook types", func() {
+			_, err := WebHookPayload("svn", "org/repo", "master", "abc")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("NewWebHookRequest", func() {
+		It("signs GitHub payloads", func() {
+			r, err := NewWebHookRequest("http://master/", WEBHOOK_GITHUB, "k", "push", "secret", []byte("{}"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(r.URL.String()).To(Equal("http://master/webhook/k/github"))
+			Expect(r.Header.Get("X-GitHub-Event")).To(Equal("push"))
+			Expect(r.Header.Get("X-Hub-Signature")).To(Equal("sha1=5d61605c3feea9799210ddcb71307d4ba264225f"))
+		})
+
+		It("maps GitLab events", func() {
+			r, err := NewWebHookRequest("http://master", WEBHOOK_GITLAB, "k", "push", "secret", []byte("{}"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(r.Header.Get("X-Gitlab-Event")).To(Equal("Push Hook"))
+			Expect(r.Header.Get("X-Gitlab-Token")).To(Equal("secret"))
+		})
+	})
+})